@@ -18,11 +18,13 @@ package ceph
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/rook/rook/cmd/rook/rook"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/daemon/ceph/agent"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
 	"github.com/rook/rook/pkg/util/exec"
 	"github.com/rook/rook/pkg/util/flags"
 	"github.com/spf13/cobra"
@@ -34,7 +36,15 @@ var agentCmd = &cobra.Command{
 	Hidden: true,
 }
 
+var (
+	nodeLostTimeout     time.Duration
+	agentMetricsAddress string
+)
+
 func init() {
+	agentCmd.Flags().DurationVar(&nodeLostTimeout, "node-lost-timeout", 5*time.Minute,
+		"time after which the volume attachments of a node that is not ready are fenced and removed. 0 to disable")
+	agentCmd.Flags().StringVar(&agentMetricsAddress, "metrics-address", metrics.DefaultAgentAddress, "address to serve the prometheus metrics on. 0 to disable")
 	flags.SetFlagsFromEnv(agentCmd.Flags(), rook.RookEnvVarPrefix)
 	agentCmd.RunE = startAgent
 }
@@ -51,6 +61,7 @@ func startAgent(cmd *cobra.Command, args []string) error {
 	}
 
 	logger.Info("starting rook ceph agent")
+	metrics.Serve(agentMetricsAddress)
 	context := &clusterd.Context{
 		Executor:              &exec.CommandExecutor{},
 		ConfigDir:             k8sutil.DataDir,
@@ -58,9 +69,10 @@ func startAgent(cmd *cobra.Command, args []string) error {
 		Clientset:             clientset,
 		APIExtensionClientset: apiExtClientset,
 		RookClientset:         rookClientset,
+		Recorder:              k8sutil.NewEventRecorder(clientset, "rook-ceph-agent"),
 	}
 
-	agent := agent.New(context)
+	agent := agent.New(context, nodeLostTimeout)
 	err = agent.Run()
 	if err != nil {
 		rook.TerminateFatal(fmt.Errorf("failed to run rook ceph agent. %+v\n", err))
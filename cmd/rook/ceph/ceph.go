@@ -62,6 +62,7 @@ func AddCommands(command *cobra.Command) {
 	command.AddCommand(operatorCmd)
 	command.AddCommand(agentCmd)
 	command.AddCommand(monCmd)
+	command.AddCommand(monRecoverCmd)
 	command.AddCommand(osdCmd)
 	command.AddCommand(mgrCmd)
 	command.AddCommand(rgwCmd)
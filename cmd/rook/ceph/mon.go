@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/go-ini/ini"
 	"github.com/rook/rook/cmd/rook/rook"
@@ -35,9 +36,16 @@ var monCmd = &cobra.Command{
 	Hidden: true,
 }
 
+var monRecoverCmd = &cobra.Command{
+	Use:    mondaemon.RecoverCommand,
+	Short:  "Removes lost mons from the monmap of a surviving mon",
+	Hidden: true,
+}
+
 var (
-	monName string
-	monPort int32
+	monName      string
+	monPort      int32
+	monsToRemove string
 )
 
 func init() {
@@ -48,6 +56,15 @@ func init() {
 	flags.SetFlagsFromEnv(monCmd.Flags(), rook.RookEnvVarPrefix)
 
 	monCmd.RunE = initMon
+
+	monRecoverCmd.Flags().StringVar(&monName, "name", "", "name of the surviving monitor")
+	monRecoverCmd.Flags().Int32Var(&monPort, "port", 0, "port of the surviving monitor")
+	monRecoverCmd.Flags().StringVar(&monsToRemove, "remove-mons", "", "comma-separated list of the lost monitors to remove from the monmap")
+	addCephFlags(monRecoverCmd)
+
+	flags.SetFlagsFromEnv(monRecoverCmd.Flags(), rook.RookEnvVarPrefix)
+
+	monRecoverCmd.RunE = recoverMon
 }
 
 func initMon(cmd *cobra.Command, args []string) error {
@@ -89,6 +106,46 @@ func initMon(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func recoverMon(cmd *cobra.Command, args []string) error {
+	required := []string{"name", "remove-mons", "fsid", "mon-secret", "admin-secret", "config-dir", "cluster-name"}
+	if err := flags.VerifyRequiredFlags(monRecoverCmd, required); err != nil {
+		return err
+	}
+
+	if err := verifyRenamedFlags(monRecoverCmd); err != nil {
+		return err
+	}
+
+	rook.SetLogLevel()
+
+	rook.LogStartupInfo(monRecoverCmd.Flags())
+
+	if monPort == 0 {
+		return fmt.Errorf("missing mon port")
+	}
+
+	if err := compareMonSecret(clusterInfo.MonitorSecret, mondaemon.GetMonRunDirPath(cfg.dataDir, monName)); err != nil {
+		rook.TerminateFatal(err)
+	}
+
+	// the surviving monitor is the only member of the recovered quorum
+	clusterInfo.Monitors = map[string]*cephconfig.MonInfo{
+		monName: cephconfig.NewMonInfo(monName, cfg.NetworkInfo().PublicAddr, monPort),
+	}
+
+	monCfg := &mondaemon.Config{
+		Name:    monName,
+		Cluster: &clusterInfo,
+		Port:    monPort,
+	}
+	err := mondaemon.RemoveMonsFromMonmap(createContext(), monCfg, strings.Split(monsToRemove, ","))
+	if err != nil {
+		rook.TerminateFatal(err)
+	}
+
+	return nil
+}
+
 // Compare the expected mon keyring secret with the cached keyring from a previous run of the monitor.
 // If these don't match we will not want to launch the monitor.
 func compareMonSecret(secret, configDir string) error {
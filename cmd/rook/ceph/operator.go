@@ -25,12 +25,15 @@ import (
 	"github.com/rook/rook/pkg/operator/ceph"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
 	"github.com/rook/rook/pkg/util/flags"
 	"github.com/spf13/cobra"
 )
 
 const containerName = "rook-ceph-operator"
 
+var operatorMetricsAddress string
+
 var operatorCmd = &cobra.Command{
 	Use:   "operator",
 	Short: "Runs the Ceph operator for orchestrating and managing Ceph storage in a Kubernetes cluster",
@@ -42,6 +45,7 @@ https://github.com/rook/rook`,
 func init() {
 	operatorCmd.Flags().DurationVar(&mon.HealthCheckInterval, "mon-healthcheck-interval", mon.HealthCheckInterval, "mon health check interval (duration)")
 	operatorCmd.Flags().DurationVar(&mon.MonOutTimeout, "mon-out-timeout", mon.MonOutTimeout, "mon out timeout (duration)")
+	operatorCmd.Flags().StringVar(&operatorMetricsAddress, "metrics-address", metrics.DefaultOperatorAddress, "address to serve the prometheus metrics on. 0 to disable")
 	flags.SetFlagsFromEnv(operatorCmd.Flags(), rook.RookEnvVarPrefix)
 
 	operatorCmd.RunE = startOperator
@@ -59,12 +63,14 @@ func startOperator(cmd *cobra.Command, args []string) error {
 	}
 
 	logger.Infof("starting operator")
+	metrics.Serve(operatorMetricsAddress)
 	context := createContext()
 	context.NetworkInfo = clusterd.NetworkInfo{}
 	context.ConfigDir = k8sutil.DataDir
 	context.Clientset = clientset
 	context.APIExtensionClientset = apiExtClientset
 	context.RookClientset = rookClientset
+	context.Recorder = k8sutil.NewEventRecorder(clientset, containerName)
 	volumeAttachment, err := attachment.New(context)
 	if err != nil {
 		rook.TerminateFatal(err)
@@ -17,11 +17,13 @@ limitations under the License.
 package ceph
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/rook/rook/cmd/rook/rook"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	"github.com/rook/rook/pkg/daemon/ceph/client"
 	mondaemon "github.com/rook/rook/pkg/daemon/ceph/mon"
 	osddaemon "github.com/rook/rook/pkg/daemon/ceph/osd"
@@ -54,12 +56,21 @@ var filestoreDeviceCmd = &cobra.Command{
 	Short:  "Runs the ceph daemon for a filestore device",
 	Hidden: true,
 }
+var zapCmd = &cobra.Command{
+	Use:    "zap",
+	Short:  "Wipes the devices and cleans the data dir of the osds on a node",
+	Hidden: true,
+}
 var (
-	osdDataDeviceFilter string
-	ownerRefID          string
-	mountSourcePath     string
-	mountPath           string
-	osdID               int
+	osdDataDeviceFilter   string
+	osdDataDeviceSelector string
+	ownerRefID            string
+	mountSourcePath       string
+	mountPath             string
+	osdID                 int
+	zapDevices            string
+	zapDataDir            string
+	zapForce              bool
 )
 
 func addOSDFlags(command *cobra.Command) {
@@ -69,6 +80,7 @@ func addOSDFlags(command *cobra.Command) {
 	// flags specific to provisioning
 	provisionCmd.Flags().StringVar(&cfg.devices, "data-devices", "", "comma separated list of devices to use for storage")
 	provisionCmd.Flags().StringVar(&osdDataDeviceFilter, "data-device-filter", "", "a regex filter for the device names to use, or \"all\"")
+	provisionCmd.Flags().StringVar(&osdDataDeviceSelector, "data-device-selector", "", "json of the selectors that narrow down the devices of the filter by size, rotational, vendor, model and path")
 	provisionCmd.Flags().StringVar(&cfg.directories, "data-directories", "", "comma separated list of directory paths to use for storage")
 	provisionCmd.Flags().StringVar(&cfg.metadataDevice, "metadata-device", "", "device to use for metadata (e.g. a high performance SSD/NVMe device)")
 	provisionCmd.Flags().BoolVar(&cfg.forceFormat, "force-format", false,
@@ -81,10 +93,16 @@ func addOSDFlags(command *cobra.Command) {
 	filestoreDeviceCmd.Flags().StringVar(&mountSourcePath, "source-path", "", "the source path of the device to mount")
 	filestoreDeviceCmd.Flags().StringVar(&mountPath, "mount-path", "", "the path where the device should be mounted")
 
+	// flags for cleaning up the osds on a node
+	zapCmd.Flags().StringVar(&zapDevices, "devices", "", "comma separated list of devices to wipe")
+	zapCmd.Flags().StringVar(&zapDataDir, "data-dir", "", "the data dir on the host whose contents are deleted")
+	zapCmd.Flags().BoolVar(&zapForce, "force", false, "true to wipe the devices even if they were not provisioned by rook.  BE CAREFUL!")
+
 	// add the subcommands to the parent osd command
 	osdCmd.AddCommand(osdConfigCmd)
 	osdCmd.AddCommand(provisionCmd)
 	osdCmd.AddCommand(filestoreDeviceCmd)
+	osdCmd.AddCommand(zapCmd)
 }
 
 func addOSDConfigFlags(command *cobra.Command) {
@@ -106,10 +124,12 @@ func init() {
 	flags.SetFlagsFromEnv(osdConfigCmd.Flags(), rook.RookEnvVarPrefix)
 	flags.SetFlagsFromEnv(provisionCmd.Flags(), rook.RookEnvVarPrefix)
 	flags.SetFlagsFromEnv(filestoreDeviceCmd.Flags(), rook.RookEnvVarPrefix)
+	flags.SetFlagsFromEnv(zapCmd.Flags(), rook.RookEnvVarPrefix)
 
 	osdConfigCmd.RunE = writeOSDConfig
 	provisionCmd.RunE = prepareOSD
 	filestoreDeviceCmd.RunE = runFilestoreDeviceOSD
+	zapCmd.RunE = zapOSDs
 }
 
 // Start the osd daemon for filestore running on a device
@@ -198,6 +218,14 @@ func prepareOSD(cmd *cobra.Command, args []string) error {
 		dataDevices = cfg.devices
 	}
 
+	var deviceSelection *rookalpha.Selection
+	if osdDataDeviceSelector != "" {
+		deviceSelection = &rookalpha.Selection{}
+		if err := json.Unmarshal([]byte(osdDataDeviceSelector), deviceSelection); err != nil {
+			return fmt.Errorf("invalid device selector %s. %+v", osdDataDeviceSelector, err)
+		}
+	}
+
 	clientset, _, rookClientset, err := rook.GetClientset()
 	if err != nil {
 		rook.TerminateFatal(fmt.Errorf("failed to init k8s client. %+v\n", err))
@@ -217,7 +245,7 @@ func prepareOSD(cmd *cobra.Command, args []string) error {
 	forceFormat := false
 	ownerRef := cluster.ClusterOwnerRef(clusterInfo.Name, ownerRefID)
 	kv := k8sutil.NewConfigMapKVStore(clusterInfo.Name, clientset, ownerRef)
-	agent := osddaemon.NewAgent(context, dataDevices, usingDeviceFilter, cfg.metadataDevice, cfg.directories, forceFormat,
+	agent := osddaemon.NewAgent(context, dataDevices, usingDeviceFilter, deviceSelection, cfg.metadataDevice, cfg.directories, forceFormat,
 		crushLocation, cfg.storeConfig, &clusterInfo, cfg.nodeName, kv)
 
 	err = osddaemon.Provision(context, agent)
@@ -235,6 +263,29 @@ func prepareOSD(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// Wipe the osd devices and clean the data dir on the node
+func zapOSDs(cmd *cobra.Command, args []string) error {
+	rook.SetLogLevel()
+	rook.LogStartupInfo(zapCmd.Flags())
+
+	if zapDevices == "" && zapDataDir == "" {
+		return fmt.Errorf("--devices or --data-dir must be specified")
+	}
+
+	context := createContext()
+	if zapDevices != "" {
+		if err := osddaemon.ZapDevices(context, strings.Split(zapDevices, ","), zapForce); err != nil {
+			rook.TerminateFatal(err)
+		}
+	}
+	if zapDataDir != "" {
+		if err := osddaemon.CleanDataDir(zapDataDir); err != nil {
+			rook.TerminateFatal(err)
+		}
+	}
+	return nil
+}
+
 func commonOSDInit(cmd *cobra.Command) {
 	rook.SetLogLevel()
 	rook.LogStartupInfo(cmd.Flags())
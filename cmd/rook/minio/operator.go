@@ -20,6 +20,7 @@ import (
 
 	"github.com/rook/rook/cmd/rook/rook"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
 	"github.com/rook/rook/pkg/operator/minio"
 	"github.com/rook/rook/pkg/util/flags"
 	"github.com/spf13/cobra"
@@ -35,9 +36,11 @@ var (
 		Long: `Tool for running the rook storage components in a kubernetes cluster.
 	https://github.com/rook/rook`,
 	}
+	metricsAddress string
 )
 
 func init() {
+	operatorCmd.Flags().StringVar(&metricsAddress, "metrics-address", metrics.DefaultOperatorAddress, "address to serve the prometheus metrics on. 0 to disable")
 	flags.SetFlagsFromEnv(operatorCmd.Flags(), rook.RookEnvVarPrefix)
 	operatorCmd.RunE = startOperator
 }
@@ -53,10 +56,12 @@ func startOperator(cmd *cobra.Command, args []string) error {
 	}
 
 	logger.Infof("starting operator")
+	metrics.Serve(metricsAddress)
 	context := createContext()
 	context.Clientset = clientset
 	context.APIExtensionClientset = apiExtClientset
 	context.RookClientset = rookClientset
+	context.Recorder = k8sutil.NewEventRecorder(clientset, containerName)
 	if err != nil {
 		rook.TerminateFatal(err)
 	}
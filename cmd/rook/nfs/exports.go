@@ -0,0 +1,51 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfs
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/cmd/rook/rook"
+	"github.com/rook/rook/pkg/daemon/nfs"
+	"github.com/rook/rook/pkg/util/flags"
+	"github.com/spf13/cobra"
+)
+
+var watchExportsCmd = &cobra.Command{
+	Use:    "watch-exports",
+	Short:  "Reloads the exports of the NFS server when its config changes",
+	Hidden: true,
+}
+
+var ganeshaConfigFile string
+
+func init() {
+	watchExportsCmd.Flags().StringVar(&ganeshaConfigFile, "config-file", "/nfs-ganesha/config/nfs-ganesha-config", "path to the ganesha config file")
+	flags.SetFlagsFromEnv(watchExportsCmd.Flags(), rook.RookEnvVarPrefix)
+	watchExportsCmd.RunE = startWatchExports
+}
+
+func startWatchExports(cmd *cobra.Command, args []string) error {
+	rook.SetLogLevel()
+	rook.LogStartupInfo(watchExportsCmd.Flags())
+
+	context := createContext()
+	if err := nfs.WatchExports(context, ganeshaConfigFile); err != nil {
+		rook.TerminateFatal(fmt.Errorf("failed to watch the exports. %+v", err))
+	}
+	return nil
+}
@@ -36,6 +36,8 @@ var (
 
 func init() {
 	Cmd.AddCommand(operatorCmd)
+	Cmd.AddCommand(watchExportsCmd)
+	Cmd.AddCommand(provisionerCmd)
 }
 
 func createContext() *clusterd.Context {
@@ -21,6 +21,7 @@ import (
 	"github.com/rook/rook/cmd/rook/rook"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
 	operator "github.com/rook/rook/pkg/operator/nfs"
 	"github.com/rook/rook/pkg/util/flags"
 	"github.com/spf13/cobra"
@@ -28,6 +29,8 @@ import (
 
 const containerName = "rook-nfs-operator"
 
+var metricsAddress string
+
 var operatorCmd = &cobra.Command{
 	Use:   "operator",
 	Short: "Runs the NFS operator to deploy and manage NFS server in kubernetes clusters",
@@ -36,6 +39,7 @@ https://github.com/rook/rook`,
 }
 
 func init() {
+	operatorCmd.Flags().StringVar(&metricsAddress, "metrics-address", metrics.DefaultOperatorAddress, "address to serve the prometheus metrics on. 0 to disable")
 	flags.SetFlagsFromEnv(operatorCmd.Flags(), rook.RookEnvVarPrefix)
 
 	operatorCmd.RunE = startOperator
@@ -51,12 +55,14 @@ func startOperator(cmd *cobra.Command, args []string) error {
 	}
 
 	logger.Infof("starting NFS operator")
+	metrics.Serve(metricsAddress)
 	context := createContext()
 	context.NetworkInfo = clusterd.NetworkInfo{}
 	context.ConfigDir = k8sutil.DataDir
 	context.Clientset = clientset
 	context.APIExtensionClientset = apiExtClientset
 	context.RookClientset = rookClientset
+	context.Recorder = k8sutil.NewEventRecorder(clientset, containerName)
 
 	// Using the current image version to deploy other rook pods
 	pod, err := k8sutil.GetRunningPod(clientset)
@@ -0,0 +1,83 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfs
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/rook/rook/cmd/rook/rook"
+	"github.com/rook/rook/pkg/operator/ceph/provisioner/controller"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	operator "github.com/rook/rook/pkg/operator/nfs"
+	"github.com/rook/rook/pkg/util/flags"
+	"github.com/spf13/cobra"
+)
+
+var provisionerCmd = &cobra.Command{
+	Use:    "provisioner",
+	Short:  "Provisions volumes in the exports of an NFS server",
+	Hidden: true,
+}
+
+var nfsServerName string
+
+func init() {
+	provisionerCmd.Flags().StringVar(&nfsServerName, "nfs-server", "", "name of the nfs server whose exports back the volumes")
+	flags.SetFlagsFromEnv(provisionerCmd.Flags(), rook.RookEnvVarPrefix)
+	provisionerCmd.RunE = startProvisioner
+}
+
+func startProvisioner(cmd *cobra.Command, args []string) error {
+	rook.SetLogLevel()
+	rook.LogStartupInfo(provisionerCmd.Flags())
+
+	if nfsServerName == "" {
+		rook.TerminateFatal(fmt.Errorf("--nfs-server is required"))
+	}
+	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
+
+	clientset, apiExtClientset, rookClientset, err := rook.GetClientset()
+	if err != nil {
+		rook.TerminateFatal(fmt.Errorf("failed to get k8s clients. %+v", err))
+	}
+	context := createContext()
+	context.Clientset = clientset
+	context.APIExtensionClientset = apiExtClientset
+	context.RookClientset = rookClientset
+
+	serverVersion, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		rook.TerminateFatal(fmt.Errorf("failed to get server version. %+v", err))
+	}
+
+	name := operator.ProvisionerName(namespace, nfsServerName)
+	pc := controller.NewProvisionController(clientset, name, operator.NewProvisioner(context, namespace, nfsServerName), serverVersion.GitVersion)
+
+	signalChan := make(chan os.Signal, 1)
+	stopChan := make(chan struct{})
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	go pc.Run(stopChan)
+	logger.Infof("nfs provisioner %s started", name)
+
+	<-signalChan
+	logger.Infof("shutdown signal received, exiting...")
+	close(stopChan)
+	return nil
+}
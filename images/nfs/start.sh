@@ -41,6 +41,12 @@ function init_dbus {
 	sleep 1
 }
 
+function watch_exports {
+	# reload the exports when the operator updates the config, without restarting ganesha
+	echo "Watching the exports"
+	/usr/local/bin/rook nfs watch-exports --config-file=${GANESHA_CONFIGFILE} &
+}
+
 function startup_script {
 	if [ -f "${STARTUP_SCRIPT}" ]; then
   	/bin/sh ${STARTUP_SCRIPT}
@@ -51,6 +57,7 @@ startup_script
 
 init_rpc
 init_dbus
+watch_exports
 
 echo "Starting Ganesha NFS"
 export LD_LIBRARY_PATH=$LD_LIBRARY_PATH:/usr/lib
@@ -0,0 +1,50 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1beta1
+
+import (
+	"fmt"
+	"time"
+)
+
+// GetHealthCheckInterval returns the interval to check the mon quorum, or the default if not set
+func (s MonSpec) GetHealthCheckInterval(defaultValue time.Duration) (time.Duration, error) {
+	return parseDuration(s.HealthCheckInterval, defaultValue)
+}
+
+// GetOutTimeout returns the duration to wait before failing over a mon, or the default if not set
+func (s MonSpec) GetOutTimeout(defaultValue time.Duration) (time.Duration, error) {
+	return parseDuration(s.OutTimeout, defaultValue)
+}
+
+// GetHealthCheckInterval returns the interval to check the osd status, or the default if not set
+func (s OSDSpec) GetHealthCheckInterval(defaultValue time.Duration) (time.Duration, error) {
+	return parseDuration(s.HealthCheckInterval, defaultValue)
+}
+
+func parseDuration(value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return defaultValue, fmt.Errorf("invalid duration %s. %+v", value, err)
+	}
+	if d <= 0 {
+		return defaultValue, fmt.Errorf("duration %s must be positive", value)
+	}
+	return d, nil
+}
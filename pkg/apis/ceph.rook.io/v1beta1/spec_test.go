@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/ghodss/yaml"
 	"github.com/stretchr/testify/assert"
@@ -32,6 +33,11 @@ dataDirHostPath: /var/lib/rook
 mon:
   count: 5
   allowMultiplePerNode: false
+  healthCheckInterval: 20s
+  outTimeout: 10m
+  preventFailover: true
+osd:
+  healthCheckInterval: 2m
 network:
   hostNetwork: true
 storage:
@@ -70,6 +76,12 @@ storage:
 		Mon: MonSpec{
 			Count:                5,
 			AllowMultiplePerNode: false,
+			HealthCheckInterval:  "20s",
+			OutTimeout:           "10m",
+			PreventFailover:      true,
+		},
+		OSD: OSDSpec{
+			HealthCheckInterval: "2m",
 		},
 		DataDirHostPath: "/var/lib/rook",
 		Network: rookalpha.NetworkSpec{
@@ -110,3 +122,32 @@ storage:
 
 	assert.Equal(t, expectedSpec, clusterSpec)
 }
+
+func TestHealthSettings(t *testing.T) {
+	// defaults are used when the settings are not set
+	mon := MonSpec{}
+	interval, err := mon.GetHealthCheckInterval(45 * time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, 45*time.Second, interval)
+	timeout, err := mon.GetOutTimeout(5 * time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, 5*time.Minute, timeout)
+
+	mon = MonSpec{HealthCheckInterval: "20s", OutTimeout: "1h"}
+	interval, err = mon.GetHealthCheckInterval(45 * time.Second)
+	assert.Nil(t, err)
+	assert.Equal(t, 20*time.Second, interval)
+	timeout, err = mon.GetOutTimeout(5 * time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Hour, timeout)
+
+	// invalid settings fall back to the default
+	osd := OSDSpec{HealthCheckInterval: "5 minutes"}
+	interval, err = osd.GetHealthCheckInterval(time.Minute)
+	assert.NotNil(t, err)
+	assert.Equal(t, time.Minute, interval)
+	osd = OSDSpec{HealthCheckInterval: "-1m"}
+	interval, err = osd.GetHealthCheckInterval(time.Minute)
+	assert.NotNil(t, err)
+	assert.Equal(t, time.Minute, interval)
+}
@@ -69,14 +69,63 @@ type ClusterSpec struct {
 	// A spec for mon related options
 	Mon MonSpec `json:"mon"`
 
+	// A spec for osd related options
+	OSD OSDSpec `json:"osd,omitempty"`
+
+	// A spec for mgr related options
+	Mgr MgrSpec `json:"mgr,omitempty"`
+
 	// Dashboard settings
 	Dashboard DashboardSpec `json:"dashboard,omitempty"`
+
+	// The policy for cleaning up the hosts when the cluster is deleted
+	CleanupPolicy CleanupPolicySpec `json:"cleanupPolicy,omitempty"`
+
+	// Prometheus Operator settings for the metrics of the cluster
+	Monitoring MonitoringSpec `json:"monitoring,omitempty"`
 }
 
 // DashboardSpec represents the settings for the Ceph dashboard
 type DashboardSpec struct {
 	// Whether to enable the dashboard
 	Enabled bool `json:"enabled,omitempty"`
+
+	// The port on which the dashboard listens. Defaults to 7000, or 8443 if ssl is enabled.
+	Port int `json:"port,omitempty"`
+
+	// The URL prefix under which the dashboard is served, for example when accessed through an ingress
+	URLPrefix string `json:"urlPrefix,omitempty"`
+
+	// Whether to serve the dashboard with SSL
+	SSL bool `json:"ssl,omitempty"`
+
+	// The name of a secret of type kubernetes.io/tls with the certificate for the dashboard. If not set
+	// and ssl is enabled, a self-signed certificate is generated.
+	CertificateSecret string `json:"certificateSecret,omitempty"`
+
+	// The name of the dashboard admin user. Defaults to "admin".
+	AdminUsername string `json:"adminUsername,omitempty"`
+}
+
+// MonitoringSpec represents the settings for monitoring the cluster with the Prometheus Operator
+type MonitoringSpec struct {
+	// Whether to create a ServiceMonitor for the mgr metrics and a PrometheusRule with the Ceph alerts
+	Enabled bool `json:"enabled,omitempty"`
+
+	// The labels added to the ServiceMonitor and PrometheusRule so they are selected by a Prometheus instance
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// The interval at which the mgr metrics are scraped, for example "30s". Defaults to the Prometheus setting.
+	Interval string `json:"interval,omitempty"`
+}
+
+// CleanupPolicySpec represents the cleanup of the hosts when the cluster is deleted
+type CleanupPolicySpec struct {
+	// Whether to wipe the devices that were provisioned as OSDs by the cluster
+	WipeDevices bool `json:"wipeDevices,omitempty"`
+
+	// Whether to delete the contents of the dataDirHostPath on each node
+	DeleteDataDir bool `json:"deleteDataDir,omitempty"`
 }
 
 type ClusterStatus struct {
@@ -96,6 +145,42 @@ const (
 type MonSpec struct {
 	Count                int  `json:"count"`
 	AllowMultiplePerNode bool `json:"allowMultiplePerNode"`
+
+	// The interval to check if the mons are in quorum (e.g. "45s")
+	HealthCheckInterval string `json:"healthCheckInterval,omitempty"`
+
+	// The duration to wait for a mon to rejoin quorum before it is failed over (e.g. "5m")
+	OutTimeout string `json:"outTimeout,omitempty"`
+
+	// Whether to prevent the failover of mons that are out of quorum, for example while nodes are under maintenance
+	PreventFailover bool `json:"preventFailover,omitempty"`
+}
+
+// OSDSpec represents the settings for the osd health checks
+type OSDSpec struct {
+	// The interval to check the status of the osds (e.g. "60s")
+	HealthCheckInterval string `json:"healthCheckInterval,omitempty"`
+}
+
+// MgrSpec represents the settings for the mgrs
+type MgrSpec struct {
+	// The number of mgrs to start. One mgr is active while the others are standby.
+	Count int `json:"count,omitempty"`
+
+	// The mgr modules to enable or disable, in addition to the modules managed by the operator
+	Modules []MgrModuleSpec `json:"modules,omitempty"`
+}
+
+// MgrModuleSpec represents the settings of a mgr module
+type MgrModuleSpec struct {
+	// The name of the module (e.g. "balancer")
+	Name string `json:"name"`
+
+	// Whether the module is enabled. A module that is not enabled is disabled in the mgr.
+	Enabled bool `json:"enabled"`
+
+	// The configuration settings of the module
+	Settings map[string]string `json:"settings,omitempty"`
 }
 
 // +genclient
@@ -246,3 +331,85 @@ type GatewaySpec struct {
 	// The resource requirements for the rgw pods
 	Resources v1.ResourceRequirements `json:"resources"`
 }
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type Bucket struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              BucketSpec   `json:"spec"`
+	Status            BucketStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type BucketList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []Bucket `json:"items"`
+}
+
+// BucketSpec represents the spec of a bucket in an object store
+type BucketSpec struct {
+	// The name of the object store in the same namespace that hosts the bucket
+	Store string `json:"store"`
+
+	// The id of the object store user that owns the bucket
+	Owner string `json:"owner"`
+
+	// The quota of the bucket
+	Quota BucketQuotaSpec `json:"quota,omitempty"`
+
+	// Whether the objects in the bucket are versioned
+	Versioning bool `json:"versioning,omitempty"`
+
+	// The rules to expire the objects in the bucket
+	Lifecycle []BucketLifecycleRule `json:"lifecycle,omitempty"`
+
+	// The bucket policy as a JSON document
+	Policy string `json:"policy,omitempty"`
+}
+
+// BucketQuotaSpec represents the quota of a bucket. Zero values are unlimited.
+type BucketQuotaSpec struct {
+	// The maximum size of the bucket, for example 10Gi
+	MaxSize string `json:"maxSize,omitempty"`
+
+	// The maximum number of objects in the bucket
+	MaxObjects int64 `json:"maxObjects,omitempty"`
+}
+
+// BucketLifecycleRule represents a rule to expire the objects in a bucket
+type BucketLifecycleRule struct {
+	// The id of the rule
+	ID string `json:"id"`
+
+	// The prefix of the objects the rule applies to. The rule applies to all objects if empty.
+	Prefix string `json:"prefix,omitempty"`
+
+	// The number of days after which the objects expire
+	ExpirationDays int `json:"expirationDays,omitempty"`
+
+	// The number of days after which noncurrent versions of the objects expire
+	NoncurrentVersionExpirationDays int `json:"noncurrentVersionExpirationDays,omitempty"`
+}
+
+type BucketStatus struct {
+	State   BucketState `json:"state,omitempty"`
+	Message string      `json:"message,omitempty"`
+
+	// The size of the objects in the bucket in bytes
+	Size uint64 `json:"size"`
+
+	// The number of objects in the bucket
+	NumberOfObjects uint64 `json:"numberOfObjects"`
+}
+
+type BucketState string
+
+const (
+	BucketStateCreated BucketState = "Created"
+	BucketStateError   BucketState = "Error"
+)
@@ -35,7 +35,8 @@ import (
 type Cluster struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
-	Spec              ClusterSpec `json:"spec"`
+	Spec              ClusterSpec   `json:"spec"`
+	Status            ClusterStatus `json:"status,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -53,4 +54,42 @@ type ClusterSpec struct {
 	VolumeSize          resource.Quantity     `json:"volumeSize,omitempty"`
 	CachePercent        int                   `json:"cachePercent,omitempty"`
 	MaxSQLMemoryPercent int                   `json:"maxSQLMemoryPercent,omitempty"`
+	Certificates        CertificatesSpec      `json:"certificates,omitempty"`
+	// The cockroachdb image of the database pods. Defaults to the image of the operator.
+	Image string `json:"image,omitempty"`
 }
+
+// ClusterStatus represents the state of a cluster and the reason of its last failure
+type ClusterStatus struct {
+	State   ClusterState `json:"state,omitempty"`
+	Message string       `json:"message,omitempty"`
+}
+
+// ClusterState is the state of a cluster
+type ClusterState string
+
+const (
+	// ClusterStateCreated means the cluster is created and its last update succeeded
+	ClusterStateCreated ClusterState = "Created"
+	// ClusterStateUpdating means an update of the cluster is in progress, the message reports the current step
+	ClusterStateUpdating ClusterState = "Updating"
+	// ClusterStateError means the last update of the cluster failed or was rejected
+	ClusterStateError ClusterState = "Error"
+)
+
+// CertificatesSpec represents how the certificates of a secure cluster are issued
+type CertificatesSpec struct {
+	// The issuer of the node and client certificates. SelfSigned (the default) creates a CA in a secret of the
+	// cluster namespace, Kubernetes signs the certificates with the certificates API of the Kubernetes cluster.
+	Issuer CertificateIssuer `json:"issuer,omitempty"`
+}
+
+// CertificateIssuer is the issuer of the certificates of a secure cluster
+type CertificateIssuer string
+
+const (
+	// CertificateIssuerSelfSigned issues the certificates with a CA created by the operator
+	CertificateIssuerSelfSigned CertificateIssuer = "SelfSigned"
+	// CertificateIssuerKubernetes issues the certificates with the certificates API of the Kubernetes cluster
+	CertificateIssuerKubernetes CertificateIssuer = "Kubernetes"
+)
@@ -63,4 +63,9 @@ type ObjectStoreSpec struct {
 
 	// The amount of storage that will be available in the object store.
 	StorageSize string `json:"storageAmount"`
+
+	// The name of a secret in the namespace of the object store with the TLS certificate of the Minio servers in the
+	// tls.crt and tls.key keys, and optionally the certificate of the CA in the ca.crt key. If set, the servers
+	// are accessed with https.
+	TLSSecret string `json:"tlsSecret,omitempty"`
 }
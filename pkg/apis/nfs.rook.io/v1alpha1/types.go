@@ -33,7 +33,8 @@ import (
 type NFSServer struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
-	Spec              NFSServerSpec `json:"spec"`
+	Spec              NFSServerSpec   `json:"spec"`
+	Status            NFSServerStatus `json:"status,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -44,6 +45,40 @@ type NFSServerList struct {
 	Items           []NFSServer `json:"items"`
 }
 
+// NFSServerStatus represents the status of the NFS server
+type NFSServerStatus struct {
+	State   NFSServerState `json:"state,omitempty"`
+	Message string         `json:"message,omitempty"`
+
+	// The name of the provisioner to use in a storage class to provision volumes from the exports
+	Provisioner string `json:"provisioner,omitempty"`
+
+	// The status of each export
+	Exports []ExportStatus `json:"exports,omitempty"`
+}
+
+// ExportStatus represents the status of an NFS export
+type ExportStatus struct {
+	// Name of the export
+	Name string `json:"name"`
+
+	// The path of the export in the NFS server
+	Path string `json:"path"`
+
+	// The address to mount the export from, in the form <service ip>:<path>
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Whether an NFS server pod is ready to serve the export
+	Ready bool `json:"ready"`
+}
+
+type NFSServerState string
+
+const (
+	NFSServerStateCreated NFSServerState = "Created"
+	NFSServerStateError   NFSServerState = "Error"
+)
+
 // NFSSpec represents the spec of NFS daemon
 type NFSServerSpec struct {
 	// Replicas of the NFS daemon
@@ -51,6 +86,21 @@ type NFSServerSpec struct {
 
 	// The parameters to configure the NFS export
 	Exports []ExportsSpec `json:"exports,omitempty"`
+
+	// The kerberos settings of the NFS server, required by the exports with a krb5 security flavor
+	Kerberos KerberosSpec `json:"kerberos,omitempty"`
+}
+
+// KerberosSpec represents the kerberos settings of the NFS server
+type KerberosSpec struct {
+	// The principal name of the NFS server. Defaults to "nfs".
+	PrincipalName string `json:"principalName,omitempty"`
+
+	// The name of the secret with the keytab of the NFS server in the "krb5.keytab" key
+	KeytabSecret string `json:"keytabSecret,omitempty"`
+
+	// The name of the config map with the kerberos configuration in the "krb5.conf" key
+	ConfigMap string `json:"configMap,omitempty"`
 }
 
 // ExportsSpec represents the spec of NFS exports
@@ -63,6 +113,21 @@ type ExportsSpec struct {
 
 	// PVC from which the NFS daemon gets storage for sharing
 	PersistentVolumeClaim v1.PersistentVolumeClaimVolumeSource `json:"persistentVolumeClaim,omitempty"`
+
+	// CephFS filesystem of a rook ceph cluster that is shared instead of a PVC
+	CephFS *CephFSExportSpec `json:"cephfs,omitempty"`
+}
+
+// CephFSExportSpec represents a path in a CephFS filesystem to be shared by the NFS server
+type CephFSExportSpec struct {
+	// The namespace of the rook ceph cluster
+	ClusterNamespace string `json:"clusterNamespace"`
+
+	// The name of the filesystem
+	Filesystem string `json:"filesystem"`
+
+	// The path in the filesystem to share. Defaults to "/".
+	Path string `json:"path,omitempty"`
 }
 
 // ServerSpec represents the spec for configuring the NFS server
@@ -78,6 +143,18 @@ type ServerSpec struct {
 
 	// The clients allowed to access the NFS export
 	AllowedClients []AllowedClientsSpec `json:"allowedClients,omitempty"`
+
+	// The NFS protocol versions of the export
+	// Valid values are 3 and 4. Defaults to 4.
+	Protocols []int `json:"protocols,omitempty"`
+
+	// The transport protocols of the export
+	// Valid values are "TCP" and "UDP". Defaults to "TCP".
+	Transports []string `json:"transports,omitempty"`
+
+	// The security flavors of the export
+	// Valid values are "sys", "krb5", "krb5i" and "krb5p". Defaults to "sys".
+	SecurityFlavors []string `json:"securityFlavors,omitempty"`
 }
 
 // AllowedClientsSpec represents the client specs for accessing the NFS export
@@ -92,11 +169,11 @@ type AllowedClientsSpec struct {
 
 	// Reading and Writing permissions for the client to access the NFS export
 	// Valid values are "ReadOnly", "ReadWrite" and "none"
-	// Gets overridden when ServerSpec.accessMode is specified
+	// Defaults to ServerSpec.accessMode
 	AccessMode string `json:"accessMode,omitempty"`
 
 	// Squash options for clients
 	// Valid values are "none", "rootid", "root", and "all"
-	// Gets overridden when ServerSpec.squash is specified
+	// Defaults to ServerSpec.squash
 	Squash string `json:"squash,omitempty"`
 }
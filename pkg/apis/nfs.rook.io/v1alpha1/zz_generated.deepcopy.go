@@ -45,11 +45,48 @@ func (in *AllowedClientsSpec) DeepCopy() *AllowedClientsSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CephFSExportSpec) DeepCopyInto(out *CephFSExportSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CephFSExportSpec.
+func (in *CephFSExportSpec) DeepCopy() *CephFSExportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CephFSExportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExportStatus) DeepCopyInto(out *ExportStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExportStatus.
+func (in *ExportStatus) DeepCopy() *ExportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ExportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExportsSpec) DeepCopyInto(out *ExportsSpec) {
 	*out = *in
 	in.Server.DeepCopyInto(&out.Server)
 	out.PersistentVolumeClaim = in.PersistentVolumeClaim
+	if in.CephFS != nil {
+		in, out := &in.CephFS, &out.CephFS
+		*out = new(CephFSExportSpec)
+		**out = **in
+	}
 	return
 }
 
@@ -63,12 +100,29 @@ func (in *ExportsSpec) DeepCopy() *ExportsSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KerberosSpec) DeepCopyInto(out *KerberosSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KerberosSpec.
+func (in *KerberosSpec) DeepCopy() *KerberosSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KerberosSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NFSServer) DeepCopyInto(out *NFSServer) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -133,6 +187,7 @@ func (in *NFSServerSpec) DeepCopyInto(out *NFSServerSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	out.Kerberos = in.Kerberos
 	return
 }
 
@@ -146,6 +201,27 @@ func (in *NFSServerSpec) DeepCopy() *NFSServerSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NFSServerStatus) DeepCopyInto(out *NFSServerStatus) {
+	*out = *in
+	if in.Exports != nil {
+		in, out := &in.Exports, &out.Exports
+		*out = make([]ExportStatus, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NFSServerStatus.
+func (in *NFSServerStatus) DeepCopy() *NFSServerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NFSServerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServerSpec) DeepCopyInto(out *ServerSpec) {
 	*out = *in
@@ -156,6 +232,21 @@ func (in *ServerSpec) DeepCopyInto(out *ServerSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Protocols != nil {
+		in, out := &in.Protocols, &out.Protocols
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+	if in.Transports != nil {
+		in, out := &in.Transports, &out.Transports
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SecurityFlavors != nil {
+		in, out := &in.SecurityFlavors, &out.SecurityFlavors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -54,7 +54,9 @@ func init() {
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&Volume{},
-		&VolumeList{})
+		&VolumeList{},
+		&NodeDevices{},
+		&NodeDevicesList{})
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
 }
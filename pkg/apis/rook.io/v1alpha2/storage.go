@@ -15,6 +15,13 @@ limitations under the License.
 */
 package v1alpha2
 
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
 // AnyUseAllDevices gets whether to use all devices
 func (s *StorageScopeSpec) AnyUseAllDevices() bool {
 	if s.Selection.GetUseAllDevices() {
@@ -27,6 +34,12 @@ func (s *StorageScopeSpec) AnyUseAllDevices() bool {
 		}
 	}
 
+	for _, g := range s.StorageGroups {
+		if g.Selection.GetUseAllDevices() {
+			return true
+		}
+	}
+
 	return false
 }
 
@@ -37,14 +50,18 @@ func (s *StorageScopeSpec) ClearUseAllDevices() {
 	for i := range s.Nodes {
 		s.Nodes[i].Selection.UseAllDevices = &clear
 	}
+	for i := range s.StorageGroups {
+		s.StorageGroups[i].Selection.UseAllDevices = &clear
+	}
 }
 
 // Fully resolves the config of the given node name, taking into account cluster level and node level specified config.
 // In general, the more fine grained the configuration is specified, the more precedence it takes.  Fully resolved
 // configuration for the node has the following order of precedence.
 // 1) Node (config defined on the node itself)
-// 2) Cluster (config defined on the cluster)
-// 3) Default values (if no config exists for the node or cluster)
+// 2) Storage group (config defined on the storage group of the node)
+// 3) Cluster (config defined on the cluster)
+// 4) Default values (if no config exists for the node, storage group or cluster)
 func (s *StorageScopeSpec) ResolveNode(nodeName string) *Node {
 	// find the requested storage node first, if it exists
 	var node *Node
@@ -63,44 +80,107 @@ func (s *StorageScopeSpec) ResolveNode(nodeName string) *Node {
 		node.Config = map[string]string{}
 	}
 
-	// now resolve all properties that haven't already been set on the node
+	// now resolve all properties that haven't already been set on the node, first from its storage group
+	if group := s.GetStorageGroup(node.StorageGroup); group != nil {
+		resolveSelection(&node.Selection, &group.Selection)
+		resolveString(&(node.Location), group.Location, "")
+		resolveConfig(node.Config, group.Config)
+	}
 	s.resolveNodeSelection(node)
 	s.resolveNodeConfig(node)
 
 	return node
 }
 
-func (s *StorageScopeSpec) resolveNodeSelection(node *Node) {
-	if node.Selection.UseAllDevices == nil {
-		if s.Selection.UseAllDevices != nil {
-			// the node does not have a value specified for use all devices, but the cluster does. Use the cluster's.
-			node.Selection.UseAllDevices = s.Selection.UseAllDevices
-		} else {
-			// neither node nor cluster have a value set for use all devices, use the default value.
-			node.Selection.UseAllDevices = newBool(false)
+// GetStorageGroup returns the storage group with the given name, or nil if it does not exist
+func (s *StorageScopeSpec) GetStorageGroup(name string) *StorageGroup {
+	if name == "" {
+		return nil
+	}
+	for i := range s.StorageGroups {
+		if s.StorageGroups[i].Name == name {
+			return &(s.StorageGroups[i])
 		}
 	}
+	return nil
+}
 
-	resolveString(&(node.Selection.DeviceFilter), s.Selection.DeviceFilter, "")
+// MatchStorageGroup returns the first storage group whose node selector matches the labels of a node,
+// or nil if no group matches
+func (s *StorageScopeSpec) MatchStorageGroup(nodeLabels map[string]string) (*StorageGroup, error) {
+	for i := range s.StorageGroups {
+		matches, err := s.StorageGroups[i].MatchesNode(nodeLabels)
+		if err != nil {
+			return nil, err
+		}
+		if matches {
+			return &(s.StorageGroups[i]), nil
+		}
+	}
+	return nil, nil
+}
 
-	if len(node.Selection.Devices) == 0 {
-		node.Selection.Devices = s.Devices
+// MatchesNode returns whether the node selector of the storage group matches the labels of a node.
+// A group without a node selector does not match any node.
+func (g *StorageGroup) MatchesNode(nodeLabels map[string]string) (bool, error) {
+	if g.NodeSelector == nil {
+		return false, nil
 	}
+	selector, err := metav1.LabelSelectorAsSelector(g.NodeSelector)
+	if err != nil {
+		return false, fmt.Errorf("invalid node selector of storage group %s. %+v", g.Name, err)
+	}
+	return selector.Matches(labels.Set(nodeLabels)), nil
+}
 
-	if len(node.Selection.Directories) == 0 {
-		node.Selection.Directories = s.Directories
+func (s *StorageScopeSpec) resolveNodeSelection(node *Node) {
+	resolveSelection(&node.Selection, &s.Selection)
+	if node.Selection.UseAllDevices == nil {
+		// neither node nor cluster have a value set for use all devices, use the default value.
+		node.Selection.UseAllDevices = newBool(false)
 	}
 }
 
 func (s *StorageScopeSpec) resolveNodeConfig(node *Node) {
 	resolveString(&(node.Location), s.Location, "")
+	resolveConfig(node.Config, s.Config)
+}
 
+// resolveSelection sets the properties of the selection that are not set from the parent scope
+func resolveSelection(selection, parent *Selection) {
+	if selection.UseAllDevices == nil {
+		selection.UseAllDevices = parent.UseAllDevices
+	}
+
+	resolveString(&(selection.DeviceFilter), parent.DeviceFilter, "")
+	resolveString(&(selection.MinDeviceSize), parent.MinDeviceSize, "")
+	resolveString(&(selection.MaxDeviceSize), parent.MaxDeviceSize, "")
+	resolveString(&(selection.VendorFilter), parent.VendorFilter, "")
+	resolveString(&(selection.ModelFilter), parent.ModelFilter, "")
+	resolveString(&(selection.DevicePathFilter), parent.DevicePathFilter, "")
+	if selection.Rotational == nil {
+		selection.Rotational = parent.Rotational
+	}
+	if selection.MaxDevices == 0 {
+		selection.MaxDevices = parent.MaxDevices
+	}
+
+	if len(selection.Devices) == 0 {
+		selection.Devices = parent.Devices
+	}
+
+	if len(selection.Directories) == 0 {
+		selection.Directories = parent.Directories
+	}
+}
+
+func resolveConfig(config, parent map[string]string) {
 	// check for any keys the parent scope has that the node does not
-	for scopeKey, scopeVal := range s.Config {
-		if _, ok := node.Config[scopeKey]; !ok {
+	for scopeKey, scopeVal := range parent {
+		if _, ok := config[scopeKey]; !ok {
 			// the node's config does not have an entry that the parent scope does, add the parent's
 			// value for that key to the node's config.
-			node.Config[scopeKey] = scopeVal
+			config[scopeKey] = scopeVal
 		}
 	}
 }
@@ -109,6 +189,13 @@ func (s *Selection) GetUseAllDevices() bool {
 	return s.UseAllDevices != nil && *(s.UseAllDevices)
 }
 
+// HasDeviceSelectors returns whether devices are selected by their properties. The selectors narrow down the devices
+// of the device filter, or select among all the devices if there is no filter.
+func (s *Selection) HasDeviceSelectors() bool {
+	return s.MinDeviceSize != "" || s.MaxDeviceSize != "" || s.Rotational != nil || s.VendorFilter != "" ||
+		s.ModelFilter != "" || s.DevicePathFilter != "" || s.MaxDevices > 0
+}
+
 func resolveString(setting *string, parent, defaultVal string) {
 	if *setting == "" {
 		if parent != "" {
@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestResolveNodeNotExist(t *testing.T) {
@@ -72,6 +73,129 @@ func TestResolveNodeInherentFromCluster(t *testing.T) {
 	assert.Equal(t, []Device{{Name: "sda"}}, node.Devices)
 }
 
+func TestResolveNodeDeviceSelectors(t *testing.T) {
+	rotational := false
+	storageSpec := StorageScopeSpec{
+		Selection: Selection{
+			MinDeviceSize: "100Gi",
+			Rotational:    &rotational,
+			ModelFilter:   "^Samsung",
+			MaxDevices:    4,
+		},
+		Nodes: []Node{
+			{Name: "node1"},
+			{Name: "node2", Selection: Selection{MinDeviceSize: "1Ti", DevicePathFilter: "/dev/disk/by-path/pci-0000:03:00.0-*"}},
+		},
+	}
+
+	// the node inherits the selectors of the cluster
+	node := storageSpec.ResolveNode("node1")
+	assert.True(t, node.Selection.HasDeviceSelectors())
+	assert.Equal(t, "100Gi", node.Selection.MinDeviceSize)
+	assert.False(t, *node.Selection.Rotational)
+	assert.Equal(t, "^Samsung", node.Selection.ModelFilter)
+	assert.Equal(t, 4, node.Selection.MaxDevices)
+
+	// the selectors set on the node override those of the cluster
+	node = storageSpec.ResolveNode("node2")
+	assert.Equal(t, "1Ti", node.Selection.MinDeviceSize)
+	assert.Equal(t, "/dev/disk/by-path/pci-0000:03:00.0-*", node.Selection.DevicePathFilter)
+	assert.Equal(t, "^Samsung", node.Selection.ModelFilter)
+
+	assert.False(t, (&Selection{DeviceFilter: "^sd."}).HasDeviceSelectors())
+}
+
+func TestResolveNodeInheritFromStorageGroup(t *testing.T) {
+	// a node inherits the properties of its storage group before those of the cluster
+	rotational := false
+	storageSpec := StorageScopeSpec{
+		Location: "rack=a1",
+		Selection: Selection{
+			DeviceFilter: "^sd.",
+			MaxDevices:   4,
+		},
+		Config: map[string]string{
+			"foo":            "bar",
+			"databaseSizeMB": "1024",
+		},
+		StorageGroups: []StorageGroup{
+			{
+				Name:     "ssd",
+				Location: "rack=b1",
+				Selection: Selection{
+					DeviceFilter: "^nvme.",
+					Rotational:   &rotational,
+				},
+				Config: map[string]string{
+					"databaseSizeMB": "4096",
+				},
+			},
+		},
+		Nodes: []Node{
+			{Name: "node1", StorageGroup: "ssd"},
+			{Name: "node2", StorageGroup: "ssd", Config: map[string]string{"databaseSizeMB": "2048"}},
+			{Name: "node3"},
+		},
+	}
+
+	node := storageSpec.ResolveNode("node1")
+	assert.NotNil(t, node)
+	assert.Equal(t, "^nvme.", node.Selection.DeviceFilter)
+	assert.False(t, *node.Selection.Rotational)
+	assert.Equal(t, 4, node.Selection.MaxDevices)
+	assert.False(t, node.Selection.GetUseAllDevices())
+	assert.Equal(t, "rack=b1", node.Location)
+	assert.Equal(t, "4096", node.Config["databaseSizeMB"])
+	assert.Equal(t, "bar", node.Config["foo"])
+
+	// the node config takes precedence over the group
+	node = storageSpec.ResolveNode("node2")
+	assert.Equal(t, "2048", node.Config["databaseSizeMB"])
+
+	// a node without a group inherits from the cluster
+	node = storageSpec.ResolveNode("node3")
+	assert.Equal(t, "^sd.", node.Selection.DeviceFilter)
+	assert.Nil(t, node.Selection.Rotational)
+	assert.Equal(t, "rack=a1", node.Location)
+	assert.Equal(t, "1024", node.Config["databaseSizeMB"])
+}
+
+func TestMatchStorageGroup(t *testing.T) {
+	storageSpec := StorageScopeSpec{
+		StorageGroups: []StorageGroup{
+			{Name: "none"},
+			{Name: "ssd", NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"storage": "ssd"}}},
+			{Name: "hdd", NodeSelector: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "storage", Operator: metav1.LabelSelectorOpIn, Values: []string{"hdd", "archive"}},
+				},
+			}},
+		},
+	}
+
+	group, err := storageSpec.MatchStorageGroup(map[string]string{"storage": "ssd"})
+	assert.Nil(t, err)
+	assert.Equal(t, "ssd", group.Name)
+
+	group, err = storageSpec.MatchStorageGroup(map[string]string{"storage": "archive", "zone": "a"})
+	assert.Nil(t, err)
+	assert.Equal(t, "hdd", group.Name)
+
+	// a group without a node selector does not match any node
+	group, err = storageSpec.MatchStorageGroup(map[string]string{})
+	assert.Nil(t, err)
+	assert.Nil(t, group)
+
+	assert.Equal(t, "ssd", storageSpec.GetStorageGroup("ssd").Name)
+	assert.Nil(t, storageSpec.GetStorageGroup("other"))
+	assert.Nil(t, storageSpec.GetStorageGroup(""))
+
+	// an invalid selector is an error
+	storageSpec.StorageGroups[1].NodeSelector.MatchExpressions = []metav1.LabelSelectorRequirement{{Key: "storage", Operator: "bad"}}
+	_, err = storageSpec.MatchStorageGroup(map[string]string{"storage": "ssd"})
+	assert.NotNil(t, err)
+}
+
 func TestResolveNodeSpecificProperties(t *testing.T) {
 	// a node with its own specific properties defined should keep those values, regardless of what the global cluster config is
 	storageSpec := StorageScopeSpec{
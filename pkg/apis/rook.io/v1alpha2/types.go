@@ -35,6 +35,8 @@ type StorageScopeSpec struct {
 	NodeCount       int               `json:"nodeCount,omitempty"`
 	Location        string            `json:"location,omitempty"`
 	Config          map[string]string `json:"config"`
+	// StorageGroups are classes of nodes selected by their labels, each with its own storage config
+	StorageGroups []StorageGroup `json:"storageGroups,omitempty"`
 	Selection
 }
 
@@ -43,12 +45,27 @@ type Node struct {
 	Location  string                  `json:"location,omitempty"`
 	Resources v1.ResourceRequirements `json:"resources,omitempty"`
 	Config    map[string]string       `json:"config"`
+	// StorageGroup is the name of the storage group whose config applies to the node
+	StorageGroup string `json:"storageGroup,omitempty"`
+	Selection
+}
+
+// StorageGroup is a named class of storage nodes. The nodes matching the node selector get OSDs with the
+// device selection, config and resources of the group.
+type StorageGroup struct {
+	Name         string                  `json:"name"`
+	NodeSelector *metav1.LabelSelector   `json:"nodeSelector,omitempty"`
+	Location     string                  `json:"location,omitempty"`
+	Resources    v1.ResourceRequirements `json:"resources,omitempty"`
+	Config       map[string]string       `json:"config"`
 	Selection
 }
 
 type Device struct {
-	Name     string            `json:"name,omitempty"`
-	FullPath string            // TODO: FullPath to be supported for devices
+	Name string `json:"name,omitempty"`
+	// FullPath is a persistent link to the device such as /dev/disk/by-id/wwn-0x5000c500a0b1c2d3, which does not
+	// change when the kernel name of the device changes
+	FullPath string            `json:"fullpath,omitempty"`
 	Config   map[string]string `json:"config"`
 }
 
@@ -67,6 +84,23 @@ type Selection struct {
 	Devices []Device `json:"devices,omitempty"`
 
 	Directories []Directory `json:"directories,omitempty"`
+
+	// The minimum and maximum capacity of the selected devices, such as "100Gi"
+	MinDeviceSize string `json:"minDeviceSize,omitempty"`
+	MaxDeviceSize string `json:"maxDeviceSize,omitempty"`
+
+	// Whether to select only rotational (true) or only non-rotational (false) devices
+	Rotational *bool `json:"rotational,omitempty"`
+
+	// Regular expressions to select devices by their vendor and model
+	VendorFilter string `json:"vendorFilter,omitempty"`
+	ModelFilter  string `json:"modelFilter,omitempty"`
+
+	// A glob pattern matched against the persistent links of the devices, such as "/dev/disk/by-path/pci-0000:03:00.0-*"
+	DevicePathFilter string `json:"devicePathFilter,omitempty"`
+
+	// The maximum number of devices selected on each node
+	MaxDevices int `json:"maxDevices,omitempty"`
 }
 
 type PlacementSpec map[string]Placement
@@ -112,6 +146,8 @@ type Attachment struct {
 	ClusterName  string `json:"clusterName"`
 	MountDir     string `json:"mountDir"`
 	ReadOnly     bool   `json:"readOnly"`
+	// AccessMode is the access mode of the persistent volume the attachment was made with
+	AccessMode v1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -121,3 +157,56 @@ type VolumeList struct {
 	metav1.ListMeta `json:"metadata"`
 	Items           []Volume `json:"items"`
 }
+
+// +genclient
+// +genclient:noStatus
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// NodeDevices is the inventory of the devices discovered on a node. It has the same name as the node.
+type NodeDevices struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Devices           []NodeDevice `json:"devices"`
+}
+
+// NodeDevice is a device discovered on a node and the cluster that uses it
+type NodeDevice struct {
+	Name string `json:"name"`
+	// FullPath is the most stable link to the device. See sys.GetPersistentDevicePath.
+	FullPath   string `json:"fullpath,omitempty"`
+	DevLinks   string `json:"devLinks,omitempty"`
+	Size       uint64 `json:"size"`
+	Type       string `json:"type"`
+	Rotational bool   `json:"rotational"`
+	Serial     string `json:"serial,omitempty"`
+	Vendor     string `json:"vendor,omitempty"`
+	Model      string `json:"model,omitempty"`
+	WWN        string `json:"wwn,omitempty"`
+	Filesystem string `json:"filesystem,omitempty"`
+	Partitions int    `json:"partitions,omitempty"`
+	// Cluster is the namespace of the cluster that claimed the device
+	Cluster string `json:"cluster,omitempty"`
+	// OSDID is the id of the OSD that was created on the device
+	OSDID *int `json:"osdID,omitempty"`
+	// Rejected is the reason that an unused device cannot be claimed by a cluster
+	Rejected DeviceRejectedReason `json:"rejected,omitempty"`
+}
+
+// DeviceRejectedReason is the reason that an unused device cannot be used by an OSD
+type DeviceRejectedReason string
+
+const (
+	DeviceHasPartitions DeviceRejectedReason = "Partitions"
+	DeviceHasFilesystem DeviceRejectedReason = "Filesystem"
+	DeviceTooSmall      DeviceRejectedReason = "TooSmall"
+	DeviceReadOnly      DeviceRejectedReason = "ReadOnly"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+type NodeDevicesList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []NodeDevices `json:"items"`
+}
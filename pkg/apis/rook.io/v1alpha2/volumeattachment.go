@@ -17,11 +17,13 @@ package v1alpha2
 
 import (
 	rookv1alpha1 "github.com/rook/rook/pkg/apis/rook.io/v1alpha1"
+	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // NewVolume creates a reference of a Volumeattach CRD object
-func NewVolume(name, namespace, node, podNamespace, podName, clusterName, mountDir string, readOnly bool) *Volume {
+func NewVolume(name, namespace, node, podNamespace, podName, clusterName, mountDir string, readOnly bool,
+	accessMode v1.PersistentVolumeAccessMode) *Volume {
 	volumeAttachmentObj := &Volume{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
@@ -35,6 +37,7 @@ func NewVolume(name, namespace, node, podNamespace, podName, clusterName, mountD
 				ClusterName:  clusterName,
 				MountDir:     mountDir,
 				ReadOnly:     readOnly,
+				AccessMode:   accessMode,
 			},
 		},
 	}
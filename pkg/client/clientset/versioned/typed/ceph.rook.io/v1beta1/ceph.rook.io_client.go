@@ -27,6 +27,7 @@ import (
 
 type CephV1beta1Interface interface {
 	RESTClient() rest.Interface
+	BucketsGetter
 	ClustersGetter
 	FilesystemsGetter
 	ObjectStoresGetter
@@ -38,6 +39,10 @@ type CephV1beta1Client struct {
 	restClient rest.Interface
 }
 
+func (c *CephV1beta1Client) Buckets(namespace string) BucketInterface {
+	return newBuckets(c, namespace)
+}
+
 func (c *CephV1beta1Client) Clusters(namespace string) ClusterInterface {
 	return newClusters(c, namespace)
 }
@@ -28,6 +28,10 @@ type FakeCephV1beta1 struct {
 	*testing.Fake
 }
 
+func (c *FakeCephV1beta1) Buckets(namespace string) v1beta1.BucketInterface {
+	return &FakeBuckets{c, namespace}
+}
+
 func (c *FakeCephV1beta1) Clusters(namespace string) v1beta1.ClusterInterface {
 	return &FakeClusters{c, namespace}
 }
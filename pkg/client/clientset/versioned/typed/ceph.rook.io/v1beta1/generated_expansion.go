@@ -18,6 +18,8 @@ limitations under the License.
 
 package v1beta1
 
+type BucketExpansion interface{}
+
 type ClusterExpansion interface{}
 
 type FilesystemExpansion interface{}
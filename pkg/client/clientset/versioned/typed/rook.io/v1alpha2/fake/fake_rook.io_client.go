@@ -28,6 +28,10 @@ type FakeRookV1alpha2 struct {
 	*testing.Fake
 }
 
+func (c *FakeRookV1alpha2) NodeDevices() v1alpha2.NodeDevicesInterface {
+	return &FakeNodeDevices{c}
+}
+
 func (c *FakeRookV1alpha2) Volumes(namespace string) v1alpha2.VolumeInterface {
 	return &FakeVolumes{c, namespace}
 }
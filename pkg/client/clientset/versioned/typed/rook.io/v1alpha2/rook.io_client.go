@@ -27,6 +27,7 @@ import (
 
 type RookV1alpha2Interface interface {
 	RESTClient() rest.Interface
+	NodeDevicesGetter
 	VolumesGetter
 }
 
@@ -35,6 +36,10 @@ type RookV1alpha2Client struct {
 	restClient rest.Interface
 }
 
+func (c *RookV1alpha2Client) NodeDevices() NodeDevicesInterface {
+	return newNodeDevices(c)
+}
+
 func (c *RookV1alpha2Client) Volumes(namespace string) VolumeInterface {
 	return newVolumes(c, namespace)
 }
@@ -69,6 +69,8 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Ceph().V1alpha1().Pools().Informer()}, nil
 
 		// Group=ceph.rook.io, Version=v1beta1
+	case v1beta1.SchemeGroupVersion.WithResource("buckets"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Ceph().V1beta1().Buckets().Informer()}, nil
 	case v1beta1.SchemeGroupVersion.WithResource("clusters"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Ceph().V1beta1().Clusters().Informer()}, nil
 	case v1beta1.SchemeGroupVersion.WithResource("filesystems"):
@@ -103,6 +105,8 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Rook().V1alpha1().VolumeAttachments().Informer()}, nil
 
 		// Group=rook.io, Version=v1alpha2
+	case v1alpha2.SchemeGroupVersion.WithResource("nodedevices"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Rook().V1alpha2().NodeDevices().Informer()}, nil
 	case v1alpha2.SchemeGroupVersion.WithResource("volumes"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Rook().V1alpha2().Volumes().Informer()}, nil
 
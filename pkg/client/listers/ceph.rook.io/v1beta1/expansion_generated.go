@@ -18,6 +18,14 @@ limitations under the License.
 
 package v1beta1
 
+// BucketListerExpansion allows custom methods to be added to
+// BucketLister.
+type BucketListerExpansion interface{}
+
+// BucketNamespaceListerExpansion allows custom methods to be added to
+// BucketNamespaceLister.
+type BucketNamespaceListerExpansion interface{}
+
 // ClusterListerExpansion allows custom methods to be added to
 // ClusterLister.
 type ClusterListerExpansion interface{}
@@ -18,6 +18,10 @@ limitations under the License.
 
 package v1alpha2
 
+// NodeDevicesListerExpansion allows custom methods to be added to
+// NodeDevicesLister.
+type NodeDevicesListerExpansion interface{}
+
 // VolumeListerExpansion allows custom methods to be added to
 // VolumeLister.
 type VolumeListerExpansion interface{}
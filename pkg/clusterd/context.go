@@ -22,6 +22,7 @@ import (
 	"github.com/rook/rook/pkg/util/sys"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 )
 
 // The context for loading or applying the configuration state of a service.
@@ -52,4 +53,7 @@ type Context struct {
 
 	// The local devices detected on the node
 	Devices []*sys.LocalDisk
+
+	// Recorder records the events of the operator or agent against the resources they manage
+	Recorder record.EventRecorder
 }
@@ -38,12 +38,14 @@ var logger = capnslog.NewPackageLogger("github.com/rook/rook", "rook-ceph-agent"
 
 // Agent represent all the references needed to manage a Rook agent
 type Agent struct {
-	context *clusterd.Context
+	context         *clusterd.Context
+	nodeLostTimeout time.Duration
 }
 
-// New creates an Agent instance
-func New(context *clusterd.Context) *Agent {
-	return &Agent{context: context}
+// New creates an Agent instance. The attachments of nodes that have not been ready for the node lost timeout are
+// fenced and removed.
+func New(context *clusterd.Context, nodeLostTimeout time.Duration) *Agent {
+	return &Agent{context: context, nodeLostTimeout: nodeLostTimeout}
 }
 
 // Run the agent
@@ -59,7 +61,7 @@ func (a *Agent) Run() error {
 		return fmt.Errorf("failed to create volume manager: %+v", err)
 	}
 
-	flexvolumeController := flexvolume.NewController(a.context, volumeAttachmentController, volumeManager)
+	flexvolumeController := flexvolume.NewController(a.context, volumeAttachmentController, volumeManager, a.nodeLostTimeout)
 
 	flexvolumeServer := flexvolume.NewFlexvolumeServer(
 		a.context,
@@ -108,6 +110,8 @@ func (a *Agent) Run() error {
 	stopChan := make(chan struct{})
 	clusterController.StartWatch(v1.NamespaceAll, stopChan)
 
+	go flexvolumeController.CleanupStaleAttachments(stopChan)
+
 	sigc := make(chan os.Signal, 1)
 	signal.Notify(sigc, syscall.SIGTERM)
 	for {
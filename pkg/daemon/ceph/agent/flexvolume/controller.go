@@ -23,6 +23,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/coreos/pkg/capnslog"
 	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
@@ -31,6 +32,7 @@ import (
 	"github.com/rook/rook/pkg/operator/ceph/cluster"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
 	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -54,6 +56,7 @@ type Controller struct {
 	context          *clusterd.Context
 	volumeManager    VolumeManager
 	volumeAttachment attachment.Attachment
+	nodeLostTimeout  time.Duration
 }
 
 type ClientAccessInfo struct {
@@ -62,23 +65,33 @@ type ClientAccessInfo struct {
 	SecretKey    string   `json:"secretKey"`
 }
 
-func NewController(context *clusterd.Context, volumeAttachment attachment.Attachment, manager VolumeManager) *Controller {
+func NewController(context *clusterd.Context, volumeAttachment attachment.Attachment, manager VolumeManager,
+	nodeLostTimeout time.Duration) *Controller {
 
 	return &Controller{
 		context:          context,
 		volumeAttachment: volumeAttachment,
 		volumeManager:    manager,
+		nodeLostTimeout:  nodeLostTimeout,
 	}
 }
 
 // Attach attaches rook volume to the node
 func (c *Controller) Attach(attachOpts AttachOptions, devicePath *string) error {
+	start := time.Now()
+	err := c.attach(attachOpts, devicePath)
+	metrics.ObserveFlexvolumeOperation("attach", start, err)
+	return err
+}
+
+func (c *Controller) attach(attachOpts AttachOptions, devicePath *string) error {
 
 	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
 	node := os.Getenv(k8sutil.NodeNameEnvVar)
 
 	// Name of CRD is the PV name. This is done so that the CRD can be use for fencing
 	crdName := attachOpts.VolumeName
+	accessMode := getAttachAccessMode(attachOpts)
 
 	// Check if this volume has been attached
 	volumeattachObj, err := c.volumeAttachment.Get(namespace, crdName)
@@ -88,7 +101,7 @@ func (c *Controller) Attach(attachOpts AttachOptions, devicePath *string) error
 		}
 		// No volumeattach CRD for this volume found. Create one
 		volumeattachObj = rookalpha.NewVolume(crdName, namespace, node, attachOpts.PodNamespace, attachOpts.Pod,
-			attachOpts.ClusterNamespace, attachOpts.MountDir, strings.ToLower(attachOpts.RW) == ReadOnly)
+			attachOpts.ClusterNamespace, attachOpts.MountDir, accessMode == v1.ReadOnlyMany, accessMode)
 		logger.Infof("Creating Volume attach Resource %s/%s: %+v", volumeattachObj.Namespace, volumeattachObj.Name, attachOpts)
 		err = c.volumeAttachment.Create(volumeattachObj)
 		if err != nil {
@@ -96,13 +109,13 @@ func (c *Controller) Attach(attachOpts AttachOptions, devicePath *string) error
 				return fmt.Errorf("failed to create volume CRD %s. %+v", crdName, err)
 			}
 			// Some other attacher beat us in this race. Kubernetes will retry again.
-			return fmt.Errorf("failed to attach volume %s for pod %s/%s. Volume is already attached by a different pod",
+			return c.refuseAttach(attachOpts, "failed to attach volume %s for pod %s/%s. Volume is already attached by a different pod",
 				crdName, attachOpts.PodNamespace, attachOpts.Pod)
 		}
 	} else {
 		// Volume has already been attached.
 		// find if the attachment object has been previously created.
-		// This could be in the case of a multiple attachment for ROs or
+		// This could be in the case of a multiple attachment for ROs and shared filesystems or
 		// it could be the the Volume record was created previously and
 		// the attach operation failed and Kubernetes retried.
 		found := false
@@ -113,7 +126,7 @@ func (c *Controller) Attach(attachOpts AttachOptions, devicePath *string) error
 		}
 
 		if !found {
-			// Check if there is already an attachment with RW.
+			// Check if there is already an attachment with a single writer.
 			index := getPodRWAttachmentObject(volumeattachObj)
 			if index != -1 {
 				// check if the RW attachment is orphaned.
@@ -121,35 +134,55 @@ func (c *Controller) Attach(attachOpts AttachOptions, devicePath *string) error
 
 				logger.Infof("Volume attachment record %s/%s exists for pod: %s/%s", volumeattachObj.Namespace, volumeattachObj.Name, attachment.PodNamespace, attachment.PodName)
 				// Note this could return the reference of the pod who is requesting the attach if this pod have the same name as the pod in the attachment record.
+				// The pod of a node that is lost is never removed, so its attachment is orphaned as well.
 				pod, err := c.context.Clientset.CoreV1().Pods(attachment.PodNamespace).Get(attachment.PodName, metav1.GetOptions{})
-				if err != nil || (attachment.PodNamespace == attachOpts.PodNamespace && attachment.PodName == attachOpts.Pod) {
+				nodeLost := attachment.Node != node && c.isNodeLost(attachment.Node)
+				if err != nil || (attachment.PodNamespace == attachOpts.PodNamespace && attachment.PodName == attachOpts.Pod) || nodeLost {
 					if err != nil && !errors.IsNotFound(err) {
 						return fmt.Errorf("failed to get pod CRD %s/%s. %+v", attachment.PodNamespace, attachment.PodName, err)
 					}
 
 					logger.Infof("Volume attachment record %s/%s is orphaned. Updating record with new attachment information for pod %s/%s", volumeattachObj.Namespace, volumeattachObj.Name, attachOpts.PodNamespace, attachOpts.Pod)
 
+					// The previous node may still have the volume mapped. Fencing blacklists the rbd client of the node, which is
+					// shared by all its volumes, so only a lost node is fenced. Otherwise Kubernetes retries the attach until the
+					// volume is detached from the previous node.
+					if attachment.Node != node {
+						if c.nodeLostTimeout == 0 || !nodeLost {
+							return c.refuseAttach(attachOpts, "failed to attach volume %s for pod %s/%s. Volume is still attached on node %s",
+								crdName, attachOpts.PodNamespace, attachOpts.Pod, attachment.Node)
+						}
+						logger.Infof("fencing the attachment of volume %s on node %s", crdName, attachment.Node)
+						if err := c.volumeManager.Fence(attachOpts.Image, attachOpts.Pool, attachOpts.ClusterNamespace); err != nil {
+							return fmt.Errorf("failed to fence volume %s on node %s. %+v", crdName, attachment.Node, err)
+						}
+						c.recordAttachEvent(attachOpts, v1.EventTypeWarning, k8sutil.EventReasonVolumeFenced,
+							"fenced volume %s on node %s to attach it to pod %s/%s on node %s", crdName, attachment.Node, attachOpts.PodNamespace, attachOpts.Pod, node)
+					}
+
 					// Attachment is orphaned. Update attachment record and proceed with attaching
 					attachment.Node = node
 					attachment.MountDir = attachOpts.MountDir
 					attachment.PodNamespace = attachOpts.PodNamespace
 					attachment.PodName = attachOpts.Pod
 					attachment.ClusterName = attachOpts.ClusterNamespace
-					attachment.ReadOnly = attachOpts.RW == ReadOnly
+					attachment.ReadOnly = accessMode == v1.ReadOnlyMany
+					attachment.AccessMode = accessMode
 					err = c.volumeAttachment.Update(volumeattachObj)
 					if err != nil {
 						return fmt.Errorf("failed to update volume CRD %s. %+v", crdName, err)
 					}
 				} else {
 					// Attachment is not orphaned. Original pod still exists. Dont attach.
-					return fmt.Errorf("failed to attach volume %s for pod %s/%s. Volume is already attached by pod %s/%s. Status %+v",
+					return c.refuseAttach(attachOpts, "failed to attach volume %s for pod %s/%s. Volume is already attached by pod %s/%s. Status %+v",
 						crdName, attachOpts.PodNamespace, attachOpts.Pod, attachment.PodNamespace, attachment.PodName, pod.Status.Phase)
 				}
 			} else {
-				// No RW attachment found. Check if this is a RW attachment request.
-				// We only support RW once attachment. No mixing either with RO
-				if attachOpts.RW == "rw" && len(volumeattachObj.Attachments) > 0 {
-					return fmt.Errorf("failed to attach volume %s for pod %s/%s. Volume is already attached by one or more pods",
+				// No RW attachment found. Check if this is a RW once attachment request.
+				// We only support RW once attachment. No mixing either with RO. RO many attachments and
+				// shared filesystems can be attached on many nodes.
+				if accessMode == v1.ReadWriteOnce && len(volumeattachObj.Attachments) > 0 {
+					return c.refuseAttach(attachOpts, "failed to attach volume %s for pod %s/%s. Volume is already attached by one or more pods",
 						crdName, attachOpts.PodNamespace, attachOpts.Pod)
 				}
 
@@ -160,7 +193,8 @@ func (c *Controller) Attach(attachOpts AttachOptions, devicePath *string) error
 					PodName:      attachOpts.Pod,
 					ClusterName:  attachOpts.ClusterNamespace,
 					MountDir:     attachOpts.MountDir,
-					ReadOnly:     attachOpts.RW == ReadOnly,
+					ReadOnly:     accessMode == v1.ReadOnlyMany,
+					AccessMode:   accessMode,
 				}
 				volumeattachObj.Attachments = append(volumeattachObj.Attachments, newAttach)
 				err = c.volumeAttachment.Update(volumeattachObj)
@@ -170,13 +204,35 @@ func (c *Controller) Attach(attachOpts AttachOptions, devicePath *string) error
 			}
 		}
 	}
-	*devicePath, err = c.volumeManager.Attach(attachOpts.Image, attachOpts.Pool, attachOpts.ClusterNamespace)
+	*devicePath, err = c.volumeManager.Attach(attachOpts.Image, attachOpts.Pool, attachOpts.ClusterNamespace, accessMode == v1.ReadOnlyMany)
 	if err != nil {
 		return fmt.Errorf("failed to attach volume %s/%s: %+v", attachOpts.Pool, attachOpts.Image, err)
 	}
 	return nil
 }
 
+// refuseAttach records why the volume is not attached to the pod and returns it as the error
+func (c *Controller) refuseAttach(attachOpts AttachOptions, messageFmt string, args ...interface{}) error {
+	err := fmt.Errorf(messageFmt, args...)
+	c.recordAttachEvent(attachOpts, v1.EventTypeWarning, k8sutil.EventReasonAttachRefused, "%v", err)
+	return err
+}
+
+// recordAttachEvent records an event against the claim of the volume, or against the pod if the volume has no claim
+func (c *Controller) recordAttachEvent(attachOpts AttachOptions, eventType, reason, messageFmt string, args ...interface{}) {
+	if c.context.Recorder == nil {
+		return
+	}
+	var ref *v1.ObjectReference
+	pv, err := c.context.Clientset.CoreV1().PersistentVolumes().Get(attachOpts.VolumeName, metav1.GetOptions{})
+	if err == nil && pv.Spec.ClaimRef != nil {
+		ref = pv.Spec.ClaimRef
+	} else {
+		ref = &v1.ObjectReference{Kind: "Pod", Namespace: attachOpts.PodNamespace, Name: attachOpts.Pod, UID: types.UID(attachOpts.PodID)}
+	}
+	k8sutil.RecordEvent(c.context.Recorder, ref, eventType, reason, messageFmt, args...)
+}
+
 // Detach detaches a rook volume to the node
 func (c *Controller) Detach(detachOpts AttachOptions, _ *struct{} /* void reply */) error {
 	return c.doDetach(detachOpts, false /* force */)
@@ -186,8 +242,10 @@ func (c *Controller) DetachForce(detachOpts AttachOptions, _ *struct{} /* void r
 	return c.doDetach(detachOpts, true /* force */)
 }
 
-func (c *Controller) doDetach(detachOpts AttachOptions, force bool) error {
-	err := c.volumeManager.Detach(detachOpts.Image, detachOpts.Pool, detachOpts.ClusterNamespace, force)
+func (c *Controller) doDetach(detachOpts AttachOptions, force bool) (err error) {
+	start := time.Now()
+	defer func() { metrics.ObserveFlexvolumeOperation("detach", start, err) }()
+	err = c.volumeManager.Detach(detachOpts.Image, detachOpts.Pool, detachOpts.ClusterNamespace, force)
 	if err != nil {
 		return fmt.Errorf("Failed to detach volume %s/%s: %+v", detachOpts.Pool, detachOpts.Image, err)
 	}
@@ -214,11 +272,13 @@ func (c *Controller) RemoveAttachmentObject(detachOpts AttachOptions, safeToDeta
 	node := os.Getenv(k8sutil.NodeNameEnvVar)
 	nodeAttachmentCount := 0
 	needUpdate := false
+	var removed rookalpha.Attachment
 	for i, v := range volumeAttach.Attachments {
 		if v.Node == node {
 			nodeAttachmentCount++
 			if v.MountDir == detachOpts.MountDir {
 				// Deleting slice
+				removed = v
 				volumeAttach.Attachments = append(volumeAttach.Attachments[:i], volumeAttach.Attachments[i+1:]...)
 				needUpdate = true
 			}
@@ -227,7 +287,8 @@ func (c *Controller) RemoveAttachmentObject(detachOpts AttachOptions, safeToDeta
 
 	if needUpdate {
 		// only one attachment on this node, which is the one that got removed.
-		if nodeAttachmentCount == 1 {
+		// Shared filesystems are not mapped to the node so there is nothing to detach.
+		if nodeAttachmentCount == 1 && removed.AccessMode != v1.ReadWriteMany {
 			*safeToDetach = true
 		}
 		return c.volumeAttachment.Update(volumeAttach)
@@ -284,6 +345,14 @@ func (c *Controller) GetAttachInfoFromMountDir(mountDir string, attachOptions *A
 		return fmt.Errorf("failed to get persistent volume %s: %+v", attachOptions.VolumeName, err)
 	}
 
+	if attachOptions.AccessMode == "" {
+		attachOptions.AccessMode = getAccessMode(pv, attachOptions.RW)
+	}
+	if attachOptions.AccessMode == v1.ReadOnlyMany {
+		// the image is mapped read-only so it can only be mounted read-only
+		attachOptions.RW = ReadOnly
+	}
+
 	if attachOptions.PodNamespace == "" {
 		// pod namespace should be the same as the PVC namespace
 		attachOptions.PodNamespace = pv.Spec.ClaimRef.Namespace
@@ -394,12 +463,56 @@ func findPodByID(pods *v1.PodList, podUID types.UID) *v1.Pod {
 }
 
 // getPodRWAttachmentObject loops through the list of attachments of the Volume
-// resource and returns the index of the first RW attachment object
+// resource and returns the index of the first single writer attachment object
 func getPodRWAttachmentObject(volumeAttachmentObject *rookalpha.Volume) int {
 	for i, a := range volumeAttachmentObject.Attachments {
-		if !a.ReadOnly {
+		if isSingleWriter(a) {
 			return i
 		}
 	}
 	return -1
 }
+
+// isSingleWriter returns whether the attachment must be the only attachment of the volume. Attachments that were
+// recorded before access modes were tracked are single writers unless they are read-only.
+func isSingleWriter(attachment rookalpha.Attachment) bool {
+	switch attachment.AccessMode {
+	case v1.ReadWriteOnce:
+		return true
+	case v1.ReadOnlyMany, v1.ReadWriteMany:
+		return false
+	}
+	return !attachment.ReadOnly
+}
+
+// getAccessMode returns the access mode to attach the persistent volume with. Ceph filesystems can be written from
+// many nodes. Images are mapped read-only on many nodes if the volume is read-only, or read-write on a single node.
+func getAccessMode(pv *v1.PersistentVolume, rw string) v1.PersistentVolumeAccessMode {
+	if pv.Spec.FlexVolume != nil && pv.Spec.FlexVolume.FSType == CephFS {
+		return v1.ReadWriteMany
+	}
+	if strings.ToLower(rw) == ReadOnly {
+		return v1.ReadOnlyMany
+	}
+	for _, mode := range pv.Spec.AccessModes {
+		if mode != v1.ReadOnlyMany {
+			return v1.ReadWriteOnce
+		}
+	}
+	if len(pv.Spec.AccessModes) > 0 {
+		return v1.ReadOnlyMany
+	}
+	return v1.ReadWriteOnce
+}
+
+// getAttachAccessMode returns the access mode of the attach request. Drivers that do not look up the access mode of
+// the persistent volume attach with the mode of the mount.
+func getAttachAccessMode(attachOpts AttachOptions) v1.PersistentVolumeAccessMode {
+	if attachOpts.AccessMode != "" {
+		return attachOpts.AccessMode
+	}
+	if strings.ToLower(attachOpts.RW) == ReadOnly {
+		return v1.ReadOnlyMany
+	}
+	return v1.ReadWriteOnce
+}
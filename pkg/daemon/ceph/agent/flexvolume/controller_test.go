@@ -25,6 +25,7 @@ import (
 	"net/http"
 	"os"
 	"testing"
+	"time"
 
 	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	rookclient "github.com/rook/rook/pkg/client/clientset/versioned/fake"
@@ -39,6 +40,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 )
 
 func TestAttach(t *testing.T) {
@@ -242,9 +244,11 @@ func TestAttachRWButROAlreadyExist(t *testing.T) {
 	os.Setenv(k8sutil.NodeNameEnvVar, "node1")
 	defer os.Unsetenv(k8sutil.NodeNameEnvVar)
 
+	recorder := record.NewFakeRecorder(10)
 	context := &clusterd.Context{
 		Clientset:     clientset,
 		RookClientset: rookclient.NewSimpleClientset(),
+		Recorder:      recorder,
 	}
 
 	existingCRD := &rookalpha.Volume{
@@ -289,6 +293,10 @@ func TestAttachRWButROAlreadyExist(t *testing.T) {
 	err = controller.Attach(opts, devicePath)
 	assert.NotNil(t, err)
 	assert.Equal(t, "failed to attach volume pvc-123 for pod Default/myPod. Volume is already attached by one or more pods", err.Error())
+
+	// the refusal is recorded as an event
+	event := <-recorder.Events
+	assert.Equal(t, "Warning AttachRefused failed to attach volume pvc-123 for pod Default/myPod. Volume is already attached by one or more pods", event)
 }
 
 func TestMultipleAttachReadOnly(t *testing.T) {
@@ -367,6 +375,119 @@ func TestMultipleAttachReadOnly(t *testing.T) {
 	), "Volume crd does not contain expected attachment")
 }
 
+func TestAttachAccessModes(t *testing.T) {
+	clientset := test.New(3)
+
+	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-system")
+	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
+
+	os.Setenv(k8sutil.NodeNameEnvVar, "node1")
+	defer os.Unsetenv(k8sutil.NodeNameEnvVar)
+
+	context := &clusterd.Context{
+		Clientset:     clientset,
+		RookClientset: rookclient.NewSimpleClientset(),
+	}
+	for _, name := range []string{"pvc-rox", "pvc-rwx"} {
+		existingCRD := rookalpha.NewVolume(name, "rook-system", "otherNode", "Default", "otherPod", "testCluster",
+			"/test/pods/other/volumes/rook.io~rook/"+name, false, v1.ReadWriteMany)
+		if name == "pvc-rox" {
+			existingCRD.Attachments[0].ReadOnly = true
+			existingCRD.Attachments[0].AccessMode = v1.ReadOnlyMany
+		}
+		_, err := context.RookClientset.RookV1alpha2().Volumes("rook-system").Create(existingCRD)
+		assert.Nil(t, err)
+	}
+
+	att, err := attachment.New(context)
+	assert.Nil(t, err)
+	mappedReadOnly := map[string]bool{}
+	controller := &Controller{
+		context:          context,
+		volumeAttachment: att,
+		volumeManager: &manager.FakeVolumeManager{
+			FakeAttach: func(image, pool, clusterName string, readOnly bool) (string, error) {
+				mappedReadOnly[image] = readOnly
+				return "/dev/rbd0", nil
+			},
+		},
+	}
+
+	// a read-only many volume is mapped read-only on every node
+	devicePath := ""
+	opts := AttachOptions{
+		Image:            "image-rox",
+		Pool:             "testpool",
+		ClusterNamespace: "testCluster",
+		MountDir:         "/test/pods/pod123/volumes/rook.io~rook/pvc-rox",
+		VolumeName:       "pvc-rox",
+		Pod:              "myPod",
+		PodNamespace:     "Default",
+		RW:               "ro",
+		AccessMode:       v1.ReadOnlyMany,
+	}
+	assert.Nil(t, controller.Attach(opts, &devicePath))
+	assert.True(t, mappedReadOnly["image-rox"])
+	volAtt, err := att.Get("rook-system", "pvc-rox")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(volAtt.Attachments))
+	assert.Equal(t, v1.ReadOnlyMany, volAtt.Attachments[1].AccessMode)
+	assert.True(t, volAtt.Attachments[1].ReadOnly)
+
+	// a single writer is not allowed next to the readers
+	opts.MountDir = "/test/pods/pod456/volumes/rook.io~rook/pvc-rox"
+	opts.Pod = "writerPod"
+	opts.AccessMode = v1.ReadWriteOnce
+	assert.NotNil(t, controller.Attach(opts, &devicePath))
+
+	// there is no single writer to enforce for a shared filesystem
+	opts = AttachOptions{
+		Image:            "image-rwx",
+		Pool:             "testpool",
+		ClusterNamespace: "testCluster",
+		MountDir:         "/test/pods/pod123/volumes/rook.io~rook/pvc-rwx",
+		VolumeName:       "pvc-rwx",
+		Pod:              "myPod",
+		PodNamespace:     "Default",
+		RW:               "rw",
+		AccessMode:       v1.ReadWriteMany,
+	}
+	assert.Nil(t, controller.Attach(opts, &devicePath))
+	assert.False(t, mappedReadOnly["image-rwx"])
+	volAtt, err = att.Get("rook-system", "pvc-rwx")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(volAtt.Attachments))
+
+	// nothing is mapped for the filesystem so there is nothing to detach
+	safeToDetach := false
+	assert.Nil(t, controller.RemoveAttachmentObject(opts, &safeToDetach))
+	assert.False(t, safeToDetach)
+	volAtt, err = att.Get("rook-system", "pvc-rwx")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(volAtt.Attachments))
+}
+
+func TestGetAccessMode(t *testing.T) {
+	pv := &v1.PersistentVolume{Spec: v1.PersistentVolumeSpec{
+		PersistentVolumeSource: v1.PersistentVolumeSource{FlexVolume: &v1.FlexPersistentVolumeSource{FSType: "ext4"}},
+	}}
+	assert.Equal(t, v1.ReadWriteOnce, getAccessMode(pv, "rw"))
+	assert.Equal(t, v1.ReadOnlyMany, getAccessMode(pv, "ro"))
+
+	pv.Spec.AccessModes = []v1.PersistentVolumeAccessMode{v1.ReadOnlyMany}
+	assert.Equal(t, v1.ReadOnlyMany, getAccessMode(pv, "rw"))
+	pv.Spec.AccessModes = []v1.PersistentVolumeAccessMode{v1.ReadOnlyMany, v1.ReadWriteOnce}
+	assert.Equal(t, v1.ReadWriteOnce, getAccessMode(pv, "rw"))
+
+	pv.Spec.FlexVolume.FSType = CephFS
+	assert.Equal(t, v1.ReadWriteMany, getAccessMode(pv, "rw"))
+
+	// attachments recorded without an access mode
+	assert.True(t, isSingleWriter(rookalpha.Attachment{}))
+	assert.False(t, isSingleWriter(rookalpha.Attachment{ReadOnly: true}))
+	assert.False(t, isSingleWriter(rookalpha.Attachment{AccessMode: v1.ReadWriteMany}))
+}
+
 func TestOrphanAttachOriginalPodDoesntExist(t *testing.T) {
 	clientset := test.New(3)
 
@@ -420,6 +541,14 @@ func TestOrphanAttachOriginalPodDoesntExist(t *testing.T) {
 		volumeManager:    &manager.FakeVolumeManager{},
 	}
 
+	// the volume is not taken over while the other node has not detached it
+	err = controller.Attach(opts, &devicePath)
+	assert.NotNil(t, err)
+
+	// the orphaned attachment on this node is taken over
+	volumeAttachment.Attachments[0].Node = "node1"
+	_, err = context.RookClientset.RookV1alpha2().Volumes("rook-system").Update(volumeAttachment)
+	assert.Nil(t, err)
 	err = controller.Attach(opts, &devicePath)
 	assert.Nil(t, err)
 
@@ -438,6 +567,93 @@ func TestOrphanAttachOriginalPodDoesntExist(t *testing.T) {
 	), "Volume crd does not contain expected attachment")
 }
 
+func TestOrphanAttachNodeLost(t *testing.T) {
+	clientset := test.New(3)
+
+	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-system")
+	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
+
+	os.Setenv(k8sutil.NodeNameEnvVar, "node1")
+	defer os.Unsetenv(k8sutil.NodeNameEnvVar)
+
+	context := &clusterd.Context{
+		Clientset:     clientset,
+		RookClientset: rookclient.NewSimpleClientset(),
+	}
+
+	// the old pod still exists on a node that has not been ready for 10 minutes
+	clientset.CoreV1().Pods("Default").Create(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "oldPod", Namespace: "Default"}})
+	clientset.CoreV1().Nodes().Create(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "otherNode"},
+		Status: v1.NodeStatus{Conditions: []v1.NodeCondition{{
+			Type:               v1.NodeReady,
+			Status:             v1.ConditionUnknown,
+			LastTransitionTime: metav1.NewTime(time.Now().Add(-10 * time.Minute)),
+		}}},
+	})
+
+	opts := AttachOptions{
+		Image:            "image123",
+		Pool:             "testpool",
+		ClusterNamespace: "testCluster",
+		MountDir:         "/test/pods/pod123/volumes/rook.io~rook/pvc-123",
+		VolumeName:       "pvc-123",
+		Pod:              "newPod",
+		PodNamespace:     "Default",
+		RW:               "rw",
+	}
+	existingCRD := &rookalpha.Volume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pvc-123",
+			Namespace: "rook-system",
+		},
+		Attachments: []rookalpha.Attachment{
+			{
+				Node:         "otherNode",
+				PodNamespace: "Default",
+				PodName:      "oldPod",
+				MountDir:     "/tmt/test",
+				ReadOnly:     false,
+			},
+		},
+	}
+	_, err := context.RookClientset.RookV1alpha2().Volumes("rook-system").Create(existingCRD)
+	assert.Nil(t, err)
+
+	att, err := attachment.New(context)
+	assert.Nil(t, err)
+
+	fenced := ""
+	devicePath := ""
+	controller := &Controller{
+		context:          context,
+		volumeAttachment: att,
+		volumeManager: &manager.FakeVolumeManager{
+			FakeFence: func(image, pool, clusterName string) error {
+				fenced = pool + "/" + image
+				return nil
+			},
+		},
+	}
+
+	// the attachment is not orphaned without a node lost timeout
+	err = controller.Attach(opts, &devicePath)
+	assert.NotNil(t, err)
+	assert.Equal(t, "", fenced)
+
+	// the volume is fenced before it is attached
+	controller.nodeLostTimeout = 5 * time.Minute
+	err = controller.Attach(opts, &devicePath)
+	assert.Nil(t, err)
+	assert.Equal(t, "testpool/image123", fenced)
+
+	volAtt, err := context.RookClientset.RookV1alpha2().Volumes("rook-system").Get("pvc-123", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(volAtt.Attachments))
+	assert.Equal(t, "node1", volAtt.Attachments[0].Node)
+	assert.Equal(t, "newPod", volAtt.Attachments[0].PodName)
+}
+
 func TestOrphanAttachOriginalPodNameSame(t *testing.T) {
 	clientset := test.New(3)
 
@@ -503,16 +719,34 @@ func TestOrphanAttachOriginalPodNameSame(t *testing.T) {
 	att, err := attachment.New(context)
 	assert.Nil(t, err)
 
+	fenced := false
 	controller := &Controller{
 		context:          context,
 		volumeAttachment: att,
-		volumeManager:    &manager.FakeVolumeManager{},
+		volumeManager: &manager.FakeVolumeManager{
+			FakeFence: func(image, pool, clusterName string) error {
+				fenced = true
+				return nil
+			},
+		},
+		nodeLostTimeout: 5 * time.Minute,
 	}
 
-	// Attach should succeed and the stale volumeattachment record should be updated to reflect the new pod information
+	// Attach should fail while the other node is healthy and has not detached the volume
+	clientset.CoreV1().Nodes().Create(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "otherNode"},
+		Status:     v1.NodeStatus{Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}}},
+	})
 	devicePath := ""
 	err = controller.Attach(opts, &devicePath)
+	assert.NotNil(t, err)
+	assert.False(t, fenced)
+
+	// Attach should succeed once the other node is lost and the stale volumeattachment record should be updated to reflect the new pod information
+	clientset.CoreV1().Nodes().Delete("otherNode", &metav1.DeleteOptions{})
+	err = controller.Attach(opts, &devicePath)
 	assert.Nil(t, err)
+	assert.True(t, fenced)
 
 	volAtt, err := context.RookClientset.RookV1alpha2().Volumes("rook-system").Get("pvc-123", metav1.GetOptions{})
 	assert.Nil(t, err)
@@ -777,6 +1011,7 @@ func TestGetAttachInfoFromMountDir(t *testing.T) {
 	assert.Equal(t, "pool123", opts.Pool)
 	assert.Equal(t, "storageClass1", opts.StorageClass)
 	assert.Equal(t, "testCluster", opts.ClusterNamespace)
+	assert.Equal(t, v1.ReadWriteOnce, opts.AccessMode)
 }
 
 func TestParseClusterNamespace(t *testing.T) {
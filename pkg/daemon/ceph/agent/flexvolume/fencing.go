@@ -0,0 +1,221 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flexvolume
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the interval at which the attachments of lost nodes are cleaned up
+var staleAttachmentInterval = time.Minute
+
+// the label of the agent pods
+const agentAppSelector = k8sutil.AppAttr + "=rook-ceph-agent"
+
+// isNodeLost returns whether the node has not been ready for longer than the node lost timeout. Nodes are never
+// lost if the timeout is not set.
+func (c *Controller) isNodeLost(nodeName string) bool {
+	if c.nodeLostTimeout == 0 {
+		return false
+	}
+
+	node, err := c.context.Clientset.CoreV1().Nodes().Get(nodeName, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			logger.Warningf("failed to get node %s. %+v", nodeName, err)
+			return false
+		}
+		// the node was removed from the cluster
+		return true
+	}
+
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status != v1.ConditionTrue && time.Since(condition.LastTransitionTime.Time) > c.nodeLostTimeout
+		}
+	}
+	return false
+}
+
+// CleanupStaleAttachments periodically removes the attachments of lost nodes from the volume records
+func (c *Controller) CleanupStaleAttachments(stopCh chan struct{}) {
+	if c.nodeLostTimeout == 0 {
+		logger.Infof("node lost timeout is not set. Stale attachments will not be cleaned up")
+		return
+	}
+
+	for {
+		select {
+		case <-stopCh:
+			logger.Infof("stopping the cleanup of stale attachments")
+			return
+
+		case <-time.After(staleAttachmentInterval):
+			cleanup, err := c.isCleanupAgent()
+			if err != nil {
+				logger.Warningf("failed to check which agent cleans up the stale attachments. %+v", err)
+				continue
+			}
+			if !cleanup {
+				continue
+			}
+			if err := c.cleanupStaleAttachments(); err != nil {
+				logger.Warningf("failed to clean up stale attachments. %+v", err)
+			}
+		}
+	}
+}
+
+// isCleanupAgent returns whether this agent cleans up the stale attachments. Only the running agent on the first ready
+// node by name does it, so that the agents do not fence the same volumes from different nodes.
+func (c *Controller) isCleanupAgent() (bool, error) {
+	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
+	pods, err := c.context.Clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: agentAppSelector})
+	if err != nil {
+		return false, fmt.Errorf("failed to list the agents in namespace %s. %+v", namespace, err)
+	}
+
+	first := ""
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != v1.PodRunning || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if first != "" && pod.Spec.NodeName >= first {
+			continue
+		}
+		node, err := c.context.Clientset.CoreV1().Nodes().Get(pod.Spec.NodeName, metav1.GetOptions{})
+		if err != nil || !isNodeReady(node) {
+			continue
+		}
+		first = pod.Spec.NodeName
+	}
+	return first != "" && first == os.Getenv(k8sutil.NodeNameEnvVar), nil
+}
+
+func isNodeReady(node *v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// cleanupStaleAttachments removes the attachments of the nodes that are lost. The volumes of single writer attachments
+// are fenced first so that they can safely be attached on another node.
+func (c *Controller) cleanupStaleAttachments() error {
+	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
+	volumes, err := c.volumeAttachment.List(namespace)
+	if err != nil {
+		return fmt.Errorf("failed to list volume attachments in namespace %s. %+v", namespace, err)
+	}
+
+	lostNodes := map[string]bool{}
+	for i := range volumes.Items {
+		volume := &volumes.Items[i]
+		attachments := []rookalpha.Attachment{}
+		for _, a := range volume.Attachments {
+			lost, ok := lostNodes[a.Node]
+			if !ok {
+				lost = c.isNodeLost(a.Node)
+				lostNodes[a.Node] = lost
+			}
+			if !lost {
+				attachments = append(attachments, a)
+				continue
+			}
+
+			// only the single writer of an image can corrupt it after the node is lost
+			if isSingleWriter(a) {
+				// the attachment may have been taken over by an attach on another node since the volumes were listed.
+				// fencing the volume would then blacklist the client of the healthy node, which all its volumes share.
+				stale, err := c.isStaleAttachment(namespace, volume.Name, a)
+				if err != nil || !stale {
+					if err != nil {
+						logger.Warningf("failed to check the attachment of volume %s on lost node %s. %+v", volume.Name, a.Node, err)
+					}
+					attachments = append(attachments, a)
+					continue
+				}
+				if err := c.fenceVolume(volume.Name, a.ClusterName); err != nil {
+					logger.Warningf("failed to fence volume %s on lost node %s. %+v", volume.Name, a.Node, err)
+					attachments = append(attachments, a)
+					continue
+				}
+			}
+			logger.Infof("removing stale attachment of volume %s for pod %s/%s on lost node %s", volume.Name, a.PodNamespace, a.PodName, a.Node)
+		}
+
+		if len(attachments) == len(volume.Attachments) {
+			continue
+		}
+		if len(attachments) == 0 {
+			err = c.volumeAttachment.Delete(namespace, volume.Name)
+		} else {
+			volume.Attachments = attachments
+			err = c.volumeAttachment.Update(volume)
+		}
+		// another agent may have cleaned up the volume at the same time
+		if err != nil && !errors.IsNotFound(err) && !errors.IsConflict(err) {
+			logger.Warningf("failed to remove stale attachments of volume %s. %+v", volume.Name, err)
+		}
+	}
+	return nil
+}
+
+// isStaleAttachment returns whether the current volume record still has the attachment on the lost node
+func (c *Controller) isStaleAttachment(namespace, volumeName string, stale rookalpha.Attachment) (bool, error) {
+	volume, err := c.volumeAttachment.Get(namespace, volumeName)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, a := range volume.Attachments {
+		if a.Node == stale.Node && a.PodNamespace == stale.PodNamespace && a.PodName == stale.PodName {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fenceVolume fences the image of the persistent volume from the clients on other nodes
+func (c *Controller) fenceVolume(volumeName, clusterNamespace string) error {
+	pv, err := c.context.Clientset.CoreV1().PersistentVolumes().Get(volumeName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.Infof("persistent volume %s does not exist. Nothing to fence", volumeName)
+			return nil
+		}
+		return fmt.Errorf("failed to get persistent volume %s: %+v", volumeName, err)
+	}
+	if pv.Spec.FlexVolume == nil {
+		return fmt.Errorf("persistent volume %s is not a flex volume", volumeName)
+	}
+
+	options := pv.Spec.FlexVolume.Options
+	return c.volumeManager.Fence(options[ImageKey], options[PoolKey], clusterNamespace)
+}
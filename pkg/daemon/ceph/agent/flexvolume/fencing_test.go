@@ -0,0 +1,198 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package flexvolume
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	rookclient "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/agent/flexvolume/attachment"
+	"github.com/rook/rook/pkg/daemon/ceph/agent/flexvolume/manager"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCleanupStaleAttachments(t *testing.T) {
+	clientset := test.New(0)
+
+	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-system")
+	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
+
+	context := &clusterd.Context{
+		Clientset:     clientset,
+		RookClientset: rookclient.NewSimpleClientset(),
+	}
+
+	now := time.Now()
+	for name, status := range map[string]v1.ConditionStatus{"ready": v1.ConditionTrue, "lost": v1.ConditionFalse} {
+		clientset.CoreV1().Nodes().Create(&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: v1.NodeStatus{Conditions: []v1.NodeCondition{{
+				Type:               v1.NodeReady,
+				Status:             status,
+				LastTransitionTime: metav1.NewTime(now.Add(-10 * time.Minute)),
+			}}},
+		})
+	}
+	clientset.CoreV1().PersistentVolumes().Create(&v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pvc-1"},
+		Spec: v1.PersistentVolumeSpec{PersistentVolumeSource: v1.PersistentVolumeSource{
+			FlexVolume: &v1.FlexPersistentVolumeSource{Options: map[string]string{ImageKey: "image1", PoolKey: "pool1"}},
+		}},
+	})
+
+	volumes := []*rookalpha.Volume{
+		{
+			ObjectMeta:  metav1.ObjectMeta{Name: "pvc-1", Namespace: "rook-system"},
+			Attachments: []rookalpha.Attachment{{Node: "lost", PodName: "pod1", ClusterName: "rook-ceph"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pvc-2", Namespace: "rook-system"},
+			Attachments: []rookalpha.Attachment{
+				{Node: "lost", PodName: "pod2", ReadOnly: true},
+				{Node: "ready", PodName: "pod3", ReadOnly: true},
+			},
+		},
+		{
+			ObjectMeta:  metav1.ObjectMeta{Name: "pvc-3", Namespace: "rook-system"},
+			Attachments: []rookalpha.Attachment{{Node: "ready", PodName: "pod4"}},
+		},
+		{
+			ObjectMeta:  metav1.ObjectMeta{Name: "pvc-4", Namespace: "rook-system"},
+			Attachments: []rookalpha.Attachment{{Node: "lost", PodName: "pod5", AccessMode: v1.ReadWriteMany}},
+		},
+	}
+	for _, volume := range volumes {
+		_, err := context.RookClientset.RookV1alpha2().Volumes("rook-system").Create(volume)
+		assert.Nil(t, err)
+	}
+
+	att, err := attachment.New(context)
+	assert.Nil(t, err)
+	fenced := []string{}
+	controller := &Controller{
+		context:          context,
+		volumeAttachment: att,
+		volumeManager: &manager.FakeVolumeManager{
+			FakeFence: func(image, pool, clusterName string) error {
+				fenced = append(fenced, clusterName+":"+pool+"/"+image)
+				return nil
+			},
+		},
+	}
+
+	// nothing is cleaned up before the node lost timeout
+	controller.nodeLostTimeout = time.Hour
+	assert.Nil(t, controller.cleanupStaleAttachments())
+	volumeList, err := att.List("rook-system")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(volumeList.Items))
+
+	// only the single writer volume of the lost node is fenced
+	controller.nodeLostTimeout = 5 * time.Minute
+	assert.Nil(t, controller.cleanupStaleAttachments())
+	assert.Equal(t, []string{"rook-ceph:pool1/image1"}, fenced)
+
+	_, err = att.Get("rook-system", "pvc-1")
+	assert.True(t, errors.IsNotFound(err))
+	volume, err := att.Get("rook-system", "pvc-2")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(volume.Attachments))
+	assert.Equal(t, "ready", volume.Attachments[0].Node)
+	volume, err = att.Get("rook-system", "pvc-3")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(volume.Attachments))
+	_, err = att.Get("rook-system", "pvc-4")
+	assert.True(t, errors.IsNotFound(err))
+
+	// a removed node is lost
+	assert.True(t, controller.isNodeLost("removed"))
+	assert.False(t, controller.isNodeLost("ready"))
+
+	// the volume is not fenced if the attachment was taken over by another node since the volumes were listed
+	fenced = []string{}
+	listed := &rookalpha.Volume{
+		ObjectMeta:  metav1.ObjectMeta{Name: "pvc-1", Namespace: "rook-system"},
+		Attachments: []rookalpha.Attachment{{Node: "lost", PodName: "pod1", ClusterName: "rook-ceph"}},
+	}
+	controller.volumeAttachment = &attachment.MockAttachment{
+		MockList: func(namespace string) (*rookalpha.VolumeList, error) {
+			return &rookalpha.VolumeList{Items: []rookalpha.Volume{*listed}}, nil
+		},
+		MockGet: func(namespace, name string) (*rookalpha.Volume, error) {
+			current := listed.DeepCopy()
+			current.Attachments[0].Node = "ready"
+			return current, nil
+		},
+		MockUpdate: func(volume *rookalpha.Volume) error {
+			return errors.NewConflict(rookalpha.Resource("volume"), volume.Name, nil)
+		},
+	}
+	assert.Nil(t, controller.cleanupStaleAttachments())
+	assert.Equal(t, 0, len(fenced))
+}
+
+func TestIsCleanupAgent(t *testing.T) {
+	clientset := test.New(3)
+	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-system")
+	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
+	controller := &Controller{context: &clusterd.Context{Clientset: clientset}}
+
+	for i := 0; i < 3; i++ {
+		node, err := clientset.CoreV1().Nodes().Get(fmt.Sprintf("node%d", i), metav1.GetOptions{})
+		assert.Nil(t, err)
+		node.Status.Conditions[0].Status = v1.ConditionTrue
+		_, err = clientset.CoreV1().Nodes().Update(node)
+		assert.Nil(t, err)
+		clientset.CoreV1().Pods("rook-system").Create(&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("rook-ceph-agent-%d", i), Namespace: "rook-system",
+				Labels: map[string]string{k8sutil.AppAttr: "rook-ceph-agent"}},
+			Spec:   v1.PodSpec{NodeName: fmt.Sprintf("node%d", i)},
+			Status: v1.PodStatus{Phase: v1.PodRunning},
+		})
+	}
+
+	// only the agent on the first ready node cleans up
+	os.Setenv(k8sutil.NodeNameEnvVar, "node0")
+	defer os.Unsetenv(k8sutil.NodeNameEnvVar)
+	cleanup, err := controller.isCleanupAgent()
+	assert.Nil(t, err)
+	assert.True(t, cleanup)
+	os.Setenv(k8sutil.NodeNameEnvVar, "node1")
+	cleanup, err = controller.isCleanupAgent()
+	assert.Nil(t, err)
+	assert.False(t, cleanup)
+
+	// the next agent takes over when the node of the first agent is not ready
+	node, err := clientset.CoreV1().Nodes().Get("node0", metav1.GetOptions{})
+	assert.Nil(t, err)
+	node.Status.Conditions[0].Status = v1.ConditionFalse
+	_, err = clientset.CoreV1().Nodes().Update(node)
+	assert.Nil(t, err)
+	cleanup, err = controller.isCleanupAgent()
+	assert.Nil(t, err)
+	assert.True(t, cleanup)
+}
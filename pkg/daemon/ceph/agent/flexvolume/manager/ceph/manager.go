@@ -95,8 +95,8 @@ func (vm *VolumeManager) Init() error {
 	return nil
 }
 
-// Attach a ceph image to the node
-func (vm *VolumeManager) Attach(image, pool, clusterNamespace string) (string, error) {
+// Attach a ceph image to the node. Read-only images are mapped read-only so that they can be shared by many nodes.
+func (vm *VolumeManager) Attach(image, pool, clusterNamespace string, readOnly bool) (string, error) {
 
 	// check if the volume is already attached
 	devicePath, err := vm.isAttached(image, pool, clusterNamespace)
@@ -109,14 +109,14 @@ func (vm *VolumeManager) Attach(image, pool, clusterNamespace string) (string, e
 	}
 
 	// attach and poll until volume is mapped
-	logger.Infof("attaching volume %s/%s cluster %s", pool, image, clusterNamespace)
+	logger.Infof("attaching volume %s/%s cluster %s (read-only: %t)", pool, image, clusterNamespace, readOnly)
 	monitors, keyring, err := getClusterInfo(vm.context, clusterNamespace)
 	defer os.Remove(keyring)
 	if err != nil {
 		return "", fmt.Errorf("failed to load cluster information from cluster %s: %+v", clusterNamespace, err)
 	}
 
-	err = cephclient.MapImage(vm.context, image, pool, clusterNamespace, keyring, monitors)
+	err = cephclient.MapImage(vm.context, image, pool, clusterNamespace, keyring, monitors, readOnly)
 	if err != nil {
 		return "", fmt.Errorf("failed to map image %s/%s cluster %s. %+v", pool, image, clusterNamespace, err)
 	}
@@ -170,6 +170,59 @@ func (vm *VolumeManager) Detach(image, pool, clusterNamespace string, force bool
 	return nil
 }
 
+// Fence blacklists the clients that have the image mapped on other nodes and breaks their locks. A node that lost
+// its connection to the cluster can then no longer write to the image once it is mapped on this node.
+func (vm *VolumeManager) Fence(image, pool, clusterNamespace string) error {
+	devicePath, err := vm.isAttached(image, pool, clusterNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to check if volume %s/%s is attached: %+v", pool, image, err)
+	}
+	if devicePath != "" {
+		logger.Infof("volume %s/%s is attached to this node. Not fencing", pool, image)
+		return nil
+	}
+
+	monitors, keyring, err := getClusterInfo(vm.context, clusterNamespace)
+	defer os.Remove(keyring)
+	if err != nil {
+		return fmt.Errorf("failed to load cluster information from cluster %s: %+v", clusterNamespace, err)
+	}
+
+	watchers, err := cephclient.ListImageWatchers(vm.context, image, pool, clusterNamespace, keyring, monitors)
+	if err != nil {
+		return err
+	}
+	locks, err := cephclient.ListImageLocks(vm.context, image, pool, clusterNamespace, keyring, monitors)
+	if err != nil {
+		return err
+	}
+
+	clients := watchers
+	for _, lock := range locks {
+		clients = append(clients, lock.Address)
+	}
+	blacklisted := map[string]bool{}
+	for _, address := range clients {
+		if blacklisted[address] {
+			continue
+		}
+		blacklisted[address] = true
+		logger.Infof("blacklisting client %s of volume %s/%s", address, pool, image)
+		if err := cephclient.BlacklistClient(vm.context, clusterNamespace, address, keyring, monitors); err != nil {
+			return err
+		}
+	}
+
+	// the blacklisted clients cannot release their locks
+	for _, lock := range locks {
+		logger.Infof("breaking lock %s of client %s on volume %s/%s", lock.ID, lock.Locker, pool, image)
+		if err := cephclient.RemoveImageLock(vm.context, image, pool, clusterNamespace, keyring, monitors, lock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Check if the volume is attached
 func (vm *VolumeManager) isAttached(image, pool, clusterNamespace string) (string, error) {
 	devicePath, err := vm.devicePathFinder.FindDevicePath(image, pool, clusterNamespace)
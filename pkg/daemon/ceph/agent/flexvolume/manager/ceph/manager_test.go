@@ -134,6 +134,7 @@ func TestAttach(t *testing.T) {
 			assert.Contains(t, args[7], "10.0.0.1:6790", fmt.Sprintf("But '%s' does contain '%s'", args[7], "10.0.0.1:6790"))
 			assert.Contains(t, args[7], "10.0.0.2:6790", fmt.Sprintf("But '%s' does contain '%s'", args[7], "10.0.0.2:6790"))
 			assert.Contains(t, args[7], "10.0.0.3:6790", fmt.Sprintf("But '%s' does contain '%s'", args[7], "10.0.0.3:6790"))
+			assert.Equal(t, "--read-only", args[9])
 			return "", nil
 		},
 	}
@@ -152,7 +153,7 @@ func TestAttach(t *testing.T) {
 	}
 	mon.CreateOrLoadClusterInfo(context, clusterNamespace, &metav1.OwnerReference{})
 
-	devicePath, err := vm.Attach("image1", "testpool", clusterNamespace)
+	devicePath, err := vm.Attach("image1", "testpool", clusterNamespace, true)
 	assert.Equal(t, "/dev/rbd3", devicePath)
 	assert.Nil(t, err)
 }
@@ -165,7 +166,7 @@ func TestAttachAlreadyExists(t *testing.T) {
 			called:   0,
 		},
 	}
-	devicePath, err := vm.Attach("image1", "testpool", "testCluster")
+	devicePath, err := vm.Attach("image1", "testpool", "testCluster", false)
 	assert.Equal(t, "/dev/rbd3", devicePath)
 	assert.Nil(t, err)
 }
@@ -233,3 +234,68 @@ func TestAlreadyDetached(t *testing.T) {
 	err := vm.Detach("image1", "testpool", "testCluster", false)
 	assert.Nil(t, err)
 }
+
+func TestFence(t *testing.T) {
+	clientset := test.New(3)
+	clusterNamespace := "testCluster"
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	cm := &v1.ConfigMap{
+		Data: map[string]string{
+			"data": "rook-ceph-mon0=10.0.0.1:6790",
+		},
+	}
+	cm.Name = "rook-ceph-mon-endpoints"
+	clientset.CoreV1().ConfigMaps(clusterNamespace).Create(cm)
+
+	commands := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if strings.Contains(command, "ceph-authtool") {
+				cephtest.CreateConfigDir(path.Join(configDir, clusterNamespace))
+			}
+
+			return "", nil
+		},
+		MockExecuteCommandWithTimeout: func(debug bool, timeout time.Duration, actionName string, command string, args ...string) (string, error) {
+			commands = append(commands, command+" "+strings.Join(args[:4], " "))
+			switch {
+			case command == "rbd" && args[0] == "status":
+				return `{"watchers":[{"address":"10.0.0.5:0/1234","client":4123,"cookie":1}]}`, nil
+			case command == "rbd" && args[0] == "lock" && args[1] == "ls":
+				return `{"auto 1":{"locker":"client.4123","address":"10.0.0.5:0/1234"}}`, nil
+			}
+			return "", nil
+		},
+	}
+
+	context := &clusterd.Context{
+		Clientset: clientset,
+		Executor:  executor,
+		ConfigDir: configDir,
+	}
+	vm := &VolumeManager{
+		context: context,
+		devicePathFinder: &fakeDevicePathFinder{
+			response: []string{"", "/dev/rbd3"},
+			called:   0,
+		},
+	}
+	mon.CreateOrLoadClusterInfo(context, clusterNamespace, &metav1.OwnerReference{})
+
+	// the previous client is blacklisted once and its lock is broken
+	err := vm.Fence("image1", "testpool", clusterNamespace)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"rbd status testpool/image1 --id admin",
+		"rbd lock ls testpool/image1 --id",
+		"ceph osd blacklist add 10.0.0.5:0/1234",
+		"rbd lock rm testpool/image1 auto 1",
+	}, commands)
+
+	// a volume attached to this node is not fenced
+	commands = []string{}
+	err = vm.Fence("image1", "testpool", clusterNamespace)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(commands))
+}
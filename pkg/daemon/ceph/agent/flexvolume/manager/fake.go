@@ -21,8 +21,9 @@ import "fmt"
 // FakeVolumeManager represents a fake (mocked) implementation of the VolumeManager interface for testing.
 type FakeVolumeManager struct {
 	FakeInit   func() error
-	FakeAttach func(image, pool, clusterName string) (string, error)
+	FakeAttach func(image, pool, clusterName string, readOnly bool) (string, error)
 	FakeDetach func(image, pool, clusterName string, force bool) error
+	FakeFence  func(image, pool, clusterName string) error
 }
 
 // Init initializes the FakeVolumeManager
@@ -34,9 +35,9 @@ func (f *FakeVolumeManager) Init() error {
 }
 
 // Attach a volume image to the node
-func (f *FakeVolumeManager) Attach(image, pool, clusterName string) (string, error) {
+func (f *FakeVolumeManager) Attach(image, pool, clusterName string, readOnly bool) (string, error) {
 	if f.FakeAttach != nil {
-		return f.FakeAttach(image, pool, clusterName)
+		return f.FakeAttach(image, pool, clusterName, readOnly)
 	}
 	return fmt.Sprintf("/%s/%s/%s", image, pool, clusterName), nil
 }
@@ -48,3 +49,11 @@ func (f *FakeVolumeManager) Detach(image, pool, clusterName string, force bool)
 	}
 	return nil
 }
+
+// Fence the clients of a volume image on other nodes
+func (f *FakeVolumeManager) Fence(image, pool, clusterName string) error {
+	if f.FakeFence != nil {
+		return f.FakeFence(image, pool, clusterName)
+	}
+	return nil
+}
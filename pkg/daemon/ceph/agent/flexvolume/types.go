@@ -16,16 +16,22 @@ limitations under the License.
 
 package flexvolume
 
+import (
+	"k8s.io/api/core/v1"
+)
+
 const (
 	ReadOnly  = "ro"
 	ReadWrite = "rw"
+	CephFS    = "ceph"
 )
 
 // VolumeManager handles flexvolume plugin storage operations
 type VolumeManager interface {
 	Init() error
-	Attach(image, pool, clusterName string) (string, error)
+	Attach(image, pool, clusterName string, readOnly bool) (string, error)
 	Detach(image, pool, clusterName string, force bool) error
+	Fence(image, pool, clusterName string) error
 }
 
 type VolumeController interface {
@@ -52,6 +58,8 @@ type AttachOptions struct {
 	Pod              string `json:"kubernetes.io/pod.name"`
 	PodID            string `json:"kubernetes.io/pod.uid"`
 	PodNamespace     string `json:"kubernetes.io/pod.namespace"`
+	// AccessMode is the access mode of the persistent volume, which is looked up by the agent
+	AccessMode v1.PersistentVolumeAccessMode `json:"accessMode,omitempty"`
 }
 
 type LogMessage struct {
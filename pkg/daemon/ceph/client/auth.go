@@ -69,6 +69,16 @@ func AuthGetOrCreateKey(context *clusterd.Context, clusterName, name string, cap
 	return parseAuthKey(buf)
 }
 
+// AuthUpdateCaps updates the capabilities of the given user.
+func AuthUpdateCaps(context *clusterd.Context, clusterName, name string, caps []string) error {
+	args := append([]string{"auth", "caps", name}, caps...)
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to update caps for %s. %+v", name, err)
+	}
+	return nil
+}
+
 // AuthDelete will delete the given user.
 func AuthDelete(context *clusterd.Context, clusterName, name string) error {
 	args := []string{"auth", "del", name}
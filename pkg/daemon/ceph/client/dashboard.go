@@ -0,0 +1,46 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/pkg/clusterd"
+)
+
+// DashboardSetLoginCredentials sets the username and password of the dashboard admin user
+func DashboardSetLoginCredentials(context *clusterd.Context, clusterName, username, password string) error {
+	args := []string{"dashboard", "set-login-credentials", username, password}
+	// run the command in debug mode so the password is not logged by default
+	_, err := executeCephCommandWithOutputFile(context, clusterName, true, args)
+	if err != nil {
+		return fmt.Errorf("failed to set dashboard login credentials for %s: %+v", username, err)
+	}
+
+	return nil
+}
+
+// DashboardCreateSelfSignedCert generates a self-signed certificate for the dashboard
+func DashboardCreateSelfSignedCert(context *clusterd.Context, clusterName string) error {
+	args := []string{"dashboard", "create-self-signed-cert"}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to create dashboard self-signed cert: %+v", err)
+	}
+
+	return nil
+}
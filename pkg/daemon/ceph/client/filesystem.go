@@ -124,19 +124,14 @@ func CreateFilesystem(context *clusterd.Context, clusterName, name, metadataPool
 
 	// add each additional pool
 	for i := 1; i < len(dataPools); i++ {
-		poolName := dataPools[i]
-		args = []string{"fs", "add_data_pool", name, poolName}
-		_, err = ExecuteCephCommand(context, clusterName, args)
-		if err != nil {
-			logger.Errorf("failed to add pool %s to file system %s. %+v", poolName, name, err)
+		if err := AddDataPoolToFilesystem(context, clusterName, name, dataPools[i]); err != nil {
+			logger.Errorf("%+v", err)
 		}
 	}
 
 	// set the number of active mds instances
 	if activeMDSCount > 1 {
-		args = []string{"fs", "set", name, "max_mds", strconv.Itoa(int(activeMDSCount))}
-		_, err = ExecuteCephCommand(context, clusterName, args)
-		if err != nil {
+		if err := SetNumMDSRanks(context, clusterName, name, activeMDSCount); err != nil {
 			logger.Warningf("failed setting active mds count to %d. %+v", activeMDSCount, err)
 		}
 	}
@@ -144,6 +139,56 @@ func CreateFilesystem(context *clusterd.Context, clusterName, name, metadataPool
 	return nil
 }
 
+// AddDataPoolToFilesystem adds an existing pool as a data pool of the file system
+func AddDataPoolToFilesystem(context *clusterd.Context, clusterName, fsName, poolName string) error {
+	args := []string{"fs", "add_data_pool", fsName, poolName}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to add pool %s to file system %s. %+v", poolName, fsName, err)
+	}
+	return nil
+}
+
+// RemoveDataPoolFromFilesystem removes a data pool from the file system. The pool itself is not deleted.
+func RemoveDataPoolFromFilesystem(context *clusterd.Context, clusterName, fsName, poolName string) error {
+	args := []string{"fs", "rm_data_pool", fsName, poolName}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to remove pool %s from file system %s. %+v", poolName, fsName, err)
+	}
+	return nil
+}
+
+// SetNumMDSRanks sets the number of active mds ranks (max_mds) of the file system
+func SetNumMDSRanks(context *clusterd.Context, clusterName, fsName string, activeMDSCount int32) error {
+	args := []string{"fs", "set", fsName, "max_mds", strconv.Itoa(int(activeMDSCount))}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to set max_mds of file system %s to %d. %+v", fsName, activeMDSCount, err)
+	}
+	return nil
+}
+
+// DeactivateMDSRank stops an active mds rank of the file system after max_mds has been reduced
+func DeactivateMDSRank(context *clusterd.Context, clusterName, fsName string, rank int) error {
+	args := []string{"mds", "deactivate", fmt.Sprintf("%s:%d", fsName, rank)}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate mds rank %d of file system %s. %+v", rank, fsName, err)
+	}
+	return nil
+}
+
+// AllowStandbyReplay sets whether the standby mds daemons of the file system follow the journal of the active ranks
+func AllowStandbyReplay(context *clusterd.Context, clusterName, fsName string, allow bool) error {
+	args := []string{"fs", "set", fsName, "allow_standby_replay", strconv.FormatBool(allow)}
+	_, err := ExecuteCephCommand(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to set allow_standby_replay of file system %s to %t. %+v", fsName, allow, err)
+	}
+	return nil
+}
+
 func MarkFilesystemAsDown(context *clusterd.Context, clusterName string, fsName string) error {
 	args := []string{"fs", "set", fsName, "cluster_down", "true"}
 	_, err := ExecuteCephCommand(context, clusterName, args)
@@ -23,6 +23,7 @@ import (
 	"strconv"
 
 	"regexp"
+	"sort"
 
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/util/exec"
@@ -123,8 +124,9 @@ func DeleteImage(context *clusterd.Context, clusterName, name, poolName string)
 	return nil
 }
 
-// MapImage maps an RBD image using admin cephfx and returns the device path
-func MapImage(context *clusterd.Context, imageName, poolName, clusterName, keyring, monitors string) error {
+// MapImage maps an RBD image using admin cephfx and returns the device path. A read-only image can be mapped on
+// many nodes at the same time.
+func MapImage(context *clusterd.Context, imageName, poolName, clusterName, keyring, monitors string, readOnly bool) error {
 	imageSpec := getImageSpec(imageName, poolName)
 	args := []string{
 		"map",
@@ -135,6 +137,9 @@ func MapImage(context *clusterd.Context, imageName, poolName, clusterName, keyri
 		"-m", monitors,
 		"--conf=/dev/null", // no config file needed because we are passing all required config as arguments
 	}
+	if readOnly {
+		args = append(args, "--read-only")
+	}
 
 	output, err := ExecuteRBDCommandWithTimeout(context, clusterName, args)
 	if err != nil {
@@ -169,6 +174,88 @@ func UnMapImage(context *clusterd.Context, imageName, poolName, clusterName, key
 	return nil
 }
 
+// ImageLock is a lock of an RBD image, e.g., the exclusive lock of a client that has mapped the image
+type ImageLock struct {
+	ID      string `json:"id"`
+	Locker  string `json:"locker"`
+	Address string `json:"address"`
+}
+
+// ListImageLocks lists the locks of an RBD image
+func ListImageLocks(context *clusterd.Context, imageName, poolName, clusterName, keyring, monitors string) ([]ImageLock, error) {
+	imageSpec := getImageSpec(imageName, poolName)
+	args := append([]string{"lock", "ls", imageSpec}, clientArgs(clusterName, keyring, monitors)...)
+	args = append(args, "--format", "json")
+	output, err := ExecuteRBDCommandWithTimeout(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list locks of image %s: %+v. output: %s", imageSpec, err, output)
+	}
+
+	// the locks are a list in mimic and a map by lock id in older versions
+	var locks []ImageLock
+	if err := json.Unmarshal([]byte(output), &locks); err == nil {
+		return locks, nil
+	}
+	lockMap := map[string]ImageLock{}
+	if err := json.Unmarshal([]byte(output), &lockMap); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal locks of image %s: %+v. output: %s", imageSpec, err, output)
+	}
+	for id, lock := range lockMap {
+		lock.ID = id
+		locks = append(locks, lock)
+	}
+	sort.Slice(locks, func(i, j int) bool { return locks[i].ID < locks[j].ID })
+	return locks, nil
+}
+
+// RemoveImageLock breaks a lock of an RBD image
+func RemoveImageLock(context *clusterd.Context, imageName, poolName, clusterName, keyring, monitors string, lock ImageLock) error {
+	imageSpec := getImageSpec(imageName, poolName)
+	args := append([]string{"lock", "rm", imageSpec, lock.ID, lock.Locker}, clientArgs(clusterName, keyring, monitors)...)
+	output, err := ExecuteRBDCommandWithTimeout(context, clusterName, args)
+	if err != nil {
+		return fmt.Errorf("failed to remove lock %s of image %s: %+v. output: %s", lock.ID, imageSpec, err, output)
+	}
+	return nil
+}
+
+// ListImageWatchers returns the addresses of the clients that watch an RBD image, i.e., that have it mapped
+func ListImageWatchers(context *clusterd.Context, imageName, poolName, clusterName, keyring, monitors string) ([]string, error) {
+	imageSpec := getImageSpec(imageName, poolName)
+	args := append([]string{"status", imageSpec}, clientArgs(clusterName, keyring, monitors)...)
+	args = append(args, "--format", "json")
+	output, err := ExecuteRBDCommandWithTimeout(context, clusterName, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status of image %s: %+v. output: %s", imageSpec, err, output)
+	}
+
+	var status struct {
+		Watchers []struct {
+			Address string `json:"address"`
+		} `json:"watchers"`
+	}
+	if err := json.Unmarshal([]byte(output), &status); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal status of image %s: %+v. output: %s", imageSpec, err, output)
+	}
+
+	addresses := []string{}
+	for _, watcher := range status.Watchers {
+		addresses = append(addresses, watcher.Address)
+	}
+	return addresses, nil
+}
+
+// clientArgs returns the args to connect to the cluster as admin without a config file
+func clientArgs(clusterName, keyring, monitors string) []string {
+	return []string{
+		"--id", "admin",
+		fmt.Sprintf("--cluster=%s", clusterName),
+		fmt.Sprintf("--keyring=%s", keyring),
+		"-m", monitors,
+		"--conf=/dev/null",
+	}
+}
+
 func getImageSpec(name, poolName string) string {
 	return fmt.Sprintf("%s/%s", poolName, name)
 }
@@ -18,6 +18,7 @@ package client
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"strings"
 
@@ -237,3 +238,34 @@ func TestListImageLogLevelDebug(t *testing.T) {
 	assert.True(t, listCalled)
 	listCalled = false
 }
+
+func TestListImageLocks(t *testing.T) {
+	output := ""
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithTimeout: func(debug bool, timeout time.Duration, actionName string, command string, args ...string) (string, error) {
+			assert.Equal(t, "rbd", command)
+			assert.Equal(t, []string{"lock", "ls", "pool1/image1"}, args[:3])
+			return output, nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	// the locks are a map by id before mimic
+	output = `{"auto 2":{"locker":"client.2","address":"10.0.0.2:0/2"},"auto 1":{"locker":"client.1","address":"10.0.0.1:0/1"}}`
+	locks, err := ListImageLocks(context, "image1", "pool1", "foocluster", "keyring", "10.0.0.10:6790")
+	assert.Nil(t, err)
+	assert.Equal(t, []ImageLock{
+		{ID: "auto 1", Locker: "client.1", Address: "10.0.0.1:0/1"},
+		{ID: "auto 2", Locker: "client.2", Address: "10.0.0.2:0/2"},
+	}, locks)
+
+	output = `[{"id":"auto 3","locker":"client.3","address":"10.0.0.3:0/3"}]`
+	locks, err = ListImageLocks(context, "image1", "pool1", "foocluster", "keyring", "10.0.0.10:6790")
+	assert.Nil(t, err)
+	assert.Equal(t, []ImageLock{{ID: "auto 3", Locker: "client.3", Address: "10.0.0.3:0/3"}}, locks)
+
+	output = `{}`
+	locks, err = ListImageLocks(context, "image1", "pool1", "foocluster", "keyring", "10.0.0.10:6790")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(locks))
+}
@@ -28,3 +28,31 @@ func enableModule(context *clusterd.Context, clusterName, name string, force boo
 
 	return nil
 }
+
+// MgrGetModuleConfig gets a configuration setting of a mgr module
+func MgrGetModuleConfig(context *clusterd.Context, clusterName, module, key string) (string, error) {
+	args := []string{"config-key", "get", moduleConfigKey(module, key)}
+	buf, err := executeCephCommandWithOutputFile(context, clusterName, true, args)
+	if err != nil {
+		return "", fmt.Errorf("failed to get mgr module %s setting %s: %+v", module, key, err)
+	}
+
+	return string(buf), nil
+}
+
+// MgrSetModuleConfig sets a configuration setting of a mgr module
+func MgrSetModuleConfig(context *clusterd.Context, clusterName, module, key, value string) error {
+	args := []string{"config-key", "set", moduleConfigKey(module, key), value}
+	// the value may be a secret such as a certificate key, only log it in debug mode
+	_, err := executeCephCommandWithOutputFile(context, clusterName, true, args)
+	if err != nil {
+		return fmt.Errorf("failed to set mgr module %s setting %s: %+v", module, key, err)
+	}
+
+	return nil
+}
+
+// the module settings are stored in the mon config-key store with the "mgr/<module>/" prefix
+func moduleConfigKey(module, key string) string {
+	return fmt.Sprintf("mgr/%s/%s", module, key)
+}
@@ -180,6 +180,18 @@ func EnableScrubbing(context *clusterd.Context, clusterName string) (string, err
 	return string(buf), nil
 }
 
+// BlacklistClient prevents a client from doing any more I/O in the cluster. The client is identified by its address,
+// e.g., the address of a watcher or locker of an RBD image. The keyring and monitors are passed explicitly for callers
+// without a cluster config file.
+func BlacklistClient(context *clusterd.Context, clusterName, address, keyring, monitors string) error {
+	args := append([]string{"osd", "blacklist", "add", address}, clientArgs(clusterName, keyring, monitors)...)
+	output, err := context.Executor.ExecuteCommandWithTimeout(false, cmdExecuteTimeout, "", CephTool, args...)
+	if err != nil {
+		return fmt.Errorf("failed to blacklist client %s: %+v. output: %s", address, err, output)
+	}
+	return nil
+}
+
 func (usage *OSDUsage) ByID(osdID int) *OSDNodeUsage {
 	for i := range usage.OSDNodes {
 		if usage.OSDNodes[i].ID == osdID {
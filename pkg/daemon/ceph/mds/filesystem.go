@@ -17,6 +17,9 @@ package mds
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/daemon/ceph/client"
@@ -35,6 +38,11 @@ const (
 	appName               = "cephfs"
 )
 
+var (
+	rankStopRetryInterval = 5 * time.Second
+	rankStopRetries       = 60
+)
+
 // A file system (an instance of CephFS)
 type Filesystem struct {
 	Name           string
@@ -86,15 +94,8 @@ func (f *Filesystem) CreateFilesystem(context *clusterd.Context, clusterName str
 	var dataPoolNames []string
 	for _, pool := range f.dataPools {
 		dataPoolNames = append(dataPoolNames, pool.Name)
-		err = client.CreatePoolWithProfile(context, clusterName, *pool, appName)
-		if err != nil {
-			return fmt.Errorf("failed to create data pool %s. %+v", pool.Name, err)
-		}
-		if pool.Type == model.ErasureCoded {
-			// An erasure coded data pool used for a file system must allow overwrites
-			if err := client.SetPoolProperty(context, clusterName, pool.Name, "allow_ec_overwrites", "true"); err != nil {
-				logger.Warningf("failed to set ec pool property. %+v", err)
-			}
+		if err := createDataPool(context, clusterName, pool); err != nil {
+			return err
 		}
 	}
 
@@ -107,6 +108,131 @@ func (f *Filesystem) CreateFilesystem(context *clusterd.Context, clusterName str
 	return nil
 }
 
+// UpdateFilesystem applies changes of the data pools and of the number of active mds to an existing file system
+func (f *Filesystem) UpdateFilesystem(context *clusterd.Context, clusterName string) error {
+	fs, err := client.GetFilesystem(context, clusterName, f.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get file system %s. %+v", f.Name, err)
+	}
+
+	if err := f.updateDataPools(context, clusterName, fs); err != nil {
+		return err
+	}
+
+	return f.updateActiveMDSCount(context, clusterName, fs)
+}
+
+func (f *Filesystem) updateDataPools(context *clusterd.Context, clusterName string, fs *client.CephFilesystemDetails) error {
+	poolNames, err := client.GetPoolNamesByID(context, clusterName)
+	if err != nil {
+		return fmt.Errorf("failed to get pool names. %+v", err)
+	}
+	current := map[string]bool{}
+	for _, id := range fs.MDSMap.DataPools {
+		current[poolNames[id]] = true
+	}
+
+	desired := map[string]bool{}
+	for _, pool := range f.dataPools {
+		desired[pool.Name] = true
+		if current[pool.Name] {
+			continue
+		}
+		if err := createDataPool(context, clusterName, pool); err != nil {
+			return err
+		}
+		if err := client.AddDataPoolToFilesystem(context, clusterName, f.Name, pool.Name); err != nil {
+			return err
+		}
+		logger.Infof("added data pool %s to file system %s", pool.Name, f.Name)
+	}
+
+	// only remove the data pools created for the file system. the first data pool holds the file system
+	// metadata and cannot be removed.
+	prefix := fmt.Sprintf("%s-%s", f.Name, dataPoolSuffix)
+	for i, id := range fs.MDSMap.DataPools {
+		name := poolNames[id]
+		if desired[name] || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if i == 0 {
+			logger.Warningf("cannot remove the default data pool %s from file system %s", name, f.Name)
+			continue
+		}
+		if err := client.RemoveDataPoolFromFilesystem(context, clusterName, f.Name, name); err != nil {
+			return err
+		}
+		logger.Infof("removed data pool %s from file system %s. the pool was not deleted", name, f.Name)
+	}
+
+	return nil
+}
+
+func (f *Filesystem) updateActiveMDSCount(context *clusterd.Context, clusterName string, fs *client.CephFilesystemDetails) error {
+	count := int(f.activeMDSCount)
+	if fs.MDSMap.MaxMDS == count {
+		return nil
+	}
+
+	logger.Infof("changing the number of active mds of file system %s from %d to %d", f.Name, fs.MDSMap.MaxMDS, count)
+	if err := client.SetNumMDSRanks(context, clusterName, f.Name, f.activeMDSCount); err != nil {
+		return err
+	}
+	if count > fs.MDSMap.MaxMDS {
+		return nil
+	}
+
+	// stop the ranks that are no longer active, starting with the highest rank
+	ranks := []int{}
+	for _, rank := range fs.MDSMap.In {
+		if rank >= count {
+			ranks = append(ranks, rank)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ranks)))
+	for _, rank := range ranks {
+		if err := client.DeactivateMDSRank(context, clusterName, f.Name, rank); err != nil {
+			// newer versions of ceph stop the ranks when max_mds is reduced
+			logger.Warningf("%+v", err)
+		}
+	}
+
+	return f.waitForRanksToStop(context, clusterName)
+}
+
+// waitForRanksToStop waits until only the desired number of ranks is active so that the mds daemons
+// can safely be removed
+func (f *Filesystem) waitForRanksToStop(context *clusterd.Context, clusterName string) error {
+	for i := 0; i < rankStopRetries; i++ {
+		fs, err := client.GetFilesystem(context, clusterName, f.Name)
+		if err != nil {
+			return fmt.Errorf("failed to get file system %s. %+v", f.Name, err)
+		}
+		if len(fs.MDSMap.In) <= int(f.activeMDSCount) {
+			logger.Infof("file system %s has %d active ranks", f.Name, len(fs.MDSMap.In))
+			return nil
+		}
+
+		logger.Infof("waiting for %d mds ranks of file system %s to stop", len(fs.MDSMap.In)-int(f.activeMDSCount), f.Name)
+		time.Sleep(rankStopRetryInterval)
+	}
+
+	return fmt.Errorf("timed out waiting for the mds ranks of file system %s to stop", f.Name)
+}
+
+func createDataPool(context *clusterd.Context, clusterName string, pool *model.Pool) error {
+	if err := client.CreatePoolWithProfile(context, clusterName, *pool, appName); err != nil {
+		return fmt.Errorf("failed to create data pool %s. %+v", pool.Name, err)
+	}
+	if pool.Type == model.ErasureCoded {
+		// An erasure coded data pool used for a file system must allow overwrites
+		if err := client.SetPoolProperty(context, clusterName, pool.Name, "allow_ec_overwrites", "true"); err != nil {
+			logger.Warningf("failed to set ec pool property. %+v", err)
+		}
+	}
+	return nil
+}
+
 // Remove the file system in ceph
 func DeleteFilesystem(context *clusterd.Context, clusterName, filesystemName string) error {
 	logger.Infof("Removing file system %s", filesystemName)
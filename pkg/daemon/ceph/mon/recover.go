@@ -0,0 +1,105 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/rook/rook/pkg/clusterd"
+)
+
+const (
+	// RecoverCommand is the `rook ceph` subcommand which will remove lost mons from the monmap
+	// of a surviving mon so that the survivor can form quorum on its own
+	RecoverCommand = "mon-recover"
+
+	cephMonCommand    = "ceph-mon"
+	monmaptoolCommand = "monmaptool"
+	recoveredMonmap   = "monmap.recover"
+)
+
+// RemoveMonsFromMonmap extracts the monmap from the store of the given mon, removes the given mons
+// from it, and injects the resulting monmap back into the store. The mon daemon must not be running
+// while its store is modified.
+func RemoveMonsFromMonmap(context *clusterd.Context, config *Config, remove []string) error {
+	logger.Infof("removing mons %v from the monmap of mon %s", remove, config.Name)
+
+	// the ceph-mon commands need the config and keyring of the surviving mon
+	if err := generateConfigFiles(context, config); err != nil {
+		return fmt.Errorf("failed to generate mon config files. %+v", err)
+	}
+
+	monmapPath := path.Join(GetMonRunDirPath(context.ConfigDir, config.Name), recoveredMonmap)
+	commonArgs := []string{
+		"--name", fmt.Sprintf("mon.%s", config.Name),
+		"--mon-data", GetMonDataDirPath(context.ConfigDir, config.Name),
+	}
+
+	args := append([]string{"--extract-monmap", monmapPath}, commonArgs...)
+	if err := context.Executor.ExecuteCommand(false, "extract monmap", cephMonCommand, args...); err != nil {
+		return fmt.Errorf("failed to extract monmap from mon %s. %+v", config.Name, err)
+	}
+
+	output, err := context.Executor.ExecuteCommandWithOutput(false, "print monmap", monmaptoolCommand, "--print", monmapPath)
+	if err != nil {
+		return fmt.Errorf("failed to print monmap. %+v", err)
+	}
+	logger.Infof("extracted monmap:\n%s", output)
+
+	// only remove the mons that are still in the monmap in case a previous attempt was interrupted
+	members := parseMonmapMembers(output)
+	for _, name := range remove {
+		if name == config.Name {
+			return fmt.Errorf("cannot remove mon %s from its own monmap", name)
+		}
+		if _, ok := members[name]; !ok {
+			logger.Infof("mon %s is not in the monmap", name)
+			continue
+		}
+		if err := context.Executor.ExecuteCommand(false, "remove mon from monmap", monmaptoolCommand, monmapPath, "--rm", name); err != nil {
+			return fmt.Errorf("failed to remove mon %s from monmap. %+v", name, err)
+		}
+		logger.Infof("removed mon %s from monmap", name)
+	}
+
+	args = append([]string{"--inject-monmap", monmapPath}, commonArgs...)
+	if err := context.Executor.ExecuteCommand(false, "inject monmap", cephMonCommand, args...); err != nil {
+		return fmt.Errorf("failed to inject monmap into mon %s. %+v", config.Name, err)
+	}
+
+	logger.Infof("injected monmap into mon %s", config.Name)
+	return nil
+}
+
+// parseMonmapMembers returns the names of the mons in the output of `monmaptool --print`, where
+// each mon is listed on a line such as "0: 10.0.0.1:6790/0 mon.a"
+func parseMonmapMembers(output string) map[string]struct{} {
+	members := map[string]struct{}{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !strings.HasSuffix(fields[0], ":") {
+			continue
+		}
+		name := fields[len(fields)-1]
+		if strings.HasPrefix(name, "mon.") {
+			members[strings.TrimPrefix(name, "mon.")] = struct{}{}
+		}
+	}
+	return members
+}
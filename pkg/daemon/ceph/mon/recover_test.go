@@ -0,0 +1,86 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+const testMonmap = `monmaptool: monmap file /var/lib/rook/mon-a/monmap.recover
+epoch 3
+fsid 7b4a6f8a-8f3a-4a5b-9d3e-5c1f1e4b2a10
+last_changed 2018-10-01 12:00:00.000000
+created 2018-10-01 11:00:00.000000
+0: 10.0.0.1:6790/0 mon.a
+1: 10.0.0.2:6790/0 mon.b
+2: 10.0.0.3:6790/0 mon.c
+`
+
+func TestParseMonmapMembers(t *testing.T) {
+	members := parseMonmapMembers(testMonmap)
+	assert.Equal(t, 3, len(members))
+	for _, name := range []string{"a", "b", "c"} {
+		_, ok := members[name]
+		assert.True(t, ok, name)
+	}
+
+	assert.Equal(t, 0, len(parseMonmapMembers("")))
+}
+
+func TestRemoveMonsFromMonmap(t *testing.T) {
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+
+	commands := []string{}
+	removed := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommand: func(debug bool, actionName string, command string, args ...string) error {
+			commands = append(commands, actionName)
+			if actionName == "remove mon from monmap" {
+				removed = append(removed, args[2])
+			}
+			return nil
+		},
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			return testMonmap, nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor, ConfigDir: configDir}
+	config := &Config{
+		Name:    "a",
+		Port:    DefaultPort,
+		Cluster: &cephconfig.ClusterInfo{Name: "rook-ceph", Monitors: map[string]*cephconfig.MonInfo{}},
+	}
+	config.Cluster.Monitors["a"] = cephconfig.NewMonInfo("a", "10.0.0.1", DefaultPort)
+
+	// mon d is not in the monmap anymore and is skipped
+	err := RemoveMonsFromMonmap(context, config, []string{"b", "c", "d"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"b", "c"}, removed)
+	assert.Equal(t, []string{"extract monmap", "remove mon from monmap", "remove mon from monmap", "inject monmap"}, commands)
+
+	// the surviving mon cannot be removed
+	err = RemoveMonsFromMonmap(context, config, []string{"a"})
+	assert.NotNil(t, err)
+}
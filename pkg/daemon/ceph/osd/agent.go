@@ -27,6 +27,7 @@ import (
 
 	"github.com/google/uuid"
 
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	"github.com/rook/rook/pkg/clusterd"
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
 	oposd "github.com/rook/rook/pkg/operator/ceph/cluster/osd"
@@ -52,6 +53,7 @@ type OsdAgent struct {
 	osdProc           map[int]*proc.MonitoredProc
 	devices           string
 	usingDeviceFilter bool
+	deviceSelection   *rookalpha.Selection
 	metadataDevice    string
 	directories       string
 	procMan           *proc.ProcManager
@@ -61,12 +63,13 @@ type OsdAgent struct {
 	osdsCompleted     chan struct{}
 }
 
-func NewAgent(context *clusterd.Context, devices string, usingDeviceFilter bool, metadataDevice, directories string, forceFormat bool,
+func NewAgent(context *clusterd.Context, devices string, usingDeviceFilter bool, deviceSelection *rookalpha.Selection, metadataDevice, directories string, forceFormat bool,
 	location string, storeConfig config.StoreConfig, cluster *cephconfig.ClusterInfo, nodeName string, kv *k8sutil.ConfigMapKVStore) *OsdAgent {
 
 	return &OsdAgent{
 		devices:           devices,
 		usingDeviceFilter: usingDeviceFilter,
+		deviceSelection:   deviceSelection,
 		metadataDevice:    metadataDevice,
 		directories:       directories,
 		forceFormat:       forceFormat,
@@ -489,6 +492,11 @@ func getOSDInfo(clusterName string, config *osdConfig, devPartInfo *devicePartIn
 	if devPartInfo != nil {
 		osd.DevicePartUUID = devPartInfo.deviceUUID
 	}
+	if config.partitionScheme != nil {
+		if data, ok := config.partitionScheme.Partitions[config.partitionScheme.GetDataPartitionType()]; ok {
+			osd.Device = data.Device
+		}
+	}
 
 	if isFilestore(config) {
 		osd.Journal = getOSDJournalPath(config.rootPath)
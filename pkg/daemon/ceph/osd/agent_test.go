@@ -353,7 +353,7 @@ func createTestAgent(t *testing.T, devices, configDir, nodeName string, storeCon
 	}
 	cluster := &cephconfig.ClusterInfo{Name: "myclust"}
 	context := &clusterd.Context{ConfigDir: configDir, Executor: executor, Clientset: testop.New(1)}
-	agent := NewAgent(context, devices, false, "", "", forceFormat, location, *storeConfig,
+	agent := NewAgent(context, devices, false, nil, "", "", forceFormat, location, *storeConfig,
 		cluster, nodeName, mockKVStore())
 
 	return agent, executor, context
@@ -421,7 +421,7 @@ func TestGetPartitionPerfScheme(t *testing.T) {
 	}
 	context.Executor = executor
 
-	devices, err := getAvailableDevices(context, "sda,sdb", "sdc", false)
+	devices, err := getAvailableDevices(context, "sda,sdb", "sdc", false, nil)
 	assert.Nil(t, err)
 	scheme, err := a.getPartitionPerfScheme(context, devices)
 	assert.Nil(t, err)
@@ -498,7 +498,7 @@ func TestGetPartitionSchemeDiskInUse(t *testing.T) {
 
 	// get the partition scheme based on the available devices.  Since sda is already in use, the partition
 	// scheme returned should reflect that.
-	devices, err := getAvailableDevices(context, "sda", "", false)
+	devices, err := getAvailableDevices(context, "sda", "", false, nil)
 	scheme, err := a.getPartitionPerfScheme(context, devices)
 	assert.Nil(t, err)
 
@@ -566,7 +566,7 @@ func TestGetPartitionSchemeDiskNameChanged(t *testing.T) {
 
 	// get the current partition scheme.  This should notice that the device names changed and update the
 	// partition scheme to have the latest device names
-	devices, err := getAvailableDevices(context, "sda-changed", "nvme01", false)
+	devices, err := getAvailableDevices(context, "sda-changed", "nvme01", false, nil)
 	scheme, err := a.getPartitionPerfScheme(context, devices)
 	assert.Nil(t, err)
 	require.NotNil(t, scheme)
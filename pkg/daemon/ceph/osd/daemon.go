@@ -19,15 +19,18 @@ package osd
 import (
 	"fmt"
 	"path"
+	"path/filepath"
 	"regexp"
 
 	"strings"
 
 	"github.com/coreos/pkg/capnslog"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	"github.com/rook/rook/pkg/clusterd"
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
 	oposd "github.com/rook/rook/pkg/operator/ceph/cluster/osd"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/osd/config"
+	"github.com/rook/rook/pkg/operator/discover"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"github.com/rook/rook/pkg/util/sys"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -83,7 +86,7 @@ func Provision(context *clusterd.Context, agent *OsdAgent) error {
 	logger.Infof("creating and starting the osds")
 
 	// determine the set of devices that can/should be used for OSDs.
-	devices, err := getAvailableDevices(context, agent.devices, agent.metadataDevice, agent.usingDeviceFilter)
+	devices, err := getAvailableDevices(context, agent.devices, agent.metadataDevice, agent.usingDeviceFilter, agent.deviceSelection)
 	if err != nil {
 		return fmt.Errorf("failed to get available devices. %+v", err)
 	}
@@ -149,14 +152,21 @@ func Provision(context *clusterd.Context, agent *OsdAgent) error {
 	return nil
 }
 
-func getAvailableDevices(context *clusterd.Context, desiredDevices string, metadataDevice string, usingDeviceFilter bool) (*DeviceOsdMapping, error) {
+func getAvailableDevices(context *clusterd.Context, desiredDevices string, metadataDevice string, usingDeviceFilter bool,
+	selection *rookalpha.Selection) (*DeviceOsdMapping, error) {
 
 	var deviceList []string
 	if !usingDeviceFilter {
 		deviceList = strings.Split(desiredDevices, ",")
 	}
+	// the devices may be given by persistent links that are resolved to the current kernel names on this node
+	resolvedDevices := make([]string, len(deviceList))
+	for i := range deviceList {
+		resolvedDevices[i] = resolveDeviceLink(deviceList[i])
+	}
 
 	available := &DeviceOsdMapping{Entries: map[string]*DeviceOsdIDEntry{}}
+	selected := 0
 
 	if oposd.IsRemovingNode(desiredDevices) {
 		// the node is being removed, just return an empty set
@@ -182,8 +192,11 @@ func getAvailableDevices(context *clusterd.Context, desiredDevices string, metad
 			// current device is desired as the metadata device
 			available.Entries[device.Name] = &DeviceOsdIDEntry{Data: unassignedOSDID, Metadata: []int{}}
 		} else if desiredDevices == "all" {
-			// user has specified all devices, use the current one for data
-			available.Entries[device.Name] = &DeviceOsdIDEntry{Data: unassignedOSDID}
+			// user has specified all devices, use the current one for data if it matches the selectors
+			if matchesDeviceSelectors(selection, device, selected) {
+				available.Entries[device.Name] = &DeviceOsdIDEntry{Data: unassignedOSDID}
+				selected++
+			}
 		} else if desiredDevices != "" {
 			var matched bool
 			var err error
@@ -192,7 +205,7 @@ func getAvailableDevices(context *clusterd.Context, desiredDevices string, metad
 				matched, err = regexp.Match(desiredDevices, []byte(device.Name))
 			} else {
 				for i := range deviceList {
-					if device.Name == deviceList[i] {
+					if sys.MatchesDevice(device, deviceList[i]) || sys.MatchesDevice(device, resolvedDevices[i]) {
 						matched = true
 						break
 					}
@@ -200,8 +213,12 @@ func getAvailableDevices(context *clusterd.Context, desiredDevices string, metad
 			}
 
 			if err == nil && matched {
+				if usingDeviceFilter && !matchesDeviceSelectors(selection, device, selected) {
+					continue
+				}
 				// the current device matches the user specifies filter/list, use it for data
 				available.Entries[device.Name] = &DeviceOsdIDEntry{Data: unassignedOSDID}
+				selected++
 			} else {
 				logger.Infof("skipping device %s that does not match the device filter/list `%s`. %+v", device.Name, desiredDevices, err)
 			}
@@ -213,6 +230,40 @@ func getAvailableDevices(context *clusterd.Context, desiredDevices string, metad
 	return available, nil
 }
 
+// matchesDeviceSelectors returns whether the device has the properties required by the device selectors and the max
+// number of devices has not been selected yet
+func matchesDeviceSelectors(selection *rookalpha.Selection, device *sys.LocalDisk, selected int) bool {
+	if selection == nil {
+		return true
+	}
+	if selection.MaxDevices > 0 && selected >= selection.MaxDevices {
+		logger.Infof("skipping device %s since %d devices are already selected", device.Name, selected)
+		return false
+	}
+	matched, err := discover.MatchesDeviceSelectors(selection, device)
+	if err != nil {
+		logger.Warningf("skipping device %s. %+v", device.Name, err)
+		return false
+	}
+	if !matched {
+		logger.Infof("skipping device %s that does not match the device selectors", device.Name)
+	}
+	return matched
+}
+
+// resolveDeviceLink returns the device path that a persistent link under /dev/disk points to
+func resolveDeviceLink(path string) string {
+	if !strings.HasPrefix(path, "/dev/disk/") {
+		return path
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		logger.Warningf("failed to resolve device link %s. %+v", path, err)
+		return path
+	}
+	return resolved
+}
+
 func getDataDirs(context *clusterd.Context, kv *k8sutil.ConfigMapKVStore, desiredDirs string,
 	devicesSpecified bool, nodeName string) (dirs, removedDirs map[string]int, err error) {
 
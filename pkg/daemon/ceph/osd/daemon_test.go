@@ -22,6 +22,7 @@ import (
 	"strings"
 	"testing"
 
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/osd/config"
 	exectest "github.com/rook/rook/pkg/util/exec/test"
@@ -203,14 +204,14 @@ NAME="sdb1" SIZE="30" TYPE="part" PKNAME="sdb"`, nil
 		{Name: "sda"},
 		{Name: "sdb"},
 		{Name: "sdc"},
-		{Name: "sdd"},
+		{Name: "sdd", DevLinks: "/dev/disk/by-id/wwn-0x5000c500a0b1c2d3 /dev/disk/by-path/pci-0000:00:1f.2-ata-4"},
 		{Name: "nvme01"},
 		{Name: "rda"},
 		{Name: "rdb"},
 	}
 
 	// select all devices, including nvme01 for metadata
-	mapping, err := getAvailableDevices(context, "all", "nvme01", true)
+	mapping, err := getAvailableDevices(context, "all", "nvme01", true, nil)
 	assert.Nil(t, err)
 	assert.Equal(t, 5, len(mapping.Entries))
 	assert.Equal(t, -1, mapping.Entries["sda"].Data)
@@ -222,34 +223,66 @@ NAME="sdb1" SIZE="30" TYPE="part" PKNAME="sdb"`, nil
 	assert.Equal(t, 0, len(mapping.Entries["nvme01"].Metadata))
 
 	// select no devices both using and not using a filter
-	mapping, err = getAvailableDevices(context, "", "", false)
+	mapping, err = getAvailableDevices(context, "", "", false, nil)
 	assert.Nil(t, err)
 	assert.Equal(t, 0, len(mapping.Entries))
 
-	mapping, err = getAvailableDevices(context, "", "", true)
+	mapping, err = getAvailableDevices(context, "", "", true, nil)
 	assert.Nil(t, err)
 	assert.Equal(t, 0, len(mapping.Entries))
 
 	// select the sd* devices
-	mapping, err = getAvailableDevices(context, "^sd.$", "", true)
+	mapping, err = getAvailableDevices(context, "^sd.$", "", true, nil)
 	assert.Nil(t, err)
 	assert.Equal(t, 2, len(mapping.Entries))
 	assert.Equal(t, -1, mapping.Entries["sda"].Data)
 	assert.Equal(t, -1, mapping.Entries["sdd"].Data)
 
 	// select an exact device
-	mapping, err = getAvailableDevices(context, "sdd", "", false)
+	mapping, err = getAvailableDevices(context, "sdd", "", false, nil)
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(mapping.Entries))
 	assert.Equal(t, -1, mapping.Entries["sdd"].Data)
 
+	// select devices by their persistent links
+	mapping, err = getAvailableDevices(context, "/dev/disk/by-id/wwn-0x5000c500a0b1c2d3,/dev/sda", "", false, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(mapping.Entries))
+	assert.Equal(t, -1, mapping.Entries["sda"].Data)
+	assert.Equal(t, -1, mapping.Entries["sdd"].Data)
+
 	// select all devices except those that have a prefix of "s"
-	mapping, err = getAvailableDevices(context, "^[^s]", "", true)
+	mapping, err = getAvailableDevices(context, "^[^s]", "", true, nil)
 	assert.Nil(t, err)
 	assert.Equal(t, 3, len(mapping.Entries))
 	assert.Equal(t, -1, mapping.Entries["rda"].Data)
 	assert.Equal(t, -1, mapping.Entries["rdb"].Data)
 	assert.Equal(t, -1, mapping.Entries["nvme01"].Data)
+
+	// select the devices by their properties
+	context.Devices[0].Size = 200 * 1024 * 1024 * 1024
+	context.Devices[3].Size = 200 * 1024 * 1024 * 1024
+	context.Devices[3].Rotational = true
+	context.Devices[5].Size = 200 * 1024 * 1024 * 1024
+	rotational := false
+	mapping, err = getAvailableDevices(context, "all", "", true, &rookalpha.Selection{MinDeviceSize: "100Gi", Rotational: &rotational})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(mapping.Entries))
+	assert.Equal(t, -1, mapping.Entries["sda"].Data)
+	assert.Equal(t, -1, mapping.Entries["rda"].Data)
+
+	// the selectors narrow down the devices of the filter
+	mapping, err = getAvailableDevices(context, "^[^s]", "", true, &rookalpha.Selection{MinDeviceSize: "100Gi"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(mapping.Entries))
+	assert.Equal(t, -1, mapping.Entries["rda"].Data)
+
+	// stop selecting devices at the max number of devices
+	mapping, err = getAvailableDevices(context, "all", "", true, &rookalpha.Selection{MaxDevices: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(mapping.Entries))
+	assert.Equal(t, -1, mapping.Entries["sda"].Data)
+	assert.Equal(t, -1, mapping.Entries["sdd"].Data)
 }
 
 func TestGetRemovedDevices(t *testing.T) {
@@ -0,0 +1,121 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util/sys"
+)
+
+// ZapDevices wipes the partitions and signatures of the given devices. Devices that were not provisioned
+// by rook are skipped unless force is set.
+func ZapDevices(context *clusterd.Context, devices []string, force bool) error {
+	var failed []string
+	for _, device := range devices {
+		if err := ZapDevice(context, device, force); err != nil {
+			logger.Errorf("failed to zap device %s. %+v", device, err)
+			failed = append(failed, device)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to zap devices %v", failed)
+	}
+	return nil
+}
+
+// ZapDevice wipes the partitions and signatures of a device. The device can be given by name, by its
+// path, or by one of its persistent links under /dev/disk.
+func ZapDevice(context *clusterd.Context, device string, force bool) error {
+	name := strings.TrimPrefix(resolveDeviceLink(device), "/dev/")
+	if name == "" {
+		return fmt.Errorf("invalid device %s", device)
+	}
+
+	partitions, _, err := sys.GetDevicePartitions(name, context.Executor)
+	if err != nil {
+		return fmt.Errorf("failed to get partitions of %s. %+v", name, err)
+	}
+
+	if !force {
+		if !sys.RookOwnsPartitions(partitions) {
+			logger.Warningf("skipping zap of device %s, it has partitions not created by rook", name)
+			return nil
+		}
+		if len(partitions) == 0 {
+			fs, err := sys.GetDeviceFilesystems(name, context.Executor)
+			if err != nil {
+				return fmt.Errorf("failed to get filesystem of %s. %+v", name, err)
+			}
+			if fs != "" {
+				logger.Warningf("skipping zap of device %s, it has a filesystem %s", name, fs)
+				return nil
+			}
+		}
+	}
+
+	logger.Infof("zapping device %s with %d partitions", name, len(partitions))
+	for _, p := range partitions {
+		if err := wipeSignatures(context, p.Name); err != nil {
+			return err
+		}
+	}
+
+	if err := sys.RemovePartitions(name, context.Executor); err != nil {
+		return err
+	}
+
+	return wipeSignatures(context, name)
+}
+
+func wipeSignatures(context *clusterd.Context, name string) error {
+	err := context.Executor.ExecuteCommand(false, fmt.Sprintf("wipefs %s", name), "wipefs", "--all", "/dev/"+name)
+	if err != nil {
+		return fmt.Errorf("failed to wipe signatures on /dev/%s. %+v", name, err)
+	}
+	return nil
+}
+
+// CleanDataDir removes the contents of the data dir. The dir itself is kept since it is usually
+// the mount point of a host path.
+func CleanDataDir(dir string) error {
+	if dir == "" || path.Clean(dir) == "/" {
+		return fmt.Errorf("refusing to clean data dir %q", dir)
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read data dir %s. %+v", dir, err)
+	}
+
+	logger.Infof("cleaning data dir %s", dir)
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return fmt.Errorf("failed to remove %s from data dir. %+v", entry.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package osd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZapDevice(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	executor.MockExecuteCommandWithOutput = func(debug bool, name string, command string, args ...string) (string, error) {
+		if command == "lsblk" {
+			if strings.Index(name, "sdb") != -1 {
+				return `NAME="sdb" SIZE="65" TYPE="disk" PKNAME=""
+NAME="sdb1" SIZE="30" TYPE="part" PKNAME="sdb"
+NAME="sdb2" SIZE="30" TYPE="part" PKNAME="sdb"`, nil
+			}
+			if strings.Index(name, "sdc") != -1 {
+				return `NAME="sdc" SIZE="65" TYPE="disk" PKNAME=""
+NAME="sdc1" SIZE="30" TYPE="part" PKNAME="sdc"`, nil
+			}
+			return "", nil
+		} else if command == "udevadm" {
+			if strings.Index(name, "sdb") != -1 {
+				return "PARTNAME=ROOK-OSD0-BLOCK", nil
+			}
+			if strings.Index(name, "sdc1") != -1 {
+				return "PARTNAME=MY-PART", nil
+			}
+			if strings.Index(name, "sdd") != -1 {
+				return udevFSOutput, nil
+			}
+			return "", nil
+		}
+		return "", nil
+	}
+	var commands []string
+	executor.MockExecuteCommand = func(debug bool, name string, command string, args ...string) error {
+		commands = append(commands, command+" "+strings.Join(args, " "))
+		return nil
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	// the rook partitions and the disk are wiped
+	err := ZapDevice(context, "/dev/sdb", false)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"wipefs --all /dev/sdb1",
+		"wipefs --all /dev/sdb2",
+		"sgdisk --zap-all /dev/sdb",
+		"sgdisk --clear --mbrtogpt /dev/sdb",
+		"wipefs --all /dev/sdb",
+	}, commands)
+
+	// a device with partitions not created by rook is skipped
+	commands = nil
+	err = ZapDevice(context, "sdc", false)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(commands))
+
+	// a device with a filesystem is skipped
+	err = ZapDevice(context, "sdd", false)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(commands))
+
+	// unless forced
+	err = ZapDevice(context, "sdc", true)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(commands))
+}
+
+func TestCleanDataDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestCleanDataDir")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.Nil(t, os.MkdirAll(filepath.Join(dir, "osd0"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "rook.config"), []byte("config"), 0644))
+
+	err = CleanDataDir(dir)
+	assert.Nil(t, err)
+	entries, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(entries))
+
+	// a missing dir is not an error
+	assert.Nil(t, CleanDataDir(filepath.Join(dir, "missing")))
+
+	// the root is never cleaned
+	assert.NotNil(t, CleanDataDir("/"))
+	assert.NotNil(t, CleanDataDir(""))
+}
@@ -211,3 +211,36 @@ func DeleteBucket(c *Context, bucketName string, purge bool) (int, error) {
 
 	return RGWErrorUnknown, fmt.Errorf("failed to delete bucket: %+v", err)
 }
+
+// SetBucketQuota sets the quota of a bucket. A quota of zero is unlimited, and the quota is disabled if
+// both the size and the number of objects are unlimited.
+func SetBucketQuota(c *Context, bucketName string, maxSize, maxObjects int64) error {
+	if maxSize == 0 && maxObjects == 0 {
+		if _, err := runAdminCommand(c, "quota", "disable", "--quota-scope=bucket", "--bucket", bucketName); err != nil {
+			return fmt.Errorf("failed to disable quota of bucket %s. %+v", bucketName, err)
+		}
+		return nil
+	}
+
+	// radosgw-admin considers negative limits as unlimited
+	if maxSize == 0 {
+		maxSize = -1
+	}
+	if maxObjects == 0 {
+		maxObjects = -1
+	}
+	_, err := runAdminCommand(c,
+		"quota",
+		"set",
+		"--quota-scope=bucket",
+		"--bucket", bucketName,
+		fmt.Sprintf("--max-size=%d", maxSize),
+		fmt.Sprintf("--max-objects=%d", maxObjects))
+	if err != nil {
+		return fmt.Errorf("failed to set quota of bucket %s. %+v", bucketName, err)
+	}
+	if _, err := runAdminCommand(c, "quota", "enable", "--quota-scope=bucket", "--bucket", bucketName); err != nil {
+		return fmt.Errorf("failed to enable quota of bucket %s. %+v", bucketName, err)
+	}
+	return nil
+}
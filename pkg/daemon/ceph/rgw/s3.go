@@ -0,0 +1,132 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rgw
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// ExpirationRule expires the objects with a prefix in a bucket after a number of days
+type ExpirationRule struct {
+	ID             string
+	Prefix         string
+	Days           int
+	NoncurrentDays int
+}
+
+// S3Agent configures buckets through the s3 api of an object store
+type S3Agent struct {
+	client *s3.S3
+}
+
+// NewS3Agent creates an s3 client for the object store endpoint with the keys of an object store user
+func NewS3Agent(endpoint, accessKey, secretKey string) *S3Agent {
+	creds := credentials.NewStaticCredentials(accessKey, secretKey, "")
+
+	// the region is ignored by rgw but required by the s3 client
+	config := aws.NewConfig().
+		WithRegion("us-east-1").
+		WithCredentials(creds).
+		WithEndpoint(endpoint).
+		WithS3ForcePathStyle(true).
+		WithMaxRetries(5)
+	return &S3Agent{client: s3.New(session.New(), config)}
+}
+
+// CreateBucket creates the bucket if it does not exist yet
+func (a *S3Agent) CreateBucket(name string) error {
+	_, err := a.client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(name)})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeBucketAlreadyOwnedByYou {
+			return nil
+		}
+		return fmt.Errorf("failed to create bucket %s. %+v", name, err)
+	}
+	return nil
+}
+
+// SetVersioning enables or suspends the versioning of the objects in the bucket
+func (a *S3Agent) SetVersioning(name string, enabled bool) error {
+	status := s3.BucketVersioningStatusSuspended
+	if enabled {
+		status = s3.BucketVersioningStatusEnabled
+	}
+	_, err := a.client.PutBucketVersioning(&s3.PutBucketVersioningInput{
+		Bucket:                  aws.String(name),
+		VersioningConfiguration: &s3.VersioningConfiguration{Status: aws.String(status)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set versioning of bucket %s. %+v", name, err)
+	}
+	return nil
+}
+
+// SetLifecycle replaces the lifecycle rules of the bucket. The lifecycle configuration is removed if there are no rules.
+func (a *S3Agent) SetLifecycle(name string, rules []ExpirationRule) error {
+	if len(rules) == 0 {
+		if _, err := a.client.DeleteBucketLifecycle(&s3.DeleteBucketLifecycleInput{Bucket: aws.String(name)}); err != nil {
+			return fmt.Errorf("failed to remove lifecycle of bucket %s. %+v", name, err)
+		}
+		return nil
+	}
+
+	lifecycleRules := []*s3.LifecycleRule{}
+	for _, rule := range rules {
+		lifecycleRule := &s3.LifecycleRule{
+			ID:     aws.String(rule.ID),
+			Filter: &s3.LifecycleRuleFilter{Prefix: aws.String(rule.Prefix)},
+			Status: aws.String(s3.ExpirationStatusEnabled),
+		}
+		if rule.Days > 0 {
+			lifecycleRule.Expiration = &s3.LifecycleExpiration{Days: aws.Int64(int64(rule.Days))}
+		}
+		if rule.NoncurrentDays > 0 {
+			lifecycleRule.NoncurrentVersionExpiration = &s3.NoncurrentVersionExpiration{NoncurrentDays: aws.Int64(int64(rule.NoncurrentDays))}
+		}
+		lifecycleRules = append(lifecycleRules, lifecycleRule)
+	}
+	_, err := a.client.PutBucketLifecycleConfiguration(&s3.PutBucketLifecycleConfigurationInput{
+		Bucket:                 aws.String(name),
+		LifecycleConfiguration: &s3.BucketLifecycleConfiguration{Rules: lifecycleRules},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set lifecycle of bucket %s. %+v", name, err)
+	}
+	return nil
+}
+
+// SetPolicy replaces the policy of the bucket. The policy is removed if it is empty.
+func (a *S3Agent) SetPolicy(name, policy string) error {
+	if policy == "" {
+		if _, err := a.client.DeleteBucketPolicy(&s3.DeleteBucketPolicyInput{Bucket: aws.String(name)}); err != nil {
+			return fmt.Errorf("failed to remove policy of bucket %s. %+v", name, err)
+		}
+		return nil
+	}
+
+	_, err := a.client.PutBucketPolicy(&s3.PutBucketPolicyInput{Bucket: aws.String(name), Policy: aws.String(policy)})
+	if err != nil {
+		return fmt.Errorf("failed to set policy of bucket %s. %+v", name, err)
+	}
+	return nil
+}
@@ -18,32 +18,38 @@ limitations under the License.
 package discover
 
 import (
-	"encoding/json"
+	"bufio"
 	"fmt"
 	"os"
+	"os/exec"
 	"os/signal"
+	"reflect"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/coreos/pkg/capnslog"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"github.com/rook/rook/pkg/util/sys"
 
-	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// minDeviceSize is the smallest device that an OSD can be created on
+	minDeviceSize = 5 * 1024 * 1024 * 1024
+)
+
 var (
-	logger                                  = capnslog.NewPackageLogger("github.com/rook/rook", "rook-discover")
-	AppName                                 = "rook-discover"
-	NodeAttr                                = "rook.io/node"
-	LocalDiskCMData                         = "devices"
-	LocalDiskCMName                         = "local-device-"
-	probeInterval                           = 30 * time.Second
-	nodeName, namespace, lastDevice, cmName string
-	cm                                      *v1.ConfigMap
+	logger          = capnslog.NewPackageLogger("github.com/rook/rook", "rook-discover")
+	AppName         = "rook-discover"
+	probeInterval   = 60 * time.Minute
+	pollInterval    = 30 * time.Second
+	udevEventPeriod = 5 * time.Second
+	nodeName        string
 )
 
 func Run(context *clusterd.Context) error {
@@ -51,85 +57,184 @@ func Run(context *clusterd.Context) error {
 		return fmt.Errorf("nil context")
 	}
 	nodeName = os.Getenv(k8sutil.NodeNameEnvVar)
-	namespace = os.Getenv(k8sutil.PodNamespaceEnvVar)
-	cmName = LocalDiskCMName + nodeName
 	sigc := make(chan os.Signal, 1)
 	signal.Notify(sigc, syscall.SIGTERM)
-	err := updateDeviceCM(context)
+	err := updateNodeDevices(context)
 	if err != nil {
-		logger.Infof("failed to update device configmap: %v", err)
+		logger.Infof("failed to update node devices: %v", err)
 		return err
 	}
+
+	// devices are probed again when udev reports that they changed. The periodic probe catches any missed events.
+	interval := probeInterval
+	udevEvents := make(chan string)
+	go udevBlockMonitor(udevEvents)
+	var udevSettled <-chan time.Time
 	for {
 		select {
 		case <-sigc:
 			logger.Infof("shutdown signal received, exiting...")
 			return nil
-		case <-time.After(probeInterval):
-			updateDeviceCM(context)
+		case device, ok := <-udevEvents:
+			if !ok {
+				logger.Warningf("udev monitor stopped. polling devices every %v", pollInterval)
+				udevEvents = nil
+				interval = pollInterval
+				continue
+			}
+			// wait for the events of the other devices and partitions that change at the same time
+			logger.Infof("device %s changed", device)
+			if udevSettled == nil {
+				udevSettled = time.After(udevEventPeriod)
+			}
+		case <-udevSettled:
+			udevSettled = nil
+			if err := updateNodeDevices(context); err != nil {
+				logger.Warningf("failed to update node devices. %+v", err)
+			}
+		case <-time.After(interval):
+			if err := updateNodeDevices(context); err != nil {
+				logger.Warningf("failed to update node devices. %+v", err)
+			}
+		}
+	}
+}
+
+// udevBlockMonitor sends the names of the block devices that udev reports as added, removed or changed. The channel
+// is closed when the monitor stops.
+func udevBlockMonitor(c chan string) {
+	defer close(c)
+
+	// udevadm buffers its output when it is not a terminal, which would delay the events
+	cmd := exec.Command("stdbuf", "-oL", "udevadm", "monitor", "--udev", "--subsystem-match=block")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		logger.Warningf("failed to get the output of the udev monitor. %+v", err)
+		return
+	}
+	if err := cmd.Start(); err != nil {
+		logger.Warningf("failed to start the udev monitor. %+v", err)
+		return
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		if device, ok := parseUdevEvent(scanner.Text()); ok {
+			c <- device
 		}
 	}
+	if err := cmd.Wait(); err != nil {
+		logger.Warningf("udev monitor failed. %+v", err)
+	}
 }
 
-func updateDeviceCM(context *clusterd.Context) error {
-	logger.Infof("updating device configmap")
+// parseUdevEvent returns the device name of a udev event line such as
+// "UDEV  [1539.123456] add      /devices/pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sdb (block)"
+func parseUdevEvent(line string) (string, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[0] != "UDEV" {
+		return "", false
+	}
+	switch fields[2] {
+	case "add", "remove", "change":
+		devPath := fields[3]
+		return devPath[strings.LastIndex(devPath, "/")+1:], true
+	}
+	return "", false
+}
+
+func updateNodeDevices(context *clusterd.Context) error {
+	logger.Infof("updating devices of node %s", nodeName)
 	devices, err := probeDevices(context)
 	if err != nil {
 		logger.Infof("failed to probe devices: %v", err)
 		return err
 	}
-	deviceJson, err := json.Marshal(devices)
+
+	nodeDevices, err := context.RookClientset.RookV1alpha2().NodeDevices().Get(nodeName, metav1.GetOptions{})
 	if err != nil {
-		logger.Infof("failed to marshal: %v", err)
-		return err
-	}
-	deviceStr := string(deviceJson)
-	if cm == nil {
-		cm, err = context.Clientset.CoreV1().ConfigMaps(namespace).Get(cmName, metav1.GetOptions{})
-	}
-	if err == nil {
-		lastDevice = cm.Data[LocalDiskCMData]
-		logger.Debugf("last devices %s", lastDevice)
-	} else {
 		if !errors.IsNotFound(err) {
-			logger.Infof("failed to get configmap: %v", err)
-			return err
+			return fmt.Errorf("failed to get devices of node %s. %+v", nodeName, err)
 		}
 
-		data := make(map[string]string, 1)
-		data[LocalDiskCMData] = deviceStr
-		// the map doesn't exist yet, create it now
-		cm = &v1.ConfigMap{
+		// the inventory doesn't exist yet, create it now
+		nodeDevices = &rookalpha.NodeDevices{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      cmName,
-				Namespace: namespace,
+				Name: nodeName,
 				Labels: map[string]string{
 					k8sutil.AppAttr: AppName,
-					NodeAttr:        nodeName,
 				},
 			},
-			Data: data,
+			Devices: toNodeDevices(devices, nil),
 		}
-		cm, err = context.Clientset.CoreV1().ConfigMaps(namespace).Create(cm)
-		if err != nil {
-			logger.Infof("failed to create configmap: %v", err)
-			return fmt.Errorf("failed to create local device map %s: %+v", cmName, err)
+		if _, err := context.RookClientset.RookV1alpha2().NodeDevices().Create(nodeDevices); err != nil {
+			return fmt.Errorf("failed to create devices of node %s. %+v", nodeName, err)
 		}
-		lastDevice = deviceStr
+		return nil
 	}
-	if deviceStr != lastDevice {
-		data := make(map[string]string, 1)
-		data[LocalDiskCMData] = deviceStr
-		cm.Data = data
-		cm, err = context.Clientset.CoreV1().ConfigMaps(namespace).Update(cm)
-		if err != nil {
-			logger.Infof("failed to update configmap %s: %v", cmName, err)
-			return err
-		}
+
+	updated := toNodeDevices(devices, nodeDevices.Devices)
+	if reflect.DeepEqual(updated, nodeDevices.Devices) {
+		logger.Debugf("devices of node %s did not change", nodeName)
+		return nil
+	}
+	nodeDevices.Devices = updated
+	if _, err := context.RookClientset.RookV1alpha2().NodeDevices().Update(nodeDevices); err != nil {
+		return fmt.Errorf("failed to update devices of node %s. %+v", nodeName, err)
 	}
 	return nil
 }
 
+// toNodeDevices converts the probed devices to the inventory entries. The cluster and OSD that own a device are kept
+// from the existing entries since only the operator knows them.
+func toNodeDevices(devices []sys.LocalDisk, existing []rookalpha.NodeDevice) []rookalpha.NodeDevice {
+	nodeDevices := []rookalpha.NodeDevice{}
+	for i := range devices {
+		device := &devices[i]
+		d := rookalpha.NodeDevice{
+			Name:       device.Name,
+			FullPath:   sys.GetPersistentDevicePath(device),
+			DevLinks:   device.DevLinks,
+			Size:       device.Size,
+			Type:       device.Type,
+			Rotational: device.Rotational,
+			Serial:     device.Serial,
+			Vendor:     device.Vendor,
+			Model:      device.Model,
+			WWN:        device.WWN,
+			Filesystem: device.Filesystem,
+			Partitions: len(device.Partitions),
+		}
+		for _, e := range existing {
+			if (d.FullPath != "" && e.FullPath == d.FullPath) || (d.FullPath == "" && e.Name == d.Name) {
+				d.Cluster = e.Cluster
+				d.OSDID = e.OSDID
+				break
+			}
+		}
+		if d.Cluster == "" {
+			d.Rejected = getRejectedReason(device)
+		}
+		nodeDevices = append(nodeDevices, d)
+	}
+	return nodeDevices
+}
+
+// getRejectedReason returns why an unused device cannot be used by an OSD, or an empty reason if it can be used
+func getRejectedReason(device *sys.LocalDisk) rookalpha.DeviceRejectedReason {
+	switch {
+	case device.Readonly:
+		return rookalpha.DeviceReadOnly
+	case len(device.Partitions) > 0:
+		return rookalpha.DeviceHasPartitions
+	case device.Filesystem != "":
+		return rookalpha.DeviceHasFilesystem
+	case device.Size < minDeviceSize:
+		return rookalpha.DeviceTooSmall
+	}
+	return ""
+}
+
 func probeDevices(context *clusterd.Context) ([]sys.LocalDisk, error) {
 	devices := make([]sys.LocalDisk, 0)
 	localDevices, err := clusterd.DiscoverDevices(context.Executor)
@@ -20,8 +20,10 @@ package discover
 import (
 	"testing"
 
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	"github.com/rook/rook/pkg/clusterd"
 	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/rook/rook/pkg/util/sys"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -105,3 +107,53 @@ testa2   centos_host13-home
 	assert.Equal(t, "ext2", devices[0].Filesystem)
 
 }
+
+func TestToNodeDevices(t *testing.T) {
+	devices := []sys.LocalDisk{
+		{Name: "sda", Type: sys.DiskType, Size: 10737418240, DevLinks: "/dev/disk/by-path/pci-0000:00:1f.2-ata-1 /dev/disk/by-id/wwn-0x5000c500a0b1c2d3"},
+		{Name: "sdb", Type: sys.DiskType, Size: 10737418240, Partitions: []sys.Partition{{Name: "sdb1"}}},
+		{Name: "sdc", Type: sys.DiskType, Size: 10737418240, Filesystem: "ext4"},
+		{Name: "sdd", Type: sys.DiskType, Size: 1073741824},
+		{Name: "sde", Type: sys.DiskType, Size: 10737418240, Readonly: true},
+	}
+
+	nodeDevices := toNodeDevices(devices, nil)
+	assert.Equal(t, 5, len(nodeDevices))
+	assert.Equal(t, "/dev/disk/by-id/wwn-0x5000c500a0b1c2d3", nodeDevices[0].FullPath)
+	assert.Equal(t, rookalpha.DeviceRejectedReason(""), nodeDevices[0].Rejected)
+	assert.Equal(t, 1, nodeDevices[1].Partitions)
+	assert.Equal(t, rookalpha.DeviceHasPartitions, nodeDevices[1].Rejected)
+	assert.Equal(t, rookalpha.DeviceHasFilesystem, nodeDevices[2].Rejected)
+	assert.Equal(t, rookalpha.DeviceTooSmall, nodeDevices[3].Rejected)
+	assert.Equal(t, rookalpha.DeviceReadOnly, nodeDevices[4].Rejected)
+
+	// the owner of a device is kept when the device is probed again, even if its kernel name changed
+	osdID := 2
+	existing := []rookalpha.NodeDevice{
+		{Name: "sdf", FullPath: "/dev/disk/by-id/wwn-0x5000c500a0b1c2d3", Cluster: "rook-ceph", OSDID: &osdID},
+		{Name: "sdb", Cluster: "rook-ceph"},
+	}
+	nodeDevices = toNodeDevices(devices, existing)
+	assert.Equal(t, "sda", nodeDevices[0].Name)
+	assert.Equal(t, "rook-ceph", nodeDevices[0].Cluster)
+	assert.Equal(t, 2, *nodeDevices[0].OSDID)
+	// the partitions of a claimed device are expected
+	assert.Equal(t, "rook-ceph", nodeDevices[1].Cluster)
+	assert.Equal(t, rookalpha.DeviceRejectedReason(""), nodeDevices[1].Rejected)
+	assert.Equal(t, "", nodeDevices[2].Cluster)
+}
+
+func TestParseUdevEvent(t *testing.T) {
+	device, ok := parseUdevEvent("UDEV  [1539.123456] add      /devices/pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sdb (block)")
+	assert.True(t, ok)
+	assert.Equal(t, "sdb", device)
+
+	device, ok = parseUdevEvent("UDEV  [1540.654321] remove   /devices/pci0000:00/0000:00:1f.2/ata1/host0/target0:0:0/0:0:0:0/block/sdb/sdb1 (block)")
+	assert.True(t, ok)
+	assert.Equal(t, "sdb1", device)
+
+	_, ok = parseUdevEvent("UDEV  [1541.000000] bind     /devices/pci0000:00/0000:00:1f.2 (pci)")
+	assert.False(t, ok)
+	_, ok = parseUdevEvent("monitor will print the received events for:")
+	assert.False(t, ok)
+}
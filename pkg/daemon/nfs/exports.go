@@ -0,0 +1,177 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package nfs to reload the exports of a running NFS server.
+package nfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/rook/rook/pkg/clusterd"
+)
+
+const (
+	dbusSend      = "dbus-send"
+	exportMgrPath = "/org/ganesha/nfsd/ExportMgr"
+	exportMgr     = "org.ganesha.nfsd.exportmgr"
+)
+
+var (
+	logger = capnslog.NewPackageLogger("github.com/rook/rook", "nfs-exports")
+
+	// the interval at which the config file is checked for changes
+	pollInterval = 10 * time.Second
+
+	exportIDRegex = regexp.MustCompile(`Export_Id\s*=\s*(\d+)\s*;`)
+)
+
+// WatchExports watches the ganesha config file and reloads the exports that changed in the running ganesha
+// server, so that the clients of the exports that did not change are not interrupted
+func WatchExports(context *clusterd.Context, configFile string) error {
+	config, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to read ganesha config %s. %+v", configFile, err)
+	}
+	current := string(config)
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGTERM)
+	logger.Infof("watching the exports in %s", configFile)
+	for {
+		select {
+		case <-sigc:
+			logger.Infof("shutdown signal received, exiting...")
+			return nil
+		case <-time.After(pollInterval):
+			config, err := ioutil.ReadFile(configFile)
+			if err != nil {
+				logger.Warningf("failed to read ganesha config %s. %+v", configFile, err)
+				continue
+			}
+			if string(config) == current {
+				continue
+			}
+			logger.Infof("ganesha config changed, reloading the exports")
+			if err := ReloadExports(context, configFile, current, string(config)); err != nil {
+				logger.Errorf("failed to reload the exports. %+v", err)
+				continue
+			}
+			current = string(config)
+		}
+	}
+}
+
+// ReloadExports removes the exports of the old config that were removed or changed in the new config, and adds
+// the exports of the new config that were added or changed
+func ReloadExports(context *clusterd.Context, configFile, oldConfig, newConfig string) error {
+	oldExports := ParseExports(oldConfig)
+	newExports := ParseExports(newConfig)
+
+	for _, id := range sortedIDs(oldExports) {
+		if newExports[id] == oldExports[id] {
+			continue
+		}
+		if err := removeExport(context, id); err != nil {
+			return err
+		}
+	}
+	for _, id := range sortedIDs(newExports) {
+		if newExports[id] == oldExports[id] {
+			continue
+		}
+		if err := addExport(context, configFile, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseExports returns the EXPORT blocks of a ganesha config by export id
+func ParseExports(config string) map[int]string {
+	exports := map[int]string{}
+	for {
+		start := strings.Index(config, "EXPORT")
+		if start < 0 {
+			return exports
+		}
+		config = config[start:]
+
+		// find the end of the block, the blocks in the export such as FSAL and CLIENT are nested
+		end, depth := -1, 0
+		for i, c := range config {
+			if c == '{' {
+				depth++
+			} else if c == '}' {
+				depth--
+				if depth == 0 {
+					end = i + 1
+					break
+				}
+			}
+		}
+		if end < 0 {
+			return exports
+		}
+
+		block := config[:end]
+		if match := exportIDRegex.FindStringSubmatch(block); match != nil {
+			id, _ := strconv.Atoi(match[1])
+			exports[id] = block
+		}
+		config = config[end:]
+	}
+}
+
+func sortedIDs(exports map[int]string) []int {
+	ids := []int{}
+	for id := range exports {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func removeExport(context *clusterd.Context, id int) error {
+	logger.Infof("removing export %d", id)
+	_, err := context.Executor.ExecuteCommandWithCombinedOutput(false, "", dbusSend,
+		"--print-reply", "--system", "--dest=org.ganesha.nfsd", exportMgrPath, exportMgr+".RemoveExport",
+		fmt.Sprintf("uint16:%d", id))
+	if err != nil {
+		return fmt.Errorf("failed to remove export %d. %+v", id, err)
+	}
+	return nil
+}
+
+func addExport(context *clusterd.Context, configFile string, id int) error {
+	logger.Infof("adding export %d", id)
+	_, err := context.Executor.ExecuteCommandWithCombinedOutput(false, "", dbusSend,
+		"--print-reply", "--system", "--dest=org.ganesha.nfsd", exportMgrPath, exportMgr+".AddExport",
+		"string:"+configFile, fmt.Sprintf("string:EXPORT(Export_Id=%d)", id))
+	if err != nil {
+		return fmt.Errorf("failed to add export %d. %+v", id, err)
+	}
+	return nil
+}
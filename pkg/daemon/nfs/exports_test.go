@@ -0,0 +1,107 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfs
+
+import (
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+const testExportA = `
+EXPORT {
+	Export_Id = 10;
+	Path = /claim-a;
+	Squash = none;
+	FSAL {
+		Name = VFS;
+	}
+}`
+
+const testExportB = `
+EXPORT {
+	Export_Id = 11;
+	Path = /claim-b;
+	Squash = none;
+	FSAL {
+		Name = VFS;
+	}
+}`
+
+const testCoreParam = `
+NFS_Core_Param
+{
+	fsid_device = true;
+}`
+
+func TestParseExports(t *testing.T) {
+	exports := ParseExports(testExportA + testExportB + testCoreParam)
+	assert.Equal(t, 2, len(exports))
+	assert.Equal(t, testExportA[1:], exports[10])
+	assert.Equal(t, testExportB[1:], exports[11])
+
+	assert.Equal(t, 0, len(ParseExports(testCoreParam)))
+}
+
+func TestReloadExports(t *testing.T) {
+	commands := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			assert.Equal(t, "dbus-send", command)
+			commands = append(commands, args[4]+" "+args[len(args)-1])
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+	configFile := "/nfs-ganesha/config/nfs-ganesha-config"
+
+	// export a is changed, export b is removed and export c is added
+	changedA := `
+EXPORT {
+	Export_Id = 10;
+	Path = /claim-a;
+	Squash = all;
+	FSAL {
+		Name = VFS;
+	}
+}`
+	exportC := `
+EXPORT {
+	Export_Id = 12;
+	Path = /claim-c;
+	Squash = none;
+	FSAL {
+		Name = VFS;
+	}
+}`
+	err := ReloadExports(context, configFile, testExportA+testExportB+testCoreParam, changedA+exportC+testCoreParam)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"org.ganesha.nfsd.exportmgr.RemoveExport uint16:10",
+		"org.ganesha.nfsd.exportmgr.RemoveExport uint16:11",
+		"org.ganesha.nfsd.exportmgr.AddExport string:EXPORT(Export_Id=10)",
+		"org.ganesha.nfsd.exportmgr.AddExport string:EXPORT(Export_Id=12)",
+	}, commands)
+
+	// nothing is reloaded if the exports did not change
+	commands = []string{}
+	err = ReloadExports(context, configFile, testExportA+testCoreParam, testExportA+"\n"+testCoreParam)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(commands))
+}
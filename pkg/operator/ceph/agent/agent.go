@@ -37,6 +37,8 @@ const (
 	flexvolumeDefaultDirPath       = "/usr/libexec/kubernetes/kubelet-plugins/volume/exec/"
 	agentDaemonsetTolerationEnv    = "AGENT_TOLERATION"
 	agentDaemonsetTolerationKeyEnv = "AGENT_TOLERATION_KEY"
+	agentNodeLostTimeoutEnv        = "AGENT_NODE_LOST_TIMEOUT"
+	agentMetricsAddressEnv         = "AGENT_METRICS_ADDRESS"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-agent")
@@ -164,6 +166,18 @@ func (a *Agent) createAgentDaemonSet(namespace, agentImage, serviceAccount strin
 		}
 	}
 
+	// Pass the node lost timeout to the agents if any
+	if nodeLostTimeout := os.Getenv(agentNodeLostTimeoutEnv); nodeLostTimeout != "" {
+		ds.Spec.Template.Spec.Containers[0].Env = append(ds.Spec.Template.Spec.Containers[0].Env,
+			v1.EnvVar{Name: "ROOK_NODE_LOST_TIMEOUT", Value: nodeLostTimeout})
+	}
+
+	// Pass the metrics address to the agents if any. The agents run on the host network.
+	if metricsAddress := os.Getenv(agentMetricsAddressEnv); metricsAddress != "" {
+		ds.Spec.Template.Spec.Containers[0].Env = append(ds.Spec.Template.Spec.Containers[0].Env,
+			v1.EnvVar{Name: "ROOK_METRICS_ADDRESS", Value: metricsAddress})
+	}
+
 	_, err := a.clientset.Extensions().DaemonSets(namespace).Create(ds)
 	if err != nil {
 		if !kserrors.IsAlreadyExists(err) {
@@ -0,0 +1,278 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	cephv1beta1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1beta1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/operator/discover"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/kubelet/apis"
+)
+
+const (
+	cleanupAppName    = "rook-ceph-cleanup"
+	cleanupAppNameFmt = "rook-ceph-cleanup-%s"
+)
+
+var (
+	// the daemons that must be stopped before their devices and data can be wiped
+	cleanupWaitAppSelector = "app in (rook-ceph-mon,rook-ceph-mgr,rook-ceph-osd,rook-ceph-osd-prepare)"
+	cleanupWaitInterval    = 5 * time.Second
+	cleanupWaitRetries     = 60
+	cleanupJobRetries      = 360
+)
+
+// cleanupHosts starts a job on each node of a deleted cluster that wipes the devices provisioned by the cluster
+// and the contents of the data dir, according to the cleanup policy. The devices of a node are only freed after its
+// job succeeded so they are not claimed by another cluster before they are wiped.
+func (c *ClusterController) cleanupHosts(cluster *cephv1beta1.Cluster) error {
+	if !cluster.Spec.CleanupPolicy.WipeDevices {
+		// the devices are not wiped so they can be freed right away
+		if err := discover.FreeDevicesByCluster(c.context, cluster.Namespace); err != nil {
+			logger.Warningf("failed to free devices of cluster %s. %+v", cluster.Namespace, err)
+		}
+	}
+
+	nodes, err := c.getCleanupNodes(cluster)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		logger.Infof("no nodes to clean up for cluster %s", cluster.Namespace)
+		return nil
+	}
+
+	if err := c.waitForDaemonsDeleted(cluster.Namespace); err != nil {
+		return err
+	}
+
+	var failed []string
+	jobs := map[string]*batch.Job{}
+	for _, node := range sortedNodes(nodes) {
+		job := c.makeCleanupJob(cluster, node, nodes[node])
+		if err := c.startCleanupJob(job); err != nil {
+			logger.Errorf("failed to start cleanup job on node %s. %+v", node, err)
+			failed = append(failed, node)
+			continue
+		}
+		logger.Infof("started cleanup job on node %s for cluster %s", node, cluster.Namespace)
+		jobs[node] = job
+	}
+
+	for _, node := range sortedNodes(nodes) {
+		job, ok := jobs[node]
+		if !ok {
+			continue
+		}
+		if err := c.waitForCleanupJob(job); err != nil {
+			logger.Errorf("failed to clean up node %s. %+v", node, err)
+			failed = append(failed, node)
+			continue
+		}
+		if len(nodes[node]) > 0 {
+			if err := discover.FreeDevices(c.context, node, cluster.Namespace); err != nil {
+				logger.Errorf("failed to free the wiped devices on node %s. %+v", node, err)
+				failed = append(failed, node)
+			}
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to clean up nodes %v. the devices on these nodes are still claimed by the cluster", failed)
+	}
+	return nil
+}
+
+// getCleanupNodes returns the nodes to clean up with the devices to wipe on each of them
+func (c *ClusterController) getCleanupNodes(cluster *cephv1beta1.Cluster) (map[string][]string, error) {
+	policy := cluster.Spec.CleanupPolicy
+	nodes := map[string][]string{}
+
+	if policy.DeleteDataDir && cluster.Spec.DataDirHostPath != "" {
+		// any node could have run a mon or osd with state in the data dir
+		list, err := c.context.Clientset.CoreV1().Nodes().List(metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list nodes. %+v", err)
+		}
+		for _, node := range list.Items {
+			nodes[node.Name] = nil
+		}
+	}
+
+	if policy.WipeDevices {
+		devices, err := discover.ListClusterDevices(c.context, cluster.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		for node, paths := range devices {
+			nodes[node] = paths
+		}
+	}
+	delete(nodes, "")
+	return nodes, nil
+}
+
+func (c *ClusterController) waitForDaemonsDeleted(namespace string) error {
+	opts := metav1.ListOptions{LabelSelector: cleanupWaitAppSelector}
+	for i := 0; i < cleanupWaitRetries; i++ {
+		pods, err := c.context.Clientset.CoreV1().Pods(namespace).List(opts)
+		if err != nil {
+			return fmt.Errorf("failed to list pods in namespace %s. %+v", namespace, err)
+		}
+		if len(pods.Items) == 0 {
+			return nil
+		}
+		logger.Infof("waiting for %d pods in namespace %s to be deleted before cleanup", len(pods.Items), namespace)
+		time.Sleep(cleanupWaitInterval)
+	}
+	return fmt.Errorf("gave up waiting for the daemons in namespace %s to be deleted", namespace)
+}
+
+func (c *ClusterController) waitForCleanupJob(job *batch.Job) error {
+	for i := 0; i < cleanupJobRetries; i++ {
+		j, err := c.context.Clientset.Batch().Jobs(job.Namespace).Get(job.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get cleanup job %s. %+v", job.Name, err)
+		}
+		if j.Status.Succeeded > 0 {
+			logger.Infof("cleanup job %s completed", job.Name)
+			return nil
+		}
+		if j.Spec.BackoffLimit != nil && j.Status.Failed > *j.Spec.BackoffLimit {
+			return fmt.Errorf("cleanup job %s failed. see the job's pod logs for details", job.Name)
+		}
+		logger.Infof("waiting for cleanup job %s to complete", job.Name)
+		time.Sleep(cleanupWaitInterval)
+	}
+	return fmt.Errorf("timed out waiting for cleanup job %s", job.Name)
+}
+
+func (c *ClusterController) makeCleanupJob(cluster *cephv1beta1.Cluster, nodeName string, devices []string) *batch.Job {
+	args := []string{"ceph", "osd", "zap"}
+	volumes := []v1.Volume{}
+	mounts := []v1.VolumeMount{}
+	if len(devices) > 0 {
+		args = append(args, fmt.Sprintf("--devices=%s", strings.Join(devices, ",")))
+		volumes = append(volumes,
+			v1.Volume{Name: "devices", VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/dev"}}},
+			v1.Volume{Name: "udev", VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/run/udev"}}})
+		mounts = append(mounts,
+			v1.VolumeMount{Name: "devices", MountPath: "/dev"},
+			v1.VolumeMount{Name: "udev", MountPath: "/run/udev"})
+	}
+	if cluster.Spec.CleanupPolicy.DeleteDataDir && cluster.Spec.DataDirHostPath != "" {
+		args = append(args, fmt.Sprintf("--data-dir=%s", k8sutil.DataDir))
+		volumes = append(volumes, v1.Volume{Name: k8sutil.DataDirVolume,
+			VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: cluster.Spec.DataDirHostPath}}})
+		mounts = append(mounts, v1.VolumeMount{Name: k8sutil.DataDirVolume, MountPath: k8sutil.DataDir})
+	}
+
+	privileged := true
+	runAsUser := int64(0)
+	labels := map[string]string{
+		k8sutil.AppAttr:     cleanupAppName,
+		k8sutil.ClusterAttr: cluster.Namespace,
+	}
+	// the job must run in the namespace of its service account. Without a service account for the cluster the jobs
+	// of all clusters run in the operator namespace, so their names include the cluster.
+	namespace := cluster.Namespace
+	serviceAccount := cluster.Spec.ServiceAccount
+	nameFormat := cleanupAppNameFmt
+	if serviceAccount == "" {
+		namespace = os.Getenv(k8sutil.PodNamespaceEnvVar)
+		serviceAccount = c.serviceAccount
+		nameFormat = fmt.Sprintf("%s-%s-%%s", cleanupAppName, cluster.Namespace)
+	}
+	podSpec := v1.PodSpec{
+		ServiceAccountName: serviceAccount,
+		NodeSelector:       map[string]string{apis.LabelHostname: nodeName},
+		Tolerations:        cleanupTolerations(cluster.Spec.Placement),
+		RestartPolicy:      v1.RestartPolicyNever,
+		Volumes:            volumes,
+		Containers: []v1.Container{
+			{
+				Name:         cleanupAppName,
+				Image:        k8sutil.MakeRookImage(c.rookImage),
+				Args:         args,
+				VolumeMounts: mounts,
+				SecurityContext: &v1.SecurityContext{
+					Privileged: &privileged,
+					RunAsUser:  &runAsUser,
+				},
+			},
+		},
+	}
+
+	// the job is not owned by the cluster since the cluster has already been deleted
+	return &batch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      k8sutil.TruncateNodeName(nameFormat, nodeName),
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: batch.JobSpec{
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       podSpec,
+			},
+		},
+	}
+}
+
+// cleanupTolerations returns the tolerations of the mons and osds so the job can run on the tainted nodes where they
+// ran. The affinities are not applied since the job must run on its node even if the placement has changed.
+func cleanupTolerations(placement rookalpha.PlacementSpec) []v1.Toleration {
+	tolerations := cephv1beta1.GetMonPlacement(placement).Tolerations
+	return append(tolerations, cephv1beta1.GetOSDPlacement(placement).Tolerations...)
+}
+
+func (c *ClusterController) startCleanupJob(job *batch.Job) error {
+	jobs := c.context.Clientset.Batch().Jobs(job.Namespace)
+	if _, err := jobs.Get(job.Name, metav1.GetOptions{}); err == nil {
+		// remove the job from a previous cleanup so the new one can run
+		propagation := metav1.DeletePropagationForeground
+		if err := jobs.Delete(job.Name, &metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to remove previous cleanup job %s. %+v", job.Name, err)
+		}
+	} else if !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get cleanup job %s. %+v", job.Name, err)
+	}
+
+	if _, err := jobs.Create(job); err != nil {
+		return fmt.Errorf("failed to create cleanup job %s. %+v", job.Name, err)
+	}
+	return nil
+}
+
+func sortedNodes(nodes map[string][]string) []string {
+	names := make([]string, 0, len(nodes))
+	for name := range nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
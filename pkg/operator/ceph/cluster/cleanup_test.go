@@ -0,0 +1,149 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	cephv1beta1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1beta1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	discoverDaemon "github.com/rook/rook/pkg/daemon/discover"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/kubernetes/pkg/kubelet/apis"
+)
+
+func TestCleanupHosts(t *testing.T) {
+	clientset := testop.New(3)
+	rookClientset := rookfake.NewSimpleClientset()
+	context := &clusterd.Context{Clientset: clientset, RookClientset: rookClientset}
+	c := NewClusterController(context, "rook/rook:myversion", "rook-ceph-system", nil)
+	cleanupWaitInterval = time.Millisecond
+	cleanupWaitRetries = 2
+	cleanupJobRetries = 2
+
+	// report the cleanup jobs as completed as soon as they are created
+	clientset.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		job := action.(k8stesting.CreateAction).GetObject().(*batch.Job)
+		job.Status.Succeeded = 1
+		return false, nil, nil
+	})
+
+	osdID := 0
+	nodeDevices := &rookalpha.NodeDevices{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "node1",
+			Labels: map[string]string{k8sutil.AppAttr: discoverDaemon.AppName},
+		},
+		Devices: []rookalpha.NodeDevice{
+			{Name: "sda", FullPath: "/dev/disk/by-id/wwn-0x5000c500a0b1c2d3", Cluster: "rook-ceph", OSDID: &osdID},
+			{Name: "sdb", Cluster: "rook-ceph"},
+			{Name: "sdc", FullPath: "/dev/disk/by-id/wwn-0x5000c500a0b1c2d4", Cluster: "other"},
+			{Name: "sdd", FullPath: "/dev/disk/by-id/wwn-0x5000c500a0b1c2d5"},
+		},
+	}
+	_, err := rookClientset.RookV1alpha2().NodeDevices().Create(nodeDevices)
+	assert.Nil(t, err)
+
+	cluster := &cephv1beta1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph", Namespace: "rook-ceph"},
+		Spec: cephv1beta1.ClusterSpec{
+			DataDirHostPath: "/var/lib/rook",
+			ServiceAccount:  "rook-ceph-cluster",
+			CleanupPolicy:   cephv1beta1.CleanupPolicySpec{WipeDevices: true},
+			Placement: rookalpha.PlacementSpec{
+				cephv1beta1.PlacementKeyOSD: rookalpha.Placement{
+					NodeAffinity: &v1.NodeAffinity{},
+					Tolerations:  []v1.Toleration{{Key: "storage", Operator: v1.TolerationOpExists}},
+				},
+			},
+		},
+	}
+
+	// the devices are not freed if the daemons are not stopped
+	osdPod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "osd0", Namespace: "rook-ceph", Labels: map[string]string{k8sutil.AppAttr: "rook-ceph-osd"}}}
+	_, err = clientset.CoreV1().Pods("rook-ceph").Create(osdPod)
+	assert.Nil(t, err)
+	err = c.cleanupHosts(cluster)
+	assert.NotNil(t, err)
+	nd, err := rookClientset.RookV1alpha2().NodeDevices().Get("node1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "rook-ceph", nd.Devices[0].Cluster)
+	err = clientset.CoreV1().Pods("rook-ceph").Delete("osd0", &metav1.DeleteOptions{})
+	assert.Nil(t, err)
+
+	// only the node with devices claimed by the cluster is cleaned up
+	err = c.cleanupHosts(cluster)
+	assert.Nil(t, err)
+	jobs, err := clientset.Batch().Jobs("rook-ceph").List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(jobs.Items))
+	job := jobs.Items[0]
+	assert.Equal(t, "rook-ceph-cleanup-node1", job.Name)
+	assert.Equal(t, "node1", job.Spec.Template.Spec.NodeSelector[apis.LabelHostname])
+	// only the tolerations of the placement are applied since the job must run on its node
+	assert.Nil(t, job.Spec.Template.Spec.Affinity)
+	assert.Equal(t, "storage", job.Spec.Template.Spec.Tolerations[0].Key)
+	assert.Equal(t, "rook-ceph-cluster", job.Spec.Template.Spec.ServiceAccountName)
+	assert.Equal(t, 0, len(job.OwnerReferences))
+	container := job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "rook/rook:myversion", container.Image)
+	assert.Equal(t, []string{"ceph", "osd", "zap", "--devices=/dev/disk/by-id/wwn-0x5000c500a0b1c2d3,/dev/sdb"}, container.Args)
+	assert.Equal(t, 2, len(container.VolumeMounts))
+
+	// the devices of the cluster are freed after they are wiped
+	nd, err = rookClientset.RookV1alpha2().NodeDevices().Get("node1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", nd.Devices[0].Cluster)
+	assert.Nil(t, nd.Devices[0].OSDID)
+	assert.Equal(t, "", nd.Devices[1].Cluster)
+	assert.Equal(t, "other", nd.Devices[2].Cluster)
+
+	// all nodes are cleaned up when the data dir is deleted
+	cluster.Spec.CleanupPolicy.DeleteDataDir = true
+	err = c.cleanupHosts(cluster)
+	assert.Nil(t, err)
+	jobs, err = clientset.Batch().Jobs("rook-ceph").List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(jobs.Items))
+	for _, job := range jobs.Items {
+		container := job.Spec.Template.Spec.Containers[0]
+		assert.Equal(t, []string{"ceph", "osd", "zap", "--data-dir=/var/lib/rook"}, container.Args)
+		assert.Equal(t, 1, len(container.VolumeMounts))
+		assert.Equal(t, "/var/lib/rook", job.Spec.Template.Spec.Volumes[0].HostPath.Path)
+	}
+
+	// without a service account for the cluster the jobs run in the operator namespace with its service account
+	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-ceph-system")
+	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
+	cluster.Spec.ServiceAccount = ""
+	err = c.cleanupHosts(cluster)
+	assert.Nil(t, err)
+	systemJob, err := clientset.Batch().Jobs("rook-ceph-system").Get("rook-ceph-cleanup-rook-ceph-node1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "rook-ceph-system", systemJob.Spec.Template.Spec.ServiceAccountName)
+}
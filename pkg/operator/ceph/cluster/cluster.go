@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"sync"
 
 	cephv1beta1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1beta1"
 	rookv1alpha2 "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
@@ -42,8 +43,15 @@ type cluster struct {
 	mons      *mon.Cluster
 	mgrs      *mgr.Cluster
 	osds      *osd.Cluster
+	monHealth *mon.HealthChecker
+	osdHealth *osd.Monitor
 	stopCh    chan struct{}
 	ownerRef  metav1.OwnerReference
+	// osdLock serializes the osd orchestration of cluster updates and nodes joining storage groups
+	osdLock sync.Mutex
+	// recoveringQuorum is set while a mon quorum recovery runs in the background
+	recoveringQuorum bool
+	recoverLock      sync.Mutex
 }
 
 func newCluster(c *cephv1beta1.Cluster, context *clusterd.Context) *cluster {
@@ -52,6 +60,23 @@ func newCluster(c *cephv1beta1.Cluster, context *clusterd.Context) *cluster {
 		ownerRef: ClusterOwnerRef(c.Namespace, string(c.UID))}
 }
 
+// startQuorumRecovery returns false if a mon quorum recovery is already in progress
+func (c *cluster) startQuorumRecovery() bool {
+	c.recoverLock.Lock()
+	defer c.recoverLock.Unlock()
+	if c.recoveringQuorum {
+		return false
+	}
+	c.recoveringQuorum = true
+	return true
+}
+
+func (c *cluster) doneQuorumRecovery() {
+	c.recoverLock.Lock()
+	defer c.recoverLock.Unlock()
+	c.recoveringQuorum = false
+}
+
 func (c *cluster) createInstance(rookImage string) error {
 
 	// Create a configmap for overriding ceph config settings
@@ -72,9 +97,15 @@ func (c *cluster) createInstance(rookImage string) error {
 		return fmt.Errorf("failed to create override configmap %s. %+v", c.Namespace, err)
 	}
 
-	// Start the mon pods
-	c.mons = mon.New(c.context, c.Namespace, c.Spec.DataDirHostPath, rookImage, c.Spec.Mon, cephv1beta1.GetMonPlacement(c.Spec.Placement),
-		c.Spec.Network.HostNetwork, cephv1beta1.GetMonResources(c.Spec.Resources), c.ownerRef)
+	// Start the mon pods. The mons are created once and updated afterwards since the mon health checker
+	// and the quorum recovery operate on the same mons.
+	if c.mons == nil {
+		c.mons = mon.New(c.context, c.Namespace, c.Spec.DataDirHostPath, rookImage, c.Spec.Mon, cephv1beta1.GetMonPlacement(c.Spec.Placement),
+			c.Spec.Network.HostNetwork, cephv1beta1.GetMonResources(c.Spec.Resources), c.ownerRef)
+	} else {
+		c.mons.UpdateSettings(c.Spec.DataDirHostPath, rookImage, c.Spec.Mon, cephv1beta1.GetMonPlacement(c.Spec.Placement),
+			c.Spec.Network.HostNetwork, cephv1beta1.GetMonResources(c.Spec.Resources))
+	}
 	err = c.mons.Start()
 	if err != nil {
 		return fmt.Errorf("failed to start the mons. %+v", err)
@@ -86,13 +117,15 @@ func (c *cluster) createInstance(rookImage string) error {
 	}
 
 	c.mgrs = mgr.New(c.context, c.Namespace, rookImage, cephv1beta1.GetMgrPlacement(c.Spec.Placement),
-		c.Spec.Network.HostNetwork, c.Spec.Dashboard, cephv1beta1.GetMgrResources(c.Spec.Resources), c.ownerRef)
+		c.Spec.Network.HostNetwork, c.Spec.Mgr, c.Spec.Dashboard, c.Spec.Monitoring, cephv1beta1.GetMgrResources(c.Spec.Resources), c.ownerRef)
 	err = c.mgrs.Start()
 	if err != nil {
 		return fmt.Errorf("failed to start the ceph mgr. %+v", err)
 	}
 
 	// Start the OSDs
+	c.osdLock.Lock()
+	defer c.osdLock.Unlock()
 	c.osds = osd.New(c.context, c.Namespace, rookImage, c.Spec.ServiceAccount, c.Spec.Storage, c.Spec.DataDirHostPath,
 		cephv1beta1.GetOSDPlacement(c.Spec.Placement), c.Spec.Network.HostNetwork, cephv1beta1.GetOSDResources(c.Spec.Resources), c.ownerRef)
 	err = c.osds.Start()
@@ -176,9 +209,27 @@ func clusterChanged(oldCluster, newCluster cephv1beta1.ClusterSpec) bool {
 		changeFound = true
 	}
 
+	if !reflect.DeepEqual(oldStorage.StorageGroups, newStorage.StorageGroups) {
+		logger.Infof("the storage groups have changed")
+		changeFound = true
+	}
+
+	if !reflect.DeepEqual(oldCluster.Mgr, newCluster.Mgr) {
+		logger.Infof("the mgr settings have changed")
+		changeFound = true
+	}
+
 	if oldCluster.Dashboard.Enabled != newCluster.Dashboard.Enabled {
 		logger.Infof("dashboard enabled has changed from %t to %t", oldCluster.Dashboard.Enabled, newCluster.Dashboard.Enabled)
 		changeFound = true
+	} else if !reflect.DeepEqual(oldCluster.Dashboard, newCluster.Dashboard) {
+		logger.Infof("the dashboard settings have changed")
+		changeFound = true
+	}
+
+	if !reflect.DeepEqual(oldCluster.Monitoring, newCluster.Monitoring) {
+		logger.Infof("the monitoring settings have changed")
+		changeFound = true
 	}
 
 	return changeFound
@@ -37,6 +37,8 @@ import (
 	"github.com/rook/rook/pkg/operator/ceph/pool"
 	"github.com/rook/rook/pkg/operator/discover"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
+	"k8s.io/api/core/v1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -53,6 +55,7 @@ const (
 	clusterCreateTimeout     = 60 * time.Minute
 	updateClusterInterval    = 30 * time.Second
 	updateClusterTimeout     = 1 * time.Hour
+	clusterControllerName    = "ceph-cluster"
 )
 
 const (
@@ -92,15 +95,17 @@ type ClusterController struct {
 	volumeAttachment attachment.Attachment
 	devicesInUse     bool
 	rookImage        string
+	serviceAccount   string
 	clusterMap       map[string]*cluster
 }
 
 // NewClusterController create controller for watching cluster custom resources created
-func NewClusterController(context *clusterd.Context, rookImage string, volumeAttachment attachment.Attachment) *ClusterController {
+func NewClusterController(context *clusterd.Context, rookImage, serviceAccount string, volumeAttachment attachment.Attachment) *ClusterController {
 	return &ClusterController{
 		context:          context,
 		volumeAttachment: volumeAttachment,
 		rookImage:        rookImage,
+		serviceAccount:   serviceAccount,
 		clusterMap:       make(map[string]*cluster),
 	}
 }
@@ -114,7 +119,7 @@ func (c *ClusterController) StartWatch(namespace string, stopCh chan struct{}) e
 	}
 
 	logger.Infof("start watching clusters in all namespaces")
-	watcher := opkit.NewWatcher(ClusterResource, namespace, resourceHandlerFuncs, c.context.RookClientset.CephV1beta1().RESTClient())
+	watcher := opkit.NewWatcher(ClusterResource, namespace, metrics.InstrumentHandlers(clusterControllerName, resourceHandlerFuncs), c.context.RookClientset.CephV1beta1().RESTClient())
 	go watcher.Watch(&cephv1beta1.Cluster{}, stopCh)
 
 	// watch for events on all legacy types too
@@ -137,6 +142,7 @@ func (c *ClusterController) onAdd(obj interface{}) {
 	clusterObj, migrationNeeded, err := getClusterObject(obj)
 	if err != nil {
 		logger.Errorf("failed to get cluster object: %+v", err)
+		metrics.ReconcileFailed(clusterControllerName, "add")
 		return
 	}
 
@@ -144,6 +150,7 @@ func (c *ClusterController) onAdd(obj interface{}) {
 		err = c.migrateClusterObject(clusterObj, obj)
 		if err != nil {
 			logger.Errorf("failed to migrate legacy cluster %s in namespace %s: %+v", clusterObj.Name, clusterObj.Namespace, err)
+			metrics.ReconcileFailed(clusterControllerName, "add")
 		}
 
 		// no matter the outcome of the migration, bail out now. if it was successful, then we'll be getting
@@ -159,6 +166,8 @@ func (c *ClusterController) onAdd(obj interface{}) {
 	if c.devicesInUse && cluster.Spec.Storage.AnyUseAllDevices() {
 		message := "using all devices in more than one namespace not supported"
 		logger.Error(message)
+		metrics.ReconcileFailed(clusterControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, clusterObj, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "%s", message)
 		if err := c.updateClusterStatus(clusterObj.Namespace, clusterObj.Name, cephv1beta1.ClusterStateError, message); err != nil {
 			logger.Errorf("failed to update cluster status in namespace %s: %+v", cluster.Namespace, err)
 		}
@@ -182,7 +191,8 @@ func (c *ClusterController) onAdd(obj interface{}) {
 		logger.Warningf("mon count is even (given: %d), should be uneven, continuing", cluster.Spec.Mon.Count)
 	}
 
-	// Start the Rook cluster components. Retry several times in case of failure.
+	// Start the Rook cluster components. Retry several times in case of failure. The failure is only counted once
+	// when giving up, not for each attempt.
 	err = wait.Poll(clusterCreateInterval, clusterCreateTimeout, func() (bool, error) {
 		if err := c.updateClusterStatus(clusterObj.Namespace, clusterObj.Name, cephv1beta1.ClusterStateCreating, ""); err != nil {
 			logger.Errorf("failed to update cluster status in namespace %s: %+v", cluster.Namespace, err)
@@ -192,6 +202,7 @@ func (c *ClusterController) onAdd(obj interface{}) {
 		err := cluster.createInstance(c.rookImage)
 		if err != nil {
 			logger.Errorf("failed to create cluster in namespace %s. %+v", cluster.Namespace, err)
+			k8sutil.RecordEvent(c.context.Recorder, clusterObj, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "failed to create cluster. %+v", err)
 			return false, nil
 		}
 
@@ -206,11 +217,14 @@ func (c *ClusterController) onAdd(obj interface{}) {
 	if err != nil {
 		message := fmt.Sprintf("giving up creating cluster in namespace %s after %s", cluster.Namespace, clusterCreateTimeout)
 		logger.Error(message)
+		metrics.ReconcileFailed(clusterControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, clusterObj, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "%s", message)
 		if err := c.updateClusterStatus(clusterObj.Namespace, clusterObj.Name, cephv1beta1.ClusterStateError, message); err != nil {
 			logger.Errorf("failed to update cluster status in namespace %s: %+v", cluster.Namespace, err)
 		}
 		return
 	}
+	k8sutil.RecordEvent(c.context.Recorder, clusterObj, v1.EventTypeNormal, k8sutil.EventReasonCreated, "created cluster in namespace %s", cluster.Namespace)
 
 	// Start pool CRD watcher
 	poolController := pool.NewPoolController(c.context)
@@ -220,22 +234,31 @@ func (c *ClusterController) onAdd(obj interface{}) {
 	objectStoreController := object.NewObjectStoreController(c.context, c.rookImage, cluster.Spec.Network.HostNetwork, cluster.ownerRef)
 	objectStoreController.StartWatch(cluster.Namespace, cluster.stopCh)
 
+	// Start bucket CRD watcher
+	bucketController := object.NewBucketController(c.context)
+	bucketController.StartWatch(cluster.Namespace, cluster.stopCh)
+
 	// Start file system CRD watcher
 	fileController := file.NewFilesystemController(c.context, c.rookImage, cluster.Spec.Network.HostNetwork, cluster.ownerRef)
 	fileController.StartWatch(cluster.Namespace, cluster.stopCh)
 
 	// Start mon health checker
-	healthChecker := mon.NewHealthChecker(cluster.mons)
-	go healthChecker.Check(cluster.stopCh)
+	cluster.monHealth = mon.NewHealthChecker(cluster.mons)
+	go cluster.monHealth.Check(cluster.stopCh)
 
 	// Start the osd health checker
-	osdChecker := osd.NewMonitor(c.context, cluster.Namespace)
-	go osdChecker.Start(cluster.stopCh)
+	cluster.osdHealth = osd.NewMonitor(c.context, cluster.Namespace)
+	cluster.osdHealth.UpdateHealthSettings(cluster.Spec.OSD)
+	go cluster.osdHealth.Start(cluster.stopCh)
+
+	// Start the osds on nodes joining the storage groups
+	cluster.watchStorageNodes()
 
 	// add the finalizer to the crd
 	err = c.addFinalizer(clusterObj)
 	if err != nil {
 		logger.Errorf("failed to add finalizer to cluster crd. %+v", err)
+		metrics.ReconcileFailed(clusterControllerName, "add")
 	}
 }
 
@@ -246,11 +269,13 @@ func (c *ClusterController) onUpdate(oldObj, newObj interface{}) {
 	oldClust, _, err := getClusterObject(oldObj)
 	if err != nil {
 		logger.Errorf("failed to get old cluster object: %+v", err)
+		metrics.ReconcileFailed(clusterControllerName, "update")
 		return
 	}
 	newClust, migrationNeeded, err := getClusterObject(newObj)
 	if err != nil {
 		logger.Errorf("failed to get new cluster object: %+v", err)
+		metrics.ReconcileFailed(clusterControllerName, "update")
 		return
 	}
 
@@ -266,6 +291,7 @@ func (c *ClusterController) onUpdate(oldObj, newObj interface{}) {
 
 		if err = c.migrateClusterObject(newClust, newObj); err != nil {
 			logger.Errorf("failed to migrate legacy cluster %s in namespace %s: %+v", newClust.Name, newClust.Namespace, err)
+			metrics.ReconcileFailed(clusterControllerName, "update")
 		}
 
 		// no matter the outcome of the migration, bail out now. if it was successful, then we'll be getting
@@ -283,6 +309,7 @@ func (c *ClusterController) onUpdate(oldObj, newObj interface{}) {
 		err := c.handleDelete(newClust, time.Duration(clusterDeleteRetryInterval)*time.Second)
 		if err != nil {
 			logger.Errorf("failed finalizer for cluster. %+v", err)
+			metrics.ReconcileFailed(clusterControllerName, "update")
 			return
 		}
 		// remove the finalizer from the crd, which indicates to k8s that the resource can safely be deleted
@@ -290,6 +317,15 @@ func (c *ClusterController) onUpdate(oldObj, newObj interface{}) {
 		return
 	}
 
+	// recover the mon quorum from a surviving mon if requested by the admin
+	if survivor, ok := newClust.Annotations[mon.RecoverQuorumAnnotation]; ok {
+		c.recoverMonQuorum(newClust, survivor)
+		return
+	}
+
+	// the health settings are applied to the running health checkers without orchestrating the cluster
+	c.updateHealthSettings(newClust)
+
 	if !clusterChanged(oldClust.Spec, newClust.Spec) {
 		logger.Infof("update event for cluster %s is not supported", newClust.Namespace)
 		return
@@ -302,6 +338,7 @@ func (c *ClusterController) onUpdate(oldObj, newObj interface{}) {
 	cluster, ok := c.clusterMap[newClust.Namespace]
 	if !ok {
 		logger.Errorf("Cannot update cluster %s that does not exist", newClust.Namespace)
+		metrics.ReconcileFailed(clusterControllerName, "update")
 		return
 	}
 	cluster.Spec = &newClust.Spec
@@ -319,13 +356,28 @@ func (c *ClusterController) onUpdate(oldObj, newObj interface{}) {
 	if err != nil {
 		message := fmt.Sprintf("giving up trying to update cluster in namespace %s after %s", cluster.Namespace, updateClusterTimeout)
 		logger.Error(message)
+		k8sutil.RecordEvent(c.context.Recorder, newClust, v1.EventTypeWarning, k8sutil.EventReasonUpdateFailed, "%s", message)
 		if err := c.updateClusterStatus(newClust.Namespace, newClust.Name, cephv1beta1.ClusterStateError, message); err != nil {
 			logger.Errorf("failed to update cluster status in namespace %s: %+v", newClust.Namespace, err)
+			metrics.ReconcileFailed(clusterControllerName, "update")
 		}
 		return
 	}
 }
 
+func (c *ClusterController) updateHealthSettings(newClust *cephv1beta1.Cluster) {
+	cluster, ok := c.clusterMap[newClust.Namespace]
+	if !ok {
+		return
+	}
+	if cluster.monHealth != nil {
+		cluster.monHealth.UpdateHealthSettings(newClust.Spec.Mon)
+	}
+	if cluster.osdHealth != nil {
+		cluster.osdHealth.UpdateHealthSettings(newClust.Spec.OSD)
+	}
+}
+
 func (c *ClusterController) handleUpdate(newClust *cephv1beta1.Cluster, cluster *cluster) (bool, error) {
 	if err := c.updateClusterStatus(newClust.Namespace, newClust.Name, cephv1beta1.ClusterStateUpdating, ""); err != nil {
 		logger.Errorf("failed to update cluster status in namespace %s: %+v", newClust.Namespace, err)
@@ -346,6 +398,52 @@ func (c *ClusterController) handleUpdate(newClust *cephv1beta1.Cluster, cluster
 	return true, nil
 }
 
+func (c *ClusterController) recoverMonQuorum(newClust *cephv1beta1.Cluster, survivor string) {
+	cluster, ok := c.clusterMap[newClust.Namespace]
+	if !ok || cluster.mons == nil {
+		logger.Errorf("Cannot recover mon quorum of cluster %s that does not exist", newClust.Namespace)
+		return
+	}
+	if !cluster.startQuorumRecovery() {
+		logger.Infof("mon quorum recovery of cluster %s is already in progress", newClust.Namespace)
+		return
+	}
+
+	// the recovery waits for jobs and mons to start, so it must not block the handling of other events.
+	// the mon health checks wait for the recovery to complete.
+	go func() {
+		defer cluster.doneQuorumRecovery()
+		c.doRecoverMonQuorum(newClust, cluster, survivor)
+	}()
+}
+
+func (c *ClusterController) doRecoverMonQuorum(newClust *cephv1beta1.Cluster, cluster *cluster, survivor string) {
+	logger.Warningf("recovering mon quorum of cluster %s from mon %s", newClust.Namespace, survivor)
+	if err := c.updateClusterStatus(newClust.Namespace, newClust.Name, cephv1beta1.ClusterStateUpdating, ""); err != nil {
+		logger.Errorf("failed to update cluster status in namespace %s: %+v", newClust.Namespace, err)
+	}
+
+	state := cephv1beta1.ClusterStateCreated
+	message := ""
+	if err := cluster.mons.RecoverQuorum(survivor); err != nil {
+		state = cephv1beta1.ClusterStateError
+		message = fmt.Sprintf("failed to recover mon quorum from mon %s. %+v", survivor, err)
+		logger.Error(message)
+	}
+
+	// remove the annotation so the recovery is only attempted once
+	clust, err := c.context.RookClientset.CephV1beta1().Clusters(newClust.Namespace).Get(newClust.Name, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to remove the mon recovery annotation. %+v", newClust.Namespace, err)
+	} else {
+		delete(clust.Annotations, mon.RecoverQuorumAnnotation)
+		clust.Status = cephv1beta1.ClusterStatus{State: state, Message: message}
+		if _, err := c.context.RookClientset.CephV1beta1().Clusters(clust.Namespace).Update(clust); err != nil {
+			logger.Errorf("failed to remove the mon recovery annotation from cluster %s. %+v", clust.Namespace, err)
+		}
+	}
+}
+
 // ************************************************************************************************
 // Delete event functions
 // ************************************************************************************************
@@ -353,6 +451,7 @@ func (c *ClusterController) onDelete(obj interface{}) {
 	clust, migrationNeeded, err := getClusterObject(obj)
 	if err != nil {
 		logger.Errorf("failed to get cluster object: %+v", err)
+		metrics.ReconcileFailed(clusterControllerName, "delete")
 		return
 	}
 
@@ -368,6 +467,7 @@ func (c *ClusterController) onDelete(obj interface{}) {
 	err = c.handleDelete(clust, time.Duration(clusterDeleteRetryInterval)*time.Second)
 	if err != nil {
 		logger.Errorf("failed to delete cluster. %+v", err)
+		metrics.ReconcileFailed(clusterControllerName, "delete")
 	}
 	if cluster, ok := c.clusterMap[clust.Namespace]; ok {
 		close(cluster.stopCh)
@@ -376,6 +476,16 @@ func (c *ClusterController) onDelete(obj interface{}) {
 	if clust.Spec.Storage.AnyUseAllDevices() {
 		c.devicesInUse = false
 	}
+	if clust.Spec.CleanupPolicy.WipeDevices || clust.Spec.CleanupPolicy.DeleteDataDir {
+		// the devices are freed after they are wiped by the cleanup jobs
+		go func() {
+			if err := c.cleanupHosts(clust); err != nil {
+				logger.Errorf("failed to clean up hosts of cluster %s. %+v", clust.Namespace, err)
+				metrics.ReconcileFailed(clusterControllerName, "delete")
+			}
+		}()
+		return
+	}
 	discover.FreeDevicesByCluster(c.context, clust.Namespace)
 }
 
@@ -104,7 +104,7 @@ func TestClusterDelete(t *testing.T) {
 	}
 
 	// create the cluster controller and tell it that the cluster has been deleted
-	controller := NewClusterController(context, "", volumeAttachmentController)
+	controller := NewClusterController(context, "", "", volumeAttachmentController)
 	clusterToDelete := &cephv1beta1.Cluster{ObjectMeta: metav1.ObjectMeta{Namespace: clusterName}}
 	controller.handleDelete(clusterToDelete, time.Microsecond)
 
@@ -152,6 +152,23 @@ func TestClusterChanged(t *testing.T) {
 		{Name: "node1", Selection: rookalpha.Selection{Devices: []rookalpha.Device{{Name: "sda"}}}},
 	}
 	assert.False(t, clusterChanged(old, new))
+
+	// adding a storage group should be a change
+	ssd := rookalpha.StorageGroup{Name: "ssd", NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"storage": "ssd"}}}
+	new.Storage.StorageGroups = []rookalpha.StorageGroup{ssd}
+	assert.True(t, clusterChanged(old, new))
+	old.Storage.StorageGroups = []rookalpha.StorageGroup{ssd}
+	assert.False(t, clusterChanged(old, new))
+
+	// changing the selector of a storage group should be a change
+	nvme := rookalpha.StorageGroup{Name: "ssd", NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"storage": "nvme"}}}
+	new.Storage.StorageGroups = []rookalpha.StorageGroup{nvme}
+	assert.True(t, clusterChanged(old, new))
+	new.Storage.StorageGroups = old.Storage.StorageGroups
+
+	// enabling the monitoring should be a change
+	new.Monitoring.Enabled = true
+	assert.True(t, clusterChanged(old, new))
 }
 
 func TestRemoveFinalizer(t *testing.T) {
@@ -160,7 +177,7 @@ func TestRemoveFinalizer(t *testing.T) {
 		Clientset:     clientset,
 		RookClientset: rookfake.NewSimpleClientset(),
 	}
-	controller := NewClusterController(context, "", &attachment.MockAttachment{})
+	controller := NewClusterController(context, "", "", &attachment.MockAttachment{})
 
 	// *****************************************
 	// start with a current version ceph cluster
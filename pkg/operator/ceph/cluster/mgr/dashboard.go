@@ -0,0 +1,256 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mgr
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	dashboardPort            = 7000
+	dashboardSSLPort         = 8443
+	dashboardPasswordName    = "rook-ceph-dashboard-password"
+	dashboardPasswordKey     = "password"
+	defaultDashboardUsername = "admin"
+	passwordLength           = 24
+)
+
+func (c *Cluster) configureDashboard() error {
+	dashboardService := c.makeDashboardService(appName)
+	if !c.dashboard.Enabled {
+		if err := client.MgrDisableModule(c.context, c.Namespace, dashboardModuleName); err != nil {
+			return fmt.Errorf("failed to disable mgr dashboard module. %+v", err)
+		}
+
+		// delete the dashboard service if it exists
+		err := c.context.Clientset.CoreV1().Services(c.Namespace).Delete(dashboardService.Name, &metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete dashboard service. %+v", err)
+		}
+		return nil
+	}
+
+	// Ceph docs about the dashboard module: http://docs.ceph.com/docs/mimic/mgr/dashboard/
+	if err := client.MgrEnableModule(c.context, c.Namespace, dashboardModuleName, true); err != nil {
+		return fmt.Errorf("failed to enable mgr dashboard module. %+v", err)
+	}
+
+	changed, err := c.configureDashboardSettings()
+	if err != nil {
+		return fmt.Errorf("failed to configure dashboard. %+v", err)
+	}
+	if changed {
+		// the dashboard only reads its settings when the module is started
+		logger.Infof("restarting the mgr dashboard module to apply the new settings")
+		if err := client.MgrDisableModule(c.context, c.Namespace, dashboardModuleName); err != nil {
+			return fmt.Errorf("failed to disable mgr dashboard module. %+v", err)
+		}
+		if err := client.MgrEnableModule(c.context, c.Namespace, dashboardModuleName, true); err != nil {
+			return fmt.Errorf("failed to enable mgr dashboard module. %+v", err)
+		}
+	}
+
+	if err := c.setDashboardLoginCredentials(); err != nil {
+		return fmt.Errorf("failed to set dashboard login credentials. %+v", err)
+	}
+
+	// expose the dashboard service
+	if _, err := c.context.Clientset.CoreV1().Services(c.Namespace).Create(dashboardService); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create dashboard mgr service. %+v", err)
+		}
+		if err := c.updateDashboardService(dashboardService); err != nil {
+			return err
+		}
+	} else {
+		logger.Infof("dashboard service started")
+	}
+
+	return nil
+}
+
+// configureDashboardSettings applies the dashboard spec to the dashboard module settings and returns
+// whether any setting was changed
+func (c *Cluster) configureDashboardSettings() (bool, error) {
+	settings := map[string]string{
+		"server_port": strconv.Itoa(c.dashboardPort()),
+		"url_prefix":  c.dashboard.URLPrefix,
+		"ssl":         strconv.FormatBool(c.dashboard.SSL),
+	}
+	if c.dashboard.SSL && c.dashboard.CertificateSecret != "" {
+		secret, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Get(c.dashboard.CertificateSecret, metav1.GetOptions{})
+		if err != nil {
+			return false, fmt.Errorf("failed to get dashboard certificate secret %s. %+v", c.dashboard.CertificateSecret, err)
+		}
+		cert, key := secret.Data[v1.TLSCertKey], secret.Data[v1.TLSPrivateKeyKey]
+		if len(cert) == 0 || len(key) == 0 {
+			return false, fmt.Errorf("dashboard certificate secret %s must contain %s and %s", c.dashboard.CertificateSecret, v1.TLSCertKey, v1.TLSPrivateKeyKey)
+		}
+		settings["crt"] = string(cert)
+		settings["key"] = string(key)
+	}
+
+	changed := false
+	for key, value := range settings {
+		// a setting that cannot be read is treated as not set
+		current, _ := client.MgrGetModuleConfig(c.context, c.Namespace, dashboardModuleName, key)
+		if current == value {
+			continue
+		}
+		if err := client.MgrSetModuleConfig(c.context, c.Namespace, dashboardModuleName, key, value); err != nil {
+			return false, err
+		}
+		logger.Infof("dashboard setting %s updated", key)
+		changed = true
+	}
+
+	if c.dashboard.SSL && c.dashboard.CertificateSecret == "" {
+		// keep the certificate that was generated or configured previously
+		if cert, _ := client.MgrGetModuleConfig(c.context, c.Namespace, dashboardModuleName, "crt"); cert == "" {
+			if err := client.DashboardCreateSelfSignedCert(c.context, c.Namespace); err != nil {
+				return false, err
+			}
+			logger.Infof("generated a self-signed certificate for the dashboard")
+			changed = true
+		}
+	}
+
+	return changed, nil
+}
+
+// setDashboardLoginCredentials sets the password of the dashboard admin user, generating the
+// password into a secret the first time
+func (c *Cluster) setDashboardLoginCredentials() error {
+	username := c.dashboard.AdminUsername
+	if username == "" {
+		username = defaultDashboardUsername
+	}
+
+	password, err := c.getOrCreateDashboardPassword()
+	if err != nil {
+		return err
+	}
+
+	return client.DashboardSetLoginCredentials(c.context, c.Namespace, username, password)
+}
+
+func (c *Cluster) getOrCreateDashboardPassword() (string, error) {
+	secret, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Get(dashboardPasswordName, metav1.GetOptions{})
+	if err == nil {
+		return string(secret.Data[dashboardPasswordKey]), nil
+	}
+	if !errors.IsNotFound(err) {
+		return "", fmt.Errorf("failed to get dashboard password secret. %+v", err)
+	}
+
+	password, err := generatePassword()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate dashboard password. %+v", err)
+	}
+	secret = &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      dashboardPasswordName,
+			Namespace: c.Namespace,
+		},
+		Data: map[string][]byte{
+			dashboardPasswordKey: []byte(password),
+		},
+		Type: k8sutil.RookType,
+	}
+	k8sutil.SetOwnerRef(c.context.Clientset, c.Namespace, &secret.ObjectMeta, &c.ownerRef)
+
+	if _, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Create(secret); err != nil {
+		return "", fmt.Errorf("failed to save dashboard password secret. %+v", err)
+	}
+	logger.Infof("dashboard password saved in secret %s", dashboardPasswordName)
+	return password, nil
+}
+
+func generatePassword() (string, error) {
+	b := make([]byte, passwordLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (c *Cluster) dashboardPort() int {
+	if c.dashboard.Port != 0 {
+		return c.dashboard.Port
+	}
+	if c.dashboard.SSL {
+		return dashboardSSLPort
+	}
+	return dashboardPort
+}
+
+func (c *Cluster) makeDashboardService(name string) *v1.Service {
+	labels := c.getLabels()
+	portName := "http-dashboard"
+	if c.dashboard.SSL {
+		portName = "https-dashboard"
+	}
+	svc := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-dashboard", name),
+			Namespace: c.Namespace,
+			Labels:    labels,
+		},
+		Spec: v1.ServiceSpec{
+			Selector: labels,
+			Type:     v1.ServiceTypeClusterIP,
+			Ports: []v1.ServicePort{
+				{
+					Name:     portName,
+					Port:     int32(c.dashboardPort()),
+					Protocol: v1.ProtocolTCP,
+				},
+			},
+		},
+	}
+	k8sutil.SetOwnerRef(c.context.Clientset, c.Namespace, &svc.ObjectMeta, &c.ownerRef)
+	return svc
+}
+
+// updateDashboardService updates the port of an existing dashboard service if the port was changed
+func (c *Cluster) updateDashboardService(service *v1.Service) error {
+	existing, err := c.context.Clientset.CoreV1().Services(c.Namespace).Get(service.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get dashboard service. %+v", err)
+	}
+	if len(existing.Spec.Ports) == 1 && existing.Spec.Ports[0].Port == service.Spec.Ports[0].Port &&
+		existing.Spec.Ports[0].Name == service.Spec.Ports[0].Name {
+		logger.Infof("dashboard service already exists")
+		return nil
+	}
+
+	existing.Spec.Ports = service.Spec.Ports
+	if _, err := c.context.Clientset.CoreV1().Services(c.Namespace).Update(existing); err != nil {
+		return fmt.Errorf("failed to update dashboard service. %+v", err)
+	}
+	logger.Infof("dashboard service updated to port %d", service.Spec.Ports[0].Port)
+	return nil
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package mgr
+
+import (
+	"strings"
+	"testing"
+
+	cephv1beta1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1beta1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/clusterd"
+	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConfigureDashboard(t *testing.T) {
+	config := map[string]string{}
+	commands := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			switch {
+			case args[0] == "config-key" && args[1] == "get":
+				return config[args[2]], nil
+			case args[0] == "config-key" && args[1] == "set":
+				config[args[2]] = args[3]
+			default:
+				commands = append(commands, strings.Join(args[:3], " "))
+			}
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor, Clientset: testop.New(1)}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "dashboard-cert", Namespace: "ns"},
+		Data:       map[string][]byte{v1.TLSCertKey: []byte("mycert"), v1.TLSPrivateKeyKey: []byte("mykey")},
+		Type:       v1.SecretTypeTLS,
+	}
+	_, err := context.Clientset.CoreV1().Secrets("ns").Create(secret)
+	assert.Nil(t, err)
+
+	dashboard := cephv1beta1.DashboardSpec{Enabled: true, SSL: true, CertificateSecret: "dashboard-cert", URLPrefix: "/ceph", AdminUsername: "rook"}
+	c := New(context, "ns", "myversion", rookalpha.Placement{}, false, cephv1beta1.MgrSpec{}, dashboard, cephv1beta1.MonitoringSpec{}, v1.ResourceRequirements{}, metav1.OwnerReference{})
+	err = c.configureDashboard()
+	assert.Nil(t, err)
+
+	// the settings are applied and the module is restarted to load them
+	assert.Equal(t, "8443", config["mgr/dashboard/server_port"])
+	assert.Equal(t, "/ceph", config["mgr/dashboard/url_prefix"])
+	assert.Equal(t, "true", config["mgr/dashboard/ssl"])
+	assert.Equal(t, "mycert", config["mgr/dashboard/crt"])
+	assert.Equal(t, "mykey", config["mgr/dashboard/key"])
+	assert.Equal(t, []string{
+		"mgr module enable",
+		"mgr module disable",
+		"mgr module enable",
+		"dashboard set-login-credentials rook"}, commands)
+
+	// the generated password is kept in a secret
+	passwordSecret, err := context.Clientset.CoreV1().Secrets("ns").Get(dashboardPasswordName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	password := string(passwordSecret.Data[dashboardPasswordKey])
+	assert.Equal(t, 32, len(password))
+
+	svc, err := context.Clientset.CoreV1().Services("ns").Get("rook-ceph-mgr-dashboard", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(8443), svc.Spec.Ports[0].Port)
+	assert.Equal(t, "https-dashboard", svc.Spec.Ports[0].Name)
+
+	// reconciling again does not restart the module or change the password
+	commands = []string{}
+	err = c.configureDashboard()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"mgr module enable", "dashboard set-login-credentials rook"}, commands)
+	passwordSecret, err = context.Clientset.CoreV1().Secrets("ns").Get(dashboardPasswordName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, password, string(passwordSecret.Data[dashboardPasswordKey]))
+
+	// the service is updated when the port changes
+	c.dashboard.Port = 9000
+	err = c.configureDashboard()
+	assert.Nil(t, err)
+	assert.Equal(t, "9000", config["mgr/dashboard/server_port"])
+	svc, err = context.Clientset.CoreV1().Services("ns").Get("rook-ceph-mgr-dashboard", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(9000), svc.Spec.Ports[0].Port)
+}
+
+func TestDashboardSelfSignedCert(t *testing.T) {
+	config := map[string]string{}
+	selfSigned := 0
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			switch {
+			case args[0] == "config-key" && args[1] == "get":
+				return config[args[2]], nil
+			case args[0] == "config-key" && args[1] == "set":
+				config[args[2]] = args[3]
+			case args[0] == "dashboard" && args[1] == "create-self-signed-cert":
+				config["mgr/dashboard/crt"] = "selfsigned"
+				selfSigned++
+			}
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor, Clientset: testop.New(1)}
+	dashboard := cephv1beta1.DashboardSpec{Enabled: true, SSL: true}
+	c := New(context, "ns", "myversion", rookalpha.Placement{}, false, cephv1beta1.MgrSpec{}, dashboard, cephv1beta1.MonitoringSpec{}, v1.ResourceRequirements{}, metav1.OwnerReference{})
+
+	// the certificate is only generated once
+	err := c.configureDashboard()
+	assert.Nil(t, err)
+	err = c.configureDashboard()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, selfSigned)
+}
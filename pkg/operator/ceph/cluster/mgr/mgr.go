@@ -19,6 +19,9 @@ package mgr
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/coreos/pkg/capnslog"
 	cephv1beta1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1beta1"
@@ -38,8 +41,11 @@ const (
 	keyringName          = "keyring"
 	prometheusModuleName = "prometheus"
 	dashboardModuleName  = "dashboard"
+	// the configmap in which the modules enabled from the mgr spec are recorded, so that they can be disabled when
+	// they are removed from the spec
+	modulesConfigMapName = "rook-ceph-mgr-modules"
+	enabledModulesKey    = "enabled"
 	metricsPort          = 9283
-	dashboardPort        = 7000
 )
 
 var mgrNames = []string{"a", "b"}
@@ -56,6 +62,10 @@ type Cluster struct {
 	resources   v1.ResourceRequirements
 	ownerRef    metav1.OwnerReference
 	dashboard   cephv1beta1.DashboardSpec
+	modules     []cephv1beta1.MgrModuleSpec
+	monitoring  cephv1beta1.MonitoringSpec
+	// the client of the prometheus operator resources, created on first use
+	monitoringClient monitoringClient
 }
 
 // mgrConfig for a single mgr
@@ -65,16 +75,27 @@ type mgrConfig struct {
 }
 
 // New creates an instance of the mgr
-func New(context *clusterd.Context, namespace, version string, placement rookalpha.Placement, hostNetwork bool, dashboard cephv1beta1.DashboardSpec,
-	resources v1.ResourceRequirements, ownerRef metav1.OwnerReference) *Cluster {
+func New(context *clusterd.Context, namespace, version string, placement rookalpha.Placement, hostNetwork bool, mgrSpec cephv1beta1.MgrSpec,
+	dashboard cephv1beta1.DashboardSpec, monitoring cephv1beta1.MonitoringSpec, resources v1.ResourceRequirements, ownerRef metav1.OwnerReference) *Cluster {
+	replicas := mgrSpec.Count
+	if replicas <= 0 {
+		replicas = 1
+	}
+	if replicas > len(mgrNames) {
+		logger.Warningf("mgr count %d is bigger than %d, not supported, changing to %d", replicas, len(mgrNames), len(mgrNames))
+		replicas = len(mgrNames)
+	}
+
 	return &Cluster{
 		context:     context,
 		Namespace:   namespace,
 		placement:   placement,
 		Version:     version,
-		Replicas:    1,
+		Replicas:    replicas,
 		dataDir:     k8sutil.DataDir,
 		dashboard:   dashboard,
+		modules:     mgrSpec.Modules,
+		monitoring:  monitoring,
 		HostNetwork: hostNetwork,
 		resources:   resources,
 		ownerRef:    ownerRef,
@@ -114,49 +135,131 @@ func (c *Cluster) Start() error {
 		}
 	}
 
+	// remove the mgrs that are no longer desired after the count was reduced
+	for i := c.Replicas; i < len(mgrNames); i++ {
+		resourceName := fmt.Sprintf("%s-%s", appName, mgrNames[i])
+		if err := k8sutil.DeleteDeployment(c.context.Clientset, c.Namespace, resourceName); err != nil {
+			return fmt.Errorf("failed to remove %s deployment. %+v", resourceName, err)
+		}
+	}
+
 	if err := c.enablePrometheusModule(c.Namespace); err != nil {
 		return fmt.Errorf("failed to enable mgr prometheus module. %+v", err)
 	}
 
 	// create the metrics service
-	service := c.makeMetricsService(appName)
-	if _, err := c.context.Clientset.CoreV1().Services(c.Namespace).Create(service); err != nil {
-		if !errors.IsAlreadyExists(err) {
-			return fmt.Errorf("failed to create mgr service. %+v", err)
-		}
-		logger.Infof("mgr metrics service already exists")
-	} else {
-		logger.Infof("mgr metrics service started")
+	service, err := c.createOrUpdateMetricsService(c.makeMetricsService(appName))
+	if err != nil {
+		return err
+	}
+
+	// the prometheus operator is optional, so failing to configure the monitoring does not fail the mgr
+	if c.monitoringClient == nil {
+		c.monitoringClient = newMonitoringClient(c.context.Clientset.CoreV1().RESTClient())
+	}
+	if err := c.configureMonitoring(service); err != nil {
+		logger.Warningf("failed to configure mgr monitoring. %+v", err)
+	}
+
+	if err := c.configureModules(); err != nil {
+		return fmt.Errorf("failed to configure mgr modules. %+v", err)
 	}
 
 	return c.configureDashboard()
 }
 
-func (c *Cluster) configureDashboard() error {
-	// enable or disable the dashboard module
-	if err := c.configureDashboardModule(c.Namespace, c.dashboard.Enabled); err != nil {
-		return fmt.Errorf("failed to enable mgr dashboard module. %+v", err)
+// configureModules enables the modules in the mgr spec and applies their settings, and disables the
+// modules that are not enabled in the spec or that were enabled by an earlier spec and removed from it
+func (c *Cluster) configureModules() error {
+	previous, err := c.loadEnabledModules()
+	if err != nil {
+		return err
 	}
 
-	dashboardService := c.makeDashboardService(appName)
-	if c.dashboard.Enabled {
-		// expose the dashboard service
-		if _, err := c.context.Clientset.CoreV1().Services(c.Namespace).Create(dashboardService); err != nil {
-			if !errors.IsAlreadyExists(err) {
-				return fmt.Errorf("failed to create dashboard mgr service. %+v", err)
+	enabled := map[string]bool{}
+	inSpec := map[string]bool{}
+	for _, module := range c.modules {
+		inSpec[module.Name] = true
+		if module.Name == prometheusModuleName || module.Name == dashboardModuleName {
+			logger.Warningf("mgr module %s is managed by the operator and is ignored in the mgr modules", module.Name)
+			continue
+		}
+
+		if !module.Enabled {
+			if err := client.MgrDisableModule(c.context, c.Namespace, module.Name); err != nil {
+				return fmt.Errorf("failed to disable mgr module %s. %+v", module.Name, err)
 			}
-			logger.Infof("dashboard service already exists")
-		} else {
-			logger.Infof("dashboard service started")
+			logger.Infof("mgr module %s disabled", module.Name)
+			continue
 		}
-	} else {
-		// delete the dashboard service if it exists
-		err := c.context.Clientset.CoreV1().Services(c.Namespace).Delete(dashboardService.Name, &metav1.DeleteOptions{})
-		if err != nil && !errors.IsNotFound(err) {
-			return fmt.Errorf("failed to delete dashboard service. %+v", err)
+
+		// apply the settings before enabling the module so that it starts with its configuration
+		for key, value := range module.Settings {
+			if err := client.MgrSetModuleConfig(c.context, c.Namespace, module.Name, key, value); err != nil {
+				return fmt.Errorf("failed to configure mgr module %s. %+v", module.Name, err)
+			}
+		}
+		if err := client.MgrEnableModule(c.context, c.Namespace, module.Name, false); err != nil {
+			return fmt.Errorf("failed to enable mgr module %s. %+v", module.Name, err)
+		}
+		enabled[module.Name] = true
+		logger.Infof("mgr module %s enabled", module.Name)
+	}
+
+	for _, name := range previous {
+		if inSpec[name] {
+			continue
+		}
+		if err := client.MgrDisableModule(c.context, c.Namespace, name); err != nil {
+			return fmt.Errorf("failed to disable removed mgr module %s. %+v", name, err)
 		}
+		logger.Infof("mgr module %s removed from the spec and disabled", name)
+	}
+
+	return c.saveEnabledModules(enabled)
+}
+
+// loadEnabledModules returns the modules that were enabled from the mgr spec by the last configuration
+func (c *Cluster) loadEnabledModules() ([]string, error) {
+	cm, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Get(modulesConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("failed to get the enabled mgr modules. %+v", err)
+	}
+	if cm.Data[enabledModulesKey] == "" {
+		return []string{}, nil
+	}
+	return strings.Split(cm.Data[enabledModulesKey], ","), nil
+}
+
+// saveEnabledModules records the modules enabled from the mgr spec
+func (c *Cluster) saveEnabledModules(enabled map[string]bool) error {
+	names := []string{}
+	for name := range enabled {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      modulesConfigMapName,
+			Namespace: c.Namespace,
+		},
+		Data: map[string]string{enabledModulesKey: strings.Join(names, ",")},
 	}
+	k8sutil.SetOwnerRef(c.context.Clientset, c.Namespace, &cm.ObjectMeta, &c.ownerRef)
 
+	configMaps := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace)
+	if _, err := configMaps.Create(cm); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to save the enabled mgr modules. %+v", err)
+		}
+		if _, err := configMaps.Update(cm); err != nil {
+			return fmt.Errorf("failed to update the enabled mgr modules. %+v", err)
+		}
+	}
 	return nil
 }
 
@@ -185,28 +288,50 @@ func (c *Cluster) makeMetricsService(name string) *v1.Service {
 	return svc
 }
 
-func (c *Cluster) makeDashboardService(name string) *v1.Service {
-	labels := c.getLabels()
-	svc := &v1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf("%s-dashboard", name),
-			Namespace: c.Namespace,
-			Labels:    labels,
-		},
-		Spec: v1.ServiceSpec{
-			Selector: labels,
-			Type:     v1.ServiceTypeClusterIP,
-			Ports: []v1.ServicePort{
-				{
-					Name:     "http-dashboard",
-					Port:     int32(dashboardPort),
-					Protocol: v1.ProtocolTCP,
-				},
-			},
-		},
+// createOrUpdateMetricsService creates the metrics service, or updates the labels, selector and ports of an
+// existing service. The service returned by the API is returned so the monitoring follows the actual service.
+func (c *Cluster) createOrUpdateMetricsService(service *v1.Service) (*v1.Service, error) {
+	created, err := c.context.Clientset.CoreV1().Services(c.Namespace).Create(service)
+	if err == nil {
+		logger.Infof("mgr metrics service started")
+		return created, nil
 	}
-	k8sutil.SetOwnerRef(c.context.Clientset, c.Namespace, &svc.ObjectMeta, &c.ownerRef)
-	return svc
+	if !errors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("failed to create mgr service. %+v", err)
+	}
+
+	existing, err := c.context.Clientset.CoreV1().Services(c.Namespace).Get(service.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mgr service. %+v", err)
+	}
+	if reflect.DeepEqual(existing.Labels, service.Labels) && reflect.DeepEqual(existing.Spec.Selector, service.Spec.Selector) &&
+		servicePortsEqual(existing.Spec.Ports, service.Spec.Ports) {
+		logger.Infof("mgr metrics service already exists")
+		return existing, nil
+	}
+
+	existing.Labels = service.Labels
+	existing.Spec.Selector = service.Spec.Selector
+	existing.Spec.Ports = service.Spec.Ports
+	updated, err := c.context.Clientset.CoreV1().Services(c.Namespace).Update(existing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update mgr service. %+v", err)
+	}
+	logger.Infof("mgr metrics service updated")
+	return updated, nil
+}
+
+// servicePortsEqual compares the fields of the ports set by the operator, ignoring the fields defaulted by the API
+func servicePortsEqual(a, b []v1.ServicePort) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Port != b[i].Port || a[i].Protocol != b[i].Protocol {
+			return false
+		}
+	}
+	return true
 }
 
 func (c *Cluster) createKeyring(clusterName, name, daemonName string) error {
@@ -255,20 +380,6 @@ func (c *Cluster) enablePrometheusModule(clusterName string) error {
 	return nil
 }
 
-// Ceph docs about the dashboard module: http://docs.ceph.com/docs/luminous/mgr/dashboard/
-func (c *Cluster) configureDashboardModule(clusterName string, enable bool) error {
-	if enable {
-		if err := client.MgrEnableModule(c.context, clusterName, dashboardModuleName, true); err != nil {
-			return fmt.Errorf("failed to enable mgr dashboard module. %+v", err)
-		}
-	} else {
-		if err := client.MgrDisableModule(c.context, clusterName, dashboardModuleName); err != nil {
-			return fmt.Errorf("failed to disable mgr dashboard module. %+v", err)
-		}
-	}
-	return nil
-}
-
 func getKeyringProperties(name string) (string, []string) {
 	username := fmt.Sprintf("mgr.%s", name)
 	access := []string{"mon", "allow *"}
@@ -45,7 +45,8 @@ func TestStartMGR(t *testing.T) {
 		Executor:  executor,
 		ConfigDir: configDir,
 		Clientset: testop.New(3)}
-	c := New(context, "ns", "myversion", rookalpha.Placement{}, false, cephv1beta1.DashboardSpec{Enabled: true}, v1.ResourceRequirements{}, metav1.OwnerReference{})
+	c := New(context, "ns", "myversion", rookalpha.Placement{}, false, cephv1beta1.MgrSpec{}, cephv1beta1.DashboardSpec{Enabled: true}, cephv1beta1.MonitoringSpec{}, v1.ResourceRequirements{}, metav1.OwnerReference{})
+	c.monitoringClient = newFakeMonitoringClient()
 	defer os.RemoveAll(c.dataDir)
 
 	// start a basic service
@@ -61,6 +62,126 @@ func TestStartMGR(t *testing.T) {
 	validateStart(t, c)
 }
 
+func TestMgrCount(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			return "{\"key\":\"mysecurekey\"}", nil
+		},
+	}
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	context := &clusterd.Context{
+		Executor:  executor,
+		ConfigDir: configDir,
+		Clientset: testop.New(3)}
+
+	// the count is limited to the supported number of mgrs
+	c := New(context, "ns", "myversion", rookalpha.Placement{}, false, cephv1beta1.MgrSpec{Count: 5}, cephv1beta1.DashboardSpec{}, cephv1beta1.MonitoringSpec{}, v1.ResourceRequirements{}, metav1.OwnerReference{})
+	assert.Equal(t, len(mgrNames), c.Replicas)
+	c.monitoringClient = newFakeMonitoringClient()
+	err := c.Start()
+	assert.Nil(t, err)
+	validateStart(t, c)
+
+	// the standby mgr is removed when the count is reduced
+	c = New(context, "ns", "myversion", rookalpha.Placement{}, false, cephv1beta1.MgrSpec{Count: 1}, cephv1beta1.DashboardSpec{}, cephv1beta1.MonitoringSpec{}, v1.ResourceRequirements{}, metav1.OwnerReference{})
+	c.monitoringClient = newFakeMonitoringClient()
+	err = c.Start()
+	assert.Nil(t, err)
+	validateStart(t, c)
+	_, err = context.Clientset.ExtensionsV1beta1().Deployments(c.Namespace).Get("rook-ceph-mgr-b", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestConfigureModules(t *testing.T) {
+	commands := [][]string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			commands = append(commands, args)
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor, Clientset: testop.New(1)}
+	mgrSpec := cephv1beta1.MgrSpec{
+		Modules: []cephv1beta1.MgrModuleSpec{
+			{Name: "balancer", Enabled: true, Settings: map[string]string{"mode": "crush-compat"}},
+			{Name: "telemetry", Enabled: false},
+			{Name: "prometheus", Enabled: false},
+		},
+	}
+	c := New(context, "ns", "myversion", rookalpha.Placement{}, false, mgrSpec, cephv1beta1.DashboardSpec{}, cephv1beta1.MonitoringSpec{}, v1.ResourceRequirements{}, metav1.OwnerReference{})
+
+	err := c.configureModules()
+	assert.Nil(t, err)
+
+	// the settings are applied before the module is enabled, and the prometheus module is left to the operator
+	assert.Equal(t, 3, len(commands))
+	assert.Equal(t, []string{"config-key", "set", "mgr/balancer/mode", "crush-compat"}, commands[0][:4])
+	assert.Equal(t, []string{"mgr", "module", "enable", "balancer"}, commands[1][:4])
+	assert.Equal(t, []string{"mgr", "module", "disable", "telemetry"}, commands[2][:4])
+
+	// a module removed from the spec is disabled if it was enabled by the operator
+	commands = [][]string{}
+	mgrSpec.Modules = []cephv1beta1.MgrModuleSpec{{Name: "pg_autoscaler", Enabled: true}}
+	c = New(context, "ns", "myversion", rookalpha.Placement{}, false, mgrSpec, cephv1beta1.DashboardSpec{}, cephv1beta1.MonitoringSpec{}, v1.ResourceRequirements{}, metav1.OwnerReference{})
+	err = c.configureModules()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(commands))
+	assert.Equal(t, []string{"mgr", "module", "enable", "pg_autoscaler"}, commands[0][:4])
+	assert.Equal(t, []string{"mgr", "module", "disable", "balancer"}, commands[1][:4])
+
+	// the modules are only disabled once
+	commands = [][]string{}
+	mgrSpec.Modules = nil
+	c = New(context, "ns", "myversion", rookalpha.Placement{}, false, mgrSpec, cephv1beta1.DashboardSpec{}, cephv1beta1.MonitoringSpec{}, v1.ResourceRequirements{}, metav1.OwnerReference{})
+	assert.Nil(t, c.configureModules())
+	assert.Equal(t, [][]string{{"mgr", "module", "disable", "pg_autoscaler"}}, [][]string{commands[0][:4]})
+	commands = [][]string{}
+	assert.Nil(t, c.configureModules())
+	assert.Equal(t, 0, len(commands))
+}
+
+func TestCreateOrUpdateMetricsService(t *testing.T) {
+	clientset := testop.New(1)
+	c := New(&clusterd.Context{Clientset: clientset}, "ns", "myversion", rookalpha.Placement{}, false, cephv1beta1.MgrSpec{},
+		cephv1beta1.DashboardSpec{}, cephv1beta1.MonitoringSpec{}, v1.ResourceRequirements{}, metav1.OwnerReference{})
+
+	// an outdated service from a previous version of the operator
+	outdated := &v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: appName, Namespace: c.Namespace, Labels: map[string]string{"app": appName}},
+		Spec: v1.ServiceSpec{
+			ClusterIP: "10.0.0.10",
+			Selector:  map[string]string{"app": appName},
+			Ports:     []v1.ServicePort{{Name: "metrics", Port: 9999, Protocol: v1.ProtocolTCP}},
+		},
+	}
+	_, err := clientset.CoreV1().Services(c.Namespace).Create(outdated)
+	assert.Nil(t, err)
+
+	// the existing service is updated and returned
+	desired := c.makeMetricsService(appName)
+	service, err := c.createOrUpdateMetricsService(desired)
+	assert.Nil(t, err)
+	assert.Equal(t, desired.Labels, service.Labels)
+	assert.Equal(t, desired.Spec.Selector, service.Spec.Selector)
+	assert.Equal(t, "http-metrics", service.Spec.Ports[0].Name)
+	assert.Equal(t, int32(metricsPort), service.Spec.Ports[0].Port)
+	assert.Equal(t, "10.0.0.10", service.Spec.ClusterIP)
+
+	existing, err := clientset.CoreV1().Services(c.Namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, desired.Labels, existing.Labels)
+	assert.Equal(t, int32(metricsPort), existing.Spec.Ports[0].Port)
+
+	// an up to date service is not updated again
+	clientset.ClearActions()
+	_, err = c.createOrUpdateMetricsService(c.makeMetricsService(appName))
+	assert.Nil(t, err)
+	for _, action := range clientset.Actions() {
+		assert.NotEqual(t, "update", action.GetVerb())
+	}
+}
+
 func validateStart(t *testing.T, c *Cluster) {
 
 	for i := 0; i < c.Replicas; i++ {
@@ -0,0 +1,275 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mgr
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	monitoringAPIVersion  = "monitoring.coreos.com/v1"
+	serviceMonitorKind    = "ServiceMonitor"
+	serviceMonitorPlural  = "servicemonitors"
+	prometheusRuleKind    = "PrometheusRule"
+	prometheusRulePlural  = "prometheusrules"
+	prometheusRuleGroup   = "ceph.rules"
+	metricsPath           = "/metrics"
+	alertSeverityLabel    = "severity"
+	alertSeverityCritical = "critical"
+	alertSeverityWarning  = "warning"
+)
+
+// The resources of the Prometheus Operator. Only the fields set by rook are declared since there is no
+// typed client for them.
+type serviceMonitor struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              serviceMonitorSpec `json:"spec"`
+}
+
+type serviceMonitorSpec struct {
+	Selector          metav1.LabelSelector     `json:"selector"`
+	NamespaceSelector namespaceSelector        `json:"namespaceSelector"`
+	Endpoints         []serviceMonitorEndpoint `json:"endpoints"`
+}
+
+type namespaceSelector struct {
+	MatchNames []string `json:"matchNames"`
+}
+
+type serviceMonitorEndpoint struct {
+	Port     string `json:"port"`
+	Path     string `json:"path"`
+	Interval string `json:"interval,omitempty"`
+}
+
+type prometheusRule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              prometheusRuleSpec `json:"spec"`
+}
+
+type prometheusRuleSpec struct {
+	Groups []ruleGroup `json:"groups"`
+}
+
+type ruleGroup struct {
+	Name  string `json:"name"`
+	Rules []rule `json:"rules"`
+}
+
+type rule struct {
+	Alert       string            `json:"alert"`
+	Expr        string            `json:"expr"`
+	For         string            `json:"for,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// monitoringClient gets and changes the resources of the Prometheus Operator
+type monitoringClient interface {
+	Get(namespace, plural, name string) ([]byte, error)
+	Create(namespace, plural string, body []byte) error
+	Update(namespace, plural, name string, body []byte) error
+	Delete(namespace, plural, name string) error
+}
+
+// restMonitoringClient sends the requests for the Prometheus Operator resources with a REST client
+type restMonitoringClient struct {
+	client rest.Interface
+}
+
+func newMonitoringClient(client rest.Interface) monitoringClient {
+	return &restMonitoringClient{client: client}
+}
+
+func monitoringPath(namespace, plural string) string {
+	return fmt.Sprintf("/apis/%s/namespaces/%s/%s", monitoringAPIVersion, namespace, plural)
+}
+
+func (r *restMonitoringClient) Get(namespace, plural, name string) ([]byte, error) {
+	return r.client.Get().AbsPath(monitoringPath(namespace, plural), name).Do().Raw()
+}
+
+func (r *restMonitoringClient) Create(namespace, plural string, body []byte) error {
+	return r.client.Post().AbsPath(monitoringPath(namespace, plural)).Body(body).Do().Error()
+}
+
+func (r *restMonitoringClient) Update(namespace, plural, name string, body []byte) error {
+	return r.client.Put().AbsPath(monitoringPath(namespace, plural), name).Body(body).Do().Error()
+}
+
+func (r *restMonitoringClient) Delete(namespace, plural, name string) error {
+	return r.client.Delete().AbsPath(monitoringPath(namespace, plural), name).Do().Error()
+}
+
+// configureMonitoring creates or updates the ServiceMonitor of the mgr metrics service and the PrometheusRule
+// with the Ceph alerts, or removes them if monitoring is not enabled
+func (c *Cluster) configureMonitoring(service *v1.Service) error {
+	if !c.monitoring.Enabled {
+		for _, plural := range []string{serviceMonitorPlural, prometheusRulePlural} {
+			if err := c.monitoringClient.Delete(c.Namespace, plural, appName); err != nil && !errors.IsNotFound(err) {
+				return fmt.Errorf("failed to delete %s %s. %+v", plural, appName, err)
+			}
+		}
+		return nil
+	}
+
+	if err := c.applyMonitoringResource(serviceMonitorPlural, c.makeServiceMonitor(service)); err != nil {
+		return err
+	}
+	if err := c.applyMonitoringResource(prometheusRulePlural, c.makePrometheusRule()); err != nil {
+		return err
+	}
+	logger.Infof("mgr monitoring configured with servicemonitor and prometheusrule %s", appName)
+	return nil
+}
+
+// applyMonitoringResource creates the resource, or updates it if it already exists
+func (c *Cluster) applyMonitoringResource(plural string, obj metav1.Object) error {
+	existing, err := c.monitoringClient.Get(c.Namespace, plural, obj.GetName())
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get %s %s. %+v", plural, obj.GetName(), err)
+		}
+		body, err := json.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s %s. %+v", plural, obj.GetName(), err)
+		}
+		if err := c.monitoringClient.Create(c.Namespace, plural, body); err != nil {
+			return fmt.Errorf("failed to create %s %s. %+v", plural, obj.GetName(), err)
+		}
+		return nil
+	}
+
+	// the update must be based on the current version of the resource
+	var meta struct {
+		metav1.ObjectMeta `json:"metadata"`
+	}
+	if err := json.Unmarshal(existing, &meta); err != nil {
+		return fmt.Errorf("failed to unmarshal %s %s. %+v", plural, obj.GetName(), err)
+	}
+	obj.SetResourceVersion(meta.ResourceVersion)
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s %s. %+v", plural, obj.GetName(), err)
+	}
+	if err := c.monitoringClient.Update(c.Namespace, plural, obj.GetName(), body); err != nil {
+		return fmt.Errorf("failed to update %s %s. %+v", plural, obj.GetName(), err)
+	}
+	return nil
+}
+
+func (c *Cluster) makeMonitoringMeta() metav1.ObjectMeta {
+	labels := c.getLabels()
+	for key, value := range c.monitoring.Labels {
+		labels[key] = value
+	}
+	meta := metav1.ObjectMeta{
+		Name:      appName,
+		Namespace: c.Namespace,
+		Labels:    labels,
+	}
+	k8sutil.SetOwnerRef(c.context.Clientset, c.Namespace, &meta, &c.ownerRef)
+	return meta
+}
+
+// makeServiceMonitor selects the mgr metrics service by its labels and port, so it follows the changes of the service
+func (c *Cluster) makeServiceMonitor(service *v1.Service) *serviceMonitor {
+	endpoints := []serviceMonitorEndpoint{}
+	for _, port := range service.Spec.Ports {
+		endpoints = append(endpoints, serviceMonitorEndpoint{Port: port.Name, Path: metricsPath, Interval: c.monitoring.Interval})
+	}
+
+	return &serviceMonitor{
+		TypeMeta:   metav1.TypeMeta{APIVersion: monitoringAPIVersion, Kind: serviceMonitorKind},
+		ObjectMeta: c.makeMonitoringMeta(),
+		Spec: serviceMonitorSpec{
+			Selector:          metav1.LabelSelector{MatchLabels: service.Labels},
+			NamespaceSelector: namespaceSelector{MatchNames: []string{service.Namespace}},
+			Endpoints:         endpoints,
+		},
+	}
+}
+
+// makePrometheusRule creates the curated alerts of the cluster. The metrics are restricted to the namespace
+// of the cluster since a Prometheus instance may scrape several clusters.
+func (c *Cluster) makePrometheusRule() *prometheusRule {
+	ns := fmt.Sprintf(`namespace="%s"`, c.Namespace)
+	critical := map[string]string{alertSeverityLabel: alertSeverityCritical}
+	warning := map[string]string{alertSeverityLabel: alertSeverityWarning}
+
+	rules := []rule{
+		{
+			Alert:       "CephHealthError",
+			Expr:        fmt.Sprintf(`ceph_health_status{%s} == 2`, ns),
+			For:         "5m",
+			Labels:      critical,
+			Annotations: map[string]string{"description": fmt.Sprintf("Ceph cluster %s is in the HEALTH_ERR state.", c.Namespace)},
+		},
+		{
+			Alert:       "CephOSDDown",
+			Expr:        fmt.Sprintf(`ceph_osd_up{%s} == 0`, ns),
+			For:         "5m",
+			Labels:      warning,
+			Annotations: map[string]string{"description": fmt.Sprintf("{{ $labels.ceph_daemon }} of Ceph cluster %s is down.", c.Namespace)},
+		},
+		{
+			Alert:       "CephOSDNearFull",
+			Expr:        fmt.Sprintf(`ceph_osd_stat_bytes_used{%s} / ceph_osd_stat_bytes{%s} > 0.85`, ns, ns),
+			For:         "5m",
+			Labels:      warning,
+			Annotations: map[string]string{"description": fmt.Sprintf("{{ $labels.ceph_daemon }} of Ceph cluster %s is more than 85%% full.", c.Namespace)},
+		},
+		{
+			Alert:       "CephPGsInactive",
+			Expr:        fmt.Sprintf(`sum(ceph_pg_total{%s}) - sum(ceph_pg_active{%s}) > 0`, ns, ns),
+			For:         "15m",
+			Labels:      critical,
+			Annotations: map[string]string{"description": fmt.Sprintf("Placement groups of Ceph cluster %s have been inactive for 15 minutes.", c.Namespace)},
+		},
+		{
+			Alert:       "CephPGsUnclean",
+			Expr:        fmt.Sprintf(`sum(ceph_pg_total{%s}) - sum(ceph_pg_clean{%s}) > 0`, ns, ns),
+			For:         "1h",
+			Labels:      warning,
+			Annotations: map[string]string{"description": fmt.Sprintf("Placement groups of Ceph cluster %s have been unclean for an hour.", c.Namespace)},
+		},
+		{
+			// losing one more mon would lose the quorum
+			Alert: "CephMonQuorumAtRisk",
+			Expr: fmt.Sprintf(`count(ceph_mon_quorum_status{%s} == 1) <= (floor(count(ceph_mon_metadata{%s}) / 2) + 1) and count(ceph_mon_metadata{%s}) > 1`,
+				ns, ns, ns),
+			For:         "5m",
+			Labels:      critical,
+			Annotations: map[string]string{"description": fmt.Sprintf("The mon quorum of Ceph cluster %s is at risk. Losing another mon would lose the quorum.", c.Namespace)},
+		},
+	}
+
+	return &prometheusRule{
+		TypeMeta:   metav1.TypeMeta{APIVersion: monitoringAPIVersion, Kind: prometheusRuleKind},
+		ObjectMeta: c.makeMonitoringMeta(),
+		Spec:       prometheusRuleSpec{Groups: []ruleGroup{{Name: prometheusRuleGroup, Rules: rules}}},
+	}
+}
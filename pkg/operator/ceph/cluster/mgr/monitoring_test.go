@@ -0,0 +1,122 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package mgr
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	cephv1beta1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1beta1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/clusterd"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// fakeMonitoringClient keeps the resources in memory by plural and name
+type fakeMonitoringClient struct {
+	resources map[string][]byte
+	updates   int
+}
+
+func newFakeMonitoringClient() *fakeMonitoringClient {
+	return &fakeMonitoringClient{resources: map[string][]byte{}}
+}
+
+func (f *fakeMonitoringClient) Get(namespace, plural, name string) ([]byte, error) {
+	body, ok := f.resources[plural+"/"+name]
+	if !ok {
+		return nil, errors.NewNotFound(schema.GroupResource{Group: "monitoring.coreos.com", Resource: plural}, name)
+	}
+	return body, nil
+}
+
+func (f *fakeMonitoringClient) Create(namespace, plural string, body []byte) error {
+	var meta struct {
+		metav1.ObjectMeta `json:"metadata"`
+	}
+	if err := json.Unmarshal(body, &meta); err != nil {
+		return err
+	}
+	f.resources[plural+"/"+meta.Name] = body
+	return nil
+}
+
+func (f *fakeMonitoringClient) Update(namespace, plural, name string, body []byte) error {
+	f.updates++
+	f.resources[plural+"/"+name] = body
+	return nil
+}
+
+func (f *fakeMonitoringClient) Delete(namespace, plural, name string) error {
+	if _, ok := f.resources[plural+"/"+name]; !ok {
+		return errors.NewNotFound(schema.GroupResource{Group: "monitoring.coreos.com", Resource: plural}, name)
+	}
+	delete(f.resources, plural+"/"+name)
+	return nil
+}
+
+func TestConfigureMonitoring(t *testing.T) {
+	monitoring := cephv1beta1.MonitoringSpec{Enabled: true, Labels: map[string]string{"team": "rook"}, Interval: "30s"}
+	c := New(&clusterd.Context{Clientset: testop.New(1)}, "ns", "myversion", rookalpha.Placement{}, false, cephv1beta1.MgrSpec{},
+		cephv1beta1.DashboardSpec{}, monitoring, v1.ResourceRequirements{}, metav1.OwnerReference{})
+	client := newFakeMonitoringClient()
+	c.monitoringClient = client
+	service := c.makeMetricsService(appName)
+
+	err := c.configureMonitoring(service)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(client.resources))
+
+	// the service monitor selects the metrics service and carries the labels of the spec
+	sm := serviceMonitor{}
+	assert.Nil(t, json.Unmarshal(client.resources["servicemonitors/rook-ceph-mgr"], &sm))
+	assert.Equal(t, "ServiceMonitor", sm.Kind)
+	assert.Equal(t, "rook", sm.Labels["team"])
+	assert.Equal(t, service.Labels, sm.Spec.Selector.MatchLabels)
+	assert.Equal(t, []string{"ns"}, sm.Spec.NamespaceSelector.MatchNames)
+	assert.Equal(t, 1, len(sm.Spec.Endpoints))
+	assert.Equal(t, "http-metrics", sm.Spec.Endpoints[0].Port)
+	assert.Equal(t, "30s", sm.Spec.Endpoints[0].Interval)
+
+	// the alerts are restricted to the namespace of the cluster
+	rule := prometheusRule{}
+	assert.Nil(t, json.Unmarshal(client.resources["prometheusrules/rook-ceph-mgr"], &rule))
+	assert.Equal(t, 1, len(rule.Spec.Groups))
+	assert.Equal(t, 6, len(rule.Spec.Groups[0].Rules))
+	for _, r := range rule.Spec.Groups[0].Rules {
+		assert.True(t, strings.Contains(r.Expr, `namespace="ns"`), r.Alert)
+		assert.NotEmpty(t, r.Labels["severity"], r.Alert)
+	}
+
+	// configuring again updates the existing resources
+	err = c.configureMonitoring(service)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, client.updates)
+
+	// the resources are removed when monitoring is disabled, and removing them again is not an error
+	c.monitoring.Enabled = false
+	err = c.configureMonitoring(service)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(client.resources))
+	err = c.configureMonitoring(service)
+	assert.Nil(t, err)
+}
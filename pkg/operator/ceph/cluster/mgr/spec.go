@@ -27,6 +27,7 @@ import (
 	"k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/kubelet/apis"
 )
 
 const (
@@ -58,6 +59,10 @@ func (c *Cluster) makeDeployment(mgrConfig *mgrConfig) *extensions.Deployment {
 		podSpec.Spec.DNSPolicy = v1.DNSClusterFirstWithHostNet
 	}
 	c.placement.ApplyToPodSpec(&podSpec.Spec)
+	if c.Replicas > 1 && c.placement.PodAntiAffinity == nil {
+		// spread the active and standby mgrs across nodes
+		podSpec.Spec.Affinity.PodAntiAffinity = c.makePodAntiAffinity()
+	}
 
 	replicas := int32(1)
 	d := &extensions.Deployment{
@@ -71,6 +76,22 @@ func (c *Cluster) makeDeployment(mgrConfig *mgrConfig) *extensions.Deployment {
 	return d
 }
 
+func (c *Cluster) makePodAntiAffinity() *v1.PodAntiAffinity {
+	return &v1.PodAntiAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []v1.WeightedPodAffinityTerm{
+			{
+				Weight: int32(100),
+				PodAffinityTerm: v1.PodAffinityTerm{
+					LabelSelector: &metav1.LabelSelector{
+						MatchLabels: c.getLabels(),
+					},
+					TopologyKey: apis.LabelHostname,
+				},
+			},
+		},
+	}
+}
+
 func (c *Cluster) makeConfigInitContainer(mgrConfig *mgrConfig) v1.Container {
 	return v1.Container{
 		Name: opspec.ConfigInitContainerName,
@@ -127,7 +148,7 @@ func (c *Cluster) makeMgrDaemonContainer(mgrConfig *mgrConfig) v1.Container {
 			},
 			{
 				Name:          "dashboard",
-				ContainerPort: int32(dashboardPort),
+				ContainerPort: int32(c.dashboardPort()),
 				Protocol:      v1.ProtocolTCP,
 			},
 		},
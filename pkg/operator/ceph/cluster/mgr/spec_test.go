@@ -43,7 +43,9 @@ func TestPodSpec(t *testing.T) {
 		"rook/rook:myversion",
 		rookalpha.Placement{},
 		false,
+		cephv1beta1.MgrSpec{},
 		cephv1beta1.DashboardSpec{},
+		cephv1beta1.MonitoringSpec{},
 		v1.ResourceRequirements{
 			Limits: v1.ResourceList{
 				v1.ResourceCPU: *resource.NewQuantity(100.0, resource.BinarySI),
@@ -145,7 +147,7 @@ func TestPodSpec(t *testing.T) {
 }
 
 func TestServiceSpec(t *testing.T) {
-	c := New(&clusterd.Context{}, "ns", "myversion", rookalpha.Placement{}, false, cephv1beta1.DashboardSpec{}, v1.ResourceRequirements{}, metav1.OwnerReference{})
+	c := New(&clusterd.Context{}, "ns", "myversion", rookalpha.Placement{}, false, cephv1beta1.MgrSpec{}, cephv1beta1.DashboardSpec{}, cephv1beta1.MonitoringSpec{}, v1.ResourceRequirements{}, metav1.OwnerReference{})
 
 	s := c.makeMetricsService("rook-mgr")
 	assert.NotNil(t, s)
@@ -160,7 +162,9 @@ func TestHostNetwork(t *testing.T) {
 		"myversion",
 		rookalpha.Placement{},
 		true,
+		cephv1beta1.MgrSpec{},
 		cephv1beta1.DashboardSpec{},
+		cephv1beta1.MonitoringSpec{},
 		v1.ResourceRequirements{},
 		metav1.OwnerReference{},
 	)
@@ -176,3 +180,34 @@ func TestHostNetwork(t *testing.T) {
 	assert.Equal(t, true, d.Spec.Template.Spec.HostNetwork)
 	assert.Equal(t, v1.DNSClusterFirstWithHostNet, d.Spec.Template.Spec.DNSPolicy)
 }
+
+func TestPodAntiAffinity(t *testing.T) {
+	mgrTestConfig := mgrConfig{
+		DaemonName:   "a",
+		ResourceName: "mgr-a",
+	}
+
+	// a single mgr does not need to be spread
+	c := New(&clusterd.Context{Clientset: testop.New(1)}, "ns", "myversion", rookalpha.Placement{}, false, cephv1beta1.MgrSpec{},
+		cephv1beta1.DashboardSpec{}, cephv1beta1.MonitoringSpec{}, v1.ResourceRequirements{}, metav1.OwnerReference{})
+	d := c.makeDeployment(&mgrTestConfig)
+	assert.Nil(t, d.Spec.Template.Spec.Affinity.PodAntiAffinity)
+
+	// the active and standby mgrs are spread across nodes
+	c = New(&clusterd.Context{Clientset: testop.New(1)}, "ns", "myversion", rookalpha.Placement{}, false, cephv1beta1.MgrSpec{Count: 2},
+		cephv1beta1.DashboardSpec{}, cephv1beta1.MonitoringSpec{}, v1.ResourceRequirements{}, metav1.OwnerReference{})
+	d = c.makeDeployment(&mgrTestConfig)
+	antiAffinity := d.Spec.Template.Spec.Affinity.PodAntiAffinity
+	assert.NotNil(t, antiAffinity)
+	assert.Equal(t, 1, len(antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution))
+	term := antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0].PodAffinityTerm
+	assert.Equal(t, appName, term.LabelSelector.MatchLabels["app"])
+	assert.Equal(t, "kubernetes.io/hostname", term.TopologyKey)
+
+	// the anti-affinity in the placement takes precedence
+	placement := rookalpha.Placement{PodAntiAffinity: &v1.PodAntiAffinity{}}
+	c = New(&clusterd.Context{Clientset: testop.New(1)}, "ns", "myversion", placement, false, cephv1beta1.MgrSpec{Count: 2},
+		cephv1beta1.DashboardSpec{}, cephv1beta1.MonitoringSpec{}, v1.ResourceRequirements{}, metav1.OwnerReference{})
+	d = c.makeDeployment(&mgrTestConfig)
+	assert.Equal(t, placement.PodAntiAffinity, d.Spec.Template.Spec.Affinity.PodAntiAffinity)
+}
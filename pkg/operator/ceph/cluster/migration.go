@@ -27,6 +27,7 @@ import (
 	rookv1alpha2 "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/osd/config"
+	"github.com/rook/rook/pkg/operator/metrics"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/cache"
@@ -38,7 +39,7 @@ func (c *ClusterController) watchLegacyClusters(namespace string, stopCh chan st
 		logger.Infof("skipping watching for legacy rook cluster events (legacy cluster CRD probably doesn't exist): %+v", err)
 	} else {
 		logger.Infof("start watching legacy rook clusters in all namespaces")
-		watcherLegacy := opkit.NewWatcher(ClusterResourceRookLegacy, namespace, resourceHandlerFuncs, c.context.RookClientset.RookV1alpha1().RESTClient())
+		watcherLegacy := opkit.NewWatcher(ClusterResourceRookLegacy, namespace, metrics.InstrumentHandlers(clusterControllerName, resourceHandlerFuncs), c.context.RookClientset.RookV1alpha1().RESTClient())
 		go watcherLegacy.Watch(&rookv1alpha1.Cluster{}, stopCh)
 	}
 }
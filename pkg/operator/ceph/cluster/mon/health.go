@@ -20,19 +20,22 @@ import (
 	"fmt"
 	"time"
 
+	cephv1beta1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1beta1"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/daemon/ceph/client"
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
 	mondaemon "github.com/rook/rook/pkg/daemon/ceph/mon"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
+	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var (
-	// HealthCheckInterval is the interval to check if the mons are in quorum
+	// HealthCheckInterval is the default interval to check if the mons are in quorum
 	HealthCheckInterval = 45 * time.Second
-	// MonOutTimeout is the duration to wait before removing/failover to a new mon pod
+	// MonOutTimeout is the default duration to wait before removing/failover to a new mon pod
 	MonOutTimeout = 300 * time.Second
 )
 
@@ -48,6 +51,11 @@ func NewHealthChecker(monCluster *Cluster) *HealthChecker {
 	}
 }
 
+// UpdateHealthSettings applies the health settings of the mon spec to the mons being checked
+func (hc *HealthChecker) UpdateHealthSettings(mon cephv1beta1.MonSpec) {
+	hc.monCluster.UpdateHealthSettings(mon)
+}
+
 // Check periodically checks the health of the monitors
 func (hc *HealthChecker) Check(stopCh chan struct{}) {
 	for {
@@ -56,26 +64,73 @@ func (hc *HealthChecker) Check(stopCh chan struct{}) {
 			logger.Infof("Stopping monitoring of mons in namespace %s", hc.monCluster.Namespace)
 			return
 
-		case <-time.After(HealthCheckInterval):
+		case <-time.After(hc.monCluster.getHealthCheckInterval()):
 			logger.Debugf("checking health of mons")
 			err := hc.monCluster.checkHealth()
 			if err != nil {
 				logger.Infof("failed to check mon health. %+v", err)
+			} else {
+				metrics.HealthCheckSucceeded(hc.monCluster.Namespace, "mon")
 			}
 		}
 	}
 }
 
+// UpdateHealthSettings applies the health check settings from the mon spec. The settings are read on
+// every health check, so changes take effect without restarting the health checker.
+func (c *Cluster) UpdateHealthSettings(mon cephv1beta1.MonSpec) {
+	interval, err := mon.GetHealthCheckInterval(HealthCheckInterval)
+	if err != nil {
+		logger.Warningf("invalid mon health check interval, using %s. %+v", interval, err)
+	}
+	outTimeout, err := mon.GetOutTimeout(MonOutTimeout)
+	if err != nil {
+		logger.Warningf("invalid mon out timeout, using %s. %+v", outTimeout, err)
+	}
+
+	c.healthLock.Lock()
+	defer c.healthLock.Unlock()
+	if c.healthCheckInterval != interval || c.outTimeout != outTimeout || c.preventFailover != mon.PreventFailover {
+		logger.Infof("mon health settings: check interval %s, out timeout %s, prevent failover %t", interval, outTimeout, mon.PreventFailover)
+	}
+	c.healthCheckInterval = interval
+	c.outTimeout = outTimeout
+	c.preventFailover = mon.PreventFailover
+}
+
+func (c *Cluster) getHealthCheckInterval() time.Duration {
+	c.healthLock.Lock()
+	defer c.healthLock.Unlock()
+	return c.healthCheckInterval
+}
+
+func (c *Cluster) getFailoverSettings() (time.Duration, bool) {
+	c.healthLock.Lock()
+	defer c.healthLock.Unlock()
+	return c.outTimeout, c.preventFailover
+}
+
 func (c *Cluster) checkHealth() error {
-	logger.Debugf("Checking health for mons. %+v", c.clusterInfo)
+	c.orchestrationLock.Lock()
+	clusterName := c.clusterInfo.Name
+	c.orchestrationLock.Unlock()
 
 	// connect to the mons
-	// get the status and check for quorum
-	status, err := client.GetMonStatus(c.context, c.clusterInfo.Name, true)
+	// get the status and check for quorum. the status is retrieved before taking the orchestration lock
+	// since the call blocks while the mons are out of quorum, which is when the orchestration and the
+	// quorum recovery need the lock.
+	status, err := client.GetMonStatus(c.context, clusterName, true)
 	if err != nil {
 		return fmt.Errorf("failed to get mon status. %+v", err)
 	}
+
+	// the mons must not be changed by the orchestration or a quorum recovery while they are checked
+	c.orchestrationLock.Lock()
+	defer c.orchestrationLock.Unlock()
+
+	logger.Debugf("Checking health for mons. %+v", c.clusterInfo)
 	logger.Debugf("Mon status: %+v", status)
+	outTimeout, preventFailover := c.getFailoverSettings()
 
 	// Source of truth of which mons should exist is our *clusterInfo*
 	monsNotFound := map[string]interface{}{}
@@ -125,11 +180,16 @@ func (c *Cluster) checkHealth() error {
 
 			// when the timeout for the mon has been reached, continue to the
 			// normal failover/delete mon pod part of the code
-			if time.Since(c.monTimeoutList[mon.Name]) <= MonOutTimeout {
+			if time.Since(c.monTimeoutList[mon.Name]) <= outTimeout {
 				logger.Warningf("mon %s not found in quorum, still in mon out timeout", mon.Name)
 				continue
 			}
 
+			if preventFailover {
+				logger.Warningf("mon %s NOT found in quorum and timeout exceeded, but mon failover is disabled", mon.Name)
+				continue
+			}
+
 			logger.Warningf("mon %s NOT found in quorum and timeout exceeded, mon will be failed over", mon.Name)
 			c.failMon(len(status.MonMap.Mons), mon.Name)
 			// only deal with one unhealthy mon per health check
@@ -137,6 +197,13 @@ func (c *Cluster) checkHealth() error {
 		}
 	}
 
+	if preventFailover {
+		if len(monsNotFound) > 0 {
+			logger.Warningf("mons %v NOT found in ceph mon map, but mon failover is disabled", monsNotFound)
+		}
+		return nil
+	}
+
 	// after all unhealthy mons have been removed/failovered
 	// handle all mons that haven't been in the Ceph mon map
 	for mon := range monsNotFound {
@@ -220,6 +287,9 @@ func (c *Cluster) failMon(monCount int, name string) {
 		// no need to create a new mon since we have an extra
 		if err := c.removeMon(name); err != nil {
 			logger.Errorf("failed to remove mon %s. %+v", name, err)
+			c.recordEvent(v1.EventTypeWarning, k8sutil.EventReasonMonFailoverFailed, "failed to remove mon %s. %+v", name, err)
+		} else {
+			c.recordEvent(v1.EventTypeNormal, k8sutil.EventReasonMonRemoved, "removed mon %s that was out of quorum", name)
 		}
 	} else {
 		// bring up a new mon to replace the unhealthy mon
@@ -229,8 +299,15 @@ func (c *Cluster) failMon(monCount int, name string) {
 	}
 }
 
-func (c *Cluster) failoverMon(name string) error {
+func (c *Cluster) failoverMon(name string) (err error) {
 	logger.Infof("Failing over monitor %s", name)
+	c.recordEvent(v1.EventTypeWarning, k8sutil.EventReasonMonFailover, "failing over mon %s", name)
+	defer func() {
+		metrics.MonFailover(c.Namespace, err)
+		if err != nil {
+			c.recordEvent(v1.EventTypeWarning, k8sutil.EventReasonMonFailoverFailed, "failed to failover mon %s. %+v", name, err)
+		}
+	}()
 
 	// Start a new monitor
 	m := newMonConfig(c.maxMonID + 1)
@@ -292,6 +369,25 @@ func (c *Cluster) removeMon(daemonName string) error {
 	if err := removeMonitorFromQuorum(c.context, c.clusterInfo.Name, daemonName); err != nil {
 		return fmt.Errorf("failed to remove mon %s from quorum. %+v", daemonName, err)
 	}
+	if err := c.removeMonResources(daemonName); err != nil {
+		return err
+	}
+
+	if err := c.saveMonConfig(); err != nil {
+		return fmt.Errorf("failed to save mon config after failing over mon %s. %+v", daemonName, err)
+	}
+
+	// make sure to rewrite the config so NO new connections are made to the removed mon
+	if err := writeConnectionConfig(c.context, c.clusterInfo); err != nil {
+		return fmt.Errorf("failed to write connection config after failing over mon %s. %+v", daemonName, err)
+	}
+
+	return nil
+}
+
+// removeMonResources removes the mon from the cluster info and the node mapping, and removes the
+// service endpoint of the mon
+func (c *Cluster) removeMonResources(daemonName string) error {
 	delete(c.clusterInfo.Monitors, daemonName)
 	// check if a mapping exists for the mon
 	if _, ok := c.mapping.Node[daemonName]; ok {
@@ -311,6 +407,10 @@ func (c *Cluster) removeMon(daemonName string) error {
 	}
 
 	// Remove the service endpoint
+	resourceName := resourceName(daemonName)
+	var gracePeriod int64
+	propagation := metav1.DeletePropagationForeground
+	options := &metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod, PropagationPolicy: &propagation}
 	if err := c.context.Clientset.CoreV1().Services(c.Namespace).Delete(resourceName, options); err != nil {
 		if errors.IsNotFound(err) {
 			logger.Infof("dead mon service %s was already gone", resourceName)
@@ -319,15 +419,6 @@ func (c *Cluster) removeMon(daemonName string) error {
 		}
 	}
 
-	if err := c.saveMonConfig(); err != nil {
-		return fmt.Errorf("failed to save mon config after failing over mon %s. %+v", daemonName, err)
-	}
-
-	// make sure to rewrite the config so NO new connections are made to the removed mon
-	if err := writeConnectionConfig(c.context, c.clusterInfo); err != nil {
-		return fmt.Errorf("failed to write connection config after failing over mon %s. %+v", daemonName, err)
-	}
-
 	return nil
 }
 
@@ -341,3 +432,8 @@ func removeMonitorFromQuorum(context *clusterd.Context, clusterName, name string
 	logger.Infof("removed monitor %s", name)
 	return nil
 }
+
+// recordEvent records an event about the mons against the cluster
+func (c *Cluster) recordEvent(eventType, reason, messageFmt string, args ...interface{}) {
+	k8sutil.RecordEvent(c.context.Recorder, k8sutil.OwnerObjectReference(c.ownerRef, c.Namespace), eventType, reason, messageFmt, args...)
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	"os"
 
@@ -36,6 +37,7 @@ import (
 	"k8s.io/api/core/v1"
 	"k8s.io/api/extensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/kubernetes/pkg/kubelet/apis"
 )
 
@@ -48,10 +50,12 @@ func TestCheckHealth(t *testing.T) {
 	clientset := test.New(1)
 	configDir, _ := ioutil.TempDir("", "")
 	defer os.RemoveAll(configDir)
+	recorder := record.NewFakeRecorder(10)
 	context := &clusterd.Context{
 		Clientset: clientset,
 		ConfigDir: configDir,
 		Executor:  executor,
+		Recorder:  recorder,
 	}
 	c := New(context, "ns", "", "myversion", cephv1beta1.MonSpec{Count: 3, AllowMultiplePerNode: true},
 		rookalpha.Placement{}, false, v1.ResourceRequirements{}, metav1.OwnerReference{})
@@ -73,6 +77,8 @@ func TestCheckHealth(t *testing.T) {
 
 	err = c.failoverMon("f")
 	assert.Nil(t, err)
+	assert.Equal(t, "Warning MonFailover failing over mon f", <-recorder.Events)
+	assert.Equal(t, 0, len(recorder.Events))
 
 	newMons := []string{
 		"g",
@@ -134,6 +140,69 @@ func TestCheckHealthNotFound(t *testing.T) {
 	}
 }
 
+func TestCheckHealthPreventFailover(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			return clienttest.MonInQuorumResponse(), nil
+		},
+	}
+	clientset := test.New(1)
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	context := &clusterd.Context{
+		Clientset: clientset,
+		ConfigDir: configDir,
+		Executor:  executor,
+	}
+	c := New(context, "ns", "", "myversion", cephv1beta1.MonSpec{Count: 3, AllowMultiplePerNode: true, PreventFailover: true},
+		rookalpha.Placement{}, false, v1.ResourceRequirements{}, metav1.OwnerReference{})
+	c.clusterInfo = test.CreateConfigDir(2)
+	c.waitForStart = false
+
+	c.mapping.Node["a"] = &NodeInfo{
+		Name: "node0",
+	}
+	c.mapping.Port["node0"] = mondaemon.DefaultPort
+	c.maxMonID = 4
+
+	// mon b is not in the mon map, but is not failed over
+	err := c.checkHealth()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(c.clusterInfo.Monitors))
+	_, ok := c.clusterInfo.Monitors["b"]
+	assert.True(t, ok)
+
+	// the mon is failed over after the failover is allowed again
+	c.UpdateHealthSettings(cephv1beta1.MonSpec{Count: 3, AllowMultiplePerNode: true})
+	err = c.checkHealth()
+	assert.Nil(t, err)
+	_, ok = c.clusterInfo.Monitors["b"]
+	assert.False(t, ok)
+	_, ok = c.clusterInfo.Monitors["f"]
+	assert.True(t, ok)
+}
+
+func TestUpdateHealthSettings(t *testing.T) {
+	c := New(&clusterd.Context{}, "ns", "", "myversion", cephv1beta1.MonSpec{Count: 3},
+		rookalpha.Placement{}, false, v1.ResourceRequirements{}, metav1.OwnerReference{})
+
+	// the defaults are used when the spec does not override them
+	assert.Equal(t, HealthCheckInterval, c.getHealthCheckInterval())
+	timeout, preventFailover := c.getFailoverSettings()
+	assert.Equal(t, MonOutTimeout, timeout)
+	assert.False(t, preventFailover)
+
+	c.UpdateHealthSettings(cephv1beta1.MonSpec{HealthCheckInterval: "10s", OutTimeout: "30m", PreventFailover: true})
+	assert.Equal(t, 10*time.Second, c.getHealthCheckInterval())
+	timeout, preventFailover = c.getFailoverSettings()
+	assert.Equal(t, 30*time.Minute, timeout)
+	assert.True(t, preventFailover)
+
+	// invalid settings fall back to the defaults
+	c.UpdateHealthSettings(cephv1beta1.MonSpec{HealthCheckInterval: "often"})
+	assert.Equal(t, HealthCheckInterval, c.getHealthCheckInterval())
+}
+
 func TestCheckHealthTwoMonsOneNode(t *testing.T) {
 	executorNextMons := false
 	executor := &exectest.MockExecutor{
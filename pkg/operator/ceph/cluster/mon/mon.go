@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coreos/pkg/capnslog"
@@ -90,6 +91,12 @@ type Cluster struct {
 	mapping              *Mapping
 	resources            v1.ResourceRequirements
 	ownerRef             metav1.OwnerReference
+	// orchestrationLock serializes the changes to the mons by the orchestration, the health checks and the quorum recovery
+	orchestrationLock   sync.Mutex
+	healthLock          sync.Mutex
+	healthCheckInterval time.Duration
+	outTimeout          time.Duration
+	preventFailover     bool
 }
 
 // monConfig for a single monitor
@@ -120,7 +127,7 @@ type NodeInfo struct {
 // New creates an instance of a mon cluster
 func New(context *clusterd.Context, namespace, dataDirHostPath, version string, mon cephv1beta1.MonSpec, placement rookalpha.Placement, hostNetwork bool,
 	resources v1.ResourceRequirements, ownerRef metav1.OwnerReference) *Cluster {
-	return &Cluster{
+	c := &Cluster{
 		context:              context,
 		placement:            placement,
 		dataDirHostPath:      dataDirHostPath,
@@ -141,11 +148,33 @@ func New(context *clusterd.Context, namespace, dataDirHostPath, version string,
 		resources: resources,
 		ownerRef:  ownerRef,
 	}
+	c.UpdateHealthSettings(mon)
+	return c
+}
+
+// UpdateSettings applies the settings of an updated cluster spec to the mons. The same mons are kept
+// across cluster updates so that the health checks and the quorum recovery keep serializing with the
+// orchestration of the mons. The new settings take effect on the next Start.
+func (c *Cluster) UpdateSettings(dataDirHostPath, version string, mon cephv1beta1.MonSpec, placement rookalpha.Placement, hostNetwork bool,
+	resources v1.ResourceRequirements) {
+	c.orchestrationLock.Lock()
+	defer c.orchestrationLock.Unlock()
+
+	c.dataDirHostPath = dataDirHostPath
+	c.Version = version
+	c.Size = mon.Count
+	c.AllowMultiplePerNode = mon.AllowMultiplePerNode
+	c.placement = placement
+	c.HostNetwork = hostNetwork
+	c.resources = resources
+	c.UpdateHealthSettings(mon)
 }
 
 // Start begins the process of running a cluster of Ceph mons.
 func (c *Cluster) Start() error {
 	logger.Infof("start running mons")
+	c.orchestrationLock.Lock()
+	defer c.orchestrationLock.Unlock()
 
 	if err := c.initClusterInfo(); err != nil {
 		return fmt.Errorf("failed to initialize ceph cluster info. %+v", err)
@@ -109,6 +109,28 @@ func TestStartMonPods(t *testing.T) {
 	validateStart(t, c)
 }
 
+func TestUpdateSettings(t *testing.T) {
+	namespace := "ns"
+	context := newTestStartCluster(namespace)
+	c := newCluster(context, namespace, false, v1.ResourceRequirements{})
+	c.Size = 1
+
+	err := c.Start()
+	assert.Nil(t, err)
+
+	// the same mons pick up the settings of the updated cluster
+	mon := cephv1beta1.MonSpec{Count: 3, AllowMultiplePerNode: true, HealthCheckInterval: "10s"}
+	c.UpdateSettings("/var/lib/rook", "newversion", mon, rookalpha.Placement{}, false, v1.ResourceRequirements{})
+	assert.Equal(t, 3, c.Size)
+	assert.Equal(t, "newversion", c.Version)
+	assert.Equal(t, "/var/lib/rook", c.dataDirHostPath)
+	assert.Equal(t, 10*time.Second, c.getHealthCheckInterval())
+
+	err = c.Start()
+	assert.Nil(t, err)
+	validateStart(t, c)
+}
+
 func TestOperatorRestart(t *testing.T) {
 	namespace := "ns"
 	context := newTestStartCluster(namespace)
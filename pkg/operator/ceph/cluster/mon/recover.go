@@ -0,0 +1,200 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	mondaemon "github.com/rook/rook/pkg/daemon/ceph/mon"
+	opspec "github.com/rook/rook/pkg/operator/ceph/spec"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubernetes/pkg/kubelet/apis"
+)
+
+const (
+	// RecoverQuorumAnnotation is the annotation on the cluster CRD that names the surviving mon from
+	// which the mon quorum should be recovered after the majority of the mons has been lost
+	RecoverQuorumAnnotation = "ceph.rook.io/mon-recover-from"
+
+	recoverAppName = "rook-ceph-mon-recover"
+)
+
+// RecoverQuorum rebuilds the mon quorum from a single surviving mon when the majority of the mons
+// has been lost and quorum can no longer be formed. All other mons are stopped and removed from the
+// monmap of the survivor, the survivor is restarted on its own, and new mons are then started until
+// the desired mon count is reached again. The health checks of the mons wait until the recovery is done.
+func (c *Cluster) RecoverQuorum(survivor string) error {
+	// the health checks must not fail over the lost mons while the quorum is recovered
+	c.orchestrationLock.Lock()
+	defer c.orchestrationLock.Unlock()
+
+	if c.dataDirHostPath == "" {
+		return fmt.Errorf("cannot recover mon quorum without dataDirHostPath, the mon stores are not persisted")
+	}
+	if _, ok := c.clusterInfo.Monitors[survivor]; !ok {
+		return fmt.Errorf("mon %s to recover quorum from is not one of the mons %s", survivor, mondaemon.FlattenMonEndpoints(c.clusterInfo.Monitors))
+	}
+	node, ok := c.mapping.Node[survivor]
+	if !ok {
+		return fmt.Errorf("mon %s to recover quorum from is not assigned to a node", survivor)
+	}
+
+	lost := []string{}
+	for name := range c.clusterInfo.Monitors {
+		if name != survivor {
+			lost = append(lost, name)
+		}
+	}
+	sort.Strings(lost)
+	logger.Warningf("recovering mon quorum from mon %s. removing mons %v", survivor, lost)
+
+	// stop all mons. the lost mons must not rejoin with the old monmap, and the store of the survivor
+	// must not be in use while its monmap is modified
+	for name := range c.clusterInfo.Monitors {
+		if err := k8sutil.DeleteDeployment(c.context.Clientset, c.Namespace, resourceName(name)); err != nil {
+			return fmt.Errorf("failed to stop mon %s. %+v", name, err)
+		}
+	}
+
+	m := &monConfig{ResourceName: resourceName(survivor), DaemonName: survivor, Port: int32(mondaemon.DefaultPort)}
+	if err := c.initMonIPs([]*monConfig{m}); err != nil {
+		return fmt.Errorf("failed to init ip of mon %s. %+v", survivor, err)
+	}
+	if err := c.runRecoverJob(m, node.Hostname, lost); err != nil {
+		return fmt.Errorf("failed to remove lost mons from the monmap of mon %s. %+v", survivor, err)
+	}
+
+	// the survivor is now the only mon in the monmap, forget about the lost mons
+	for _, name := range lost {
+		if err := c.removeMonResources(name); err != nil {
+			return err
+		}
+	}
+	if err := c.saveMonConfig(); err != nil {
+		return fmt.Errorf("failed to save mon config after recovering quorum. %+v", err)
+	}
+
+	// restart the survivor and wait for it to form quorum on its own
+	if err := c.startMon(m, node.Hostname); err != nil {
+		return fmt.Errorf("failed to restart mon %s. %+v", survivor, err)
+	}
+	if err := c.waitForMonsToJoin([]*monConfig{m}); err != nil {
+		return err
+	}
+	logger.Infof("mon quorum recovered with mon %s", survivor)
+
+	// grow the quorum back to the desired mon count
+	return c.startMons()
+}
+
+func (c *Cluster) runRecoverJob(m *monConfig, hostname string, lost []string) error {
+	job := c.makeRecoverJob(m, hostname, lost)
+
+	// remove the job from a previous recovery attempt
+	var gracePeriod int64
+	propagation := metav1.DeletePropagationForeground
+	options := &metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod, PropagationPolicy: &propagation}
+	if err := c.context.Clientset.Batch().Jobs(c.Namespace).Delete(job.Name, options); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to remove previous mon recovery job %s. %+v", job.Name, err)
+	}
+
+	for i := 0; ; i++ {
+		_, err := c.context.Clientset.Batch().Jobs(c.Namespace).Create(job)
+		if err == nil {
+			break
+		}
+		if !errors.IsAlreadyExists(err) || time.Duration(i)*c.monPodRetryInterval > c.monPodTimeout {
+			return fmt.Errorf("failed to create mon recovery job %s. %+v", job.Name, err)
+		}
+		logger.Infof("waiting for previous mon recovery job %s to be removed", job.Name)
+		time.Sleep(c.monPodRetryInterval)
+	}
+	logger.Infof("started mon recovery job %s on node %s", job.Name, hostname)
+
+	for i := 0; time.Duration(i)*c.monPodRetryInterval <= c.monPodTimeout; i++ {
+		j, err := c.context.Clientset.Batch().Jobs(c.Namespace).Get(job.Name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to get mon recovery job %s. %+v", job.Name, err)
+		}
+		if j.Status.Succeeded > 0 {
+			logger.Infof("mon recovery job %s completed", job.Name)
+			return nil
+		}
+		if j.Spec.BackoffLimit != nil && j.Status.Failed > *j.Spec.BackoffLimit {
+			return fmt.Errorf("mon recovery job %s failed. see the job's pod logs for details", job.Name)
+		}
+		logger.Infof("waiting for mon recovery job %s to complete", job.Name)
+		time.Sleep(c.monPodRetryInterval)
+	}
+
+	return fmt.Errorf("timed out waiting for mon recovery job %s", job.Name)
+}
+
+func (c *Cluster) makeRecoverJob(m *monConfig, hostname string, lost []string) *batch.Job {
+	container := c.makeConfigInitContainer(m)
+	container.Name = "mon-recover"
+	container.Args = []string{
+		"ceph",
+		mondaemon.RecoverCommand,
+		fmt.Sprintf("--config-dir=%s", k8sutil.DataDir),
+		fmt.Sprintf("--name=%s", m.DaemonName),
+		fmt.Sprintf("--port=%d", m.Port),
+		fmt.Sprintf("--fsid=%s", c.clusterInfo.FSID),
+		fmt.Sprintf("--remove-mons=%s", strings.Join(lost, ",")),
+	}
+
+	podSpec := v1.PodSpec{
+		Containers:    []v1.Container{container},
+		RestartPolicy: v1.RestartPolicyOnFailure,
+		NodeSelector:  map[string]string{apis.LabelHostname: hostname},
+		Volumes:       opspec.PodVolumes(c.dataDirHostPath),
+		HostNetwork:   c.HostNetwork,
+	}
+	if c.HostNetwork {
+		podSpec.DNSPolicy = v1.DNSClusterFirstWithHostNet
+	}
+	c.placement.ApplyToPodSpec(&podSpec)
+
+	labels := map[string]string{
+		k8sutil.AppAttr: recoverAppName,
+		monClusterAttr:  c.Namespace,
+	}
+	backoffLimit := int32(2)
+	job := &batch.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", recoverAppName, m.DaemonName),
+			Namespace: c.Namespace,
+			Labels:    labels,
+		},
+		Spec: batch.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       podSpec,
+			},
+		},
+	}
+	k8sutil.SetOwnerRef(c.context.Clientset, c.Namespace, &job.ObjectMeta, &c.ownerRef)
+	return job
+}
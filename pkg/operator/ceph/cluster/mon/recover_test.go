@@ -0,0 +1,96 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batch "k8s.io/api/batch/v1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestRecoverQuorum(t *testing.T) {
+	namespace := "ns"
+	context := newTestStartCluster(namespace)
+	c := newCluster(context, namespace, false, v1.ResourceRequirements{})
+	c.dataDirHostPath = "/var/lib/rook"
+
+	err := c.Start()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(c.clusterInfo.Monitors))
+
+	// the survivor must be a known mon
+	err = c.RecoverQuorum("z")
+	assert.NotNil(t, err)
+
+	// report the recovery job as completed as soon as it is created
+	var recoverJob *batch.Job
+	clientset := context.Clientset.(*fake.Clientset)
+	clientset.PrependReactor("create", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		recoverJob = action.(k8stesting.CreateAction).GetObject().(*batch.Job)
+		return false, nil, nil
+	})
+	clientset.PrependReactor("get", "jobs", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &batch.Job{Status: batch.JobStatus{Succeeded: 1}}, nil
+	})
+
+	err = c.RecoverQuorum("b")
+	assert.Nil(t, err)
+
+	// the recovery job runs on the node of the survivor and removes the lost mons
+	assert.NotNil(t, recoverJob)
+	assert.Equal(t, "rook-ceph-mon-recover-b", recoverJob.Name)
+	podSpec := recoverJob.Spec.Template.Spec
+	assert.Equal(t, c.mapping.Node["b"].Hostname, podSpec.NodeSelector["kubernetes.io/hostname"])
+	assert.Equal(t, 1, len(podSpec.Containers))
+	assert.Contains(t, podSpec.Containers[0].Args, "mon-recover")
+	assert.Contains(t, podSpec.Containers[0].Args, "--name=b")
+	assert.Contains(t, podSpec.Containers[0].Args, "--remove-mons=a,c")
+
+	// the lost mons are replaced by new mons
+	assert.Equal(t, 3, len(c.clusterInfo.Monitors))
+	for _, name := range []string{"b", "d", "e"} {
+		_, ok := c.clusterInfo.Monitors[name]
+		assert.True(t, ok, name)
+		_, ok = c.mapping.Node[name]
+		assert.True(t, ok, name)
+	}
+	for _, name := range []string{"a", "c"} {
+		_, err := context.Clientset.CoreV1().Services(namespace).Get(resourceName(name), metav1.GetOptions{})
+		assert.NotNil(t, err)
+	}
+}
+
+func TestRecoverQuorumWithoutDataDir(t *testing.T) {
+	namespace := "ns"
+	context := newTestStartCluster(namespace)
+	c := newCluster(context, namespace, false, v1.ResourceRequirements{})
+
+	err := c.Start()
+	assert.Nil(t, err)
+
+	// the mon stores are lost with the pods when they are not persisted on the host
+	err = c.RecoverQuorum("a")
+	assert.NotNil(t, err)
+	_, err = context.Clientset.Extensions().Deployments(namespace).Get("rook-ceph-mon-a", metav1.GetOptions{})
+	assert.Nil(t, err)
+}
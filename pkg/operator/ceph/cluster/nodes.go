@@ -0,0 +1,127 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"reflect"
+
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+// watchStorageNodes starts the osds on the nodes that join a storage group, either when they are added to the
+// cluster, become ready, have their labels changed to match the node selector of the group, or have their devices
+// discovered.
+func (c *cluster) watchStorageNodes() {
+	source := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return c.context.Clientset.CoreV1().Nodes().List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return c.context.Clientset.CoreV1().Nodes().Watch(options)
+		},
+	}
+	_, controller := cache.NewInformer(source, &v1.Node{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.onNodeAdd,
+		UpdateFunc: c.onNodeUpdate,
+	})
+	go controller.Run(c.stopCh)
+
+	devicesSource := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return c.context.RookClientset.RookV1alpha2().NodeDevices().List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return c.context.RookClientset.RookV1alpha2().NodeDevices().Watch(options)
+		},
+	}
+	_, devicesController := cache.NewInformer(devicesSource, &rookalpha.NodeDevices{}, 0, cache.ResourceEventHandlerFuncs{
+		AddFunc: c.onNodeDevicesAdd,
+	})
+	go devicesController.Run(c.stopCh)
+}
+
+func (c *cluster) onNodeAdd(obj interface{}) {
+	node, ok := obj.(*v1.Node)
+	if !ok {
+		return
+	}
+	c.startStorageGroupNode(node)
+}
+
+func (c *cluster) onNodeUpdate(oldObj, newObj interface{}) {
+	oldNode, ok := oldObj.(*v1.Node)
+	if !ok {
+		return
+	}
+	newNode, ok := newObj.(*v1.Node)
+	if !ok {
+		return
+	}
+	// ignore the frequent status updates, only a change of the labels, taints or schedulability, or the node becoming
+	// ready can add the node
+	if reflect.DeepEqual(oldNode.Labels, newNode.Labels) && reflect.DeepEqual(oldNode.Spec, newNode.Spec) &&
+		(isNodeReady(oldNode) || !isNodeReady(newNode)) {
+		return
+	}
+	c.startStorageGroupNode(newNode)
+}
+
+// onNodeDevicesAdd retries the nodes that joined a storage group before the discover daemon reported their devices
+func (c *cluster) onNodeDevicesAdd(obj interface{}) {
+	devices, ok := obj.(*rookalpha.NodeDevices)
+	if !ok {
+		return
+	}
+	node, err := c.context.Clientset.CoreV1().Nodes().Get(devices.Name, metav1.GetOptions{})
+	if err != nil {
+		logger.Warningf("failed to get node %s with discovered devices. %+v", devices.Name, err)
+		return
+	}
+	c.startStorageGroupNode(node)
+}
+
+func (c *cluster) startStorageGroupNode(node *v1.Node) {
+	c.osdLock.Lock()
+	defer c.osdLock.Unlock()
+
+	if c.osds == nil || !isNodeReady(node) {
+		return
+	}
+
+	started, err := c.osds.StartStorageGroupNode(*node)
+	if err != nil {
+		logger.Errorf("failed to start the osds on node %s. %+v", node.Name, err)
+		return
+	}
+	if started {
+		logger.Infof("started the osds on node %s in namespace %s", node.Name, c.Namespace)
+	}
+}
+
+func isNodeReady(node *v1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -16,10 +16,13 @@ limitations under the License.
 package osd
 
 import (
+	"sync"
 	"time"
 
+	cephv1beta1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1beta1"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/metrics"
 )
 
 const upStatus = 1
@@ -37,11 +40,36 @@ type Monitor struct {
 	// lastStatus keeps track of OSDs status
 	// key - OSD id; value: time of the status change.
 	lastStatus map[int]time.Time
+
+	intervalLock sync.Mutex
+	interval     time.Duration
 }
 
 // newMonitor instantiates OSD monitoring
 func NewMonitor(context *clusterd.Context, clusterName string) *Monitor {
-	return &Monitor{context, clusterName, make(map[int]time.Time)}
+	return &Monitor{context: context, clusterName: clusterName, lastStatus: make(map[int]time.Time), interval: healthCheckInterval}
+}
+
+// UpdateHealthSettings applies the health check settings from the osd spec. The interval is read
+// before every check, so changes take effect without restarting the monitoring.
+func (m *Monitor) UpdateHealthSettings(spec cephv1beta1.OSDSpec) {
+	interval, err := spec.GetHealthCheckInterval(healthCheckInterval)
+	if err != nil {
+		logger.Warningf("invalid osd health check interval, using %s. %+v", interval, err)
+	}
+
+	m.intervalLock.Lock()
+	defer m.intervalLock.Unlock()
+	if m.interval != interval {
+		logger.Infof("osd health check interval: %s", interval)
+	}
+	m.interval = interval
+}
+
+func (m *Monitor) getInterval() time.Duration {
+	m.intervalLock.Lock()
+	defer m.intervalLock.Unlock()
+	return m.interval
 }
 
 // Run runs monitoring logic for osds status at set intervals
@@ -49,11 +77,13 @@ func (m *Monitor) Start(stopCh chan struct{}) {
 
 	for {
 		select {
-		case <-time.After(healthCheckInterval):
+		case <-time.After(m.getInterval()):
 			logger.Debug("Checking osd processes status.")
 			err := m.osdStatus()
 			if err != nil {
 				logger.Warningf("Failed OSD status check: %+v", err)
+			} else {
+				metrics.HealthCheckSucceeded(m.clusterName, "osd")
 			}
 
 		case <-stopCh:
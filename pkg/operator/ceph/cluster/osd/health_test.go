@@ -19,6 +19,7 @@ import (
 	"testing"
 	"time"
 
+	cephv1beta1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1beta1"
 	"github.com/rook/rook/pkg/clusterd"
 	exectest "github.com/rook/rook/pkg/util/exec/test"
 
@@ -83,3 +84,15 @@ func TestMonitorStart(t *testing.T) {
 	go osdMon.Start(stopCh)
 	close(stopCh)
 }
+
+func TestMonitorUpdateHealthSettings(t *testing.T) {
+	osdMon := NewMonitor(&clusterd.Context{}, "cluster")
+	assert.Equal(t, healthCheckInterval, osdMon.getInterval())
+
+	osdMon.UpdateHealthSettings(cephv1beta1.OSDSpec{HealthCheckInterval: "10s"})
+	assert.Equal(t, 10*time.Second, osdMon.getInterval())
+
+	// an invalid interval falls back to the default
+	osdMon.UpdateHealthSettings(cephv1beta1.OSDSpec{HealthCheckInterval: "often"})
+	assert.Equal(t, healthCheckInterval, osdMon.getInterval())
+}
@@ -19,7 +19,6 @@ package osd
 
 import (
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -116,6 +115,8 @@ type OSDInfo struct {
 	IsFileStore    bool   `json:"is-file-store"`
 	IsDirectory    bool   `json:"is-directory"`
 	DevicePartUUID string `json:"device-part-uuid"`
+	// Device is the name of the device that holds the OSD data
+	Device string `json:"device,omitempty"`
 }
 
 type OrchestrationStatus struct {
@@ -128,8 +129,8 @@ type OrchestrationStatus struct {
 func (c *Cluster) Start() error {
 	logger.Infof("start running osds in namespace %s", c.Namespace)
 
-	if c.Storage.UseAllNodes == false && len(c.Storage.Nodes) == 0 {
-		logger.Warningf("useAllNodes is set to false and no nodes are specified, no OSD pods are going to be created")
+	if c.Storage.UseAllNodes == false && len(c.Storage.Nodes) == 0 && len(c.Storage.StorageGroups) == 0 {
+		logger.Warningf("useAllNodes is set to false and no nodes or storage groups are specified, no OSD pods are going to be created")
 	}
 
 	// disable scrubbing during orchestration and ensure it gets enabled again afterwards
@@ -145,10 +146,9 @@ func (c *Cluster) Start() error {
 	if c.Storage.UseAllNodes {
 		// resolve all storage nodes
 		c.Storage.Nodes = nil
-		rookSystemNS := os.Getenv(k8sutil.PodNamespaceEnvVar)
-		allNodeDevices, err := discover.ListDevices(c.context, rookSystemNS, "" /* all nodes */)
+		allNodeDevices, err := discover.ListDevices(c.context, "" /* all nodes */)
 		if err != nil {
-			logger.Warningf("failed to get storage nodes from namespace %s: %v", rookSystemNS, err)
+			logger.Warningf("failed to get storage nodes: %v", err)
 			return err
 		}
 		for nodeName := range allNodeDevices {
@@ -159,6 +159,11 @@ func (c *Cluster) Start() error {
 		}
 		logger.Debugf("storage nodes: %+v", c.Storage.Nodes)
 	}
+	if len(c.Storage.StorageGroups) > 0 {
+		if err := c.addStorageGroupNodes(); err != nil {
+			return err
+		}
+	}
 	validNodes := k8sutil.GetValidNodes(c.Storage.Nodes, c.context.Clientset, c.placement)
 	// no valid node is ready to run an osd
 	if len(validNodes) == 0 {
@@ -199,57 +204,64 @@ func (c *Cluster) startProvisioning(config *provisionConfig) {
 
 	// start with nodes currently in the storage spec
 	for _, node := range c.Storage.Nodes {
-		// fully resolve the storage config and resources for this node
-		n := c.resolveNode(node.Name)
-		if n == nil {
-			logger.Warningf("node %s did not resolve", node.Name)
-			continue
-		}
+		c.provisionNode(config, node.Name)
+	}
+}
 
-		if n.Name == "" {
-			logger.Warningf("skipping node with a blank name! %+v", n)
-			continue
-		}
+// provisionNode starts the job that provisions the osds on the node. Returns whether the job was started.
+func (c *Cluster) provisionNode(config *provisionConfig, nodeName string) bool {
+	// fully resolve the storage config and resources for this node
+	n := c.resolveNode(nodeName)
+	if n == nil {
+		logger.Warningf("node %s did not resolve", nodeName)
+		return false
+	}
 
-		// update the orchestration status of this node to the starting state
-		status := OrchestrationStatus{Status: OrchestrationStatusStarting}
-		if err := c.updateNodeStatus(n.Name, status); err != nil {
-			config.addError("failed to set orchestration starting status for node %s: %+v", n.Name, err)
-			continue
-		}
-		config.devicesToUse[n.Name] = n.Devices
-		availDev, deviceErr := discover.GetAvailableDevices(c.context, n.Name, c.Namespace, n.Devices, n.Selection.DeviceFilter, n.Selection.GetUseAllDevices())
-		if deviceErr != nil {
-			logger.Warningf("failed to get devices for node %s cluster %s: %v", n.Name, c.Namespace, deviceErr)
-		} else {
-			config.devicesToUse[n.Name] = availDev
-			logger.Infof("avail devices for node %s: %+v", n.Name, availDev)
-		}
-		if len(availDev) == 0 && len(c.dataDirHostPath) == 0 {
-			config.addError("empty volumes for node %s", n.Name)
-			continue
-		}
+	if n.Name == "" {
+		logger.Warningf("skipping node with a blank name! %+v", n)
+		return false
+	}
 
-		// create the job that prepares osds on the node
-		storeConfig := osdconfig.ToStoreConfig(n.Config)
-		metadataDevice := osdconfig.MetadataDevice(n.Config)
-		job, err := c.makeJob(n.Name, config.devicesToUse[n.Name], n.Selection, n.Resources, storeConfig, metadataDevice, n.Location)
-		if err != nil {
-			message := fmt.Sprintf("failed to create prepare job node %s: %v", n.Name, err)
-			config.addError(message)
-			status := OrchestrationStatus{Status: OrchestrationStatusCompleted, Message: message}
-			if err := c.updateNodeStatus(n.Name, status); err != nil {
-				config.addError("failed to update node %s status. %+v", n.Name, err)
-				continue
-			}
+	// update the orchestration status of this node to the starting state
+	status := OrchestrationStatus{Status: OrchestrationStatusStarting}
+	if err := c.updateNodeStatus(n.Name, status); err != nil {
+		config.addError("failed to set orchestration starting status for node %s: %+v", n.Name, err)
+		return false
+	}
+	config.devicesToUse[n.Name] = n.Devices
+	availDev, deviceErr := discover.GetAvailableDevices(c.context, n.Name, c.Namespace, &n.Selection)
+	if deviceErr != nil {
+		logger.Warningf("failed to get devices for node %s cluster %s: %v", n.Name, c.Namespace, deviceErr)
+	} else {
+		config.devicesToUse[n.Name] = availDev
+		logger.Infof("avail devices for node %s: %+v", n.Name, availDev)
+	}
+	if len(availDev) == 0 && len(c.dataDirHostPath) == 0 {
+		config.addError("empty volumes for node %s", n.Name)
+		return false
+	}
+
+	// create the job that prepares osds on the node
+	storeConfig := osdconfig.ToStoreConfig(n.Config)
+	metadataDevice := osdconfig.MetadataDevice(n.Config)
+	job, err := c.makeJob(n.Name, config.devicesToUse[n.Name], n.Selection, n.Resources, storeConfig, metadataDevice, n.Location)
+	if err != nil {
+		message := fmt.Sprintf("failed to create prepare job node %s: %v", n.Name, err)
+		config.addError(message)
+		status := OrchestrationStatus{Status: OrchestrationStatusCompleted, Message: message}
+		if err := c.updateNodeStatus(n.Name, status); err != nil {
+			config.addError("failed to update node %s status. %+v", n.Name, err)
 		}
+		return false
+	}
 
-		if !c.updateJob(job, n.Name, config, "provision") {
-			if err = discover.FreeDevices(c.context, n.Name, c.Namespace); err != nil {
-				logger.Warningf("failed to free devices: %s", err)
-			}
+	if !c.updateJob(job, n.Name, config, "provision") {
+		if err = discover.FreeDevices(c.context, n.Name, c.Namespace); err != nil {
+			logger.Warningf("failed to free devices: %s", err)
 		}
+		return false
 	}
+	return true
 }
 
 func (c *Cluster) updateJob(job *batch.Job, nodeName string, config *provisionConfig, action string) bool {
@@ -364,6 +376,11 @@ func (c *Cluster) startOSDDaemonsOnNode(nodeName string, config *provisionConfig
 		}
 
 		logger.Infof("started deployment for osd %d (dir=%t, type=%s)", osd.ID, osd.IsDirectory, storeConfig.StoreType)
+		if osd.Device != "" {
+			if err := discover.SetDeviceOSD(c.context, n.Name, c.Namespace, osd.Device, osd.ID); err != nil {
+				logger.Warningf("failed to record osd %d on device %s. %+v", osd.ID, osd.Device, err)
+			}
+		}
 	}
 }
 
@@ -569,13 +586,117 @@ func getIDFromDeployment(deployment *extensions.Deployment) int {
 	return unknownID
 }
 
+// addStorageGroupNodes adds the nodes with labels matching a storage group to the storage nodes. Nodes
+// that are already storage nodes and are not assigned to a group explicitly are assigned to the matching group.
+func (c *Cluster) addStorageGroupNodes() error {
+	nodes, err := c.context.Clientset.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list nodes for the storage groups. %+v", err)
+	}
+
+	for _, node := range nodes.Items {
+		group, err := c.Storage.MatchStorageGroup(node.Labels)
+		if err != nil {
+			return err
+		}
+		if group == nil {
+			continue
+		}
+
+		found := false
+		for i := range c.Storage.Nodes {
+			if c.Storage.Nodes[i].Name == node.Name {
+				if c.Storage.Nodes[i].StorageGroup == "" {
+					c.Storage.Nodes[i].StorageGroup = group.Name
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			logger.Infof("adding node %s of storage group %s", node.Name, group.Name)
+			c.Storage.Nodes = append(c.Storage.Nodes, rookalpha.Node{Name: node.Name, StorageGroup: group.Name})
+		}
+	}
+	return nil
+}
+
+// IsNewStorageGroupNode returns whether a node matches a storage group, but is not yet a storage node
+func (c *Cluster) IsNewStorageGroupNode(node v1.Node) bool {
+	group, err := c.Storage.MatchStorageGroup(node.Labels)
+	if err != nil {
+		logger.Warningf("failed to match storage groups of node %s. %+v", node.Name, err)
+		return false
+	}
+	if group == nil {
+		return false
+	}
+	for _, n := range c.Storage.Nodes {
+		if n.Name == node.Name {
+			return false
+		}
+	}
+
+	valid, err := k8sutil.ValidNode(node, c.placement)
+	if err != nil {
+		logger.Warningf("failed to validate node %s. %+v", node.Name, err)
+		return false
+	}
+	return valid
+}
+
+// StartStorageGroupNode provisions and starts the osds on a node that joined a storage group. The node is only added
+// to the storage nodes once its devices are discovered and its provisioning started, so that it is retried on the
+// next change of the node or of its devices otherwise. Returns whether the node was added.
+func (c *Cluster) StartStorageGroupNode(node v1.Node) (bool, error) {
+	if !c.IsNewStorageGroupNode(node) {
+		return false, nil
+	}
+	discovered, err := discover.NodeDevicesDiscovered(c.context, node.Name)
+	if err != nil {
+		return false, err
+	}
+	if !discovered {
+		logger.Infof("waiting for the devices of node %s to be discovered before starting its osds", node.Name)
+		return false, nil
+	}
+
+	group, err := c.Storage.MatchStorageGroup(node.Labels)
+	if err != nil || group == nil {
+		return false, fmt.Errorf("node %s does not match a storage group. %+v", node.Name, err)
+	}
+	logger.Infof("node %s joined storage group %s, starting osds in namespace %s", node.Name, group.Name, c.Namespace)
+	c.Storage.Nodes = append(c.Storage.Nodes, rookalpha.Node{Name: node.Name, StorageGroup: group.Name})
+
+	config := newProvisionConfig()
+	config.devicesToUse = map[string][]rookalpha.Device{}
+	if !c.provisionNode(config, node.Name) {
+		// forget the node so that its provisioning is retried
+		c.Storage.Nodes = c.Storage.Nodes[:len(c.Storage.Nodes)-1]
+		return false, fmt.Errorf("failed to start provisioning node %s. %s", node.Name, strings.Join(config.errorMessages, "\n"))
+	}
+
+	// start the osds of the node once it is provisioned
+	c.completeProvision(config)
+	if len(config.errorMessages) > 0 {
+		return true, fmt.Errorf("%d failures encountered while running osds on node %s: %+v",
+			len(config.errorMessages), node.Name, strings.Join(config.errorMessages, "\n"))
+	}
+	return true, nil
+}
+
 func (c *Cluster) resolveNode(nodeName string) *rookalpha.Node {
 	// fully resolve the storage config and resources for this node
 	rookNode := c.Storage.ResolveNode(nodeName)
 	if rookNode == nil {
 		return nil
 	}
-	rookNode.Resources = k8sutil.MergeResourceRequirements(rookNode.Resources, c.resources)
+	resources := c.resources
+	if group := c.Storage.GetStorageGroup(rookNode.StorageGroup); group != nil {
+		// the group resources take precedence over the cluster resources
+		resources = k8sutil.MergeResourceRequirements(*group.Resources.DeepCopy(), c.resources)
+	}
+	rookNode.Resources = k8sutil.MergeResourceRequirements(rookNode.Resources, resources)
 
 	// ensure no invalid dirs are specified
 	var validDirs []rookalpha.Directory
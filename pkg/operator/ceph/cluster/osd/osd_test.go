@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
 	"github.com/rook/rook/pkg/clusterd"
 	discoverDaemon "github.com/rook/rook/pkg/daemon/discover"
 	"github.com/rook/rook/pkg/operator/ceph/cluster/osd/config"
@@ -31,6 +32,7 @@ import (
 	"k8s.io/api/core/v1"
 	corev1 "k8s.io/api/core/v1"
 	extensions "k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
@@ -52,21 +54,25 @@ func TestStart(t *testing.T) {
 	assert.Nil(t, err)
 }
 
-func createDiscoverConfigmap(nodeName, ns string, clientset *fake.Clientset) error {
-	data := make(map[string]string, 1)
-	data[discoverDaemon.LocalDiskCMData] = `[{"name":"sdx","parent":"","hasChildren":false,"devLinks":"/dev/disk/by-id/scsi-36001405f826bd553d8c4dbf9f41c18be    /dev/disk/by-id/wwn-0x6001405f826bd553d8c4dbf9f41c18be /dev/disk/by-path/ip-127.0.0.1:3260-iscsi-iqn.2016-06.world.srv:storage.target01-lun-1","size":10737418240,"uuid":"","serial":"36001405f826bd553d8c4dbf9f41c18be","type":"disk","rotational":true,"readOnly":false,"ownPartition":true,"filesystem":"","vendor":"LIO-ORG","model":"disk02","wwn":"0x6001405f826bd553","wwnVendorExtension":"0x6001405f826bd553d8c4dbf9f41c18be","empty":true}]`
-	cm := &v1.ConfigMap{
+func createNodeDevices(nodeName string, rookClientset *rookfake.Clientset) error {
+	nodeDevices := &rookalpha.NodeDevices{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "local-device-" + nodeName,
-			Namespace: ns,
-			Labels: map[string]string{
-				k8sutil.AppAttr:         discoverDaemon.AppName,
-				discoverDaemon.NodeAttr: nodeName,
+			Name:   nodeName,
+			Labels: map[string]string{k8sutil.AppAttr: discoverDaemon.AppName},
+		},
+		Devices: []rookalpha.NodeDevice{
+			{
+				Name:       "sdx",
+				FullPath:   "/dev/disk/by-id/wwn-0x6001405f826bd553d8c4dbf9f41c18be",
+				DevLinks:   "/dev/disk/by-id/scsi-36001405f826bd553d8c4dbf9f41c18be /dev/disk/by-id/wwn-0x6001405f826bd553d8c4dbf9f41c18be",
+				Size:       10737418240,
+				Type:       "disk",
+				Rotational: true,
+				Serial:     "36001405f826bd553d8c4dbf9f41c18be",
 			},
 		},
-		Data: data,
 	}
-	_, err := clientset.CoreV1().ConfigMaps(ns).Create(cm)
+	_, err := rookClientset.RookV1alpha2().NodeDevices().Create(nodeDevices)
 	return err
 }
 
@@ -138,13 +144,14 @@ func TestAddRemoveNode(t *testing.T) {
 
 	nodeErr := createNode(nodeName, v1.NodeReady, clientset)
 	assert.Nil(t, nodeErr)
-	cmErr := createDiscoverConfigmap(nodeName, "rook-system", clientset)
-	assert.Nil(t, cmErr)
+	rookClientset := rookfake.NewSimpleClientset()
+	devErr := createNodeDevices(nodeName, rookClientset)
+	assert.Nil(t, devErr)
 
 	statusMapWatcher := watch.NewFake()
 	clientset.PrependWatchReactor("configmaps", k8stesting.DefaultWatchReactor(statusMapWatcher, nil))
 
-	c := New(&clusterd.Context{Clientset: clientset, ConfigDir: "/var/lib/rook", Executor: &exectest.MockExecutor{}}, "ns-add-remove", "myversion", "",
+	c := New(&clusterd.Context{Clientset: clientset, RookClientset: rookClientset, ConfigDir: "/var/lib/rook", Executor: &exectest.MockExecutor{}}, "ns-add-remove", "myversion", "",
 		storageSpec, "", rookalpha.Placement{}, false, v1.ResourceRequirements{}, metav1.OwnerReference{})
 
 	// kick off the start of the orchestration in a goroutine
@@ -229,7 +236,7 @@ func TestAddRemoveNode(t *testing.T) {
 
 	// modify the storage spec to remove the node from the cluster
 	storageSpec.Nodes = []rookalpha.Node{}
-	c = New(&clusterd.Context{Clientset: clientset, ConfigDir: "/var/lib/rook", Executor: mockExec}, "ns-add-remove", "myversion", "",
+	c = New(&clusterd.Context{Clientset: clientset, RookClientset: rookClientset, ConfigDir: "/var/lib/rook", Executor: mockExec}, "ns-add-remove", "myversion", "",
 		storageSpec, "", rookalpha.Placement{}, false, v1.ResourceRequirements{}, metav1.OwnerReference{})
 
 	// reset the orchestration status watcher
@@ -333,10 +340,11 @@ func TestAddNodeFailure(t *testing.T) {
 	os.Setenv(k8sutil.PodNamespaceEnvVar, "rook-system")
 	defer os.Unsetenv(k8sutil.PodNamespaceEnvVar)
 
-	cmErr := createDiscoverConfigmap(nodeName, "rook-system", clientset)
-	assert.Nil(t, cmErr)
+	rookClientset := rookfake.NewSimpleClientset()
+	devErr := createNodeDevices(nodeName, rookClientset)
+	assert.Nil(t, devErr)
 
-	c := New(&clusterd.Context{Clientset: clientset, ConfigDir: "/var/lib/rook", Executor: &exectest.MockExecutor{}}, "ns-add-remove", "myversion", "",
+	c := New(&clusterd.Context{Clientset: clientset, RookClientset: rookClientset, ConfigDir: "/var/lib/rook", Executor: &exectest.MockExecutor{}}, "ns-add-remove", "myversion", "",
 		storageSpec, "", rookalpha.Placement{}, false, v1.ResourceRequirements{}, metav1.OwnerReference{})
 
 	// kick off the start of the orchestration in a goroutine
@@ -354,3 +362,65 @@ func TestAddNodeFailure(t *testing.T) {
 	assert.True(t, startCompleted)
 	assert.NotNil(t, startErr)
 }
+
+func TestStorageGroupNodes(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	for i, label := range []string{"ssd", "hdd", ""} {
+		node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("node%d", i), Labels: map[string]string{"storage": label}}}
+		_, err := clientset.CoreV1().Nodes().Create(node)
+		assert.Nil(t, err)
+	}
+
+	storageSpec := rookalpha.StorageScopeSpec{
+		Nodes: []rookalpha.Node{{Name: "node1", StorageGroup: "ssd"}},
+		StorageGroups: []rookalpha.StorageGroup{
+			{
+				Name:         "ssd",
+				NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"storage": "ssd"}},
+				Resources: v1.ResourceRequirements{
+					Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("4Gi")},
+				},
+			},
+			{
+				Name:         "hdd",
+				NodeSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"storage": "hdd"}},
+			},
+		},
+	}
+	resources := v1.ResourceRequirements{
+		Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("2Gi"), v1.ResourceCPU: resource.MustParse("1")},
+	}
+	c := New(&clusterd.Context{Clientset: clientset}, "ns", "myversion", "",
+		storageSpec, "", rookalpha.Placement{}, false, resources, metav1.OwnerReference{})
+
+	// the ssd node is added, while the explicit node keeps its group
+	err := c.addStorageGroupNodes()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(c.Storage.Nodes))
+	assert.Equal(t, "node1", c.Storage.Nodes[0].Name)
+	assert.Equal(t, "ssd", c.Storage.Nodes[0].StorageGroup)
+	assert.Equal(t, "node0", c.Storage.Nodes[1].Name)
+	assert.Equal(t, "ssd", c.Storage.Nodes[1].StorageGroup)
+
+	// the resources of the group take precedence over the cluster resources
+	n := c.resolveNode("node0")
+	assert.Equal(t, "4Gi", n.Resources.Limits.Memory().String())
+	assert.Equal(t, "1", n.Resources.Limits.Cpu().String())
+
+	// a node that joins a group is new until it is a storage node
+	joined := v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node3", Labels: map[string]string{"storage": "hdd"}}}
+	assert.True(t, c.IsNewStorageGroupNode(joined))
+	joined.Spec.Unschedulable = true
+	assert.False(t, c.IsNewStorageGroupNode(joined))
+	assert.False(t, c.IsNewStorageGroupNode(v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node0", Labels: map[string]string{"storage": "ssd"}}}))
+	assert.False(t, c.IsNewStorageGroupNode(v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node4"}}))
+
+	// the node is not added before its devices are discovered, so it is retried
+	c.context.RookClientset = rookfake.NewSimpleClientset()
+	joined.Spec.Unschedulable = false
+	started, err := c.StartStorageGroupNode(joined)
+	assert.Nil(t, err)
+	assert.False(t, started)
+	assert.Equal(t, 2, len(c.Storage.Nodes))
+	assert.True(t, c.IsNewStorageGroupNode(joined))
+}
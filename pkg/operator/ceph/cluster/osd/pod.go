@@ -18,6 +18,7 @@ limitations under the License.
 package osd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path"
@@ -254,7 +255,7 @@ func (c *Cluster) provisionPodTemplateSpec(devices []rookalpha.Device, selection
 	}
 
 	// by default, don't define any volume config unless it is required
-	if len(devices) > 0 || selection.DeviceFilter != "" || selection.GetUseAllDevices() || metadataDevice != "" {
+	if len(devices) > 0 || selection.DeviceFilter != "" || selection.GetUseAllDevices() || selection.HasDeviceSelectors() || metadataDevice != "" {
 		// create volume config for the data dir and /dev so the pod can access devices on the host
 		devVolume := v1.Volume{Name: "devices", VolumeSource: v1.VolumeSource{HostPath: &v1.HostPathVolumeSource{Path: "/dev"}}}
 		volumes = append(volumes, devVolume)
@@ -348,17 +349,25 @@ func (c *Cluster) provisionOSDContainer(devices []rookalpha.Device, selection ro
 	if len(devices) > 0 {
 		deviceNames := make([]string, len(devices))
 		for i := range devices {
+			// the persistent path follows the disk if its kernel name changes
 			deviceNames[i] = devices[i].Name
+			if devices[i].FullPath != "" {
+				deviceNames[i] = devices[i].FullPath
+			}
 		}
 		envVars = append(envVars, dataDevicesEnvVar(strings.Join(deviceNames, ",")))
 		devMountNeeded = true
 	} else if selection.DeviceFilter != "" {
 		envVars = append(envVars, deviceFilterEnvVar(selection.DeviceFilter))
 		devMountNeeded = true
-	} else if selection.GetUseAllDevices() {
+	} else if selection.GetUseAllDevices() || selection.HasDeviceSelectors() {
 		envVars = append(envVars, deviceFilterEnvVar("all"))
 		devMountNeeded = true
 	}
+	if len(devices) == 0 && selection.HasDeviceSelectors() {
+		// the selectors narrow down the devices of the filter
+		envVars = append(envVars, deviceSelectorEnvVar(selection))
+	}
 
 	if metadataDevice != "" {
 		envVars = append(envVars, metadataDeviceEnvVar(metadataDevice))
@@ -426,6 +435,21 @@ func deviceFilterEnvVar(filter string) v1.EnvVar {
 	return v1.EnvVar{Name: "ROOK_DATA_DEVICE_FILTER", Value: filter}
 }
 
+func deviceSelectorEnvVar(selection rookalpha.Selection) v1.EnvVar {
+	selectors := rookalpha.Selection{
+		MinDeviceSize:    selection.MinDeviceSize,
+		MaxDeviceSize:    selection.MaxDeviceSize,
+		Rotational:       selection.Rotational,
+		VendorFilter:     selection.VendorFilter,
+		ModelFilter:      selection.ModelFilter,
+		DevicePathFilter: selection.DevicePathFilter,
+		MaxDevices:       selection.MaxDevices,
+	}
+	// the selection only has strings, numbers and bools, which always marshal
+	value, _ := json.Marshal(selectors)
+	return v1.EnvVar{Name: "ROOK_DATA_DEVICE_SELECTOR", Value: string(value)}
+}
+
 func metadataDeviceEnvVar(metadataDevice string) v1.EnvVar {
 	return v1.EnvVar{Name: osdMetadataDeviceEnvVarName, Value: metadataDevice}
 }
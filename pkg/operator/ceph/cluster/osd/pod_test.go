@@ -131,6 +131,28 @@ func verifyEnvVar(t *testing.T, envVars []v1.EnvVar, expectedName, expectedValue
 	assert.Equal(t, expectedFound, found)
 }
 
+func TestDeviceSelectorEnvVar(t *testing.T) {
+	c := New(&clusterd.Context{Clientset: fake.NewSimpleClientset(), ConfigDir: "/var/lib/rook", Executor: &exectest.MockExecutor{}}, "ns", "rook/rook:myversion", "mysa",
+		rookalpha.StorageScopeSpec{}, "", rookalpha.Placement{}, false, v1.ResourceRequirements{}, metav1.OwnerReference{})
+
+	// the devices are selected by the osd provisioner when they were not resolved by the operator
+	selection := rookalpha.Selection{MinDeviceSize: "100Gi", MaxDevices: 2}
+	container := c.provisionOSDContainer([]rookalpha.Device{}, selection, v1.ResourceRequirements{}, config.StoreConfig{}, "", "")
+	env := map[string]string{}
+	for _, e := range container.Env {
+		env[e.Name] = e.Value
+	}
+	assert.Equal(t, "all", env["ROOK_DATA_DEVICE_FILTER"])
+	assert.Equal(t, `{"minDeviceSize":"100Gi","maxDevices":2}`, env["ROOK_DATA_DEVICE_SELECTOR"])
+	assert.True(t, *container.SecurityContext.Privileged)
+
+	// the selectors were already applied to the devices resolved by the operator
+	container = c.provisionOSDContainer([]rookalpha.Device{{Name: "sdb"}}, selection, v1.ResourceRequirements{}, config.StoreConfig{}, "", "")
+	for _, e := range container.Env {
+		assert.NotEqual(t, "ROOK_DATA_DEVICE_SELECTOR", e.Name)
+	}
+}
+
 func TestStorageSpecDevicesAndDirectories(t *testing.T) {
 	storageSpec := rookalpha.StorageScopeSpec{
 		Selection: rookalpha.Selection{
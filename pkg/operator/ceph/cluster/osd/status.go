@@ -24,6 +24,7 @@ import (
 
 	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
 	"github.com/rook/rook/pkg/util"
 	"k8s.io/api/core/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -89,9 +90,16 @@ func (c *Cluster) handleOrchestrationFailure(config *provisionConfig, nodeName,
 	status := OrchestrationStatus{Status: OrchestrationStatusFailed, Message: message}
 	if err := c.updateNodeStatus(nodeName, status); err != nil {
 		config.addError("failed to update status for node %s. %+v", nodeName, err)
+		// the failed status is not seen by the orchestration, so the event is recorded here
+		c.recordProvisionFailed(nodeName, message)
 	}
 }
 
+func (c *Cluster) recordProvisionFailed(nodeName, message string) {
+	k8sutil.RecordEvent(c.context.Recorder, k8sutil.NodeObjectReference(nodeName), v1.EventTypeWarning,
+		k8sutil.EventReasonOSDProvisionFailed, "failed to provision osds for cluster %s. %s", c.Namespace, message)
+}
+
 func isStatusCompleted(status OrchestrationStatus) bool {
 	return status.Status == OrchestrationStatusCompleted || status.Status == OrchestrationStatusFailed
 }
@@ -237,6 +245,9 @@ func (c *Cluster) completeOSDsForAllNodes(config *provisionConfig, configOSDs bo
 				currentTimeoutMinutes++
 				if currentTimeoutMinutes == timeoutMinutes {
 					config.addError("timed out waiting for %d nodes: %+v", remainingNodes.Count(), remainingNodes)
+					metrics.OSDProvisionTimedOut(c.Namespace, remainingNodes.Count())
+					k8sutil.RecordEvent(c.context.Recorder, k8sutil.OwnerObjectReference(c.ownerRef, c.Namespace), v1.EventTypeWarning,
+						k8sutil.EventReasonOSDProvisionTimeout, "timed out waiting for the osd provisioning of nodes %v", remainingNodes.ToSlice())
 					return false
 				}
 				logger.Infof("waiting on orchestration status update from %d remaining nodes", remainingNodes.Count())
@@ -259,11 +270,16 @@ func (c *Cluster) handleStatusConfigMapStatus(nodeName string, config *provision
 		}
 		// remove the status configmap that indicated the progress
 		c.kv.ClearStore(fmt.Sprintf(orchestrationStatusMapName, nodeName))
+		metrics.OSDProvisioned(c.Namespace, metrics.ProvisionCompleted)
+		k8sutil.RecordEvent(c.context.Recorder, k8sutil.NodeObjectReference(nodeName), v1.EventTypeNormal,
+			k8sutil.EventReasonOSDProvisioned, "provisioned osds for cluster %s", c.Namespace)
 		return true
 	}
 
 	if status.Status == OrchestrationStatusFailed {
 		config.addError("orchestration for node %s failed: %+v", nodeName, status)
+		metrics.OSDProvisioned(c.Namespace, metrics.ProvisionFailed)
+		c.recordProvisionFailed(nodeName, status.Message)
 		return true
 	}
 	return false
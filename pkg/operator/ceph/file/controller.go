@@ -29,6 +29,9 @@ import (
 	rookv1alpha1 "github.com/rook/rook/pkg/apis/rook.io/v1alpha1"
 	rookv1alpha2 "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
+	"k8s.io/api/core/v1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -38,6 +41,7 @@ import (
 const (
 	customResourceName       = "filesystem"
 	customResourceNamePlural = "filesystems"
+	filesystemControllerName = "ceph-filesystem"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-file")
@@ -89,7 +93,7 @@ func (c *FilesystemController) StartWatch(namespace string, stopCh chan struct{}
 	}
 
 	logger.Infof("start watching filesystem resource in namespace %s", namespace)
-	watcher := opkit.NewWatcher(FilesystemResource, namespace, resourceHandlerFuncs, c.context.RookClientset.CephV1beta1().RESTClient())
+	watcher := opkit.NewWatcher(FilesystemResource, namespace, metrics.InstrumentHandlers(filesystemControllerName, resourceHandlerFuncs), c.context.RookClientset.CephV1beta1().RESTClient())
 	go watcher.Watch(&cephv1beta1.Filesystem{}, stopCh)
 
 	// watch for events on all legacy types too
@@ -102,12 +106,14 @@ func (c *FilesystemController) onAdd(obj interface{}) {
 	filesystem, migrationNeeded, err := getFilesystemObject(obj)
 	if err != nil {
 		logger.Errorf("failed to get filesystem object: %+v", err)
+		metrics.ReconcileFailed(filesystemControllerName, "add")
 		return
 	}
 
 	if migrationNeeded {
 		if err = c.migrateFilesystemObject(filesystem, obj); err != nil {
 			logger.Errorf("failed to migrate filesystem %s in namespace %s: %+v", filesystem.Name, filesystem.Namespace, err)
+			metrics.ReconcileFailed(filesystemControllerName, "add")
 		}
 		return
 	}
@@ -115,24 +121,31 @@ func (c *FilesystemController) onAdd(obj interface{}) {
 	err = CreateFilesystem(c.context, *filesystem, c.rookImage, c.hostNetwork, c.filesystemOwners(filesystem))
 	if err != nil {
 		logger.Errorf("failed to create file system %s. %+v", filesystem.Name, err)
+		metrics.ReconcileFailed(filesystemControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, filesystem, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "failed to create file system. %+v", err)
+		return
 	}
+	k8sutil.RecordEvent(c.context.Recorder, filesystem, v1.EventTypeNormal, k8sutil.EventReasonCreated, "created file system %s", filesystem.Name)
 }
 
 func (c *FilesystemController) onUpdate(oldObj, newObj interface{}) {
 	oldFS, _, err := getFilesystemObject(oldObj)
 	if err != nil {
 		logger.Errorf("failed to get old filesystem object: %+v", err)
+		metrics.ReconcileFailed(filesystemControllerName, "update")
 		return
 	}
 	newFS, migrationNeeded, err := getFilesystemObject(newObj)
 	if err != nil {
 		logger.Errorf("failed to get new filesystem object: %+v", err)
+		metrics.ReconcileFailed(filesystemControllerName, "update")
 		return
 	}
 
 	if migrationNeeded {
 		if err = c.migrateFilesystemObject(newFS, newObj); err != nil {
 			logger.Errorf("failed to migrate filesystem %s in namespace %s: %+v", newFS.Name, newFS.Namespace, err)
+			metrics.ReconcileFailed(filesystemControllerName, "update")
 		}
 		return
 	}
@@ -142,18 +155,30 @@ func (c *FilesystemController) onUpdate(oldObj, newObj interface{}) {
 		return
 	}
 
+	if err := validateFilesystemUpdate(oldFS.Spec, newFS.Spec); err != nil {
+		logger.Errorf("invalid update of file system %s. %+v", newFS.Name, err)
+		metrics.ReconcileFailed(filesystemControllerName, "update")
+		k8sutil.RecordEvent(c.context.Recorder, newFS, v1.EventTypeWarning, k8sutil.EventReasonUpdateFailed, "invalid update of file system. %+v", err)
+		return
+	}
+
 	// if the file system is modified, allow the file system to be created if it wasn't already
 	logger.Infof("updating filesystem %s", newFS.Name)
 	err = CreateFilesystem(c.context, *newFS, c.rookImage, c.hostNetwork, c.filesystemOwners(newFS))
 	if err != nil {
 		logger.Errorf("failed to create (modify) file system %s. %+v", newFS.Name, err)
+		metrics.ReconcileFailed(filesystemControllerName, "update")
+		k8sutil.RecordEvent(c.context.Recorder, newFS, v1.EventTypeWarning, k8sutil.EventReasonUpdateFailed, "failed to update file system. %+v", err)
+		return
 	}
+	k8sutil.RecordEvent(c.context.Recorder, newFS, v1.EventTypeNormal, k8sutil.EventReasonUpdated, "updated file system %s", newFS.Name)
 }
 
 func (c *FilesystemController) onDelete(obj interface{}) {
 	filesystem, migrationNeeded, err := getFilesystemObject(obj)
 	if err != nil {
 		logger.Errorf("failed to get filesystem object: %+v", err)
+		metrics.ReconcileFailed(filesystemControllerName, "delete")
 		return
 	}
 
@@ -165,6 +190,7 @@ func (c *FilesystemController) onDelete(obj interface{}) {
 	err = DeleteFilesystem(c.context, *filesystem)
 	if err != nil {
 		logger.Errorf("failed to delete file system %s. %+v", filesystem.Name, err)
+		metrics.ReconcileFailed(filesystemControllerName, "delete")
 	}
 }
 
@@ -178,8 +204,8 @@ func (c *FilesystemController) filesystemOwners(fs *cephv1beta1.Filesystem) []me
 }
 
 func filesystemChanged(oldFS, newFS cephv1beta1.FilesystemSpec) bool {
-	if len(oldFS.DataPools) != len(newFS.DataPools) {
-		logger.Infof("number of data pools changed from %d to %d", len(oldFS.DataPools), len(newFS.DataPools))
+	if !reflect.DeepEqual(oldFS.DataPools, newFS.DataPools) {
+		logger.Infof("data pools changed from %+v to %+v", oldFS.DataPools, newFS.DataPools)
 		return true
 	}
 	if oldFS.MetadataServer.ActiveCount != newFS.MetadataServer.ActiveCount {
@@ -193,13 +219,25 @@ func filesystemChanged(oldFS, newFS cephv1beta1.FilesystemSpec) bool {
 	return false
 }
 
+// validateFilesystemUpdate rejects the changes of the data pools other than adding or removing pools at the end of the
+// list. The data pools are named after their position, so changing or removing a pool in the middle of the list would
+// change or remove the data pool of another entry.
+func validateFilesystemUpdate(oldFS, newFS cephv1beta1.FilesystemSpec) error {
+	for i := 0; i < len(oldFS.DataPools) && i < len(newFS.DataPools); i++ {
+		if !reflect.DeepEqual(oldFS.DataPools[i], newFS.DataPools[i]) {
+			return fmt.Errorf("data pool %d cannot be changed. only the data pools at the end of the list can be added or removed", i)
+		}
+	}
+	return nil
+}
+
 func (c *FilesystemController) watchLegacyFilesystems(namespace string, stopCh chan struct{}, resourceHandlerFuncs cache.ResourceEventHandlerFuncs) {
 	// watch for filesystem.rook.io/v1alpha1 events if the CRD exists
 	if _, err := c.context.RookClientset.RookV1alpha1().Filesystems(namespace).List(metav1.ListOptions{}); err != nil {
 		logger.Infof("skipping watching for legacy rook filesystem events (legacy filesystem CRD probably doesn't exist): %+v", err)
 	} else {
 		logger.Infof("start watching legacy rook filesystems in all namespaces")
-		watcherLegacy := opkit.NewWatcher(FilesystemResourceRookLegacy, namespace, resourceHandlerFuncs, c.context.RookClientset.RookV1alpha1().RESTClient())
+		watcherLegacy := opkit.NewWatcher(FilesystemResourceRookLegacy, namespace, metrics.InstrumentHandlers(filesystemControllerName, resourceHandlerFuncs), c.context.RookClientset.RookV1alpha1().RESTClient())
 		go watcherLegacy.Watch(&rookv1alpha1.Filesystem{}, stopCh)
 	}
 }
@@ -46,6 +46,26 @@ func TestFilesystemChanged(t *testing.T) {
 
 	new = cephv1beta1.FilesystemSpec{MetadataServer: cephv1beta1.MetadataServerSpec{ActiveCount: 1, ActiveStandby: false}}
 	assert.True(t, filesystemChanged(old, new))
+
+	// changing a data pool without changing the number of pools is a change
+	old.DataPools = []cephv1beta1.PoolSpec{{Replicated: cephv1beta1.ReplicatedSpec{Size: 1}}}
+	new = old
+	new.DataPools = []cephv1beta1.PoolSpec{{Replicated: cephv1beta1.ReplicatedSpec{Size: 3}}}
+	assert.True(t, filesystemChanged(old, new))
+}
+
+func TestValidateFilesystemUpdate(t *testing.T) {
+	ssd := cephv1beta1.PoolSpec{FailureDomain: "host", Replicated: cephv1beta1.ReplicatedSpec{Size: 3}}
+	hdd := cephv1beta1.PoolSpec{ErasureCoded: cephv1beta1.ErasureCodedSpec{DataChunks: 2, CodingChunks: 1}}
+	old := cephv1beta1.FilesystemSpec{DataPools: []cephv1beta1.PoolSpec{ssd, hdd}}
+
+	// the pools at the end of the list can be added or removed
+	assert.Nil(t, validateFilesystemUpdate(old, cephv1beta1.FilesystemSpec{DataPools: []cephv1beta1.PoolSpec{ssd}}))
+	assert.Nil(t, validateFilesystemUpdate(old, cephv1beta1.FilesystemSpec{DataPools: []cephv1beta1.PoolSpec{ssd, hdd, ssd}}))
+
+	// removing or changing a pool in the middle of the list would affect the pool of another entry
+	assert.NotNil(t, validateFilesystemUpdate(old, cephv1beta1.FilesystemSpec{DataPools: []cephv1beta1.PoolSpec{hdd}}))
+	assert.NotNil(t, validateFilesystemUpdate(old, cephv1beta1.FilesystemSpec{DataPools: []cephv1beta1.PoolSpec{ssd, ssd}}))
 }
 
 func TestGetFilesystemObject(t *testing.T) {
@@ -19,6 +19,7 @@ package file
 
 import (
 	"fmt"
+	"reflect"
 	"strconv"
 
 	cephv1beta1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1beta1"
@@ -55,6 +56,15 @@ func CreateFilesystem(context *clusterd.Context, fs cephv1beta1.Filesystem, vers
 		return fmt.Errorf("failed to create file system %s: %+v", fs.Name, err)
 	}
 
+	// apply changes to the data pools and active mds count if the file system already existed
+	if err := f.UpdateFilesystem(context, fs.Namespace); err != nil {
+		return fmt.Errorf("failed to update file system %s: %+v", fs.Name, err)
+	}
+	if err := client.AllowStandbyReplay(context, fs.Namespace, fs.Name, fs.Spec.MetadataServer.ActiveStandby); err != nil {
+		// older versions of ceph configure standby replay only on the mds daemons
+		logger.Warningf("%+v", err)
+	}
+
 	filesystem, err := client.GetFilesystem(context, fs.Namespace, fs.Name)
 	if err != nil {
 		return fmt.Errorf("failed to get file system %s. %+v", fs.Name, err)
@@ -69,7 +79,9 @@ func CreateFilesystem(context *clusterd.Context, fs cephv1beta1.Filesystem, vers
 		if !errors.IsAlreadyExists(err) {
 			return fmt.Errorf("failed to create mds deployment. %+v", err)
 		}
-		logger.Infof("mds deployment %s already exists", deployment.Name)
+		if err := updateDeployment(context, deployment); err != nil {
+			return err
+		}
 	} else {
 		logger.Infof("mds deployment %s started", deployment.Name)
 	}
@@ -77,6 +89,26 @@ func CreateFilesystem(context *clusterd.Context, fs cephv1beta1.Filesystem, vers
 	return nil
 }
 
+// updateDeployment updates the number of mds daemons and their standby settings in an existing deployment
+func updateDeployment(context *clusterd.Context, deployment *extensions.Deployment) error {
+	existing, err := context.Clientset.ExtensionsV1beta1().Deployments(deployment.Namespace).Get(deployment.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get mds deployment %s. %+v", deployment.Name, err)
+	}
+	if existing.Spec.Replicas != nil && *existing.Spec.Replicas == *deployment.Spec.Replicas && reflect.DeepEqual(existing.Spec.Template, deployment.Spec.Template) {
+		logger.Infof("mds deployment %s already exists", deployment.Name)
+		return nil
+	}
+
+	existing.Spec.Replicas = deployment.Spec.Replicas
+	existing.Spec.Template = deployment.Spec.Template
+	if _, err := context.Clientset.ExtensionsV1beta1().Deployments(deployment.Namespace).Update(existing); err != nil {
+		return fmt.Errorf("failed to update mds deployment %s. %+v", deployment.Name, err)
+	}
+	logger.Infof("mds deployment %s updated to %d mds", deployment.Name, *deployment.Spec.Replicas)
+	return nil
+}
+
 // Delete the file system
 func DeleteFilesystem(context *clusterd.Context, fs cephv1beta1.Filesystem) error {
 	// Delete the mds deployment
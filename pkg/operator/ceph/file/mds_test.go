@@ -16,6 +16,7 @@ limitations under the License.
 package file
 
 import (
+	"encoding/json"
 	"errors"
 	"io/ioutil"
 	"os"
@@ -25,6 +26,7 @@ import (
 
 	cephv1beta1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1beta1"
 	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
 	cephtest "github.com/rook/rook/pkg/daemon/ceph/test"
 	testop "github.com/rook/rook/pkg/operator/test"
 	exectest "github.com/rook/rook/pkg/util/exec/test"
@@ -41,6 +43,12 @@ func TestStartMDS(t *testing.T) {
 
 	executor := &exectest.MockExecutor{
 		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if contains(args, "lspools") {
+				return testPools, nil
+			}
+			if args[0] == "fs" && args[1] == "get" {
+				return testFilesystem(1, []int{0}, []int{2}), nil
+			}
 			return "{\"key\":\"mysecurekey\"}", nil
 		},
 		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
@@ -106,6 +114,96 @@ func TestStartMDS(t *testing.T) {
 	assert.Equal(t, "failed to create file system myfs: Cannot create multiple filesystems. Enable ROOK_ALLOW_MULTIPLE_FILESYSTEMS env variable to create more than one", err.Error())
 }
 
+const testPools = `[{"poolnum":1,"poolname":"myfs-metadata"},{"poolnum":2,"poolname":"myfs-data0"},{"poolnum":3,"poolname":"myfs-data1"}]`
+
+func testFilesystem(maxMDS int, in, dataPools []int) string {
+	fs := client.CephFilesystemDetails{
+		ID:     1,
+		MDSMap: client.MDSMap{FilesystemName: "myfs", MaxMDS: maxMDS, In: in, MetadataPool: 1, DataPools: dataPools},
+	}
+	b, _ := json.Marshal(fs)
+	return string(b)
+}
+
+func TestUpdateFilesystem(t *testing.T) {
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+
+	// the file system has two active ranks and two data pools
+	maxMDS := 2
+	in := []int{0, 1}
+	dataPools := []int{2, 3}
+	commands := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			switch {
+			case contains(args, "lspools"):
+				return testPools, nil
+			case args[0] == "fs" && args[1] == "get":
+				return testFilesystem(maxMDS, in, dataPools), nil
+			case args[0] == "fs" && args[1] == "set" && args[3] == "max_mds":
+				maxMDS = 1
+			case args[0] == "mds" && args[1] == "deactivate":
+				in = []int{0}
+			case args[0] == "fs" && args[1] == "rm_data_pool":
+				dataPools = []int{2}
+			}
+			commands = append(commands, strings.Join(args[:4], " "))
+			return "{\"key\":\"mysecurekey\"}", nil
+		},
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if strings.Contains(command, "ceph-authtool") {
+				cephtest.CreateConfigDir(path.Join(configDir, "ns"))
+			}
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{
+		Executor:  executor,
+		ConfigDir: configDir,
+		Clientset: testop.New(3)}
+	fs := cephv1beta1.Filesystem{
+		ObjectMeta: metav1.ObjectMeta{Name: "myfs", Namespace: "ns"},
+		Spec: cephv1beta1.FilesystemSpec{
+			MetadataPool: cephv1beta1.PoolSpec{Replicated: cephv1beta1.ReplicatedSpec{Size: 1}},
+			DataPools: []cephv1beta1.PoolSpec{
+				{Replicated: cephv1beta1.ReplicatedSpec{Size: 1}},
+				{Replicated: cephv1beta1.ReplicatedSpec{Size: 1}}},
+			MetadataServer: cephv1beta1.MetadataServerSpec{ActiveCount: 2},
+		},
+	}
+	err := CreateFilesystem(context, fs, "v0.1", false, []metav1.OwnerReference{})
+	assert.Nil(t, err)
+	d, err := context.Clientset.ExtensionsV1beta1().Deployments(fs.Namespace).Get("rook-ceph-mds-myfs", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(4), *d.Spec.Replicas)
+
+	// reduce the active mds and remove the second data pool
+	commands = []string{}
+	fs.Spec.MetadataServer.ActiveCount = 1
+	fs.Spec.MetadataServer.ActiveStandby = true
+	fs.Spec.DataPools = fs.Spec.DataPools[:1]
+	err = CreateFilesystem(context, fs, "v0.1", false, []metav1.OwnerReference{})
+	assert.Nil(t, err)
+	assert.True(t, contains(commands, "fs rm_data_pool myfs myfs-data1"))
+	assert.True(t, contains(commands, "fs set myfs max_mds"))
+	assert.True(t, contains(commands, "mds deactivate myfs:1 --cluster=ns"))
+	assert.True(t, contains(commands, "fs set myfs allow_standby_replay"))
+	assert.Equal(t, 1, maxMDS)
+
+	// the mds deployment follows the active count
+	d, err = context.Clientset.ExtensionsV1beta1().Deployments(fs.Namespace).Get("rook-ceph-mds-myfs", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), *d.Spec.Replicas)
+
+	// add the data pool back
+	commands = []string{}
+	fs.Spec.DataPools = append(fs.Spec.DataPools, cephv1beta1.PoolSpec{Replicated: cephv1beta1.ReplicatedSpec{Size: 1}})
+	err = CreateFilesystem(context, fs, "v0.1", false, []metav1.OwnerReference{})
+	assert.Nil(t, err)
+	assert.True(t, contains(commands, "fs add_data_pool myfs myfs-data1"))
+}
+
 func contains(arr []string, str string) bool {
 	for _, a := range arr {
 		if a == str {
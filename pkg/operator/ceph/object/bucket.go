@@ -0,0 +1,304 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	opkit "github.com/rook/operator-kit"
+	cephv1beta1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1beta1"
+	"github.com/rook/rook/pkg/clusterd"
+	rgwdaemon "github.com/rook/rook/pkg/daemon/ceph/rgw"
+	"github.com/rook/rook/pkg/operator/metrics"
+	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	bucketResourceName       = "bucket"
+	bucketResourceNamePlural = "buckets"
+	bucketControllerName     = "ceph-bucket"
+)
+
+// the interval at which the usage of the buckets is refreshed in their status
+var bucketUsageInterval = time.Minute
+
+// BucketResource represents the bucket custom resource
+var BucketResource = opkit.CustomResource{
+	Name:    bucketResourceName,
+	Plural:  bucketResourceNamePlural,
+	Group:   cephv1beta1.CustomResourceGroup,
+	Version: cephv1beta1.Version,
+	Scope:   apiextensionsv1beta1.NamespaceScoped,
+	Kind:    reflect.TypeOf(cephv1beta1.Bucket{}).Name(),
+}
+
+// bucketAgent configures the settings of a bucket that are only available in the s3 api
+type bucketAgent interface {
+	CreateBucket(name string) error
+	SetVersioning(name string, enabled bool) error
+	SetLifecycle(name string, rules []rgwdaemon.ExpirationRule) error
+	SetPolicy(name, policy string) error
+}
+
+// BucketController represents a controller object for bucket custom resources
+type BucketController struct {
+	context  *clusterd.Context
+	newAgent func(endpoint, accessKey, secretKey string) bucketAgent
+}
+
+// NewBucketController create controller for watching bucket custom resources created
+func NewBucketController(context *clusterd.Context) *BucketController {
+	return &BucketController{
+		context: context,
+		newAgent: func(endpoint, accessKey, secretKey string) bucketAgent {
+			return rgwdaemon.NewS3Agent(endpoint, accessKey, secretKey)
+		},
+	}
+}
+
+// StartWatch watches for instances of Bucket custom resources and acts on them
+func (c *BucketController) StartWatch(namespace string, stopCh chan struct{}) error {
+
+	resourceHandlerFuncs := cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.onAdd,
+		UpdateFunc: c.onUpdate,
+		DeleteFunc: c.onDelete,
+	}
+
+	logger.Infof("start watching bucket resources in namespace %s", namespace)
+	watcher := opkit.NewWatcher(BucketResource, namespace, metrics.InstrumentHandlers(bucketControllerName, resourceHandlerFuncs), c.context.RookClientset.CephV1beta1().RESTClient())
+	go watcher.Watch(&cephv1beta1.Bucket{}, stopCh)
+
+	go c.refreshUsage(namespace, stopCh)
+
+	return nil
+}
+
+func (c *BucketController) onAdd(obj interface{}) {
+	bucket := obj.(*cephv1beta1.Bucket).DeepCopy()
+	c.reconcile(bucket, "add")
+}
+
+func (c *BucketController) onUpdate(oldObj, newObj interface{}) {
+	oldBucket := oldObj.(*cephv1beta1.Bucket)
+	newBucket := newObj.(*cephv1beta1.Bucket).DeepCopy()
+
+	// ignore the updates of the status
+	if reflect.DeepEqual(oldBucket.Spec, newBucket.Spec) {
+		return
+	}
+	c.reconcile(newBucket, "update")
+}
+
+func (c *BucketController) onDelete(obj interface{}) {
+	bucket := obj.(*cephv1beta1.Bucket)
+
+	// only an empty bucket is removed, the objects are never deleted with the resource
+	objContext := rgwdaemon.NewContext(c.context, bucket.Spec.Store, bucket.Namespace)
+	code, err := rgwdaemon.DeleteBucket(objContext, bucket.Name, false)
+	if err != nil && code != rgwdaemon.RGWErrorNotFound {
+		logger.Warningf("failed to delete bucket %s from object store %s, it is kept if it is not empty. %+v", bucket.Name, bucket.Spec.Store, err)
+		metrics.ReconcileFailed(bucketControllerName, "delete")
+		return
+	}
+	logger.Infof("bucket %s deleted from object store %s", bucket.Name, bucket.Spec.Store)
+}
+
+func (c *BucketController) reconcile(bucket *cephv1beta1.Bucket, event string) {
+	if err := c.createOrUpdateBucket(bucket); err != nil {
+		logger.Errorf("failed to configure bucket %s. %+v", bucket.Name, err)
+		metrics.ReconcileFailed(bucketControllerName, event)
+		bucket.Status.State = cephv1beta1.BucketStateError
+		bucket.Status.Message = err.Error()
+		c.updateStatus(bucket)
+		return
+	}
+	logger.Infof("bucket %s configured in object store %s", bucket.Name, bucket.Spec.Store)
+
+	bucket.Status.State = cephv1beta1.BucketStateCreated
+	bucket.Status.Message = ""
+	c.setUsage(bucket)
+	c.updateStatus(bucket)
+}
+
+func (c *BucketController) createOrUpdateBucket(bucket *cephv1beta1.Bucket) error {
+	if err := validateBucket(bucket); err != nil {
+		return fmt.Errorf("invalid bucket %s. %+v", bucket.Name, err)
+	}
+
+	store, err := c.context.RookClientset.CephV1beta1().ObjectStores(bucket.Namespace).Get(bucket.Spec.Store, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get object store %s. %+v", bucket.Spec.Store, err)
+	}
+	objContext := rgwdaemon.NewContext(c.context, store.Name, store.Namespace)
+
+	// the bucket is created with the keys of the owner so that the owner has full access to it
+	user, _, err := rgwdaemon.GetUser(objContext, bucket.Spec.Owner)
+	if err != nil {
+		return fmt.Errorf("failed to get owner %s. %+v", bucket.Spec.Owner, err)
+	}
+	if user.AccessKey == nil || user.SecretKey == nil {
+		return fmt.Errorf("owner %s does not have s3 keys", bucket.Spec.Owner)
+	}
+
+	existing, code, err := rgwdaemon.GetBucket(objContext, bucket.Name)
+	if err != nil && code != rgwdaemon.RGWErrorNotFound {
+		return fmt.Errorf("failed to get bucket. %+v", err)
+	}
+	if err == nil && existing.Owner != bucket.Spec.Owner {
+		return fmt.Errorf("bucket is already owned by %s", existing.Owner)
+	}
+
+	agent := c.newAgent(bucketEndpoint(*store), *user.AccessKey, *user.SecretKey)
+	if err := agent.CreateBucket(bucket.Name); err != nil {
+		return err
+	}
+
+	// the size was validated already
+	maxSize, _ := bucketMaxSize(bucket.Spec.Quota)
+	if err := rgwdaemon.SetBucketQuota(objContext, bucket.Name, maxSize, bucket.Spec.Quota.MaxObjects); err != nil {
+		return err
+	}
+
+	if err := agent.SetVersioning(bucket.Name, bucket.Spec.Versioning); err != nil {
+		return err
+	}
+
+	rules := []rgwdaemon.ExpirationRule{}
+	for _, rule := range bucket.Spec.Lifecycle {
+		rules = append(rules, rgwdaemon.ExpirationRule{
+			ID:             rule.ID,
+			Prefix:         rule.Prefix,
+			Days:           rule.ExpirationDays,
+			NoncurrentDays: rule.NoncurrentVersionExpirationDays,
+		})
+	}
+	if err := agent.SetLifecycle(bucket.Name, rules); err != nil {
+		return err
+	}
+
+	return agent.SetPolicy(bucket.Name, bucket.Spec.Policy)
+}
+
+func validateBucket(bucket *cephv1beta1.Bucket) error {
+	if bucket.Spec.Store == "" {
+		return fmt.Errorf("missing store")
+	}
+	if bucket.Spec.Owner == "" {
+		return fmt.Errorf("missing owner")
+	}
+	if _, err := bucketMaxSize(bucket.Spec.Quota); err != nil {
+		return err
+	}
+	if bucket.Spec.Quota.MaxObjects < 0 {
+		return fmt.Errorf("invalid quota maxObjects %d", bucket.Spec.Quota.MaxObjects)
+	}
+	ids := map[string]bool{}
+	for _, rule := range bucket.Spec.Lifecycle {
+		if rule.ID == "" {
+			return fmt.Errorf("missing id of lifecycle rule")
+		}
+		if ids[rule.ID] {
+			return fmt.Errorf("duplicate lifecycle rule %s", rule.ID)
+		}
+		ids[rule.ID] = true
+		if rule.ExpirationDays <= 0 && rule.NoncurrentVersionExpirationDays <= 0 {
+			return fmt.Errorf("lifecycle rule %s must expire objects after a number of days", rule.ID)
+		}
+	}
+	if bucket.Spec.Policy != "" {
+		var policy map[string]interface{}
+		if err := json.Unmarshal([]byte(bucket.Spec.Policy), &policy); err != nil {
+			return fmt.Errorf("invalid policy. %+v", err)
+		}
+	}
+	return nil
+}
+
+// bucketMaxSize returns the maximum size of the bucket in bytes, or zero if the size is unlimited
+func bucketMaxSize(quota cephv1beta1.BucketQuotaSpec) (int64, error) {
+	if quota.MaxSize == "" {
+		return 0, nil
+	}
+	size, err := resource.ParseQuantity(quota.MaxSize)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quota maxSize %s. %+v", quota.MaxSize, err)
+	}
+	if size.Value() < 0 {
+		return 0, fmt.Errorf("invalid quota maxSize %s", quota.MaxSize)
+	}
+	return size.Value(), nil
+}
+
+// bucketEndpoint returns the url of the rgw service of the object store
+func bucketEndpoint(store cephv1beta1.ObjectStore) string {
+	if store.Spec.Gateway.Port != 0 {
+		return fmt.Sprintf("http://%s.%s:%d", instanceName(store), store.Namespace, store.Spec.Gateway.Port)
+	}
+	return fmt.Sprintf("https://%s.%s:%d", instanceName(store), store.Namespace, store.Spec.Gateway.SecurePort)
+}
+
+// refreshUsage periodically updates the usage in the status of the buckets
+func (c *BucketController) refreshUsage(namespace string, stopCh chan struct{}) {
+	for {
+		select {
+		case <-stopCh:
+			logger.Infof("stopping the bucket usage refresh")
+			return
+
+		case <-time.After(bucketUsageInterval):
+			buckets, err := c.context.RookClientset.CephV1beta1().Buckets(namespace).List(metav1.ListOptions{})
+			if err != nil {
+				logger.Warningf("failed to list buckets. %+v", err)
+				continue
+			}
+			for i := range buckets.Items {
+				bucket := &buckets.Items[i]
+				if bucket.Status.State == cephv1beta1.BucketStateCreated && c.setUsage(bucket) {
+					c.updateStatus(bucket)
+				}
+			}
+		}
+	}
+}
+
+// setUsage sets the size and the number of objects in the bucket status and returns whether they changed
+func (c *BucketController) setUsage(bucket *cephv1beta1.Bucket) bool {
+	objContext := rgwdaemon.NewContext(c.context, bucket.Spec.Store, bucket.Namespace)
+	stats, _, err := rgwdaemon.GetBucketStats(objContext, bucket.Name)
+	if err != nil {
+		logger.Warningf("failed to get usage of bucket %s. %+v", bucket.Name, err)
+		return false
+	}
+
+	changed := bucket.Status.Size != stats.Size || bucket.Status.NumberOfObjects != stats.NumberOfObjects
+	bucket.Status.Size = stats.Size
+	bucket.Status.NumberOfObjects = stats.NumberOfObjects
+	return changed
+}
+
+func (c *BucketController) updateStatus(bucket *cephv1beta1.Bucket) {
+	if _, err := c.context.RookClientset.CephV1beta1().Buckets(bucket.Namespace).Update(bucket); err != nil {
+		logger.Warningf("failed to update status of bucket %s. %+v", bucket.Name, err)
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package object
+
+import (
+	"strings"
+	"testing"
+
+	cephv1beta1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1beta1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	rgwdaemon "github.com/rook/rook/pkg/daemon/ceph/rgw"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeBucketAgent struct {
+	endpoint   string
+	created    []string
+	versioning bool
+	rules      []rgwdaemon.ExpirationRule
+	policy     string
+}
+
+func (a *fakeBucketAgent) CreateBucket(name string) error {
+	a.created = append(a.created, name)
+	return nil
+}
+
+func (a *fakeBucketAgent) SetVersioning(name string, enabled bool) error {
+	a.versioning = enabled
+	return nil
+}
+
+func (a *fakeBucketAgent) SetLifecycle(name string, rules []rgwdaemon.ExpirationRule) error {
+	a.rules = rules
+	return nil
+}
+
+func (a *fakeBucketAgent) SetPolicy(name, policy string) error {
+	a.policy = policy
+	return nil
+}
+
+func TestCreateBucket(t *testing.T) {
+	store := simpleStore()
+	bucket := &cephv1beta1.Bucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "mybucket", Namespace: store.Namespace},
+		Spec: cephv1beta1.BucketSpec{
+			Store:      store.Name,
+			Owner:      "myuser",
+			Quota:      cephv1beta1.BucketQuotaSpec{MaxSize: "10Gi", MaxObjects: 1000},
+			Versioning: true,
+			Lifecycle:  []cephv1beta1.BucketLifecycleRule{{ID: "logs", Prefix: "logs/", ExpirationDays: 30}},
+			Policy:     `{"Version": "2012-10-17", "Statement": []}`,
+		},
+	}
+
+	bucketExists := false
+	quotaCommands := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			switch {
+			case args[0] == "user" && args[1] == "info":
+				return `{"user_id":"myuser","display_name":"my user","keys":[{"access_key":"myaccess","secret_key":"mysecret"}]}`, nil
+			case args[0] == "bucket" && args[1] == "stats":
+				if !bucketExists {
+					return "could not get bucket info for bucket=mybucket", nil
+				}
+				return `{"bucket":"mybucket","usage":{"rgw.main":{"size":2048,"num_objects":3}}}`, nil
+			case args[0] == "metadata" && args[1] == "get":
+				return `{"data":{"owner":"myuser","creation_time":"2018-10-01 12:00:00.000000Z"}}`, nil
+			case args[0] == "quota":
+				quotaCommands = append(quotaCommands, strings.Join(args[:6], " "))
+				// the bucket is created before its quota is set
+				bucketExists = true
+			}
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor, RookClientset: rookfake.NewSimpleClientset(&store, bucket)}
+	agent := &fakeBucketAgent{}
+	c := NewBucketController(context)
+	c.newAgent = func(endpoint, accessKey, secretKey string) bucketAgent {
+		assert.Equal(t, "myaccess", accessKey)
+		assert.Equal(t, "mysecret", secretKey)
+		agent.endpoint = endpoint
+		return agent
+	}
+
+	c.onAdd(bucket)
+	assert.Equal(t, "http://rook-ceph-rgw-default.mycluster:123", agent.endpoint)
+	assert.Equal(t, []string{"mybucket"}, agent.created)
+	assert.True(t, agent.versioning)
+	assert.Equal(t, []rgwdaemon.ExpirationRule{{ID: "logs", Prefix: "logs/", Days: 30}}, agent.rules)
+	assert.Equal(t, bucket.Spec.Policy, agent.policy)
+	assert.Equal(t, []string{
+		"quota set --quota-scope=bucket --bucket mybucket --max-size=10737418240",
+		"quota enable --quota-scope=bucket --bucket mybucket --rgw-realm=default"}, quotaCommands)
+
+	// the usage is reported in the status
+	b, err := context.RookClientset.CephV1beta1().Buckets(store.Namespace).Get("mybucket", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, cephv1beta1.BucketStateCreated, b.Status.State)
+	assert.Equal(t, uint64(2048), b.Status.Size)
+	assert.Equal(t, uint64(3), b.Status.NumberOfObjects)
+
+	// updates of the status only are ignored
+	agent.created = nil
+	c.onUpdate(bucket, b)
+	assert.Nil(t, agent.created)
+
+	// removing the quota and the lifecycle
+	quotaCommands = []string{}
+	updated := b.DeepCopy()
+	updated.Spec.Quota = cephv1beta1.BucketQuotaSpec{}
+	updated.Spec.Lifecycle = nil
+	c.onUpdate(b, updated)
+	assert.Equal(t, []string{"mybucket"}, agent.created)
+	assert.Equal(t, 0, len(agent.rules))
+	assert.Equal(t, []string{"quota disable --quota-scope=bucket --bucket mybucket --rgw-realm=default"}, quotaCommands)
+
+	// a bucket owned by another user is not configured
+	bucketExists = true
+	updated.Spec.Owner = "otheruser"
+	c.onUpdate(b, updated)
+	b, err = context.RookClientset.CephV1beta1().Buckets(store.Namespace).Get("mybucket", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, cephv1beta1.BucketStateError, b.Status.State)
+	assert.Contains(t, b.Status.Message, "already owned by myuser")
+}
+
+func TestValidateBucket(t *testing.T) {
+	bucket := &cephv1beta1.Bucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "mybucket", Namespace: "ns"},
+		Spec:       cephv1beta1.BucketSpec{Store: "store", Owner: "user"},
+	}
+	assert.Nil(t, validateBucket(bucket))
+
+	bucket.Spec.Quota.MaxSize = "lots"
+	assert.NotNil(t, validateBucket(bucket))
+	bucket.Spec.Quota.MaxSize = "1Ti"
+	assert.Nil(t, validateBucket(bucket))
+
+	bucket.Spec.Lifecycle = []cephv1beta1.BucketLifecycleRule{{ID: "a"}}
+	assert.NotNil(t, validateBucket(bucket))
+	bucket.Spec.Lifecycle = []cephv1beta1.BucketLifecycleRule{{ID: "a", NoncurrentVersionExpirationDays: 1}, {ID: "a", ExpirationDays: 1}}
+	assert.NotNil(t, validateBucket(bucket))
+	bucket.Spec.Lifecycle[1].ID = "b"
+	assert.Nil(t, validateBucket(bucket))
+
+	bucket.Spec.Policy = "{"
+	assert.NotNil(t, validateBucket(bucket))
+
+	bucket.Spec.Policy = ""
+	bucket.Spec.Owner = ""
+	assert.NotNil(t, validateBucket(bucket))
+}
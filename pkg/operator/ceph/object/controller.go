@@ -28,15 +28,21 @@ import (
 	rookv1alpha2 "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/ceph/pool"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
+	"k8s.io/api/core/v1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/tools/cache"
 )
 
 const (
-	customResourceName       = "objectstore"
-	customResourceNamePlural = "objectstores"
+	customResourceName        = "objectstore"
+	customResourceNamePlural  = "objectstores"
+	objectStoreControllerName = "ceph-objectstore"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-object")
@@ -88,12 +94,15 @@ func (c *ObjectStoreController) StartWatch(namespace string, stopCh chan struct{
 	}
 
 	logger.Infof("start watching object store resources in namespace %s", namespace)
-	watcher := opkit.NewWatcher(ObjectStoreResource, namespace, resourceHandlerFuncs, c.context.RookClientset.CephV1beta1().RESTClient())
+	watcher := opkit.NewWatcher(ObjectStoreResource, namespace, metrics.InstrumentHandlers(objectStoreControllerName, resourceHandlerFuncs), c.context.RookClientset.CephV1beta1().RESTClient())
 	go watcher.Watch(&cephv1beta1.ObjectStore{}, stopCh)
 
 	// watch for events on all legacy types too
 	c.watchLegacyObjectStores(namespace, stopCh, resourceHandlerFuncs)
 
+	// restart the rgw pods when their ssl certificate is updated
+	c.watchCertificates(namespace, stopCh)
+
 	return nil
 }
 
@@ -101,19 +110,25 @@ func (c *ObjectStoreController) onAdd(obj interface{}) {
 	objectstore, migrationNeeded, err := getObjectStoreObject(obj)
 	if err != nil {
 		logger.Errorf("failed to get objectstore object: %+v", err)
+		metrics.ReconcileFailed(objectStoreControllerName, "add")
 		return
 	}
 
 	if migrationNeeded {
 		if err = c.migrateObjectStoreObject(objectstore, obj); err != nil {
 			logger.Errorf("failed to migrate objectstore %s in namespace %s: %+v", objectstore.Name, objectstore.Namespace, err)
+			metrics.ReconcileFailed(objectStoreControllerName, "add")
 		}
 		return
 	}
 
 	if err = CreateStore(c.context, *objectstore, c.rookImage, c.hostNetwork, c.storeOwners(objectstore)); err != nil {
 		logger.Errorf("failed to create object store %s. %+v", objectstore.Name, err)
+		metrics.ReconcileFailed(objectStoreControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, objectstore, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "failed to create object store. %+v", err)
+		return
 	}
+	k8sutil.RecordEvent(c.context.Recorder, objectstore, v1.EventTypeNormal, k8sutil.EventReasonCreated, "created object store %s", objectstore.Name)
 }
 
 func (c *ObjectStoreController) onUpdate(oldObj, newObj interface{}) {
@@ -121,17 +136,20 @@ func (c *ObjectStoreController) onUpdate(oldObj, newObj interface{}) {
 	oldStore, _, err := getObjectStoreObject(oldObj)
 	if err != nil {
 		logger.Errorf("failed to get old objectstore object: %+v", err)
+		metrics.ReconcileFailed(objectStoreControllerName, "update")
 		return
 	}
 	newStore, migrationNeeded, err := getObjectStoreObject(newObj)
 	if err != nil {
 		logger.Errorf("failed to get new objectstore object: %+v", err)
+		metrics.ReconcileFailed(objectStoreControllerName, "update")
 		return
 	}
 
 	if migrationNeeded {
 		if err = c.migrateObjectStoreObject(newStore, newObj); err != nil {
 			logger.Errorf("failed to migrate objectstore %s in namespace %s: %+v", newStore.Name, newStore.Namespace, err)
+			metrics.ReconcileFailed(objectStoreControllerName, "update")
 		}
 		return
 	}
@@ -144,13 +162,18 @@ func (c *ObjectStoreController) onUpdate(oldObj, newObj interface{}) {
 	logger.Infof("applying object store %s changes", newStore.Name)
 	if err = UpdateStore(c.context, *newStore, c.rookImage, c.hostNetwork, c.storeOwners(newStore)); err != nil {
 		logger.Errorf("failed to create (modify) object store %s. %+v", newStore.Name, err)
+		metrics.ReconcileFailed(objectStoreControllerName, "update")
+		k8sutil.RecordEvent(c.context.Recorder, newStore, v1.EventTypeWarning, k8sutil.EventReasonUpdateFailed, "failed to update object store. %+v", err)
+		return
 	}
+	k8sutil.RecordEvent(c.context.Recorder, newStore, v1.EventTypeNormal, k8sutil.EventReasonUpdated, "updated object store %s", newStore.Name)
 }
 
 func (c *ObjectStoreController) onDelete(obj interface{}) {
 	objectstore, migrationNeeded, err := getObjectStoreObject(obj)
 	if err != nil {
 		logger.Errorf("failed to get objectstore object: %+v", err)
+		metrics.ReconcileFailed(objectStoreControllerName, "delete")
 		return
 	}
 
@@ -161,6 +184,7 @@ func (c *ObjectStoreController) onDelete(obj interface{}) {
 
 	if err = DeleteStore(c.context, *objectstore); err != nil {
 		logger.Errorf("failed to delete object store %s. %+v", objectstore.Name, err)
+		metrics.ReconcileFailed(objectStoreControllerName, "delete")
 	}
 }
 
@@ -201,16 +225,65 @@ func storeChanged(oldStore, newStore cephv1beta1.ObjectStoreSpec) bool {
 		logger.Infof("SSLCertificateRef changed from %s to %s", oldStore.Gateway.SSLCertificateRef, newStore.Gateway.SSLCertificateRef)
 		return true
 	}
+	if !reflect.DeepEqual(oldStore.Gateway, newStore.Gateway) {
+		logger.Infof("gateway placement or resources changed")
+		return true
+	}
 	return false
 }
 
+func (c *ObjectStoreController) watchCertificates(namespace string, stopCh chan struct{}) {
+	source := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return c.context.Clientset.CoreV1().Secrets(namespace).List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return c.context.Clientset.CoreV1().Secrets(namespace).Watch(options)
+		},
+	}
+	_, controller := cache.NewInformer(source, &v1.Secret{}, 0, cache.ResourceEventHandlerFuncs{
+		UpdateFunc: c.onSecretUpdate,
+	})
+	go controller.Run(stopCh)
+}
+
+func (c *ObjectStoreController) onSecretUpdate(oldObj, newObj interface{}) {
+	oldSecret, ok := oldObj.(*v1.Secret)
+	if !ok {
+		return
+	}
+	newSecret, ok := newObj.(*v1.Secret)
+	if !ok {
+		return
+	}
+	if reflect.DeepEqual(oldSecret.Data, newSecret.Data) {
+		return
+	}
+
+	stores, err := c.context.RookClientset.CephV1beta1().ObjectStores(newSecret.Namespace).List(metav1.ListOptions{})
+	if err != nil {
+		logger.Errorf("failed to list object stores using secret %s. %+v", newSecret.Name, err)
+		return
+	}
+	for _, store := range stores.Items {
+		if store.Spec.Gateway.SSLCertificateRef != newSecret.Name {
+			continue
+		}
+
+		logger.Infof("ssl certificate %s of object store %s changed, restarting the rgw pods", newSecret.Name, store.Name)
+		if err := startRGWPods(c.context, store, c.rookImage, c.hostNetwork, true, c.storeOwners(&store)); err != nil {
+			logger.Errorf("failed to restart the rgw pods of object store %s. %+v", store.Name, err)
+		}
+	}
+}
+
 func (c *ObjectStoreController) watchLegacyObjectStores(namespace string, stopCh chan struct{}, resourceHandlerFuncs cache.ResourceEventHandlerFuncs) {
 	// watch for objectstore.rook.io/v1alpha1 events if the CRD exists
 	if _, err := c.context.RookClientset.RookV1alpha1().ObjectStores(namespace).List(metav1.ListOptions{}); err != nil {
 		logger.Infof("skipping watching for legacy rook objectstore events (legacy objectstore CRD probably doesn't exist): %+v", err)
 	} else {
 		logger.Infof("start watching legacy rook objectstores in all namespaces")
-		watcherLegacy := opkit.NewWatcher(ObjectStoreResourceRookLegacy, namespace, resourceHandlerFuncs, c.context.RookClientset.RookV1alpha1().RESTClient())
+		watcherLegacy := opkit.NewWatcher(ObjectStoreResourceRookLegacy, namespace, metrics.InstrumentHandlers(objectStoreControllerName, resourceHandlerFuncs), c.context.RookClientset.RookV1alpha1().RESTClient())
 		go watcherLegacy.Watch(&rookv1alpha1.ObjectStore{}, stopCh)
 	}
 }
@@ -18,6 +18,8 @@ limitations under the License.
 package object
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 
 	cephv1beta1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1beta1"
@@ -54,6 +56,55 @@ func TestObjectStoreChanged(t *testing.T) {
 
 	new = cephv1beta1.ObjectStoreSpec{Gateway: cephv1beta1.GatewaySpec{Port: 80, SecurePort: 443, Instances: 1, AllNodes: false, SSLCertificateRef: "mysecret"}}
 	assert.True(t, storeChanged(old, new))
+
+	new = cephv1beta1.ObjectStoreSpec{Gateway: cephv1beta1.GatewaySpec{Port: 80, SecurePort: 443, Instances: 1, AllNodes: false, SSLCertificateRef: "",
+		Resources: v1.ResourceRequirements{Limits: v1.ResourceList{v1.ResourceCPU: *resource.NewQuantity(100.0, resource.BinarySI)}}}}
+	assert.True(t, storeChanged(old, new))
+}
+
+func TestCertificateUpdate(t *testing.T) {
+	store := simpleStore()
+	store.Spec.Gateway.SSLCertificateRef = "mycert"
+	store.Spec.Gateway.SecurePort = 443
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "mycert", Namespace: store.Namespace},
+		Data:       map[string][]byte{certKeyName: []byte("cert1")},
+	}
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	context := &clusterd.Context{
+		Clientset:     testop.New(1),
+		RookClientset: rookfake.NewSimpleClientset(&store),
+		Executor:      rgwTestExecutor(),
+		ConfigDir:     configDir,
+	}
+	_, err := context.Clientset.CoreV1().Secrets(store.Namespace).Create(secret)
+	assert.Nil(t, err)
+	err = CreateStore(context, store, "v1.0", false, []metav1.OwnerReference{})
+	assert.Nil(t, err)
+	d, err := context.Clientset.ExtensionsV1beta1().Deployments(store.Namespace).Get(instanceName(store), metav1.GetOptions{})
+	assert.Nil(t, err)
+	hash := d.Spec.Template.Annotations[certHashAnnotation]
+	assert.NotEqual(t, "", hash)
+
+	// a secret that is not referenced by the store is ignored
+	controller := NewObjectStoreController(context, "v1.0", false, metav1.OwnerReference{})
+	other := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: store.Namespace}, Data: map[string][]byte{"a": []byte("b")}}
+	controller.onSecretUpdate(&v1.Secret{ObjectMeta: other.ObjectMeta}, other)
+	d, err = context.Clientset.ExtensionsV1beta1().Deployments(store.Namespace).Get(instanceName(store), metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, hash, d.Spec.Template.Annotations[certHashAnnotation])
+
+	// the rgw pods are rolled when the certificate changes
+	updated := secret.DeepCopy()
+	updated.Data[certKeyName] = []byte("cert2")
+	_, err = context.Clientset.CoreV1().Secrets(store.Namespace).Update(updated)
+	assert.Nil(t, err)
+	controller.onSecretUpdate(secret, updated)
+	d, err = context.Clientset.ExtensionsV1beta1().Deployments(store.Namespace).Get(instanceName(store), metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", d.Spec.Template.Annotations[certHashAnnotation])
+	assert.NotEqual(t, hash, d.Spec.Template.Annotations[certHashAnnotation])
 }
 
 func TestGetObjectStoreObject(t *testing.T) {
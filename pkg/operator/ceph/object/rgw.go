@@ -18,6 +18,7 @@ limitations under the License.
 package object
 
 import (
+	"crypto/sha256"
 	"fmt"
 	"path"
 
@@ -42,6 +43,10 @@ const (
 	certMountPath  = "/etc/rook/private"
 	certKeyName    = "cert"
 	certFilename   = "rgw-cert.pem"
+
+	// certHashAnnotation is the pod annotation with the hash of the ssl certificate. The rgw pods are
+	// restarted when the certificate in the secret changes.
+	certHashAnnotation = "ceph.rook.io/cert-hash"
 )
 
 // Start the rgw manager
@@ -97,35 +102,45 @@ func createOrUpdate(context *clusterd.Context, store cephv1beta1.ObjectStore, ve
 }
 
 func startRGWPods(context *clusterd.Context, store cephv1beta1.ObjectStore, version string, hostNetwork, update bool, ownerRefs []metav1.OwnerReference) error {
-
-	// if intended to update, remove the old pods so they can be created with the new spec settings
-	if update {
-		err := k8sutil.DeleteDeployment(context.Clientset, store.Namespace, instanceName(store))
-		if err != nil {
-			logger.Warningf(err.Error())
-		}
-		err = k8sutil.DeleteDaemonset(context.Clientset, store.Namespace, instanceName(store))
-		if err != nil {
-			logger.Warningf(err.Error())
-		}
+	certHash, err := getCertHash(context, store)
+	if err != nil {
+		return err
 	}
 
-	// start the deployment or daemonset
+	// start the deployment or daemonset, and remove the other form in case the gateway was changed
+	// between running on all nodes and running a number of instances
 	var rgwType string
-	var err error
 	if store.Spec.Gateway.AllNodes {
 		rgwType = "daemonset"
-		err = startDaemonset(context, store, version, hostNetwork, ownerRefs)
+		if err := k8sutil.DeleteDeployment(context.Clientset, store.Namespace, instanceName(store)); err != nil {
+			logger.Warningf(err.Error())
+		}
+		err = startDaemonset(context, store, version, hostNetwork, certHash, ownerRefs)
 	} else {
 		rgwType = "deployment"
-		err = startDeployment(context, store, version, store.Spec.Gateway.Instances, hostNetwork, ownerRefs)
+		if err := k8sutil.DeleteDaemonset(context.Clientset, store.Namespace, instanceName(store)); err != nil {
+			logger.Warningf(err.Error())
+		}
+		err = startDeployment(context, store, version, store.Spec.Gateway.Instances, hostNetwork, certHash, ownerRefs)
 	}
 
 	if err != nil {
 		if !errors.IsAlreadyExists(err) {
-			return fmt.Errorf("failed to create rgw %store. %+v", rgwType, err)
+			return fmt.Errorf("failed to create rgw %s. %+v", rgwType, err)
 		}
-		logger.Infof("rgw %s already exists", rgwType)
+		if !update {
+			logger.Infof("rgw %s already exists", rgwType)
+			return nil
+		}
+		if store.Spec.Gateway.AllNodes {
+			err = updateDaemonset(context, store, version, hostNetwork, certHash)
+		} else {
+			err = updateDeployment(context, store, version, store.Spec.Gateway.Instances, hostNetwork, certHash)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update rgw %s. %+v", rgwType, err)
+		}
+		logger.Infof("rgw %s updated", rgwType)
 	} else {
 		logger.Infof("rgw %s started", rgwType)
 	}
@@ -133,6 +148,22 @@ func startRGWPods(context *clusterd.Context, store cephv1beta1.ObjectStore, vers
 	return nil
 }
 
+// getCertHash returns a hash of the ssl certificate of the gateway, or an empty string if ssl is not configured
+func getCertHash(context *clusterd.Context, store cephv1beta1.ObjectStore) (string, error) {
+	if store.Spec.Gateway.SSLCertificateRef == "" {
+		return "", nil
+	}
+	secret, err := context.Clientset.CoreV1().Secrets(store.Namespace).Get(store.Spec.Gateway.SSLCertificateRef, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get rgw certificate secret %s. %+v", store.Spec.Gateway.SSLCertificateRef, err)
+	}
+	cert, ok := secret.Data[certKeyName]
+	if !ok {
+		return "", fmt.Errorf("rgw certificate secret %s does not contain the key %s", store.Spec.Gateway.SSLCertificateRef, certKeyName)
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(cert)), nil
+}
+
 // Delete the object store.
 // WARNING: This is a very destructive action that deletes all metadata and data pools.
 func DeleteStore(context *clusterd.Context, store cephv1beta1.ObjectStore) error {
@@ -290,21 +321,54 @@ func makeRGWPodSpec(store cephv1beta1.ObjectStore, version string, hostNetwork b
 	}
 }
 
-func startDeployment(context *clusterd.Context, store cephv1beta1.ObjectStore, version string, replicas int32, hostNetwork bool, ownerRefs []metav1.OwnerReference) error {
+// makeRGWPodTemplate returns the pod template for the rgw deployment or daemonset
+func makeRGWPodTemplate(store cephv1beta1.ObjectStore, version string, hostNetwork bool, certHash string) v1.PodTemplateSpec {
+	podSpec := makeRGWPodSpec(store, version, hostNetwork)
+	if certHash != "" {
+		podSpec.Annotations[certHashAnnotation] = certHash
+	}
+	return podSpec
+}
+
+func startDeployment(context *clusterd.Context, store cephv1beta1.ObjectStore, version string, replicas int32, hostNetwork bool, certHash string, ownerRefs []metav1.OwnerReference) error {
 
 	deployment := &extensions.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      instanceName(store),
 			Namespace: store.Namespace,
 		},
-		Spec: extensions.DeploymentSpec{Template: makeRGWPodSpec(store, version, hostNetwork), Replicas: &replicas},
+		Spec: extensions.DeploymentSpec{Template: makeRGWPodTemplate(store, version, hostNetwork, certHash), Replicas: &replicas},
 	}
 	k8sutil.SetOwnerRefs(context.Clientset, store.Namespace, &deployment.ObjectMeta, ownerRefs)
 	_, err := context.Clientset.ExtensionsV1beta1().Deployments(store.Namespace).Create(deployment)
 	return err
 }
 
-func startDaemonset(context *clusterd.Context, store cephv1beta1.ObjectStore, version string, hostNetwork bool, ownerRefs []metav1.OwnerReference) error {
+// updateDeployment applies the gateway spec to the existing deployment, which rolls the rgw pods if the pod template changed
+func updateDeployment(context *clusterd.Context, store cephv1beta1.ObjectStore, version string, replicas int32, hostNetwork bool, certHash string) error {
+	deployment, err := context.Clientset.ExtensionsV1beta1().Deployments(store.Namespace).Get(instanceName(store), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	deployment.Spec.Replicas = &replicas
+	deployment.Spec.Template = makeRGWPodTemplate(store, version, hostNetwork, certHash)
+	_, err = context.Clientset.ExtensionsV1beta1().Deployments(store.Namespace).Update(deployment)
+	return err
+}
+
+// updateDaemonset applies the gateway spec to the existing daemonset, which rolls the rgw pods if the pod template changed
+func updateDaemonset(context *clusterd.Context, store cephv1beta1.ObjectStore, version string, hostNetwork bool, certHash string) error {
+	daemonset, err := context.Clientset.ExtensionsV1beta1().DaemonSets(store.Namespace).Get(instanceName(store), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	daemonset.Spec.UpdateStrategy = extensions.DaemonSetUpdateStrategy{Type: extensions.RollingUpdateDaemonSetStrategyType}
+	daemonset.Spec.Template = makeRGWPodTemplate(store, version, hostNetwork, certHash)
+	_, err = context.Clientset.ExtensionsV1beta1().DaemonSets(store.Namespace).Update(daemonset)
+	return err
+}
+
+func startDaemonset(context *clusterd.Context, store cephv1beta1.ObjectStore, version string, hostNetwork bool, certHash string, ownerRefs []metav1.OwnerReference) error {
 
 	daemonset := &extensions.DaemonSet{
 		ObjectMeta: metav1.ObjectMeta{
@@ -315,7 +379,7 @@ func startDaemonset(context *clusterd.Context, store cephv1beta1.ObjectStore, ve
 			UpdateStrategy: extensions.DaemonSetUpdateStrategy{
 				Type: extensions.RollingUpdateDaemonSetStrategyType,
 			},
-			Template: makeRGWPodSpec(store, version, hostNetwork),
+			Template: makeRGWPodTemplate(store, version, hostNetwork, certHash),
 		},
 	}
 	k8sutil.SetOwnerRefs(context.Clientset, store.Namespace, &daemonset.ObjectMeta, ownerRefs)
@@ -392,13 +456,48 @@ func startService(context *clusterd.Context, store cephv1beta1.ObjectStore, host
 			return "", fmt.Errorf("failed to create rgw service. %+v", err)
 		}
 		logger.Infof("Gateway service already running")
-		return "", nil
+		return updateService(context, store)
 	}
 
 	logger.Infof("Gateway service running at %s:%d", svc.Spec.ClusterIP, store.Spec.Gateway.Port)
 	return svc.Spec.ClusterIP, nil
 }
 
+// updateService updates the ports of the existing rgw service if the gateway ports changed
+func updateService(context *clusterd.Context, store cephv1beta1.ObjectStore) (string, error) {
+	svc, err := context.Clientset.CoreV1().Services(store.Namespace).Get(instanceName(store), metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get rgw service. %+v", err)
+	}
+
+	existingPorts := svc.Spec.Ports
+	svc.Spec.Ports = nil
+	addPort(svc, "http", store.Spec.Gateway.Port)
+	addPort(svc, "https", store.Spec.Gateway.SecurePort)
+	if servicePortsEqual(existingPorts, svc.Spec.Ports) {
+		return svc.Spec.ClusterIP, nil
+	}
+
+	svc, err = context.Clientset.CoreV1().Services(store.Namespace).Update(svc)
+	if err != nil {
+		return "", fmt.Errorf("failed to update rgw service. %+v", err)
+	}
+	logger.Infof("Gateway service updated to port %d and secure port %d", store.Spec.Gateway.Port, store.Spec.Gateway.SecurePort)
+	return svc.Spec.ClusterIP, nil
+}
+
+func servicePortsEqual(a, b []v1.ServicePort) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].Port != b[i].Port || a[i].TargetPort != b[i].TargetPort {
+			return false
+		}
+	}
+	return true
+}
+
 func addPort(service *v1.Service, name string, port int32) {
 	if port == 0 {
 		return
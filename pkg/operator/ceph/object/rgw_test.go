@@ -37,7 +37,28 @@ import (
 
 func TestStartRGW(t *testing.T) {
 	clientset := testop.New(3)
-	executor := &exectest.MockExecutor{
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	context := &clusterd.Context{Clientset: clientset, Executor: rgwTestExecutor(), ConfigDir: configDir}
+	store := simpleStore()
+	version := "v1.1.0"
+
+	// start a basic cluster
+	err := CreateStore(context, store, version, false, []metav1.OwnerReference{})
+	assert.Nil(t, err)
+
+	validateStart(t, store, clientset, false)
+
+	// starting again should update the pods with the new settings
+	store.Spec.Gateway.AllNodes = true
+	err = UpdateStore(context, store, version, false, []metav1.OwnerReference{})
+	assert.Nil(t, err)
+
+	validateStart(t, store, clientset, true)
+}
+
+func rgwTestExecutor() *exectest.MockExecutor {
+	return &exectest.MockExecutor{
 		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
 			return `{"key":"mysecurekey"}`, nil
 		},
@@ -45,25 +66,53 @@ func TestStartRGW(t *testing.T) {
 			return `{"id":"test-id"}`, nil
 		},
 	}
+}
 
+func TestUpdateGateway(t *testing.T) {
+	clientset := testop.New(3)
 	configDir, _ := ioutil.TempDir("", "")
 	defer os.RemoveAll(configDir)
-	context := &clusterd.Context{Clientset: clientset, Executor: executor, ConfigDir: configDir}
+	context := &clusterd.Context{Clientset: clientset, Executor: rgwTestExecutor(), ConfigDir: configDir}
 	store := simpleStore()
-	version := "v1.1.0"
+	store.Spec.Gateway.Instances = 1
 
-	// start a basic cluster
-	err := CreateStore(context, store, version, false, []metav1.OwnerReference{})
+	err := CreateStore(context, store, "v1.1.0", false, []metav1.OwnerReference{})
+	assert.Nil(t, err)
+	s, err := clientset.CoreV1().Services(store.Namespace).Get(instanceName(store), metav1.GetOptions{})
 	assert.Nil(t, err)
+	assert.Equal(t, 1, len(s.Spec.Ports))
 
+	// the service and the deployment are updated in place
+	store.Spec.Gateway.Instances = 3
+	store.Spec.Gateway.Port = 8080
+	store.Spec.Gateway.SecurePort = 8443
+	err = UpdateStore(context, store, "v1.1.0", false, []metav1.OwnerReference{})
+	assert.Nil(t, err)
 	validateStart(t, store, clientset, false)
+	d, err := clientset.ExtensionsV1beta1().Deployments(store.Namespace).Get(instanceName(store), metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), *d.Spec.Replicas)
+	assert.Contains(t, d.Spec.Template.Spec.Containers[0].Args, "--rgw-port=8080")
+	s, err = clientset.CoreV1().Services(store.Namespace).Get(instanceName(store), metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(s.Spec.Ports))
+	assert.Equal(t, int32(8080), s.Spec.Ports[0].Port)
+	assert.Equal(t, int32(8443), s.Spec.Ports[1].Port)
 
-	// starting again should update the pods with the new settings
+	// switching back from a daemonset removes the daemonset
 	store.Spec.Gateway.AllNodes = true
-	err = UpdateStore(context, store, version, false, []metav1.OwnerReference{})
+	err = UpdateStore(context, store, "v1.1.0", false, []metav1.OwnerReference{})
 	assert.Nil(t, err)
-
 	validateStart(t, store, clientset, true)
+	store.Spec.Gateway.AllNodes = false
+	err = UpdateStore(context, store, "v1.1.0", false, []metav1.OwnerReference{})
+	assert.Nil(t, err)
+	validateStart(t, store, clientset, false)
+
+	// the certificate secret must exist
+	store.Spec.Gateway.SSLCertificateRef = "mycert"
+	err = UpdateStore(context, store, "v1.1.0", false, []metav1.OwnerReference{})
+	assert.NotNil(t, err)
 }
 
 func validateStart(t *testing.T, store cephv1beta1.ObjectStore, clientset *fake.Clientset, allNodes bool) {
@@ -72,10 +72,10 @@ type Operator struct {
 
 // New creates an operator instance
 func New(context *clusterd.Context, volumeAttachmentWrapper attachment.Attachment, rookImage, securityAccount string) *Operator {
-	clusterController := cluster.NewClusterController(context, rookImage, volumeAttachmentWrapper)
+	clusterController := cluster.NewClusterController(context, rookImage, securityAccount, volumeAttachmentWrapper)
 
 	schemes := []opkit.CustomResource{cluster.ClusterResource, pool.PoolResource, object.ObjectStoreResource,
-		file.FilesystemResource, attachment.VolumeResource}
+		object.BucketResource, file.FilesystemResource, attachment.VolumeResource}
 	return &Operator{
 		context:           context,
 		clusterController: clusterController,
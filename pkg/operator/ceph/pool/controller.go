@@ -28,6 +28,9 @@ import (
 	"github.com/rook/rook/pkg/clusterd"
 	ceph "github.com/rook/rook/pkg/daemon/ceph/client"
 	"github.com/rook/rook/pkg/daemon/ceph/model"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
+	"k8s.io/api/core/v1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -40,6 +43,7 @@ const (
 	replicatedType           = "replicated"
 	erasureCodeType          = "erasure-coded"
 	poolApplicationNameRBD   = "rbd"
+	poolControllerName       = "ceph-pool"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-pool")
@@ -85,7 +89,7 @@ func (c *PoolController) StartWatch(namespace string, stopCh chan struct{}) erro
 	}
 
 	logger.Infof("start watching pool resources in namespace %s", namespace)
-	watcher := opkit.NewWatcher(PoolResource, namespace, resourceHandlerFuncs, c.context.RookClientset.CephV1beta1().RESTClient())
+	watcher := opkit.NewWatcher(PoolResource, namespace, metrics.InstrumentHandlers(poolControllerName, resourceHandlerFuncs), c.context.RookClientset.CephV1beta1().RESTClient())
 	go watcher.Watch(&cephv1beta1.Pool{}, stopCh)
 
 	// watch for events on all legacy types too
@@ -98,12 +102,14 @@ func (c *PoolController) onAdd(obj interface{}) {
 	pool, migrationNeeded, err := getPoolObject(obj)
 	if err != nil {
 		logger.Errorf("failed to get pool object: %+v", err)
+		metrics.ReconcileFailed(poolControllerName, "add")
 		return
 	}
 
 	if migrationNeeded {
 		if err = c.migratePoolObject(pool, obj); err != nil {
 			logger.Errorf("failed to migrate pool %s in namespace %s: %+v", pool.Name, pool.Namespace, err)
+			metrics.ReconcileFailed(poolControllerName, "add")
 		}
 		return
 	}
@@ -111,34 +117,45 @@ func (c *PoolController) onAdd(obj interface{}) {
 	err = createPool(c.context, pool)
 	if err != nil {
 		logger.Errorf("failed to create pool %s. %+v", pool.ObjectMeta.Name, err)
+		metrics.ReconcileFailed(poolControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, pool, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "failed to create pool. %+v", err)
+		return
 	}
+	k8sutil.RecordEvent(c.context.Recorder, pool, v1.EventTypeNormal, k8sutil.EventReasonCreated, "created pool %s", pool.Name)
 }
 
 func (c *PoolController) onUpdate(oldObj, newObj interface{}) {
 	oldPool, _, err := getPoolObject(oldObj)
 	if err != nil {
 		logger.Errorf("failed to get old pool object: %+v", err)
+		metrics.ReconcileFailed(poolControllerName, "update")
 		return
 	}
 	pool, migrationNeeded, err := getPoolObject(newObj)
 	if err != nil {
 		logger.Errorf("failed to get new pool object: %+v", err)
+		metrics.ReconcileFailed(poolControllerName, "update")
 		return
 	}
 
 	if migrationNeeded {
 		if err = c.migratePoolObject(pool, newObj); err != nil {
 			logger.Errorf("failed to migrate pool %s in namespace %s: %+v", pool.Name, pool.Namespace, err)
+			metrics.ReconcileFailed(poolControllerName, "update")
 		}
 		return
 	}
 
 	if oldPool.Name != pool.Name {
 		logger.Errorf("failed to update pool %s. name update not allowed", pool.Name)
+		metrics.ReconcileFailed(poolControllerName, "update")
+		k8sutil.RecordEvent(c.context.Recorder, pool, v1.EventTypeWarning, k8sutil.EventReasonUpdateFailed, "name update not allowed")
 		return
 	}
 	if pool.Spec.ErasureCoded.CodingChunks != 0 && pool.Spec.ErasureCoded.DataChunks != 0 {
 		logger.Errorf("failed to update pool %s. erasurecoded update not allowed", pool.Name)
+		metrics.ReconcileFailed(poolControllerName, "update")
+		k8sutil.RecordEvent(c.context.Recorder, pool, v1.EventTypeWarning, k8sutil.EventReasonUpdateFailed, "erasurecoded update not allowed")
 		return
 	}
 	if !poolChanged(oldPool.Spec, pool.Spec) {
@@ -150,7 +167,11 @@ func (c *PoolController) onUpdate(oldObj, newObj interface{}) {
 	logger.Infof("updating pool %s", pool.Name)
 	if err := createPool(c.context, pool); err != nil {
 		logger.Errorf("failed to create (modify) pool %s. %+v", pool.ObjectMeta.Name, err)
+		metrics.ReconcileFailed(poolControllerName, "update")
+		k8sutil.RecordEvent(c.context.Recorder, pool, v1.EventTypeWarning, k8sutil.EventReasonUpdateFailed, "failed to update pool. %+v", err)
+		return
 	}
+	k8sutil.RecordEvent(c.context.Recorder, pool, v1.EventTypeNormal, k8sutil.EventReasonUpdated, "updated pool %s", pool.Name)
 }
 
 func poolChanged(old, new cephv1beta1.PoolSpec) bool {
@@ -165,6 +186,7 @@ func (c *PoolController) onDelete(obj interface{}) {
 	pool, migrationNeeded, err := getPoolObject(obj)
 	if err != nil {
 		logger.Errorf("failed to get pool object: %+v", err)
+		metrics.ReconcileFailed(poolControllerName, "delete")
 		return
 	}
 
@@ -175,6 +197,7 @@ func (c *PoolController) onDelete(obj interface{}) {
 
 	if err := deletePool(c.context, pool); err != nil {
 		logger.Errorf("failed to delete pool %s. %+v", pool.ObjectMeta.Name, err)
+		metrics.ReconcileFailed(poolControllerName, "delete")
 	}
 }
 
@@ -297,7 +320,7 @@ func (c *PoolController) watchLegacyPools(namespace string, stopCh chan struct{}
 		logger.Infof("skipping watching for legacy rook pool events (legacy pool CRD probably doesn't exist): %+v", err)
 	} else {
 		logger.Infof("start watching legacy rook pools in all namespaces")
-		watcherLegacy := opkit.NewWatcher(PoolResourceRookLegacy, namespace, resourceHandlerFuncs, c.context.RookClientset.RookV1alpha1().RESTClient())
+		watcherLegacy := opkit.NewWatcher(PoolResourceRookLegacy, namespace, metrics.InstrumentHandlers(poolControllerName, resourceHandlerFuncs), c.context.RookClientset.RookV1alpha1().RESTClient())
 		go watcherLegacy.Watch(&rookv1alpha1.Pool{}, stopCh)
 	}
 }
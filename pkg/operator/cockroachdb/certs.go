@@ -0,0 +1,402 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cockroachdb
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path"
+	"time"
+
+	cockroachdbv1alpha1 "github.com/rook/rook/pkg/apis/cockroachdb.rook.io/v1alpha1"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	certificatesv1beta1 "k8s.io/api/certificates/v1beta1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	caSecretName         = "rook-cockroachdb-ca"
+	nodeSecretName       = "rook-cockroachdb-node"
+	clientRootSecretName = "rook-cockroachdb-client-root"
+	certsVolumeName      = "certs"
+	certsDir             = "/cockroach/cockroach-certs"
+
+	// the names of the certificate files expected by cockroachdb in the certs dir
+	caCertFile         = "ca.crt"
+	caKeyFile          = "ca.key"
+	nodeCertFile       = "node.crt"
+	nodeKeyFile        = "node.key"
+	clientRootCertFile = "client.root.crt"
+	clientRootKeyFile  = "client.root.key"
+
+	rsaKeySize   = 2048
+	caValidity   = 10 * 365 * 24 * time.Hour
+	certValidity = 5 * 365 * 24 * time.Hour
+)
+
+var (
+	// the CA of the kubernetes cluster, which signs the certificates approved with the certificates API
+	serviceAccountCAFile = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+
+	csrInterval = 2 * time.Second
+	csrTimeout  = 2 * time.Minute
+)
+
+// certSigner signs the certificates of a secure cluster
+type certSigner interface {
+	// sign returns the PEM encoded certificate of the template for the key
+	sign(name string, template *x509.Certificate, key *rsa.PrivateKey) ([]byte, error)
+	// caCert returns the PEM encoded certificate of the CA that signs the certificates
+	caCert() []byte
+}
+
+// createCertificates creates the secrets with the node certificate mounted in the cockroachdb pods and the root
+// client certificate for the applications. The certificates are only created if their secrets do not exist.
+func (c *ClusterController) createCertificates(cluster *cluster) error {
+	if !cluster.spec.Secure {
+		return nil
+	}
+
+	// only the missing certificates are created, the existing ones are kept so the nodes and clients still trust them
+	secrets := c.context.Clientset.CoreV1().Secrets(cluster.namespace)
+	createNode, createRoot := false, false
+	if _, err := secrets.Get(nodeSecretName, metav1.GetOptions{}); err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get node certificate secret: %+v", err)
+		}
+		createNode = true
+	}
+	if _, err := secrets.Get(clientRootSecretName, metav1.GetOptions{}); err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get root client certificate secret: %+v", err)
+		}
+		createRoot = true
+	}
+	if !createNode && !createRoot {
+		logger.Infof("certificates of cluster in namespace %s already exist", cluster.namespace)
+		return nil
+	}
+
+	signer, err := c.newCertSigner(cluster)
+	if err != nil {
+		return err
+	}
+
+	if createNode {
+		if err := c.createNodeCertificate(cluster, signer); err != nil {
+			return err
+		}
+	}
+	if createRoot {
+		return c.createRootCertificate(cluster, signer)
+	}
+	return nil
+}
+
+// createNodeCertificate creates the secret with the certificate of the nodes, which is valid for all the pods
+func (c *ClusterController) createNodeCertificate(cluster *cluster, signer certSigner) error {
+	nodeTemplate := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "node"},
+		DNSNames:    nodeCertHosts(cluster.namespace),
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	nodeCert, nodeKey, err := createCertificate(signer, "node", nodeTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to create node certificate: %+v", err)
+	}
+	return c.createCertSecret(cluster, nodeSecretName, map[string][]byte{
+		caCertFile:   signer.caCert(),
+		nodeCertFile: nodeCert,
+		nodeKeyFile:  nodeKey,
+	})
+}
+
+// createRootCertificate creates the secret with the certificate of the root client
+func (c *ClusterController) createRootCertificate(cluster *cluster, signer certSigner) error {
+	rootTemplate := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "root"},
+		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	rootCert, rootKey, err := createCertificate(signer, "client-root", rootTemplate)
+	if err != nil {
+		return fmt.Errorf("failed to create root client certificate: %+v", err)
+	}
+	return c.createCertSecret(cluster, clientRootSecretName, map[string][]byte{
+		caCertFile:         signer.caCert(),
+		clientRootCertFile: rootCert,
+		clientRootKeyFile:  rootKey,
+	})
+}
+
+// nodeCertHosts returns the host names of the cockroachdb nodes through the replica and the client services
+func nodeCertHosts(namespace string) []string {
+	hosts := []string{"localhost"}
+	for _, service := range []string{appName, "*." + appName, clientServiceName} {
+		hosts = append(hosts,
+			service,
+			fmt.Sprintf("%s.%s", service, namespace),
+			fmt.Sprintf("%s.%s.svc.cluster.local", service, namespace))
+	}
+	return hosts
+}
+
+func (c *ClusterController) newCertSigner(cluster *cluster) (certSigner, error) {
+	if cluster.spec.Certificates.Issuer == cockroachdbv1alpha1.CertificateIssuerKubernetes {
+		caCert, err := ioutil.ReadFile(serviceAccountCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA of the kubernetes cluster: %+v", err)
+		}
+		return &kubernetesSigner{controller: c, namespace: cluster.namespace, ca: caCert}, nil
+	}
+	return c.loadOrCreateCA(cluster)
+}
+
+func (c *ClusterController) createCertSecret(cluster *cluster, name string, data map[string][]byte) error {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: cluster.namespace,
+			Labels:    createAppLabels(),
+		},
+		Data: data,
+	}
+	k8sutil.SetOwnerRef(c.context.Clientset, cluster.namespace, &secret.ObjectMeta, &cluster.ownerRef)
+
+	secrets := c.context.Clientset.CoreV1().Secrets(cluster.namespace)
+	if _, err := secrets.Create(secret); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create secret %s: %+v", name, err)
+		}
+		if _, err := secrets.Update(secret); err != nil {
+			return fmt.Errorf("failed to update secret %s: %+v", name, err)
+		}
+	}
+	logger.Infof("certificate secret %s created in namespace %s", name, cluster.namespace)
+	return nil
+}
+
+// createCertificate creates a key and returns the PEM encoded certificate and key
+func createCertificate(signer certSigner, name string, template *x509.Certificate) ([]byte, []byte, error) {
+	key, err := rsa.GenerateKey(rand.Reader, rsaKeySize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %+v", err)
+	}
+	cert, err := signer.sign(name, template, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, encodeKey(key), nil
+}
+
+func encodeKey(key *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func newSerialNumber() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// selfSignedCA signs the certificates with a CA stored in a secret of the cluster namespace
+type selfSignedCA struct {
+	cert    *x509.Certificate
+	key     *rsa.PrivateKey
+	certPEM []byte
+}
+
+func (c *ClusterController) loadOrCreateCA(cluster *cluster) (*selfSignedCA, error) {
+	secret, err := c.context.Clientset.CoreV1().Secrets(cluster.namespace).Get(caSecretName, metav1.GetOptions{})
+	if err == nil {
+		return parseCA(secret.Data[caCertFile], secret.Data[caKeyFile])
+	}
+	if !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get CA secret: %+v", err)
+	}
+
+	logger.Infof("creating CA of cluster in namespace %s", cluster.namespace)
+	key, err := rsa.GenerateKey(rand.Reader, rsaKeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %+v", err)
+	}
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("Rook CockroachDB CA %s", cluster.namespace)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %+v", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := c.createCertSecret(cluster, caSecretName, map[string][]byte{caCertFile: certPEM, caKeyFile: encodeKey(key)}); err != nil {
+		return nil, err
+	}
+	return parseCA(certPEM, encodeKey(key))
+}
+
+func parseCA(certPEM, keyPEM []byte) (*selfSignedCA, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, fmt.Errorf("invalid CA secret %s", caSecretName)
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA certificate: %+v", err)
+	}
+	key, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA key: %+v", err)
+	}
+	return &selfSignedCA{cert: cert, key: key, certPEM: certPEM}, nil
+}
+
+func (ca *selfSignedCA) sign(name string, template *x509.Certificate, key *rsa.PrivateKey) ([]byte, error) {
+	return ca.signPublicKey(name, template, &key.PublicKey)
+}
+
+func (ca *selfSignedCA) signPublicKey(name string, template *x509.Certificate, publicKey interface{}) ([]byte, error) {
+	serial, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(certValidity)
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, publicKey, ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign %s certificate: %+v", name, err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), nil
+}
+
+func (ca *selfSignedCA) caCert() []byte {
+	return ca.certPEM
+}
+
+// kubernetesSigner signs the certificates with the certificates API. The operator approves its own requests.
+type kubernetesSigner struct {
+	controller *ClusterController
+	namespace  string
+	ca         []byte
+}
+
+func (s *kubernetesSigner) sign(name string, template *x509.Certificate, key *rsa.PrivateKey) ([]byte, error) {
+	request, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:     template.Subject,
+		DNSNames:    template.DNSNames,
+		IPAddresses: template.IPAddresses,
+	}, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s certificate request: %+v", name, err)
+	}
+
+	usages := []certificatesv1beta1.KeyUsage{certificatesv1beta1.UsageDigitalSignature, certificatesv1beta1.UsageKeyEncipherment}
+	for _, usage := range template.ExtKeyUsage {
+		switch usage {
+		case x509.ExtKeyUsageServerAuth:
+			usages = append(usages, certificatesv1beta1.UsageServerAuth)
+		case x509.ExtKeyUsageClientAuth:
+			usages = append(usages, certificatesv1beta1.UsageClientAuth)
+		}
+	}
+
+	csrName := fmt.Sprintf("%s.%s.%s", appName, s.namespace, name)
+	csrs := s.controller.context.Clientset.CertificatesV1beta1().CertificateSigningRequests()
+	if err := csrs.Delete(csrName, &metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to delete previous certificate request %s: %+v", csrName, err)
+	}
+	csr, err := csrs.Create(&certificatesv1beta1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: csrName, Labels: createAppLabels()},
+		Spec: certificatesv1beta1.CertificateSigningRequestSpec{
+			Request: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: request}),
+			Usages:  usages,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate request %s: %+v", csrName, err)
+	}
+
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1beta1.CertificateSigningRequestCondition{
+		Type:    certificatesv1beta1.CertificateApproved,
+		Reason:  "RookApproved",
+		Message: "approved by the rook cockroachdb operator",
+	})
+	if _, err := csrs.UpdateApproval(csr); err != nil {
+		return nil, fmt.Errorf("failed to approve certificate request %s: %+v", csrName, err)
+	}
+
+	// wait for the certificate to be issued by the signer of the kubernetes cluster
+	var cert []byte
+	err = wait.Poll(csrInterval, csrTimeout, func() (bool, error) {
+		csr, err := csrs.Get(csrName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		cert = csr.Status.Certificate
+		return len(cert) > 0, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("certificate request %s was not signed: %+v", csrName, err)
+	}
+	return cert, nil
+}
+
+func (s *kubernetesSigner) caCert() []byte {
+	return s.ca
+}
+
+// writeClientCerts writes the root client certificate to a local dir for the cockroach commands run by the operator
+func (c *ClusterController) writeClientCerts(cluster *cluster) (string, error) {
+	secret, err := c.context.Clientset.CoreV1().Secrets(cluster.namespace).Get(clientRootSecretName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get root client certificate: %+v", err)
+	}
+
+	dir := path.Join(c.context.ConfigDir, cluster.namespace, "cockroach-certs")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create certs dir %s: %+v", dir, err)
+	}
+	for _, file := range []string{caCertFile, clientRootCertFile, clientRootKeyFile} {
+		// cockroach refuses keys that are readable by other users
+		if err := ioutil.WriteFile(path.Join(dir, file), secret.Data[file], 0600); err != nil {
+			return "", fmt.Errorf("failed to write %s: %+v", file, err)
+		}
+	}
+	return dir, nil
+}
@@ -0,0 +1,232 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cockroachdb
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	cockroachdbv1alpha1 "github.com/rook/rook/pkg/apis/cockroachdb.rook.io/v1alpha1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newSecureCluster(namespace string, issuer cockroachdbv1alpha1.CertificateIssuer) *cockroachdbv1alpha1.Cluster {
+	return &cockroachdbv1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "secure", Namespace: namespace},
+		Spec: cockroachdbv1alpha1.ClusterSpec{
+			Storage:      rookalpha.StorageScopeSpec{NodeCount: 3},
+			Secure:       true,
+			VolumeSize:   resource.MustParse("1Mi"),
+			Certificates: cockroachdbv1alpha1.CertificatesSpec{Issuer: issuer},
+		},
+	}
+}
+
+func verifyCert(t *testing.T, secret *v1.Secret, certFile, host string, usage x509.ExtKeyUsage) *x509.Certificate {
+	roots := x509.NewCertPool()
+	assert.True(t, roots.AppendCertsFromPEM(secret.Data[caCertFile]))
+	block, _ := pem.Decode(secret.Data[certFile])
+	if !assert.NotNil(t, block) {
+		return nil
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	assert.Nil(t, err)
+	_, err = cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: host, KeyUsages: []x509.ExtKeyUsage{usage}})
+	assert.Nil(t, err)
+	return cert
+}
+
+func TestSecureCluster(t *testing.T) {
+	namespace := "rook-cockroachdb-secure"
+	cluster := newSecureCluster(namespace, "")
+
+	configDir, err := ioutil.TempDir("", "cockroachdb")
+	assert.Nil(t, err)
+	defer os.RemoveAll(configDir)
+
+	initArgs := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithCombinedOutput: func(debug bool, actionName string, command string, arg ...string) (string, error) {
+			if arg[0] == "init" {
+				initArgs = arg
+			}
+			return "", nil
+		},
+	}
+	clientset := testop.New(3)
+	context := &clusterd.Context{Clientset: clientset, RookClientset: rookfake.NewSimpleClientset(cluster), Executor: executor, ConfigDir: configDir}
+	controller := NewClusterController(context, "rook/cockroachdb:mockTag")
+	controller.createInitRetryInterval = 1 * time.Millisecond
+	go simulatePodsRunning(clientset, namespace, cluster.Spec.Storage.NodeCount)
+	controller.onAdd(cluster)
+
+	// the node certificate is valid for the pods and the client service
+	nodeSecret, err := clientset.CoreV1().Secrets(namespace).Get(nodeSecretName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	cert := verifyCert(t, nodeSecret, nodeCertFile, "rook-cockroachdb-2.rook-cockroachdb.rook-cockroachdb-secure", x509.ExtKeyUsageServerAuth)
+	assert.Equal(t, "node", cert.Subject.CommonName)
+	verifyCert(t, nodeSecret, nodeCertFile, "cockroachdb-public", x509.ExtKeyUsageServerAuth)
+	verifyCert(t, nodeSecret, nodeCertFile, "localhost", x509.ExtKeyUsageClientAuth)
+
+	// the root client certificate is available to the applications
+	clientSecret, err := clientset.CoreV1().Secrets(namespace).Get(clientRootSecretName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	cert = verifyCert(t, clientSecret, clientRootCertFile, "", x509.ExtKeyUsageClientAuth)
+	assert.Equal(t, "root", cert.Subject.CommonName)
+	assert.NotNil(t, clientSecret.Data[clientRootKeyFile])
+
+	// the certificates are mounted in the pods
+	ss, err := clientset.AppsV1beta1().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, nodeSecretName, ss.Spec.Template.Spec.Volumes[1].Secret.SecretName)
+	assert.Equal(t, int32(0400), *ss.Spec.Template.Spec.Volumes[1].Secret.DefaultMode)
+	container := ss.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, v1.VolumeMount{Name: certsVolumeName, MountPath: certsDir}, container.VolumeMounts[1])
+	assert.Equal(t, v1.URISchemeHTTPS, container.LivenessProbe.HTTPGet.Scheme)
+	assert.Contains(t, container.Command[2], "start --logtostderr --certs-dir /cockroach/cockroach-certs --advertise-host")
+	assert.Equal(t, []v1.EnvVar{{Name: "COCKROACH_CHANNEL", Value: "kubernetes-secure"}}, container.Env)
+
+	// the cluster is initialized with the root client certificate
+	certsDir := path.Join(configDir, namespace, "cockroach-certs")
+	assert.Equal(t, "--certs-dir="+certsDir, initArgs[1])
+	key, err := ioutil.ReadFile(path.Join(certsDir, clientRootKeyFile))
+	assert.Nil(t, err)
+	assert.Equal(t, clientSecret.Data[clientRootKeyFile], key)
+
+	// the certificates are not replaced when the cluster is added again
+	assert.Nil(t, controller.createCertificates(newCluster(cluster, context)))
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(nodeSecretName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, nodeSecret.Data, secret.Data)
+
+	// only a missing certificate is created again, with the same CA
+	assert.Nil(t, clientset.CoreV1().Secrets(namespace).Delete(clientRootSecretName, &metav1.DeleteOptions{}))
+	assert.Nil(t, controller.createCertificates(newCluster(cluster, context)))
+	secret, err = clientset.CoreV1().Secrets(namespace).Get(nodeSecretName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, nodeSecret.Data, secret.Data)
+	secret, err = clientset.CoreV1().Secrets(namespace).Get(clientRootSecretName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.NotEqual(t, clientSecret.Data[clientRootKeyFile], secret.Data[clientRootKeyFile])
+	assert.Equal(t, clientSecret.Data[caCertFile], secret.Data[caCertFile])
+
+	// the certificates are not created when the secrets cannot be read
+	assert.Nil(t, clientset.CoreV1().Secrets(namespace).Delete(nodeSecretName, &metav1.DeleteOptions{}))
+	clientset.PrependReactor("get", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("mock failure")
+	})
+	assert.NotNil(t, controller.createCertificates(newCluster(cluster, context)))
+	clientset.ReactionChain = clientset.ReactionChain[1:]
+	_, err = clientset.CoreV1().Secrets(namespace).Get(nodeSecretName, metav1.GetOptions{})
+	assert.NotNil(t, err)
+}
+
+func TestKubernetesIssuer(t *testing.T) {
+	namespace := "rook-cockroachdb-csr"
+	cluster := newSecureCluster(namespace, cockroachdbv1alpha1.CertificateIssuerKubernetes)
+
+	// a CA plays the signer of the kubernetes cluster
+	signerController := NewClusterController(&clusterd.Context{Clientset: testop.New(1)}, "")
+	ca, err := signerController.loadOrCreateCA(newCluster(newSecureCluster("signer", ""), signerController.context))
+	assert.Nil(t, err)
+	caFile, err := ioutil.TempFile("", "ca.crt")
+	assert.Nil(t, err)
+	defer os.Remove(caFile.Name())
+	caFile.Write(ca.certPEM)
+	caFile.Close()
+	defaultCAFile, defaultInterval := serviceAccountCAFile, csrInterval
+	defer func() { serviceAccountCAFile, csrInterval = defaultCAFile, defaultInterval }()
+	serviceAccountCAFile = caFile.Name()
+	csrInterval = time.Millisecond
+
+	clientset := testop.New(1)
+	stop := make(chan struct{})
+	defer close(stop)
+	go simulateCSRSigner(clientset, ca, stop)
+
+	context := &clusterd.Context{Clientset: clientset}
+	controller := NewClusterController(context, "rook/cockroachdb:mockTag")
+	assert.Nil(t, controller.createCertificates(newCluster(cluster, context)))
+
+	nodeSecret, err := clientset.CoreV1().Secrets(namespace).Get(nodeSecretName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, ca.certPEM, nodeSecret.Data[caCertFile])
+	verifyCert(t, nodeSecret, nodeCertFile, "rook-cockroachdb-0.rook-cockroachdb.rook-cockroachdb-csr", x509.ExtKeyUsageServerAuth)
+	clientSecret, err := clientset.CoreV1().Secrets(namespace).Get(clientRootSecretName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	verifyCert(t, clientSecret, clientRootCertFile, "", x509.ExtKeyUsageClientAuth)
+
+	// no CA is created in the cluster namespace
+	_, err = clientset.CoreV1().Secrets(namespace).Get(caSecretName, metav1.GetOptions{})
+	assert.NotNil(t, err)
+}
+
+// simulateCSRSigner signs the approved certificate requests like the signer of the kubernetes controller manager
+func simulateCSRSigner(clientset *fake.Clientset, ca *selfSignedCA, stop chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(time.Millisecond):
+		}
+		csrs, err := clientset.CertificatesV1beta1().CertificateSigningRequests().List(metav1.ListOptions{})
+		if err != nil {
+			continue
+		}
+		for _, csr := range csrs.Items {
+			if len(csr.Status.Conditions) == 0 || len(csr.Status.Certificate) > 0 {
+				continue
+			}
+			block, _ := pem.Decode(csr.Spec.Request)
+			request, err := x509.ParseCertificateRequest(block.Bytes)
+			if err != nil {
+				continue
+			}
+			template := &x509.Certificate{
+				Subject:     request.Subject,
+				DNSNames:    request.DNSNames,
+				IPAddresses: request.IPAddresses,
+				ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			}
+			for _, usage := range csr.Spec.Usages {
+				if strings.HasPrefix(string(usage), "server") {
+					template.ExtKeyUsage = append(template.ExtKeyUsage, x509.ExtKeyUsageServerAuth)
+				}
+			}
+			csr.Status.Certificate, _ = ca.signPublicKey(csr.Name, template, request.PublicKey)
+			clientset.CertificatesV1beta1().CertificateSigningRequests().UpdateStatus(&csr)
+		}
+	}
+}
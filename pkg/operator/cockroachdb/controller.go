@@ -24,6 +24,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	opkit "github.com/rook/operator-kit"
@@ -31,6 +32,7 @@ import (
 	rookv1alpha2 "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
 	appsv1beta1 "k8s.io/api/apps/v1beta1"
 	"k8s.io/api/core/v1"
 	policyv1beta1 "k8s.io/api/policy/v1beta1"
@@ -48,9 +50,10 @@ const (
 	CustomResourceName             = "cluster"
 	CustomResourceNamePlural       = "clusters"
 	appName                        = "rook-cockroachdb"
+	clientServiceName              = "cockroachdb-public"
 	createInitRetryIntervalDefault = 6 * time.Second
 	createInitTimeout              = 5 * time.Minute
-	updateClusterInterval          = 30 * time.Second
+	updateClusterIntervalDefault   = 30 * time.Second
 	updateClusterTimeout           = 1 * time.Hour
 	httpPortDefault                = int32(8080)
 	httpPortName                   = "http"
@@ -60,6 +63,7 @@ const (
 	envVarChannel                  = "COCKROACH_CHANNEL"
 	envVarValChannelSecure         = "kubernetes-secure"
 	envVarValChannelInsecure       = "kubernetes-insecure"
+	cockroachdbControllerName      = "cockroachdb"
 )
 
 var ClusterResource = opkit.CustomResource{
@@ -75,6 +79,12 @@ type ClusterController struct {
 	context                 *clusterd.Context
 	containerImage          string
 	createInitRetryInterval time.Duration
+	updateClusterInterval   time.Duration
+	// updateLock protects pendingUpdates, which has an entry for each namespace with a running cluster update. The
+	// entry is the next spec to apply when the running update is done, or nil if no update is queued.
+	updateLock     sync.Mutex
+	pendingUpdates map[string]*cluster
+	updates        sync.WaitGroup
 }
 
 func NewClusterController(context *clusterd.Context, containerImage string) *ClusterController {
@@ -82,11 +92,14 @@ func NewClusterController(context *clusterd.Context, containerImage string) *Clu
 		context:                 context,
 		containerImage:          containerImage,
 		createInitRetryInterval: createInitRetryIntervalDefault,
+		updateClusterInterval:   updateClusterIntervalDefault,
+		pendingUpdates:          map[string]*cluster{},
 	}
 }
 
 type cluster struct {
 	context   *clusterd.Context
+	name      string
 	namespace string
 	spec      cockroachdbv1alpha1.ClusterSpec
 	ownerRef  metav1.OwnerReference
@@ -95,6 +108,7 @@ type cluster struct {
 func newCluster(c *cockroachdbv1alpha1.Cluster, context *clusterd.Context) *cluster {
 	return &cluster{
 		context:   context,
+		name:      c.Name,
 		namespace: c.Namespace,
 		spec:      c.Spec,
 		ownerRef:  clusterOwnerRef(c.Namespace, string(c.UID)),
@@ -121,7 +135,7 @@ func (c *ClusterController) StartWatch(namespace string, stopCh chan struct{}) e
 	}
 
 	logger.Infof("start watching cockroachdb clusters in all namespaces")
-	watcher := opkit.NewWatcher(ClusterResource, namespace, resourceHandlerFuncs, c.context.RookClientset.CockroachdbV1alpha1().RESTClient())
+	watcher := opkit.NewWatcher(ClusterResource, namespace, metrics.InstrumentHandlers(cockroachdbControllerName, resourceHandlerFuncs), c.context.RookClientset.CockroachdbV1alpha1().RESTClient())
 	go watcher.Watch(&cockroachdbv1alpha1.Cluster{}, stopCh)
 
 	return nil
@@ -135,26 +149,43 @@ func (c *ClusterController) onAdd(obj interface{}) {
 
 	if err := validateClusterSpec(cluster.spec); err != nil {
 		logger.Errorf("invalid cluster spec: %+v", err)
+		metrics.ReconcileFailed(cockroachdbControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, clusterObj, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "invalid cluster spec: %+v", err)
 		return
 	}
 
 	if err := c.createClientService(cluster); err != nil {
 		logger.Errorf("failed to create client service: %+v", err)
+		metrics.ReconcileFailed(cockroachdbControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, clusterObj, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "failed to create client service: %+v", err)
 		return
 	}
 
 	if err := c.createReplicaService(cluster); err != nil {
 		logger.Errorf("failed to create replica service: %+v", err)
+		metrics.ReconcileFailed(cockroachdbControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, clusterObj, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "failed to create replica service: %+v", err)
 		return
 	}
 
 	if err := c.createPodDisruptionBudget(cluster); err != nil {
 		logger.Errorf("failed to create pod disruption budget: %+v", err)
+		metrics.ReconcileFailed(cockroachdbControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, clusterObj, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "failed to create pod disruption budget: %+v", err)
+		return
+	}
+
+	if err := c.createCertificates(cluster); err != nil {
+		logger.Errorf("failed to create certificates: %+v", err)
+		metrics.ReconcileFailed(cockroachdbControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, clusterObj, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "failed to create certificates: %+v", err)
 		return
 	}
 
 	if err := c.createStatefulSet(cluster); err != nil {
 		logger.Errorf("failed to create stateful set: %+v", err)
+		metrics.ReconcileFailed(cockroachdbControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, clusterObj, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "failed to create stateful set: %+v", err)
 		return
 	}
 
@@ -174,16 +205,102 @@ func (c *ClusterController) onAdd(obj interface{}) {
 	})
 	if err != nil {
 		logger.Errorf("failed to initialize cluster in namespace %s: %+v", cluster.namespace, err)
+		metrics.ReconcileFailed(cockroachdbControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, clusterObj, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "failed to initialize cluster in namespace %s: %+v", cluster.namespace, err)
 		return
 	}
 
 	logger.Infof("succeeded creating and initializing cluster in namespace %s", cluster.namespace)
+	c.updateClusterStatus(cluster, cockroachdbv1alpha1.ClusterStateCreated, "")
+	k8sutil.RecordEvent(c.context.Recorder, clusterObj, v1.EventTypeNormal, k8sutil.EventReasonCreated, "created and initialized cluster in namespace %s", cluster.namespace)
 }
 
 func (c *ClusterController) onUpdate(oldObj, newObj interface{}) {
-	_ = oldObj.(*cockroachdbv1alpha1.Cluster).DeepCopy()
-	newCluster := newObj.(*cockroachdbv1alpha1.Cluster).DeepCopy()
-	logger.Infof("cluster %s updated in namespace %s", newCluster.Name, newCluster.Namespace)
+	oldClusterObj := oldObj.(*cockroachdbv1alpha1.Cluster).DeepCopy()
+	newClusterObj := newObj.(*cockroachdbv1alpha1.Cluster).DeepCopy()
+	if reflect.DeepEqual(oldClusterObj.Spec, newClusterObj.Spec) {
+		// only the status changed
+		return
+	}
+	logger.Infof("cluster %s updated in namespace %s", newClusterObj.Name, newClusterObj.Namespace)
+
+	cluster := newCluster(newClusterObj, c.context)
+	if err := validateClusterSpec(cluster.spec); err != nil {
+		logger.Errorf("invalid cluster spec: %+v", err)
+		metrics.ReconcileFailed(cockroachdbControllerName, "update")
+		c.updateClusterStatus(cluster, cockroachdbv1alpha1.ClusterStateError, fmt.Sprintf("invalid cluster spec: %+v", err))
+		return
+	}
+	if err := validateClusterUpdate(oldClusterObj.Spec, newClusterObj.Spec); err != nil {
+		logger.Errorf("invalid cluster update: %+v", err)
+		metrics.ReconcileFailed(cockroachdbControllerName, "update")
+		c.updateClusterStatus(cluster, cockroachdbv1alpha1.ClusterStateError, fmt.Sprintf("invalid cluster update: %+v", err))
+		return
+	}
+
+	c.startUpdate(cluster)
+}
+
+// startUpdate applies the spec of the cluster in the background since decommissioning and upgrading the nodes can take
+// hours, during which the events of the other clusters must still be handled. The updates of a cluster are applied one
+// at a time, and only the latest spec is applied when several updates are queued behind a running one.
+func (c *ClusterController) startUpdate(cluster *cluster) {
+	c.updateLock.Lock()
+	_, running := c.pendingUpdates[cluster.namespace]
+	if running {
+		c.pendingUpdates[cluster.namespace] = cluster
+	} else {
+		c.pendingUpdates[cluster.namespace] = nil
+		c.updates.Add(1)
+		go c.runUpdates(cluster)
+	}
+	c.updateLock.Unlock()
+
+	if running {
+		logger.Infof("cluster in namespace %s is being updated, the new spec is applied when the update is done", cluster.namespace)
+		c.updateClusterStatus(cluster, cockroachdbv1alpha1.ClusterStateUpdating, "waiting for the running update to finish")
+	}
+}
+
+// runUpdates applies the spec of the cluster and then the specs queued during the update
+func (c *ClusterController) runUpdates(cluster *cluster) {
+	defer c.updates.Done()
+	for cluster != nil {
+		c.updateClusterStatus(cluster, cockroachdbv1alpha1.ClusterStateUpdating, "")
+		if err := c.updateCluster(cluster); err != nil {
+			logger.Errorf("failed to update cluster in namespace %s: %+v", cluster.namespace, err)
+			metrics.ReconcileFailed(cockroachdbControllerName, "update")
+			c.updateClusterStatus(cluster, cockroachdbv1alpha1.ClusterStateError, fmt.Sprintf("failed to update cluster: %+v", err))
+		} else {
+			logger.Infof("succeeded updating cluster in namespace %s", cluster.namespace)
+			c.updateClusterStatus(cluster, cockroachdbv1alpha1.ClusterStateCreated, "")
+		}
+
+		c.updateLock.Lock()
+		namespace := cluster.namespace
+		cluster = c.pendingUpdates[namespace]
+		if cluster == nil {
+			delete(c.pendingUpdates, namespace)
+		} else {
+			c.pendingUpdates[namespace] = nil
+		}
+		c.updateLock.Unlock()
+	}
+}
+
+// updateClusterStatus sets the status on the latest version of the cluster. A failure is only logged since the
+// status does not change the cluster.
+func (c *ClusterController) updateClusterStatus(cluster *cluster, state cockroachdbv1alpha1.ClusterState, message string) {
+	clusters := c.context.RookClientset.CockroachdbV1alpha1().Clusters(cluster.namespace)
+	latest, err := clusters.Get(cluster.name, metav1.GetOptions{})
+	if err != nil {
+		logger.Warningf("failed to get cluster %s to update its status: %+v", cluster.name, err)
+		return
+	}
+	latest.Status = cockroachdbv1alpha1.ClusterStatus{State: state, Message: message}
+	if _, err := clusters.Update(latest); err != nil {
+		logger.Warningf("failed to update the status of cluster %s: %+v", cluster.name, err)
+	}
 }
 
 func (c *ClusterController) onDelete(obj interface{}) {
@@ -201,7 +318,7 @@ func (c *ClusterController) createClientService(cluster *cluster) error {
 	// automatically load balance connections to the different database pods.
 	clientService := &v1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "cockroachdb-public",
+			Name:      clientServiceName,
 			Namespace: cluster.namespace,
 			Labels:    createAppLabels(),
 		},
@@ -326,7 +443,7 @@ func (c *ClusterController) createStatefulSet(cluster *cluster) error {
 					Namespace: cluster.namespace,
 					Labels:    createAppLabels(),
 				},
-				Spec: createPodSpec(cluster, c.containerImage, httpPort, grpcPort),
+				Spec: createPodSpec(cluster, c.clusterImage(cluster), httpPort, grpcPort),
 			},
 			PodManagementPolicy: appsv1beta1.ParallelPodManagement,
 			UpdateStrategy: appsv1beta1.StatefulSetUpdateStrategy{
@@ -392,23 +509,51 @@ func createPodSpec(cluster *cluster, containerImage string, httpPort, grpcPort i
 		Containers: []v1.Container{createContainer(cluster, containerImage, httpPort, grpcPort)},
 		//  No pre-stop hook is required, a SIGTERM plus some time is all that's needed for graceful shutdown of a node.
 		TerminationGracePeriodSeconds: &terminationGracePeriodSeconds,
-		Volumes: []v1.Volume{
-			{
-				Name: volumeNameDataDir,
-				VolumeSource: v1.VolumeSource{
-					PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
-						ClaimName: volumeNameDataDir,
-					},
+		Volumes:                       createVolumes(cluster),
+	}
+}
+
+func createVolumes(cluster *cluster) []v1.Volume {
+	volumes := []v1.Volume{
+		{
+			Name: volumeNameDataDir,
+			VolumeSource: v1.VolumeSource{
+				PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{
+					ClaimName: volumeNameDataDir,
 				},
 			},
 		},
 	}
+	if cluster.spec.Secure {
+		// cockroach refuses keys that are readable by other users
+		keyMode := int32(0400)
+		volumes = append(volumes, v1.Volume{
+			Name: certsVolumeName,
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{
+					SecretName:  nodeSecretName,
+					DefaultMode: &keyMode,
+				},
+			},
+		})
+	}
+	return volumes
 }
 
 func createContainer(cluster *cluster, containerImage string, httpPort, grpcPort int32) v1.Container {
 	var envVarChannelVal string
+	probeScheme := v1.URISchemeHTTP
+	volumeMounts := []v1.VolumeMount{
+		{
+			Name:      volumeNameDataDir,
+			MountPath: "/cockroach/cockroach-data",
+		},
+	}
 	if cluster.spec.Secure {
 		envVarChannelVal = envVarValChannelSecure
+		// the http endpoints are served with tls in a secure cluster
+		probeScheme = v1.URISchemeHTTPS
+		volumeMounts = append(volumeMounts, v1.VolumeMount{Name: certsVolumeName, MountPath: certsDir})
 	} else {
 		envVarChannelVal = envVarValChannelInsecure
 	}
@@ -430,8 +575,9 @@ func createContainer(cluster *cluster, containerImage string, httpPort, grpcPort
 		LivenessProbe: &v1.Probe{
 			Handler: v1.Handler{
 				HTTPGet: &v1.HTTPGetAction{
-					Path: "/health",
-					Port: intstr.FromString(httpPortName),
+					Path:   "/health",
+					Port:   intstr.FromString(httpPortName),
+					Scheme: probeScheme,
 				},
 			},
 			InitialDelaySeconds: int32(30),
@@ -440,20 +586,16 @@ func createContainer(cluster *cluster, containerImage string, httpPort, grpcPort
 		ReadinessProbe: &v1.Probe{
 			Handler: v1.Handler{
 				HTTPGet: &v1.HTTPGetAction{
-					Path: "/health?ready=1",
-					Port: intstr.FromString(httpPortName),
+					Path:   "/health?ready=1",
+					Port:   intstr.FromString(httpPortName),
+					Scheme: probeScheme,
 				},
 			},
 			InitialDelaySeconds: int32(10),
 			PeriodSeconds:       int32(5),
 			FailureThreshold:    int32(2),
 		},
-		VolumeMounts: []v1.VolumeMount{
-			{
-				Name:      volumeNameDataDir,
-				MountPath: "/cockroach/cockroach-data",
-			},
-		},
+		VolumeMounts: volumeMounts,
 		Env: []v1.EnvVar{
 			{
 				Name:  envVarChannel,
@@ -490,9 +632,14 @@ func (c *ClusterController) initCluster(cluster *cluster) error {
 		return nil
 	}
 
+	securityFlag, err := c.clientSecurityFlag(cluster)
+	if err != nil {
+		return err
+	}
+
 	hostFlag := fmt.Sprintf("--host=%s", createQualifiedReplicaServiceName(0, cluster.namespace))
 	out, err := c.context.Executor.ExecuteCommandWithCombinedOutput(false, "cockroachdb init",
-		"/cockroach/cockroach", "init", "--insecure", hostFlag)
+		cockroachBinary, "init", securityFlag, hostFlag)
 	if err != nil {
 		return fmt.Errorf("cluster init failed for namespace %s: %+v. %s", cluster.namespace, err, out)
 	}
@@ -501,6 +648,28 @@ func (c *ClusterController) initCluster(cluster *cluster) error {
 	return nil
 }
 
+// clientSecurityFlag returns the flag of the cockroach client commands run by the operator
+func (c *ClusterController) clientSecurityFlag(cluster *cluster) (string, error) {
+	if !cluster.spec.Secure {
+		return "--insecure", nil
+	}
+
+	// the operator connects with the root client certificate
+	dir, err := c.writeClientCerts(cluster)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("--certs-dir=%s", dir), nil
+}
+
+// clusterImage returns the image of the database pods
+func (c *ClusterController) clusterImage(cluster *cluster) string {
+	if cluster.spec.Image != "" {
+		return cluster.spec.Image
+	}
+	return c.containerImage
+}
+
 func validateClusterSpec(spec cockroachdbv1alpha1.ClusterSpec) error {
 	if spec.Storage.NodeCount < 1 {
 		return fmt.Errorf("invalid node count: %d. Must be at least 1", spec.Storage.NodeCount)
@@ -517,6 +686,13 @@ func validateClusterSpec(spec cockroachdbv1alpha1.ClusterSpec) error {
 		return err
 	}
 
+	switch spec.Certificates.Issuer {
+	case "", cockroachdbv1alpha1.CertificateIssuerSelfSigned, cockroachdbv1alpha1.CertificateIssuerKubernetes:
+	default:
+		return fmt.Errorf("invalid certificate issuer: %s. Must be %s or %s", spec.Certificates.Issuer,
+			cockroachdbv1alpha1.CertificateIssuerSelfSigned, cockroachdbv1alpha1.CertificateIssuerKubernetes)
+	}
+
 	return nil
 }
 
@@ -580,9 +756,9 @@ func createQualifiedReplicaServiceName(replicaNum int, namespace string) string
 }
 
 func createCommand(cluster *cluster, httpPort, grpcPort int32) string {
-	var insecureFlag string
-	if !cluster.spec.Secure {
-		insecureFlag = "--insecure"
+	securityFlag := "--insecure"
+	if cluster.spec.Secure {
+		securityFlag = fmt.Sprintf("--certs-dir %s", certsDir)
 	}
 
 	var joinFlag string
@@ -599,5 +775,5 @@ func createCommand(cluster *cluster, httpPort, grpcPort int32) string {
 
 	// The use of qualified `hostname -f` is crucial: Other nodes aren't able to look up the unqualified hostname.
 	return fmt.Sprintf("exec /cockroach/cockroach start --logtostderr %s --advertise-host $(hostname -f) --http-host 0.0.0.0 --port %d --http-port %d %s --cache %s%% --max-sql-memory %s%%",
-		insecureFlag, grpcPort, httpPort, joinFlag, strconv.Itoa(cluster.spec.CachePercent), strconv.Itoa(cluster.spec.MaxSQLMemoryPercent))
+		securityFlag, grpcPort, httpPort, joinFlag, strconv.Itoa(cluster.spec.CachePercent), strconv.Itoa(cluster.spec.MaxSQLMemoryPercent))
 }
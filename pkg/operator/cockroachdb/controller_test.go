@@ -23,6 +23,7 @@ import (
 
 	cockroachdbv1alpha1 "github.com/rook/rook/pkg/apis/cockroachdb.rook.io/v1alpha1"
 	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	testop "github.com/rook/rook/pkg/operator/test"
@@ -142,7 +143,7 @@ func TestOnAdd(t *testing.T) {
 
 	// initialize the controller and its dependencies
 	clientset := testop.New(3)
-	context := &clusterd.Context{Clientset: clientset, Executor: executor}
+	context := &clusterd.Context{Clientset: clientset, RookClientset: rookfake.NewSimpleClientset(cluster), Executor: executor}
 	controller := NewClusterController(context, "rook/cockroachdb:mockTag")
 	controller.createInitRetryInterval = 1 * time.Millisecond
 
@@ -152,6 +153,11 @@ func TestOnAdd(t *testing.T) {
 	// call onAdd given the specified cluster
 	controller.onAdd(cluster)
 
+	// the cluster is reported as created
+	clusterObj, err := context.RookClientset.CockroachdbV1alpha1().Clusters(namespace).Get(cluster.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, cockroachdbv1alpha1.ClusterStateCreated, clusterObj.Status.State)
+
 	expectedServicePorts := []v1.ServicePort{
 		{Name: "grpc", Port: int32(456), TargetPort: intstr.FromInt(456)},
 		{Name: "http", Port: int32(123), TargetPort: intstr.FromInt(123)},
@@ -0,0 +1,441 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cockroachdb
+
+import (
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	cockroachdbv1alpha1 "github.com/rook/rook/pkg/apis/cockroachdb.rook.io/v1alpha1"
+	appsv1beta1 "k8s.io/api/apps/v1beta1"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	cockroachBinary = "/cockroach/cockroach"
+	// finalizes the upgrade so that the features of the new version are enabled
+	finalizeVersionStatement = "SET CLUSTER SETTING version = crdb_internal.node_executable_version()"
+	// the cluster cannot be scaled down below the default replication factor of cockroachdb
+	minScaleDownNodeCount = 3
+	numReplicasKey        = "num_replicas:"
+)
+
+// nodeStatus is the status of a cockroachdb node as reported by the node commands
+type nodeStatus struct {
+	id              string
+	address         string
+	live            bool
+	decommissioning bool
+	replicas        int
+}
+
+// updateCluster reconciles the stateful set with the node count and image of the cluster. Removed nodes are
+// decommissioned first, image changes are rolled out one pod at a time and new nodes are added last.
+func (c *ClusterController) updateCluster(cluster *cluster) error {
+	statefulSet, err := c.context.Clientset.AppsV1beta1().StatefulSets(cluster.namespace).Get(appName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get stateful set: %+v", err)
+	}
+	current := int(*statefulSet.Spec.Replicas)
+	desired := cluster.spec.Storage.NodeCount
+
+	if desired < current {
+		replicationFactor, err := c.replicationFactor(cluster)
+		if err != nil {
+			return err
+		}
+		if desired < replicationFactor {
+			return fmt.Errorf("cannot scale down to %d nodes, fewer than the replication factor %d of the cluster", desired, replicationFactor)
+		}
+		if err := c.removeNodes(cluster, desired, current); err != nil {
+			return err
+		}
+		current = desired
+	}
+
+	httpPort, grpcPort, err := getPortsFromSpec(cluster.spec.Network)
+	if err != nil {
+		return err
+	}
+	podSpec := createPodSpec(cluster, c.clusterImage(cluster), httpPort, grpcPort)
+	container := statefulSet.Spec.Template.Spec.Containers[0]
+	if container.Image != podSpec.Containers[0].Image {
+		if err := c.upgradeCluster(cluster, podSpec, current); err != nil {
+			return err
+		}
+	} else if !reflect.DeepEqual(container.Command, podSpec.Containers[0].Command) {
+		// the pods are restarted one at a time with the new settings and join list
+		logger.Infof("updating the pod template of the cluster in namespace %s", cluster.namespace)
+		err := c.updateStatefulSet(cluster.namespace, func(statefulSet *appsv1beta1.StatefulSet) {
+			statefulSet.Spec.Template.Spec = podSpec
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if desired > current {
+		return c.addNodes(cluster, current, desired)
+	}
+	return nil
+}
+
+// removeNodes decommissions the nodes of the highest ordinals and waits for their replicas to move to the remaining
+// nodes before their pods are removed
+func (c *ClusterController) removeNodes(cluster *cluster, desired, current int) error {
+	nodes, err := c.nodesOfOrdinals(cluster, desired, current)
+	if err != nil {
+		return err
+	}
+
+	if len(nodes) > 0 {
+		args := []string{"node", "decommission"}
+		for _, node := range nodes {
+			args = append(args, node.id)
+		}
+		args = append(args, "--wait=none", "--format=csv")
+		logger.Infof("decommissioning nodes %v of the cluster in namespace %s", args[2:len(nodes)+2], cluster.namespace)
+		c.updateClusterStatus(cluster, cockroachdbv1alpha1.ClusterStateUpdating, fmt.Sprintf("decommissioning nodes %v", args[2:len(nodes)+2]))
+
+		// the decommission command is idempotent and reports the replicas left on the nodes
+		err = wait.Poll(c.updateClusterInterval, updateClusterTimeout, func() (bool, error) {
+			out, err := c.runCockroach(cluster, args...)
+			if err != nil {
+				logger.Warningf("failed to decommission nodes: %+v", err)
+				return false, nil
+			}
+			statuses, err := parseNodeStatus(out)
+			if err != nil || len(statuses) != len(nodes) {
+				logger.Warningf("unexpected decommission status: %+v. %s", err, out)
+				return false, nil
+			}
+
+			replicas := 0
+			for _, status := range statuses {
+				replicas += status.replicas
+			}
+			if replicas > 0 {
+				logger.Infof("waiting for %d replicas to move off the decommissioned nodes", replicas)
+				return false, nil
+			}
+			return true, nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to decommission nodes: %+v", err)
+		}
+	}
+
+	logger.Infof("scaling down the cluster in namespace %s from %d to %d nodes", cluster.namespace, current, desired)
+	return c.updateStatefulSet(cluster.namespace, func(statefulSet *appsv1beta1.StatefulSet) {
+		replicas := int32(desired)
+		statefulSet.Spec.Replicas = &replicas
+	})
+}
+
+// addNodes extends the stateful set. Nodes that were decommissioned by an earlier scale down are recommissioned first
+// since their pods start again with the same data volumes.
+func (c *ClusterController) addNodes(cluster *cluster, current, desired int) error {
+	nodes, err := c.nodesOfOrdinals(cluster, current, desired)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"node", "recommission"}
+	for _, node := range nodes {
+		if node.decommissioning {
+			args = append(args, node.id)
+		}
+	}
+	if len(args) > 2 {
+		logger.Infof("recommissioning nodes %v of the cluster in namespace %s", args[2:], cluster.namespace)
+		if _, err := c.runCockroach(cluster, args...); err != nil {
+			return fmt.Errorf("failed to recommission nodes: %+v", err)
+		}
+	}
+
+	logger.Infof("scaling up the cluster in namespace %s from %d to %d nodes", cluster.namespace, current, desired)
+	return c.updateStatefulSet(cluster.namespace, func(statefulSet *appsv1beta1.StatefulSet) {
+		replicas := int32(desired)
+		statefulSet.Spec.Replicas = &replicas
+	})
+}
+
+// upgradeCluster rolls out the pod spec one pod at a time from the highest ordinal with a partitioned rolling update,
+// waiting for the cluster to be healthy after each pod. The cluster version is finalized when all the pods are upgraded.
+func (c *ClusterController) upgradeCluster(cluster *cluster, podSpec v1.PodSpec, nodeCount int) error {
+	image := podSpec.Containers[0].Image
+	if err := c.checkClusterHealth(cluster, nodeCount); err != nil {
+		return fmt.Errorf("cluster is not healthy before the upgrade: %+v", err)
+	}
+
+	logger.Infof("upgrading the cluster in namespace %s to image %s", cluster.namespace, image)
+	for ordinal := nodeCount - 1; ordinal >= 0; ordinal-- {
+		c.updateClusterStatus(cluster, cockroachdbv1alpha1.ClusterStateUpdating, fmt.Sprintf("upgrading pod %s-%d to image %s", appName, ordinal, image))
+		partition := int32(ordinal)
+		err := c.updateStatefulSet(cluster.namespace, func(statefulSet *appsv1beta1.StatefulSet) {
+			statefulSet.Spec.Template.Spec = podSpec
+			statefulSet.Spec.UpdateStrategy = appsv1beta1.StatefulSetUpdateStrategy{
+				Type:          appsv1beta1.RollingUpdateStatefulSetStrategyType,
+				RollingUpdate: &appsv1beta1.RollingUpdateStatefulSetStrategy{Partition: &partition},
+			}
+		})
+		if err != nil {
+			return err
+		}
+
+		err = wait.Poll(c.updateClusterInterval, updateClusterTimeout, func() (bool, error) {
+			if !c.isPodUpgraded(cluster.namespace, ordinal, image) {
+				return false, nil
+			}
+			if err := c.checkClusterHealth(cluster, nodeCount); err != nil {
+				logger.Infof("waiting for the cluster to be healthy: %+v", err)
+				return false, nil
+			}
+			return true, nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upgrade pod %s-%d: %+v", appName, ordinal, err)
+		}
+		logger.Infof("upgraded pod %s-%d in namespace %s", appName, ordinal, cluster.namespace)
+	}
+
+	// remove the partition so that later changes of the pod template roll out to all the pods
+	err := c.updateStatefulSet(cluster.namespace, func(statefulSet *appsv1beta1.StatefulSet) {
+		statefulSet.Spec.UpdateStrategy.RollingUpdate = nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.runCockroach(cluster, "sql", "--execute="+finalizeVersionStatement); err != nil {
+		return fmt.Errorf("failed to finalize the cluster version: %+v", err)
+	}
+	logger.Infof("upgraded the cluster in namespace %s to image %s", cluster.namespace, image)
+	return nil
+}
+
+// isPodUpgraded returns whether the pod of the ordinal runs the image and is ready
+func (c *ClusterController) isPodUpgraded(namespace string, ordinal int, image string) bool {
+	name := fmt.Sprintf("%s-%d", appName, ordinal)
+	pod, err := c.context.Clientset.CoreV1().Pods(namespace).Get(name, metav1.GetOptions{})
+	if err != nil {
+		logger.Infof("waiting for pod %s: %+v", name, err)
+		return false
+	}
+	if pod.DeletionTimestamp != nil || len(pod.Spec.Containers) == 0 || pod.Spec.Containers[0].Image != image {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// checkClusterHealth returns an error unless all the nodes that are not decommissioned are live
+func (c *ClusterController) checkClusterHealth(cluster *cluster, nodeCount int) error {
+	nodes, err := c.nodeStatus(cluster)
+	if err != nil {
+		return err
+	}
+
+	live := 0
+	for _, node := range nodes {
+		if node.decommissioning {
+			continue
+		}
+		if !node.live {
+			return fmt.Errorf("node %s at %s is not live", node.id, node.address)
+		}
+		live++
+	}
+	if live < nodeCount {
+		return fmt.Errorf("%d of %d nodes are live", live, nodeCount)
+	}
+	return nil
+}
+
+// nodesOfOrdinals returns the nodes advertised by the pods with an ordinal in [from, to)
+func (c *ClusterController) nodesOfOrdinals(cluster *cluster, from, to int) ([]nodeStatus, error) {
+	nodes, err := c.nodeStatus(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []nodeStatus{}
+	for _, node := range nodes {
+		if ordinal := nodeOrdinal(node.address); ordinal >= from && ordinal < to {
+			result = append(result, node)
+		}
+	}
+	return result, nil
+}
+
+// replicationFactor returns the number of replicas of the default zone config, which the ranges of the databases
+// inherit unless their zone config was changed
+func (c *ClusterController) replicationFactor(cluster *cluster) (int, error) {
+	out, err := c.runCockroach(cluster, "zone", "get", ".default")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the default zone config: %+v", err)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, numReplicasKey) {
+			continue
+		}
+		replicas, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, numReplicasKey)))
+		if err != nil {
+			return 0, fmt.Errorf("invalid replication factor in the default zone config: %s", line)
+		}
+		return replicas, nil
+	}
+	return 0, fmt.Errorf("missing replication factor in the default zone config: %s", out)
+}
+
+func (c *ClusterController) nodeStatus(cluster *cluster) ([]nodeStatus, error) {
+	out, err := c.runCockroach(cluster, "node", "status", "--decommission", "--format=csv")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get node status: %+v", err)
+	}
+	return parseNodeStatus(out)
+}
+
+// runCockroach runs a cockroach client command against the cluster through the client service
+func (c *ClusterController) runCockroach(cluster *cluster, args ...string) (string, error) {
+	securityFlag, err := c.clientSecurityFlag(cluster)
+	if err != nil {
+		return "", err
+	}
+	_, grpcPort, err := getPortsFromSpec(cluster.spec.Network)
+	if err != nil {
+		return "", err
+	}
+
+	args = append(args, securityFlag,
+		fmt.Sprintf("--host=%s.%s", clientServiceName, cluster.namespace),
+		fmt.Sprintf("--port=%d", grpcPort))
+	out, err := c.context.Executor.ExecuteCommandWithOutput(false, "cockroachdb "+args[0], cockroachBinary, args...)
+	if err != nil {
+		return "", fmt.Errorf("cockroach %s failed: %+v. %s", args[0], err, out)
+	}
+	return out, nil
+}
+
+// updateStatefulSet applies the change to the latest version of the stateful set
+func (c *ClusterController) updateStatefulSet(namespace string, change func(*appsv1beta1.StatefulSet)) error {
+	statefulSets := c.context.Clientset.AppsV1beta1().StatefulSets(namespace)
+	statefulSet, err := statefulSets.Get(appName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get stateful set: %+v", err)
+	}
+
+	change(statefulSet)
+	if _, err := statefulSets.Update(statefulSet); err != nil {
+		return fmt.Errorf("failed to update stateful set: %+v", err)
+	}
+	return nil
+}
+
+// parseNodeStatus parses the csv output of the node status and decommission commands
+func parseNodeStatus(output string) ([]nodeStatus, error) {
+	reader := csv.NewReader(strings.NewReader(output))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse node status: %+v", err)
+	}
+	if len(records) == 0 {
+		return []nodeStatus{}, nil
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[name] = i
+	}
+	if _, ok := columns["id"]; !ok {
+		return nil, fmt.Errorf("missing node id in node status: %s", output)
+	}
+	value := func(record []string, names ...string) string {
+		for _, name := range names {
+			if i, ok := columns[name]; ok {
+				return record[i]
+			}
+		}
+		return ""
+	}
+
+	nodes := []nodeStatus{}
+	for _, record := range records[1:] {
+		// skip the summary lines
+		if len(record) != len(records[0]) {
+			continue
+		}
+		node := nodeStatus{
+			id:              value(record, "id"),
+			address:         value(record, "address"),
+			live:            value(record, "is_live") == "true",
+			decommissioning: value(record, "is_decommissioning") == "true",
+		}
+		if replicas := value(record, "replicas", "gossiped_replicas"); replicas != "" {
+			if node.replicas, err = strconv.Atoi(replicas); err != nil {
+				return nil, fmt.Errorf("invalid replica count %s of node %s", replicas, node.id)
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// nodeOrdinal returns the ordinal of the pod from the advertised address of a node, e.g.,
+// rook-cockroachdb-2.rook-cockroachdb.rook-cockroachdb.svc.cluster.local:26257, or -1 for other addresses
+func nodeOrdinal(address string) int {
+	prefix := appName + "-"
+	if !strings.HasPrefix(address, prefix) {
+		return -1
+	}
+	host := strings.SplitN(strings.TrimPrefix(address, prefix), ".", 2)[0]
+	ordinal, err := strconv.Atoi(strings.SplitN(host, ":", 2)[0])
+	if err != nil {
+		return -1
+	}
+	return ordinal
+}
+
+func validateClusterUpdate(oldSpec, newSpec cockroachdbv1alpha1.ClusterSpec) error {
+	if newSpec.Storage.NodeCount < oldSpec.Storage.NodeCount && newSpec.Storage.NodeCount < minScaleDownNodeCount {
+		return fmt.Errorf("scaling down to %d nodes is not supported. Must be at least %d nodes", newSpec.Storage.NodeCount, minScaleDownNodeCount)
+	}
+	if oldSpec.Secure != newSpec.Secure {
+		return fmt.Errorf("changing secure is not supported")
+	}
+	if oldSpec.VolumeSize.Cmp(newSpec.VolumeSize) != 0 {
+		return fmt.Errorf("changing the volume size from %s to %s is not supported", oldSpec.VolumeSize.String(), newSpec.VolumeSize.String())
+	}
+
+	oldHTTPPort, oldGRPCPort, _ := getPortsFromSpec(oldSpec.Network)
+	newHTTPPort, newGRPCPort, _ := getPortsFromSpec(newSpec.Network)
+	if oldHTTPPort != newHTTPPort || oldGRPCPort != newGRPCPort {
+		return fmt.Errorf("changing the ports is not supported")
+	}
+	return nil
+}
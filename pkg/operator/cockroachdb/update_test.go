@@ -0,0 +1,197 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cockroachdb
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	cockroachdbv1alpha1 "github.com/rook/rook/pkg/apis/cockroachdb.rook.io/v1alpha1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// nodeStatusOutput returns the csv node status of nodes 1..n at ordinals 0..n-1
+func nodeStatusOutput(namespace string, count int, decommissioned map[int]bool) string {
+	lines := []string{"id,address,build,updated_at,started_at,is_live,gossiped_replicas,is_decommissioning,is_draining"}
+	for i := 0; i < count; i++ {
+		lines = append(lines, fmt.Sprintf("%d,%s.svc.cluster.local:26257,v2.0.2,,,%t,10,%t,false",
+			i+1, createQualifiedReplicaServiceName(i, namespace), !decommissioned[i], decommissioned[i]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestOnUpdate(t *testing.T) {
+	namespace := "rook-cockroachdb-315"
+	cluster := &cockroachdbv1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-824", Namespace: namespace},
+		Spec: cockroachdbv1alpha1.ClusterSpec{
+			Storage:             rookalpha.StorageScopeSpec{NodeCount: 5},
+			VolumeSize:          resource.MustParse("1Mi"),
+			CachePercent:        30,
+			MaxSQLMemoryPercent: 40,
+		},
+	}
+
+	decommissioned := map[int]bool{}
+	numReplicas := 3
+	commands := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, arg ...string) (string, error) {
+			assert.Equal(t, cockroachBinary, command)
+			commands = append(commands, strings.Join(arg, " "))
+			switch {
+			case arg[0] == "node" && arg[1] == "status":
+				return nodeStatusOutput(namespace, 5, decommissioned), nil
+			case arg[0] == "node" && arg[1] == "decommission":
+				// the replicas have moved off nodes 4 and 5
+				assert.Equal(t, []string{"4", "5", "--wait=none"}, arg[2:5])
+				decommissioned[3], decommissioned[4] = true, true
+				return "id,is_live,replicas,is_decommissioning,is_draining\n4,true,0,true,false\n5,true,0,true,false", nil
+			case arg[0] == "zone" && arg[1] == "get":
+				return fmt.Sprintf(".default\nrange_min_bytes: 1048576\nrange_max_bytes: 67108864\ngc:\n  ttlseconds: 90000\nnum_replicas: %d\nconstraints: []", numReplicas), nil
+			case arg[0] == "node" && arg[1] == "recommission":
+				assert.Equal(t, "4", arg[2])
+				decommissioned[3] = false
+			}
+			return "", nil
+		},
+	}
+	clientset := testop.New(3)
+	context := &clusterd.Context{Clientset: clientset, RookClientset: rookfake.NewSimpleClientset(cluster), Executor: executor}
+	controller := NewClusterController(context, "rook/cockroachdb:v1")
+	controller.updateClusterInterval = time.Millisecond
+	assert.Nil(t, controller.createStatefulSet(newCluster(cluster, context)))
+
+	// scale down decommissions the nodes of the highest ordinals
+	scaledDown := cluster.DeepCopy()
+	scaledDown.Spec.Storage.NodeCount = 3
+	controller.onUpdate(cluster, scaledDown)
+	controller.updates.Wait()
+	ss, err := clientset.AppsV1beta1().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), *ss.Spec.Replicas)
+	assert.True(t, decommissioned[3] && decommissioned[4])
+	assert.Contains(t, ss.Spec.Template.Spec.Containers[0].Command[2], "--join rook-cockroachdb-0.rook-cockroachdb.rook-cockroachdb-315,rook-cockroachdb-1.rook-cockroachdb.rook-cockroachdb-315,rook-cockroachdb-2.rook-cockroachdb.rook-cockroachdb-315 ")
+
+	// an upgrade rolls out the image to ready pods one at a time and finalizes the version
+	upgraded := scaledDown.DeepCopy()
+	upgraded.Spec.Image = "cockroachdb/cockroach:v2.1.0"
+	for i := 0; i < 3; i++ {
+		clientset.CoreV1().Pods(namespace).Create(&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%d", appName, i), Namespace: namespace},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Image: upgraded.Spec.Image}}},
+			Status:     v1.PodStatus{Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}},
+		})
+	}
+	commands = []string{}
+	controller.onUpdate(scaledDown, upgraded)
+	controller.updates.Wait()
+	ss, err = clientset.AppsV1beta1().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "cockroachdb/cockroach:v2.1.0", ss.Spec.Template.Spec.Containers[0].Image)
+	assert.Nil(t, ss.Spec.UpdateStrategy.RollingUpdate)
+	assert.Equal(t, "sql --execute="+finalizeVersionStatement+" --insecure --host=cockroachdb-public.rook-cockroachdb-315 --port=26257", commands[len(commands)-1])
+
+	// the cluster is not healthy unless enough nodes are live
+	assert.Nil(t, controller.checkClusterHealth(newCluster(upgraded, context), 3))
+	assert.NotNil(t, controller.checkClusterHealth(newCluster(upgraded, context), 4))
+
+	// scale up recommissions the nodes that come back with their data
+	scaledUp := upgraded.DeepCopy()
+	scaledUp.Spec.Storage.NodeCount = 4
+	controller.onUpdate(upgraded, scaledUp)
+	controller.updates.Wait()
+	ss, err = clientset.AppsV1beta1().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(4), *ss.Spec.Replicas)
+	assert.False(t, decommissioned[3])
+	assert.Contains(t, ss.Spec.Template.Spec.Containers[0].Command[2], "rook-cockroachdb-3.rook-cockroachdb.rook-cockroachdb-315 ")
+
+	// scaling down below the replication factor is rejected
+	numReplicas = 4
+	scaledDown = scaledUp.DeepCopy()
+	scaledDown.Spec.Storage.NodeCount = 3
+	controller.onUpdate(scaledUp, scaledDown)
+	controller.updates.Wait()
+	ss, err = clientset.AppsV1beta1().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(4), *ss.Spec.Replicas)
+	clusterObj, err := context.RookClientset.CockroachdbV1alpha1().Clusters(namespace).Get(cluster.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, cockroachdbv1alpha1.ClusterStateError, clusterObj.Status.State)
+	assert.Contains(t, clusterObj.Status.Message, "replication factor 4")
+
+	// the updates of a cluster run in the background one at a time, the latest queued spec is applied next
+	numReplicas = 3
+	controller.updateLock.Lock()
+	controller.pendingUpdates[namespace] = nil
+	controller.updateLock.Unlock()
+	queued := scaledUp.DeepCopy()
+	queued.Spec.Storage.NodeCount = 5
+	controller.onUpdate(scaledUp, queued)
+	controller.onUpdate(queued, scaledUp)
+	clusterObj, err = context.RookClientset.CockroachdbV1alpha1().Clusters(namespace).Get(cluster.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, cockroachdbv1alpha1.ClusterStateUpdating, clusterObj.Status.State)
+	controller.updates.Add(1)
+	go controller.runUpdates(newCluster(scaledUp, context))
+	controller.updates.Wait()
+	assert.Equal(t, 0, len(controller.pendingUpdates))
+	ss, err = clientset.AppsV1beta1().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(4), *ss.Spec.Replicas)
+	clusterObj, err = context.RookClientset.CockroachdbV1alpha1().Clusters(namespace).Get(cluster.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, cockroachdbv1alpha1.ClusterStateCreated, clusterObj.Status.State)
+
+	// scaling down below 3 nodes is rejected
+	scaledDown.Spec.Storage.NodeCount = 2
+	assert.NotNil(t, validateClusterUpdate(scaledUp.Spec, scaledDown.Spec))
+	scaledDown.Spec.Storage.NodeCount = 1
+	assert.Nil(t, validateClusterUpdate(scaledDown.Spec, scaledDown.Spec))
+
+	// changing the volume size is not supported
+	resized := scaledUp.DeepCopy()
+	resized.Spec.VolumeSize = resource.MustParse("2Mi")
+	assert.NotNil(t, validateClusterUpdate(scaledUp.Spec, resized.Spec))
+	assert.Nil(t, validateClusterUpdate(cluster.Spec, scaledUp.Spec))
+}
+
+func TestParseNodeStatus(t *testing.T) {
+	nodes, err := parseNodeStatus(nodeStatusOutput("ns", 2, map[int]bool{1: true}) + "\n(2 rows)")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(nodes))
+	assert.Equal(t, "1", nodes[0].id)
+	assert.True(t, nodes[0].live)
+	assert.False(t, nodes[0].decommissioning)
+	assert.Equal(t, 10, nodes[0].replicas)
+	assert.True(t, nodes[1].decommissioning)
+	assert.Equal(t, 1, nodeOrdinal(nodes[1].address))
+	assert.Equal(t, -1, nodeOrdinal("other-1.svc:26257"))
+
+	_, err = parseNodeStatus("address\nfoo")
+	assert.NotNil(t, err)
+}
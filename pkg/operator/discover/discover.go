@@ -42,9 +42,12 @@ const (
 	discoverDaemonsetName             = "rook-discover"
 	discoverDaemonsetTolerationEnv    = "DISCOVER_TOLERATION"
 	discoverDaemonsetTolerationKeyEnv = "DISCOVER_TOLERATION_KEY"
-	deviceInUseCMName                 = "local-device-in-use-cluster-%s-node-%s"
-	deviceInUseAppName                = "rook-claimed-devices"
-	deviceInUseClusterAttr            = "rook.io/cluster"
+
+	// the configmaps in which earlier versions recorded the devices claimed by the clusters
+	deviceInUseAppName     = "rook-claimed-devices"
+	deviceInUseNodeAttr    = "rook.io/node"
+	deviceInUseClusterAttr = "rook.io/cluster"
+	deviceInUseData        = "devices"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-discover")
@@ -155,7 +158,9 @@ func (d *Discover) createDiscoverDaemonSet(namespace, discoverImage, securityAcc
 							},
 						},
 					},
-					HostNetwork: false,
+					// udev events are only received in the host network namespace
+					HostNetwork: true,
+					DNSPolicy:   v1.DNSClusterFirstWithHostNet,
 				},
 			},
 		},
@@ -191,17 +196,17 @@ func (d *Discover) createDiscoverDaemonSet(namespace, discoverImage, securityAcc
 }
 
 // ListDevices lists all devices discovered on all nodes or specific node if node name is provided.
-func ListDevices(context *clusterd.Context, namespace, nodeName string) (map[string][]sys.LocalDisk, error) {
-	var devices map[string][]sys.LocalDisk
+func ListDevices(context *clusterd.Context, nodeName string) (map[string][]rookalpha.NodeDevice, error) {
+	var devices map[string][]rookalpha.NodeDevice
 	listOpts := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", k8sutil.AppAttr, discoverDaemon.AppName)}
-	// wait for device discovery configmaps
+	// wait for the devices to be discovered
 	retryCount := 0
 	retryMax := 30
 	sleepTime := 5
 	for {
 		retryCount++
 		if retryCount > retryMax {
-			return devices, fmt.Errorf("exceeded max retry count waiting for device configmap to appear")
+			return devices, fmt.Errorf("exceeded max retry count waiting for node devices to appear")
 		}
 
 		if retryCount > 1 {
@@ -209,34 +214,21 @@ func ListDevices(context *clusterd.Context, namespace, nodeName string) (map[str
 			<-time.After(time.Duration(sleepTime) * time.Second)
 		}
 
-		cms, err := context.Clientset.CoreV1().ConfigMaps(namespace).List(listOpts)
+		nodes, err := context.RookClientset.RookV1alpha2().NodeDevices().List(listOpts)
 		if err != nil {
-			logger.Warningf("failed to list device configmaps: %v", err)
-			return devices, fmt.Errorf("failed to list device configmaps: %+v", err)
+			logger.Warningf("failed to list node devices: %v", err)
+			return devices, fmt.Errorf("failed to list node devices: %+v", err)
 		}
-		if len(cms.Items) == 0 {
-			logger.Infof("no configmap match, retry #%d", retryCount)
+		if len(nodes.Items) == 0 {
+			logger.Infof("no node devices found, retry #%d", retryCount)
 			continue
 		}
-		devices = make(map[string][]sys.LocalDisk, len(cms.Items))
-		for _, cm := range cms.Items {
-			node := cm.ObjectMeta.Labels[discoverDaemon.NodeAttr]
-			if len(nodeName) > 0 && node != nodeName {
-				continue
-			}
-			deviceJson := cm.Data[discoverDaemon.LocalDiskCMData]
-			logger.Debugf("node %s, device %s", node, deviceJson)
-
-			if len(node) == 0 || len(deviceJson) == 0 {
+		devices = make(map[string][]rookalpha.NodeDevice, len(nodes.Items))
+		for _, node := range nodes.Items {
+			if len(nodeName) > 0 && node.Name != nodeName {
 				continue
 			}
-			var d []sys.LocalDisk
-			err = json.Unmarshal([]byte(deviceJson), &d)
-			if err != nil {
-				logger.Warningf("failed to unmarshal %s", deviceJson)
-				continue
-			}
-			devices[node] = d
+			devices[node.Name] = node.Devices
 		}
 		break
 	}
@@ -244,43 +236,16 @@ func ListDevices(context *clusterd.Context, namespace, nodeName string) (map[str
 	return devices, nil
 }
 
-// ListDevicesInUse lists all devices on a node that are already used by existing clusters.
-func ListDevicesInUse(context *clusterd.Context, namespace, nodeName string) ([]sys.LocalDisk, error) {
-	var devices []sys.LocalDisk
-
-	if len(nodeName) == 0 {
-		return devices, fmt.Errorf("empty node name")
-	}
-
-	listOpts := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", k8sutil.AppAttr, deviceInUseAppName)}
-	cms, err := context.Clientset.CoreV1().ConfigMaps(namespace).List(listOpts)
+// NodeDevicesDiscovered returns whether the discover daemon has reported the devices of the node
+func NodeDevicesDiscovered(context *clusterd.Context, nodeName string) (bool, error) {
+	_, err := context.RookClientset.RookV1alpha2().NodeDevices().Get(nodeName, metav1.GetOptions{})
 	if err != nil {
-		return devices, fmt.Errorf("failed to list device in use configmaps: %+v", err)
-	}
-
-	for _, cm := range cms.Items {
-		node := cm.ObjectMeta.Labels[discoverDaemon.NodeAttr]
-		if node != nodeName {
-			continue
-		}
-		deviceJson := cm.Data[discoverDaemon.LocalDiskCMData]
-		logger.Debugf("node %s, device in use %s", node, deviceJson)
-
-		if len(node) == 0 || len(deviceJson) == 0 {
-			continue
-		}
-		var d []sys.LocalDisk
-		err = json.Unmarshal([]byte(deviceJson), &d)
-		if err != nil {
-			logger.Warningf("failed to unmarshal %s", deviceJson)
-			continue
-		}
-		for i := range d {
-			devices = append(devices, d[i])
+		if kserrors.IsNotFound(err) {
+			return false, nil
 		}
+		return false, fmt.Errorf("failed to get devices of node %s. %+v", nodeName, err)
 	}
-	logger.Debugf("devices in use %+v", devices)
-	return devices, nil
+	return true, nil
 }
 
 // FreeDevices frees up devices used by a cluster on a node.
@@ -288,48 +253,90 @@ func FreeDevices(context *clusterd.Context, nodeName, clusterName string) error
 	if len(nodeName) == 0 || len(clusterName) == 0 {
 		return nil
 	}
-	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
-	cmName := fmt.Sprintf(deviceInUseCMName, clusterName, nodeName)
-	// delete configmap
-	err := context.Clientset.CoreV1().ConfigMaps(namespace).Delete(cmName, &metav1.DeleteOptions{})
+	if err := migrateDevicesInUse(context, nodeName); err != nil {
+		return err
+	}
+	err := updateDevices(context, nodeName, func(devices []rookalpha.NodeDevice) {
+		freeClusterDevices(devices, clusterName)
+	})
 	if err != nil && !kserrors.IsNotFound(err) {
-		return fmt.Errorf("failed to delete configmap %s/%s. %+v", namespace, cmName, err)
+		return fmt.Errorf("failed to free devices of cluster %s on node %s. %+v", clusterName, nodeName, err)
 	}
-
 	return nil
 }
 
 // FreeDevicesByCluster frees devices on all nodes that are used by the cluster
 func FreeDevicesByCluster(context *clusterd.Context, clusterName string) error {
 	logger.Infof("freeing devices used by cluster %s", clusterName)
-	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
-	listOpts := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", deviceInUseClusterAttr, clusterName)}
-	cms, err := context.Clientset.CoreV1().ConfigMaps(namespace).List(listOpts)
+	if err := migrateDevicesInUse(context, ""); err != nil {
+		return err
+	}
+	listOpts := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", k8sutil.AppAttr, discoverDaemon.AppName)}
+	nodes, err := context.RookClientset.RookV1alpha2().NodeDevices().List(listOpts)
 	if err != nil {
-		return fmt.Errorf("failed to list device in use configmaps for cluster %s: %+v", clusterName, err)
+		return fmt.Errorf("failed to list node devices for cluster %s: %+v", clusterName, err)
 	}
 
-	for _, cm := range cms.Items {
-		// delete configmap
-		cmName := cm.Name
-		err := context.Clientset.CoreV1().ConfigMaps(namespace).Delete(cmName, &metav1.DeleteOptions{})
-		logger.Infof("deleting configmap %s: %+v", cmName, err)
-		if err != nil {
+	for _, node := range nodes.Items {
+		if err := FreeDevices(context, node.Name, clusterName); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// ListClusterDevices lists the paths of the devices claimed by the cluster on each node
+func ListClusterDevices(context *clusterd.Context, clusterName string) (map[string][]string, error) {
+	if err := migrateDevicesInUse(context, ""); err != nil {
+		return nil, err
+	}
+	listOpts := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", k8sutil.AppAttr, discoverDaemon.AppName)}
+	nodes, err := context.RookClientset.RookV1alpha2().NodeDevices().List(listOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list node devices for cluster %s: %+v", clusterName, err)
+	}
+
+	devices := map[string][]string{}
+	for _, node := range nodes.Items {
+		for _, device := range node.Devices {
+			if device.Cluster != clusterName {
+				continue
+			}
+			path := device.FullPath
+			if path == "" {
+				path = "/dev/" + device.Name
+			}
+			devices[node.Name] = append(devices[node.Name], path)
+		}
+	}
+	return devices, nil
+}
+
+// SetDeviceOSD records the OSD that was created on a device claimed by the cluster
+func SetDeviceOSD(context *clusterd.Context, nodeName, clusterName, devicePath string, osdID int) error {
+	return updateDevices(context, nodeName, func(devices []rookalpha.NodeDevice) {
+		for i := range devices {
+			if devices[i].Cluster == clusterName && matchesNodeDevice(&devices[i], devicePath) {
+				id := osdID
+				devices[i].OSDID = &id
+			}
+		}
+	})
+}
+
 // GetAvailableDevices conducts outer join using input filters with free devices that a node has. It marks the devices from join result as in-use.
-func GetAvailableDevices(context *clusterd.Context, nodeName, clusterName string, devices []rookalpha.Device, filter string, useAllDevices bool) ([]rookalpha.Device, error) {
+func GetAvailableDevices(context *clusterd.Context, nodeName, clusterName string, selection *rookalpha.Selection) ([]rookalpha.Device, error) {
 	results := []rookalpha.Device{}
-	if len(devices) == 0 && len(filter) == 0 && !useAllDevices {
+	devices := selection.Devices
+	filter := selection.DeviceFilter
+	if len(devices) == 0 && len(filter) == 0 && !selection.GetUseAllDevices() && !selection.HasDeviceSelectors() {
 		return results, nil
 	}
-	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
+	if err := migrateDevicesInUse(context, nodeName); err != nil {
+		return results, err
+	}
 	// find all devices
-	allDevices, err := ListDevices(context, namespace, nodeName)
+	allDevices, err := ListDevices(context, nodeName)
 	if err != nil {
 		return results, err
 	}
@@ -338,86 +345,209 @@ func GetAvailableDevices(context *clusterd.Context, nodeName, clusterName string
 	if !ok {
 		return results, fmt.Errorf("node %s has no devices", nodeName)
 	}
-	// find those in use on the node
-	devicesInUse, err := ListDevicesInUse(context, namespace, nodeName)
-	if err != nil {
-		return results, err
-	}
 
-	nodeDevices := []sys.LocalDisk{}
+	// the devices in use by this cluster are retained so the provisioner will continue to configure the same OSDs
+	nodeDevices := []rookalpha.NodeDevice{}
 	for _, nodeDevice := range nodeAllDevices {
-		// TODO: Filter out devices that are in use by another cluster.
-		// We need to retain the devices in use for this cluster so the provisioner will continue to configure the same OSDs.
-		for _, device := range devicesInUse {
-			if nodeDevice.Name == device.Name {
-				break
-			}
+		if nodeDevice.Cluster != "" && nodeDevice.Cluster != clusterName {
+			logger.Debugf("device %s on node %s is in use by cluster %s", nodeDevice.Name, nodeName, nodeDevice.Cluster)
+			continue
 		}
 		nodeDevices = append(nodeDevices, nodeDevice)
 	}
-	claimedDevices := []sys.LocalDisk{}
+	claimedDevices := map[string]bool{}
 	// now those left are free to use
 	if len(devices) > 0 {
 		for i := range devices {
 			for j := range nodeDevices {
-				if devices[i].Name == nodeDevices[j].Name {
-					results = append(results, devices[i])
-					claimedDevices = append(claimedDevices, nodeDevices[j])
+				if matchesDevice(devices[i], &nodeDevices[j]) {
+					d := devices[i]
+					if d.Name == "" {
+						d.Name = nodeDevices[j].Name
+					}
+					if d.FullPath == "" {
+						d.FullPath = nodeDevices[j].FullPath
+					}
+					results = append(results, d)
+					claimedDevices[nodeDevices[j].Name] = true
 				}
 			}
 		}
-	} else if len(filter) >= 0 {
-		for i := range nodeDevices {
-			//TODO support filter based on other keys
-			matched, err := regexp.Match(filter, []byte(nodeDevices[i].Name))
-			if err == nil && matched {
-				d := rookalpha.Device{
-					Name: nodeDevices[i].Name,
-				}
-				claimedDevices = append(claimedDevices, nodeDevices[i])
-				results = append(results, d)
-			}
+	} else {
+		selected, err := selectDevices(nodeDevices, selection, clusterName)
+		if err != nil {
+			return results, fmt.Errorf("failed to select devices on node %s. %+v", nodeName, err)
 		}
-	} else if useAllDevices {
-		for i := range nodeDevices {
+		for _, nodeDevice := range selected {
 			d := rookalpha.Device{
-				Name: nodeDevices[i].Name,
+				Name:     nodeDevice.Name,
+				FullPath: nodeDevice.FullPath,
 			}
 			results = append(results, d)
-			claimedDevices = append(claimedDevices, nodeDevices[i])
+			claimedDevices[nodeDevice.Name] = true
 		}
 	}
 	// mark these devices in use
 	if len(claimedDevices) > 0 {
-		deviceJson, err := json.Marshal(claimedDevices)
+		err := updateDevices(context, nodeName, func(devices []rookalpha.NodeDevice) {
+			for i := range devices {
+				if claimedDevices[devices[i].Name] && devices[i].Cluster == "" {
+					devices[i].Cluster = clusterName
+					devices[i].Rejected = ""
+				}
+			}
+		})
 		if err != nil {
-			logger.Infof("failed to marshal: %v", err)
-			return results, err
+			return results, fmt.Errorf("failed to update device in use for cluster %s node %s: %v", clusterName, nodeName, err)
 		}
-		data := make(map[string]string, 1)
-		data[discoverDaemon.LocalDiskCMData] = string(deviceJson)
-
-		cm := &v1.ConfigMap{
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      fmt.Sprintf(deviceInUseCMName, clusterName, nodeName),
-				Namespace: namespace,
-				Labels: map[string]string{
-					k8sutil.AppAttr:         deviceInUseAppName,
-					discoverDaemon.NodeAttr: nodeName,
-					deviceInUseClusterAttr:  clusterName,
-				},
-			},
-			Data: data,
+	}
+	return results, nil
+}
+
+// selectDevices returns the devices that match the device filter and the device selectors. If a max number of devices
+// is set, the devices already claimed by the cluster are kept first so the existing OSDs are not dropped. The devices
+// rejected by the discovery are skipped since the OSDs would not be created on them.
+func selectDevices(nodeDevices []rookalpha.NodeDevice, selection *rookalpha.Selection, clusterName string) ([]rookalpha.NodeDevice, error) {
+	var claimed, unclaimed []rookalpha.NodeDevice
+	for i := range nodeDevices {
+		if nodeDevices[i].Rejected != "" && nodeDevices[i].Cluster != clusterName {
+			logger.Debugf("device %s is rejected. %s", nodeDevices[i].Name, nodeDevices[i].Rejected)
+			continue
 		}
-		_, err = context.Clientset.CoreV1().ConfigMaps(namespace).Create(cm)
+		if selection.DeviceFilter != "" {
+			//TODO support filter based on other keys
+			matched, err := regexp.Match(selection.DeviceFilter, []byte(nodeDevices[i].Name))
+			if err != nil || !matched {
+				continue
+			}
+		}
+		matched, err := MatchesDeviceSelectors(selection, toLocalDisk(&nodeDevices[i]))
 		if err != nil {
-			if !kserrors.IsAlreadyExists(err) {
-				return results, fmt.Errorf("failed to update device in use for cluster %s node %s: %v", clusterName, nodeName, err)
+			return nil, err
+		}
+		if !matched {
+			logger.Debugf("device %s does not match the device selectors", nodeDevices[i].Name)
+			continue
+		}
+		if nodeDevices[i].Cluster == clusterName {
+			claimed = append(claimed, nodeDevices[i])
+		} else {
+			unclaimed = append(unclaimed, nodeDevices[i])
+		}
+	}
+
+	selected := append(claimed, unclaimed...)
+	if selection.MaxDevices > 0 && len(selected) > selection.MaxDevices {
+		logger.Infof("selecting %d of the %d matching devices", selection.MaxDevices, len(selected))
+		selected = selected[:selection.MaxDevices]
+	}
+	return selected, nil
+}
+
+// migrateDevicesInUse records the devices claimed in the configmaps of earlier versions as claimed by their cluster in
+// the node devices, so that the devices of the existing OSDs are still claimed by their cluster after an upgrade. The
+// configmaps are deleted once migrated. The configmap of a node whose devices were not discovered yet is kept until
+// they are. The devices of all the nodes are migrated if no node name is given.
+func migrateDevicesInUse(context *clusterd.Context, nodeName string) error {
+	namespace := os.Getenv(k8sutil.PodNamespaceEnvVar)
+	selector := fmt.Sprintf("%s=%s", k8sutil.AppAttr, deviceInUseAppName)
+	if nodeName != "" {
+		selector = fmt.Sprintf("%s,%s=%s", selector, deviceInUseNodeAttr, nodeName)
+	}
+	cms, err := context.Clientset.CoreV1().ConfigMaps(namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return fmt.Errorf("failed to list device in use configmaps: %+v", err)
+	}
+
+	for _, cm := range cms.Items {
+		node := cm.Labels[deviceInUseNodeAttr]
+		clusterName := cm.Labels[deviceInUseClusterAttr]
+		var inUse []sys.LocalDisk
+		if err := json.Unmarshal([]byte(cm.Data[deviceInUseData]), &inUse); err != nil {
+			logger.Warningf("failed to unmarshal the devices in use of configmap %s. %+v", cm.Name, err)
+		}
+
+		if node != "" && clusterName != "" && len(inUse) > 0 {
+			err := updateDevices(context, node, func(devices []rookalpha.NodeDevice) {
+				for i := range devices {
+					for j := range inUse {
+						path := sys.GetPersistentDevicePath(&inUse[j])
+						if path == "" {
+							path = inUse[j].Name
+						}
+						if devices[i].Cluster == "" && matchesNodeDevice(&devices[i], path) {
+							devices[i].Cluster = clusterName
+						}
+					}
+				}
+			})
+			if kserrors.IsNotFound(err) {
+				logger.Infof("devices of node %s not discovered yet, not migrating the devices in use by cluster %s", node, clusterName)
+				continue
 			}
-			if _, err := context.Clientset.CoreV1().ConfigMaps(namespace).Update(cm); err != nil {
-				return results, fmt.Errorf("failed to update devices in use. %+v", err)
+			if err != nil {
+				return fmt.Errorf("failed to migrate the devices in use by cluster %s on node %s. %+v", clusterName, node, err)
 			}
+			logger.Infof("migrated the devices in use by cluster %s on node %s", clusterName, node)
+		}
+
+		err := context.Clientset.CoreV1().ConfigMaps(namespace).Delete(cm.Name, &metav1.DeleteOptions{})
+		if err != nil && !kserrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete device in use configmap %s. %+v", cm.Name, err)
 		}
 	}
-	return results, nil
+	return nil
+}
+
+// updateDevices applies the change to the devices of the node, retrying if the discover daemon updated them at the
+// same time
+func updateDevices(context *clusterd.Context, nodeName string, change func([]rookalpha.NodeDevice)) error {
+	for {
+		node, err := context.RookClientset.RookV1alpha2().NodeDevices().Get(nodeName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		change(node.Devices)
+		_, err = context.RookClientset.RookV1alpha2().NodeDevices().Update(node)
+		if err == nil || !kserrors.IsConflict(err) {
+			return err
+		}
+		logger.Infof("devices of node %s were modified, retrying the update", nodeName)
+	}
+}
+
+// freeClusterDevices releases the devices claimed by the cluster
+func freeClusterDevices(devices []rookalpha.NodeDevice, clusterName string) {
+	for i := range devices {
+		if devices[i].Cluster == clusterName {
+			devices[i].Cluster = ""
+			devices[i].OSDID = nil
+		}
+	}
+}
+
+// matchesDevice returns whether the device of the storage spec refers to the discovered device. Devices are matched by
+// their persistent path if it is set, otherwise by their kernel name.
+func matchesDevice(device rookalpha.Device, nodeDevice *rookalpha.NodeDevice) bool {
+	if device.FullPath != "" {
+		return matchesNodeDevice(nodeDevice, device.FullPath)
+	}
+	return device.Name == nodeDevice.Name
+}
+
+// matchesNodeDevice returns whether the name or path refers to the discovered device
+func matchesNodeDevice(nodeDevice *rookalpha.NodeDevice, nameOrPath string) bool {
+	return sys.MatchesDevice(toLocalDisk(nodeDevice), nameOrPath)
+}
+
+// toLocalDisk returns the discovered properties of the device that the devices are matched against
+func toLocalDisk(nodeDevice *rookalpha.NodeDevice) *sys.LocalDisk {
+	return &sys.LocalDisk{
+		Name:       nodeDevice.Name,
+		DevLinks:   nodeDevice.DevLinks,
+		Size:       nodeDevice.Size,
+		Rotational: nodeDevice.Rotational,
+		Vendor:     nodeDevice.Vendor,
+		Model:      nodeDevice.Model,
+	}
 }
@@ -18,18 +18,22 @@ limitations under the License.
 package discover
 
 import (
+	"fmt"
 	"os"
 	"testing"
 
 	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
 	"github.com/rook/rook/pkg/clusterd"
 	discoverDaemon "github.com/rook/rook/pkg/daemon/discover"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"github.com/rook/rook/pkg/operator/test"
+	"github.com/rook/rook/pkg/util/sys"
 
 	"github.com/stretchr/testify/assert"
 
 	"k8s.io/api/core/v1"
+	kserrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -65,10 +69,12 @@ func TestStartDiscoveryDaemonset(t *testing.T) {
 	image := agentDS.Spec.Template.Spec.Containers[0].Image
 	assert.Equal(t, "rook/rook:myversion", image)
 	assert.Nil(t, agentDS.Spec.Template.Spec.Tolerations)
+	assert.True(t, agentDS.Spec.Template.Spec.HostNetwork)
 }
 
 func TestGetAvailableDevices(t *testing.T) {
 	clientset := test.New(3)
+	rookClientset := rookfake.NewSimpleClientset()
 
 	ns := "rook-system"
 	nodeName := "node123"
@@ -78,23 +84,24 @@ func TestGetAvailableDevices(t *testing.T) {
 	os.Setenv(k8sutil.PodNameEnvVar, "rook-operator")
 	defer os.Unsetenv(k8sutil.PodNameEnvVar)
 
-	data := make(map[string]string, 1)
-	data[discoverDaemon.LocalDiskCMData] = `[{"name":"sdd","parent":"","hasChildren":false,"devLinks":"/dev/disk/by-id/scsi-36001405f826bd553d8c4dbf9f41c18be    /dev/disk/by-id/wwn-0x6001405f826bd553d8c4dbf9f41c18be /dev/disk/by-path/ip-127.0.0.1:3260-iscsi-iqn.2016-06.world.srv:storage.target01-lun-1","size":10737418240,"uuid":"","serial":"36001405f826bd553d8c4dbf9f41c18be","type":"disk","rotational":true,"readOnly":false,"ownPartition":true,"filesystem":"","vendor":"LIO-ORG","model":"disk02","wwn":"0x6001405f826bd553","wwnVendorExtension":"0x6001405f826bd553d8c4dbf9f41c18be","empty":true},{"name":"sdb","parent":"","hasChildren":false,"devLinks":"/dev/disk/by-id/scsi-3600140577f462d9908b409d94114e042   /dev/disk/by-id/wwn-0x600140577f462d9908b409d94114e042 /dev/disk/by-path/ip-127.0.0.1:3260-iscsi-iqn.2016-06.world.srv:storage.target01-lun-3","size":5368709120,"uuid":"","serial":"3600140577f462d9908b409d94114e042","type":"disk","rotational":true,"readOnly":false,"ownPartition":false,"filesystem":"","vendor":"LIO-ORG","model":"disk04","wwn":"0x600140577f462d99","wwnVendorExtension":"0x600140577f462d9908b409d94114e042","empty":true},{"name":"sdc","parent":"","hasChildren":false,"devLinks":"/dev/disk/by-id/scsi-3600140568c0bd28d4ee43769387c9f02    /dev/disk/by-id/wwn-0x600140568c0bd28d4ee43769387c9f02 /dev/disk/by-path/ip-127.0.0.1:3260-iscsi-iqn.2016-06.world.srv:storage.target01-lun-2","size":5368709120,"uuid":"","serial":"3600140568c0bd28d4ee43769387c9f02","type":"disk","rotational":true,"readOnly":false,"ownPartition":true,"filesystem":"","vendor":"LIO-ORG","model":"disk03","wwn":"0x600140568c0bd28d","wwnVendorExtension":"0x600140568c0bd28d4ee43769387c9f02","empty":true},{"name":"sda","parent":"","hasChildren":false,"devLinks":"/dev/disk/by-id/scsi-36001405fc00c75fb4c243aa9d61987bd    /dev/disk/by-id/wwn-0x6001405fc00c75fb4c243aa9d61987bd /dev/disk/by-path/ip-127.0.0.1:3260-iscsi-iqn.2016-06.world.srv:storage.target01-lun-0","size":10737418240,"uuid":"","serial":"36001405fc00c75fb4c243aa9d61987bd","type":"disk","rotational":true,"readOnly":false,"ownPartition":false,"filesystem":"","vendor":"LIO-ORG","model":"disk01","wwn":"0x6001405fc00c75fb","wwnVendorExtension":"0x6001405fc00c75fb4c243aa9d61987bd","empty":true},{"name":"nvme0n1","parent":"","hasChildren":false,"devLinks":"/dev/disk/by-id/nvme-eui.002538c5710091a7","size":512110190592,"uuid":"","serial":"","type":"disk","rotational":false,"readOnly":false,"ownPartition":false,"filesystem":"","vendor":"","model":"","wwn":"","wwnVendorExtension":"","empty":true}]`
-	cm := &v1.ConfigMap{
+	nodeDevices := &rookalpha.NodeDevices{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "local-device-" + nodeName,
-			Namespace: ns,
-			Labels: map[string]string{
-				k8sutil.AppAttr:         discoverDaemon.AppName,
-				discoverDaemon.NodeAttr: nodeName,
-			},
+			Name:   nodeName,
+			Labels: map[string]string{k8sutil.AppAttr: discoverDaemon.AppName},
+		},
+		Devices: []rookalpha.NodeDevice{
+			newNodeDevice("sdd", "0x6001405f826bd553d8c4dbf9f41c18be", "lun-1", 10737418240),
+			newNodeDevice("sdb", "0x600140577f462d9908b409d94114e042", "lun-3", 5368709120),
+			newNodeDevice("sdc", "0x600140568c0bd28d4ee43769387c9f02", "lun-2", 5368709120),
+			newNodeDevice("sda", "0x6001405fc00c75fb4c243aa9d61987bd", "lun-0", 10737418240),
+			{Name: "nvme0n1", FullPath: "/dev/disk/by-id/nvme-eui.002538c5710091a7", DevLinks: "/dev/disk/by-id/nvme-eui.002538c5710091a7", Size: 512110190592, Type: "disk"},
 		},
-		Data: data,
 	}
-	_, err := clientset.CoreV1().ConfigMaps(ns).Create(cm)
+	_, err := rookClientset.RookV1alpha2().NodeDevices().Create(nodeDevices)
 	assert.Nil(t, err)
 	context := &clusterd.Context{
-		Clientset: clientset,
+		Clientset:     clientset,
+		RookClientset: rookClientset,
 	}
 	d := []rookalpha.Device{
 		{
@@ -105,40 +112,203 @@ func TestGetAvailableDevices(t *testing.T) {
 		},
 	}
 
-	nodeDevices, err := ListDevices(context, ns, "" /* all nodes */)
+	allDevices, err := ListDevices(context, "" /* all nodes */)
 	assert.Nil(t, err)
-	assert.Equal(t, 1, len(nodeDevices))
+	assert.Equal(t, 1, len(allDevices))
 
-	devices, err := GetAvailableDevices(context, nodeName, ns, d, "^sd.", false)
+	devices, err := GetAvailableDevices(context, nodeName, ns, &rookalpha.Selection{Devices: d, DeviceFilter: "^sd."})
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(devices))
+	// the device is claimed by the cluster now
+	assert.Equal(t, []string{"sdc"}, claimedBy(t, context, nodeName, ns))
 	// devices should be in use now, 2nd try gets the same list
-	devices, err = GetAvailableDevices(context, nodeName, ns, d, "^sd.", false)
+	devices, err = GetAvailableDevices(context, nodeName, ns, &rookalpha.Selection{Devices: d, DeviceFilter: "^sd."})
 	assert.Nil(t, err)
 	assert.Equal(t, 1, len(devices))
 
+	// another cluster cannot claim the device
+	devices, err = GetAvailableDevices(context, nodeName, "other", &rookalpha.Selection{DeviceFilter: "^sd."})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(devices))
+	assert.Equal(t, []string{"sdd", "sdb", "sda"}, claimedBy(t, context, nodeName, "other"))
+	err = FreeDevices(context, nodeName, "other")
+	assert.Nil(t, err)
+
 	err = FreeDevices(context, nodeName, ns)
 	assert.Nil(t, err)
+	assert.Equal(t, 0, len(claimedBy(t, context, nodeName, ns)))
 	// all devices freed
-	devices, err = GetAvailableDevices(context, nodeName, ns, nil, "^sd.", false)
+	devices, err = GetAvailableDevices(context, nodeName, ns, &rookalpha.Selection{DeviceFilter: "^sd."})
 	assert.Nil(t, err)
 	assert.Equal(t, 4, len(devices))
 	// devices should be in use now, 2nd try gets the same list
-	devices, err = GetAvailableDevices(context, nodeName, ns, nil, "^sd.", false)
+	devices, err = GetAvailableDevices(context, nodeName, ns, &rookalpha.Selection{DeviceFilter: "^sd."})
 	assert.Nil(t, err)
 	assert.Equal(t, 4, len(devices))
 
 	err = FreeDevices(context, nodeName, ns)
 	assert.Nil(t, err)
 
-	devices, err = GetAvailableDevices(context, nodeName, ns, nil, "", true)
+	useAllDevices := true
+	devices, err = GetAvailableDevices(context, nodeName, ns, &rookalpha.Selection{UseAllDevices: &useAllDevices})
 	assert.Nil(t, err)
 	assert.Equal(t, 5, len(devices))
 	// devices should be in use now, 2nd try gets the same list
-	devices, err = GetAvailableDevices(context, nodeName, ns, nil, "", true)
+	devices, err = GetAvailableDevices(context, nodeName, ns, &rookalpha.Selection{UseAllDevices: &useAllDevices})
 	assert.Nil(t, err)
 	assert.Equal(t, 5, len(devices))
 
+	// the osd is recorded on the device that it was created on
+	err = SetDeviceOSD(context, nodeName, ns, "sdb", 3)
+	assert.Nil(t, err)
+	node, err := rookClientset.RookV1alpha2().NodeDevices().Get(nodeName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, *node.Devices[1].OSDID)
+	assert.Nil(t, node.Devices[0].OSDID)
+
+	err = FreeDevicesByCluster(context, ns)
+	assert.Nil(t, err)
+	node, err = rookClientset.RookV1alpha2().NodeDevices().Get(nodeName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", node.Devices[1].Cluster)
+	assert.Nil(t, node.Devices[1].OSDID)
+
+	// devices are matched by their persistent paths, which follow the disks when their kernel names change
+	d = []rookalpha.Device{
+		{FullPath: "/dev/disk/by-path/ip-127.0.0.1:3260-iscsi-iqn.2016-06.world.srv:storage.target01-lun-2"},
+		{Name: "nvme0n1"},
+	}
+	devices, err = GetAvailableDevices(context, nodeName, ns, &rookalpha.Selection{Devices: d})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(devices))
+	assert.Equal(t, "sdc", devices[0].Name)
+	assert.Equal(t, d[0].FullPath, devices[0].FullPath)
+	assert.Equal(t, "/dev/disk/by-id/nvme-eui.002538c5710091a7", devices[1].FullPath)
+
+	err = FreeDevices(context, nodeName, ns)
+	assert.Nil(t, err)
+
+	// devices are selected by their properties
+	rotational := true
+	devices, err = GetAvailableDevices(context, nodeName, ns, &rookalpha.Selection{MinDeviceSize: "8Gi", Rotational: &rotational})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(devices))
+	assert.Equal(t, "sdd", devices[0].Name)
+	assert.Equal(t, "sda", devices[1].Name)
+
+	// the devices claimed by the cluster are kept when the number of devices is limited
+	devices, err = GetAvailableDevices(context, nodeName, ns, &rookalpha.Selection{DevicePathFilter: "/dev/disk/by-path/*", MaxDevices: 3})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(devices))
+	assert.Equal(t, "sdd", devices[0].Name)
+	assert.Equal(t, "sda", devices[1].Name)
+	assert.Equal(t, "sdb", devices[2].Name)
+
+	err = FreeDevices(context, nodeName, ns)
+	assert.Nil(t, err)
+
+	// the rejected devices do not count towards the max number of devices
+	err = updateDevices(context, nodeName, func(devices []rookalpha.NodeDevice) {
+		devices[0].Rejected = rookalpha.DeviceHasPartitions
+	})
+	assert.Nil(t, err)
+	devices, err = GetAvailableDevices(context, nodeName, ns, &rookalpha.Selection{DeviceFilter: "^sd.", MaxDevices: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(devices))
+	assert.Equal(t, "sdb", devices[0].Name)
+	assert.Equal(t, "sdc", devices[1].Name)
+	assert.Equal(t, []string{"sdb", "sdc"}, claimedBy(t, context, nodeName, ns))
+
 	err = FreeDevices(context, nodeName, ns)
 	assert.Nil(t, err)
+
+	// the devices in use recorded in the configmaps of earlier versions stay claimed by their cluster
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "local-device-in-use-cluster-" + ns + "-node-" + nodeName,
+			Namespace: ns,
+			Labels: map[string]string{
+				k8sutil.AppAttr:        deviceInUseAppName,
+				deviceInUseNodeAttr:    nodeName,
+				deviceInUseClusterAttr: ns,
+			},
+		},
+		Data: map[string]string{deviceInUseData: `[{"name":"sdd","devLinks":"/dev/disk/by-id/wwn-0x6001405f826bd553d8c4dbf9f41c18be"}]`},
+	}
+	_, err = clientset.CoreV1().ConfigMaps(ns).Create(cm)
+	assert.Nil(t, err)
+	devices, err = GetAvailableDevices(context, nodeName, "other", &rookalpha.Selection{DeviceFilter: "^sd."})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"sdd"}, claimedBy(t, context, nodeName, ns))
+	_, err = clientset.CoreV1().ConfigMaps(ns).Get(cm.Name, metav1.GetOptions{})
+	assert.True(t, kserrors.IsNotFound(err))
+	// the partitions of the migrated device do not prevent its cluster from selecting it
+	devices, err = GetAvailableDevices(context, nodeName, ns, &rookalpha.Selection{DeviceFilter: "^sdd"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(devices))
+	assert.Equal(t, "sdd", devices[0].Name)
+
+	err = FreeDevicesByCluster(context, ns)
+	assert.Nil(t, err)
+	err = FreeDevicesByCluster(context, "other")
+	assert.Nil(t, err)
+}
+
+func TestMatchesDeviceSelectors(t *testing.T) {
+	rotational := false
+	device := &sys.LocalDisk{
+		Name:       "nvme0n1",
+		Size:       512110190592,
+		Rotational: false,
+		Vendor:     "",
+		Model:      "Samsung SSD 960 PRO 512GB",
+		DevLinks:   "/dev/disk/by-id/nvme-eui.002538c5710091a7 /dev/disk/by-path/pci-0000:03:00.0-nvme-1",
+	}
+
+	tests := []struct {
+		selection rookalpha.Selection
+		matched   bool
+	}{
+		{rookalpha.Selection{}, true},
+		{rookalpha.Selection{MinDeviceSize: "400Gi", MaxDeviceSize: "1Ti"}, true},
+		{rookalpha.Selection{MinDeviceSize: "1Ti"}, false},
+		{rookalpha.Selection{MaxDeviceSize: "100G"}, false},
+		{rookalpha.Selection{Rotational: &rotational}, true},
+		{rookalpha.Selection{ModelFilter: "^Samsung"}, true},
+		{rookalpha.Selection{ModelFilter: "^Intel"}, false},
+		{rookalpha.Selection{VendorFilter: "^LIO"}, false},
+		{rookalpha.Selection{DevicePathFilter: "/dev/disk/by-path/pci-0000:03:00.0-*"}, true},
+		{rookalpha.Selection{DevicePathFilter: "/dev/disk/by-path/pci-0000:04:00.0-*"}, false},
+	}
+	for i, test := range tests {
+		matched, err := MatchesDeviceSelectors(&test.selection, device)
+		assert.Nil(t, err)
+		assert.Equal(t, test.matched, matched, "selection %d", i)
+	}
+
+	_, err := MatchesDeviceSelectors(&rookalpha.Selection{MinDeviceSize: "big"}, device)
+	assert.NotNil(t, err)
+}
+
+func newNodeDevice(name, wwn, lun string, size uint64) rookalpha.NodeDevice {
+	return rookalpha.NodeDevice{
+		Name:       name,
+		FullPath:   "/dev/disk/by-id/wwn-" + wwn,
+		DevLinks:   fmt.Sprintf("/dev/disk/by-id/wwn-%s /dev/disk/by-path/ip-127.0.0.1:3260-iscsi-iqn.2016-06.world.srv:storage.target01-%s", wwn, lun),
+		Size:       size,
+		Type:       "disk",
+		Rotational: true,
+	}
+}
+
+func claimedBy(t *testing.T, context *clusterd.Context, nodeName, clusterName string) []string {
+	node, err := context.RookClientset.RookV1alpha2().NodeDevices().Get(nodeName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	names := []string{}
+	for _, device := range node.Devices {
+		if device.Cluster == clusterName {
+			names = append(names, device.Name)
+		}
+	}
+	return names
 }
@@ -0,0 +1,89 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package discover
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/util/sys"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// MatchesDeviceSelectors returns whether the discovered device has all the properties required by the device selectors
+// of the storage selection. The max number of devices is not evaluated here since it depends on the other devices.
+func MatchesDeviceSelectors(selection *rookalpha.Selection, device *sys.LocalDisk) (bool, error) {
+	if selection.MinDeviceSize != "" {
+		minSize, err := resource.ParseQuantity(selection.MinDeviceSize)
+		if err != nil {
+			return false, fmt.Errorf("invalid minDeviceSize %s. %+v", selection.MinDeviceSize, err)
+		}
+		if device.Size < uint64(minSize.Value()) {
+			return false, nil
+		}
+	}
+	if selection.MaxDeviceSize != "" {
+		maxSize, err := resource.ParseQuantity(selection.MaxDeviceSize)
+		if err != nil {
+			return false, fmt.Errorf("invalid maxDeviceSize %s. %+v", selection.MaxDeviceSize, err)
+		}
+		if device.Size > uint64(maxSize.Value()) {
+			return false, nil
+		}
+	}
+	if selection.Rotational != nil && *selection.Rotational != device.Rotational {
+		return false, nil
+	}
+	if selection.VendorFilter != "" {
+		matched, err := regexp.MatchString(selection.VendorFilter, device.Vendor)
+		if err != nil {
+			return false, fmt.Errorf("invalid vendorFilter %s. %+v", selection.VendorFilter, err)
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	if selection.ModelFilter != "" {
+		matched, err := regexp.MatchString(selection.ModelFilter, device.Model)
+		if err != nil {
+			return false, fmt.Errorf("invalid modelFilter %s. %+v", selection.ModelFilter, err)
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	if selection.DevicePathFilter != "" {
+		return matchesDevicePath(selection.DevicePathFilter, device)
+	}
+	return true, nil
+}
+
+// matchesDevicePath returns whether any of the persistent links of the device match the glob pattern
+func matchesDevicePath(pattern string, device *sys.LocalDisk) (bool, error) {
+	for _, link := range sys.GetDevLinks(device) {
+		matched, err := filepath.Match(pattern, link)
+		if err != nil {
+			return false, fmt.Errorf("invalid devicePathFilter %s. %+v", pattern, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8sutil
+
+import (
+	rookscheme "github.com/rook/rook/pkg/client/clientset/versioned/scheme"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// The reasons of the events recorded by the operators and agents
+const (
+	EventReasonCreated             = "Created"
+	EventReasonCreateFailed        = "CreateFailed"
+	EventReasonUpdated             = "Updated"
+	EventReasonUpdateFailed        = "UpdateFailed"
+	EventReasonDeleteFailed        = "DeleteFailed"
+	EventReasonOSDProvisioned      = "OSDProvisioned"
+	EventReasonOSDProvisionFailed  = "OSDProvisionFailed"
+	EventReasonOSDProvisionTimeout = "OSDProvisionTimeout"
+	EventReasonMonFailover         = "MonFailover"
+	EventReasonMonFailoverFailed   = "MonFailoverFailed"
+	EventReasonMonRemoved          = "MonRemoved"
+	EventReasonAttachRefused       = "AttachRefused"
+	EventReasonVolumeFenced        = "VolumeFenced"
+)
+
+func init() {
+	// the rook resources must be known to the scheme to record events against them
+	rookscheme.AddToScheme(scheme.Scheme)
+}
+
+// NewEventRecorder creates a recorder that posts the events of the given component to the API server
+func NewEventRecorder(clientset kubernetes.Interface, component string) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartLogging(logger.Debugf)
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: component})
+}
+
+// RecordEvent records an event against the object. Nothing is recorded if there is no recorder.
+func RecordEvent(recorder record.EventRecorder, obj runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	if recorder == nil || obj == nil {
+		return
+	}
+	recorder.Eventf(obj, eventType, reason, messageFmt, args...)
+}
+
+// OwnerObjectReference returns the reference of the owner of the resources in the namespace, such as a cluster
+func OwnerObjectReference(ownerRef metav1.OwnerReference, namespace string) *v1.ObjectReference {
+	return &v1.ObjectReference{
+		APIVersion: ownerRef.APIVersion,
+		Kind:       ownerRef.Kind,
+		Name:       ownerRef.Name,
+		Namespace:  namespace,
+		UID:        ownerRef.UID,
+	}
+}
+
+// NodeObjectReference returns the reference of a node to record events against
+func NodeObjectReference(nodeName string) *v1.ObjectReference {
+	// the node name is used as the uid like the kubelet does, so the events show with the node
+	return &v1.ObjectReference{Kind: "Node", Name: nodeName, UID: types.UID(nodeName)}
+}
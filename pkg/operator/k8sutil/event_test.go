@@ -0,0 +1,44 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package k8sutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestRecordEvent(t *testing.T) {
+	node := NodeObjectReference("node1")
+	assert.Equal(t, "Node", node.Kind)
+	assert.Equal(t, "node1", string(node.UID))
+
+	// nothing is recorded without a recorder
+	RecordEvent(nil, node, v1.EventTypeNormal, EventReasonOSDProvisioned, "provisioned osds for cluster %s", "rook-ceph")
+
+	recorder := record.NewFakeRecorder(10)
+	RecordEvent(recorder, node, v1.EventTypeNormal, EventReasonOSDProvisioned, "provisioned osds for cluster %s", "rook-ceph")
+	assert.Equal(t, "Normal OSDProvisioned provisioned osds for cluster rook-ceph", <-recorder.Events)
+
+	owner := metav1.OwnerReference{APIVersion: "ceph.rook.io/v1beta1", Kind: "Cluster", Name: "rook-ceph", UID: "123"}
+	ref := OwnerObjectReference(owner, "rook-ceph")
+	assert.Equal(t, "Cluster", ref.Kind)
+	assert.Equal(t, "rook-ceph", ref.Namespace)
+	assert.Equal(t, "123", string(ref.UID))
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics exposes the Prometheus metrics of the rook operators and agents.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/coreos/pkg/capnslog"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	namespace = "rook"
+
+	// DefaultOperatorAddress is the default address of the metrics endpoint of the operators
+	DefaultOperatorAddress = ":9090"
+	// DefaultAgentAddress is the default address of the metrics endpoint of the agent, which runs on the host network
+	DefaultAgentAddress = ":9124"
+
+	// the results of the OSD provisioning jobs
+	ProvisionCompleted = "completed"
+	ProvisionFailed    = "failed"
+	ProvisionTimeout   = "timeout"
+)
+
+var logger = capnslog.NewPackageLogger("github.com/rook/rook", "metrics")
+
+var (
+	reconcilesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "operator",
+		Name:      "reconciles_total",
+		Help:      "Number of events handled by each controller.",
+	}, []string{"controller", "event"})
+
+	reconcileErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "operator",
+		Name:      "reconcile_errors_total",
+		Help:      "Number of events that each controller failed to handle.",
+	}, []string{"controller", "event"})
+
+	reconcileDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "operator",
+		Name:      "reconcile_duration_seconds",
+		Help:      "Time taken by each controller to handle an event.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 15),
+	}, []string{"controller", "event"})
+
+	osdProvisionJobsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ceph",
+		Name:      "osd_provision_jobs_total",
+		Help:      "Number of OSD provisioning jobs per node by result.",
+	}, []string{"cluster", "result"})
+
+	monFailoversTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ceph",
+		Name:      "mon_failovers_total",
+		Help:      "Number of mon failovers started by the operator.",
+	}, []string{"cluster"})
+
+	monFailoverErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "ceph",
+		Name:      "mon_failover_errors_total",
+		Help:      "Number of mon failovers that failed.",
+	}, []string{"cluster"})
+
+	healthCheckLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "ceph",
+		Name:      "health_check_last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful health check. The age of the check is time() minus this value.",
+	}, []string{"cluster", "check"})
+
+	flexvolumeDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "flexvolume",
+		Name:      "operation_duration_seconds",
+		Help:      "Time taken by the agent to attach or detach a volume.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+	}, []string{"operation"})
+
+	flexvolumeErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "flexvolume",
+		Name:      "operation_errors_total",
+		Help:      "Number of volume attach or detach operations that failed.",
+	}, []string{"operation"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		reconcilesTotal,
+		reconcileErrorsTotal,
+		reconcileDuration,
+		osdProvisionJobsTotal,
+		monFailoversTotal,
+		monFailoverErrorsTotal,
+		healthCheckLastSuccess,
+		flexvolumeDuration,
+		flexvolumeErrorsTotal,
+	)
+}
+
+// Serve starts serving the metrics on the /metrics path of the given address. The metrics are not served
+// if the address is empty or "0".
+func Serve(address string) {
+	if address == "" || address == "0" {
+		logger.Infof("metrics are disabled")
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", prometheus.Handler())
+	go func() {
+		logger.Infof("serving metrics on %s/metrics", address)
+		if err := http.ListenAndServe(address, mux); err != nil {
+			logger.Errorf("failed to serve metrics on %s. %+v", address, err)
+		}
+	}()
+}
+
+// InstrumentHandlers counts and times the events handled by a controller
+func InstrumentHandlers(controller string, handlers cache.ResourceEventHandlerFuncs) cache.ResourceEventHandlerFuncs {
+	instrumented := cache.ResourceEventHandlerFuncs{}
+	if handlers.AddFunc != nil {
+		instrumented.AddFunc = func(obj interface{}) {
+			defer observeReconcile(controller, "add", time.Now())
+			handlers.AddFunc(obj)
+		}
+	}
+	if handlers.UpdateFunc != nil {
+		instrumented.UpdateFunc = func(oldObj, newObj interface{}) {
+			defer observeReconcile(controller, "update", time.Now())
+			handlers.UpdateFunc(oldObj, newObj)
+		}
+	}
+	if handlers.DeleteFunc != nil {
+		instrumented.DeleteFunc = func(obj interface{}) {
+			defer observeReconcile(controller, "delete", time.Now())
+			handlers.DeleteFunc(obj)
+		}
+	}
+	return instrumented
+}
+
+func observeReconcile(controller, event string, start time.Time) {
+	reconcilesTotal.WithLabelValues(controller, event).Inc()
+	reconcileDuration.WithLabelValues(controller, event).Observe(time.Since(start).Seconds())
+}
+
+// ReconcileFailed counts an event that a controller failed to handle
+func ReconcileFailed(controller, event string) {
+	reconcileErrorsTotal.WithLabelValues(controller, event).Inc()
+}
+
+// OSDProvisioned counts the result of an OSD provisioning job
+func OSDProvisioned(cluster, result string) {
+	osdProvisionJobsTotal.WithLabelValues(cluster, result).Inc()
+}
+
+// OSDProvisionTimedOut counts the OSD provisioning jobs that did not report a result in time
+func OSDProvisionTimedOut(cluster string, nodes int) {
+	osdProvisionJobsTotal.WithLabelValues(cluster, ProvisionTimeout).Add(float64(nodes))
+}
+
+// MonFailover counts a mon failover and whether it failed
+func MonFailover(cluster string, err error) {
+	monFailoversTotal.WithLabelValues(cluster).Inc()
+	if err != nil {
+		monFailoverErrorsTotal.WithLabelValues(cluster).Inc()
+	}
+}
+
+// HealthCheckSucceeded records the time of a successful health check
+func HealthCheckSucceeded(cluster, check string) {
+	healthCheckLastSuccess.WithLabelValues(cluster, check).Set(float64(time.Now().Unix()))
+}
+
+// ObserveFlexvolumeOperation times an attach or detach operation of the agent and counts its failure
+func ObserveFlexvolumeOperation(operation string, start time.Time, err error) {
+	flexvolumeDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		flexvolumeErrorsTotal.WithLabelValues(operation).Inc()
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/tools/cache"
+)
+
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	m := &dto.Metric{}
+	assert.Nil(t, c.Write(m))
+	return m.GetCounter().GetValue()
+}
+
+func TestInstrumentHandlers(t *testing.T) {
+	added := 0
+	updated := 0
+	handlers := InstrumentHandlers("test", cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			added++
+			ReconcileFailed("test", "add")
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) { updated++ },
+	})
+
+	handlers.OnAdd(nil)
+	handlers.OnUpdate(nil, nil)
+	handlers.OnUpdate(nil, nil)
+	// the missing delete handler is not called
+	handlers.OnDelete(nil)
+
+	assert.Equal(t, 1, added)
+	assert.Equal(t, 2, updated)
+	assert.Nil(t, handlers.DeleteFunc)
+	assert.Equal(t, float64(1), counterValue(t, reconcilesTotal.WithLabelValues("test", "add")))
+	assert.Equal(t, float64(2), counterValue(t, reconcilesTotal.WithLabelValues("test", "update")))
+	assert.Equal(t, float64(1), counterValue(t, reconcileErrorsTotal.WithLabelValues("test", "add")))
+	assert.Equal(t, float64(0), counterValue(t, reconcileErrorsTotal.WithLabelValues("test", "update")))
+}
+
+func TestOperationMetrics(t *testing.T) {
+	OSDProvisioned("ns", ProvisionCompleted)
+	OSDProvisioned("ns", ProvisionFailed)
+	OSDProvisionTimedOut("ns", 3)
+	assert.Equal(t, float64(1), counterValue(t, osdProvisionJobsTotal.WithLabelValues("ns", ProvisionCompleted)))
+	assert.Equal(t, float64(1), counterValue(t, osdProvisionJobsTotal.WithLabelValues("ns", ProvisionFailed)))
+	assert.Equal(t, float64(3), counterValue(t, osdProvisionJobsTotal.WithLabelValues("ns", ProvisionTimeout)))
+
+	MonFailover("ns", nil)
+	MonFailover("ns", errors.New("failed"))
+	assert.Equal(t, float64(2), counterValue(t, monFailoversTotal.WithLabelValues("ns")))
+	assert.Equal(t, float64(1), counterValue(t, monFailoverErrorsTotal.WithLabelValues("ns")))
+
+	ObserveFlexvolumeOperation("attach", time.Now(), nil)
+	ObserveFlexvolumeOperation("detach", time.Now(), errors.New("failed"))
+	assert.Equal(t, float64(0), counterValue(t, flexvolumeErrorsTotal.WithLabelValues("attach")))
+	assert.Equal(t, float64(1), counterValue(t, flexvolumeErrorsTotal.WithLabelValues("detach")))
+}
@@ -20,18 +20,27 @@ package minio
 import (
 	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/coreos/pkg/capnslog"
 	opkit "github.com/rook/operator-kit"
 	miniov1alpha1 "github.com/rook/rook/pkg/apis/minio.rook.io/v1alpha1"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
 	"k8s.io/api/apps/v1beta2"
 	"k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -45,6 +54,21 @@ const (
 	minioPVCName             = "minio-pvc"
 	minioVolumeName          = "data"
 	objectStoreDataDir       = "/data"
+	minioCertsVolumeName     = "certs"
+	minioCertsDir            = "/etc/minio/certs"
+
+	// the keys of the tls secret, which are mounted with the names expected by minio in the certs dir
+	tlsCertKey = "tls.crt"
+	tlsKeyKey  = "tls.key"
+	tlsCAKey   = "ca.crt"
+
+	// the largest erasure set of minio, the servers of a pool are split in sets of up to 16 servers
+	maxErasureSetSize = 16
+
+	// serverPoolsAnnotation is the stateful set annotation with the number of servers in each server pool. The
+	// servers of a pool form their own erasure sets, the object store is expanded by adding a new pool.
+	serverPoolsAnnotation = "minio.rook.io/server-pools"
+	minioControllerName   = "minio"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "minio-op-object")
@@ -82,9 +106,12 @@ func (c *MinioController) StartWatch(namespace string, stopCh chan struct{}) err
 	}
 
 	logger.Infof("start watching object store resources in namespace %s", namespace)
-	watcher := opkit.NewWatcher(ObjectStoreResource, namespace, resourceHandlerFuncs, c.context.RookClientset.MinioV1alpha1().RESTClient())
+	watcher := opkit.NewWatcher(ObjectStoreResource, namespace, metrics.InstrumentHandlers(minioControllerName, resourceHandlerFuncs), c.context.RookClientset.MinioV1alpha1().RESTClient())
 	go watcher.Watch(&miniov1alpha1.ObjectStore{}, stopCh)
 
+	// restart the minio pods when their credentials are rotated
+	c.watchCredentials(namespace, stopCh)
+
 	return nil
 }
 
@@ -101,6 +128,8 @@ func (c *MinioController) makeMinioHeadlessService(name, namespace string, spec
 			Selector:  map[string]string{k8sutil.AppAttr: minioLabel},
 			Ports:     []v1.ServicePort{{Port: spec.Port}},
 			ClusterIP: v1.ClusterIPNone,
+			// the servers only become ready once they reach their peers, so they must be resolvable while not ready
+			PublishNotReadyAddresses: true,
 		},
 	})
 	k8sutil.SetOwnerRef(c.context.Clientset, namespace, &svc.ObjectMeta, &ownerRef)
@@ -108,21 +137,47 @@ func (c *MinioController) makeMinioHeadlessService(name, namespace string, spec
 	return svc, err
 }
 
-func (c *MinioController) buildMinioCtrArgs(statefulSetPrefix, headlessServiceName, namespace string, serverCount int32) []string {
-	args := []string{"server"}
-	for i := int32(0); i < serverCount; i++ {
-		serverAddress := fmt.Sprintf("http://%s-%d.%s.%s%s", statefulSetPrefix, i, headlessServiceName, namespace, objectStoreDataDir)
-		args = append(args, serverAddress)
+func (c *MinioController) buildMinioCtrArgs(statefulSetPrefix, headlessServiceName, namespace string, port int32, tls bool, serverPools []int32) []string {
+	args := []string{"server", "--address", fmt.Sprintf(":%d", port)}
+	scheme := "http"
+	if tls {
+		args = append(args, "--certs-dir", minioCertsDir)
+		scheme = "https"
+	}
+	if len(serverPools) == 1 {
+		for i := int32(0); i < serverPools[0]; i++ {
+			serverAddress := fmt.Sprintf("%s://%s-%d.%s.%s%s", scheme, statefulSetPrefix, i, headlessServiceName, namespace, objectStoreDataDir)
+			args = append(args, serverAddress)
+		}
+	} else {
+		// each server pool is passed as a range of servers so that minio creates separate erasure sets for it
+		first := int32(0)
+		for _, count := range serverPools {
+			serverAddress := fmt.Sprintf("%s://%s-{%d...%d}.%s.%s%s", scheme, statefulSetPrefix, first, first+count-1, headlessServiceName, namespace, objectStoreDataDir)
+			args = append(args, serverAddress)
+			first += count
+		}
 	}
 
 	logger.Infof("Building Minio container args: %v", args)
 	return args
 }
 
-func (c *MinioController) makeMinioPodSpec(name, namespace string, ctrName string, ctrImage string, port int32, envVars map[string]string, numServers int32) v1.PodTemplateSpec {
+func (c *MinioController) makeMinioPodSpec(name, namespace string, ctrName string, ctrImage string, port int32, envVars map[string]string, tlsSecret *v1.Secret, serverPools []int32) v1.PodTemplateSpec {
+	// the env vars are sorted so that the pod template only changes when their values change
+	keys := []string{}
+	for k := range envVars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 	var env []v1.EnvVar
-	for k, v := range envVars {
-		env = append(env, v1.EnvVar{Name: k, Value: v})
+	for _, k := range keys {
+		env = append(env, v1.EnvVar{Name: k, Value: envVars[k]})
+	}
+
+	probeScheme := v1.URISchemeHTTP
+	if tlsSecret != nil {
+		probeScheme = v1.URISchemeHTTPS
 	}
 
 	podSpec := v1.PodTemplateSpec{
@@ -139,13 +194,35 @@ func (c *MinioController) makeMinioPodSpec(name, namespace string, ctrName strin
 					Env:     env,
 					Command: []string{"/usr/bin/minio"},
 					Ports:   []v1.ContainerPort{{ContainerPort: port}},
-					Args:    c.buildMinioCtrArgs(name, name, namespace, numServers),
+					Args:    c.buildMinioCtrArgs(name, name, namespace, port, tlsSecret != nil, serverPools),
 					VolumeMounts: []v1.VolumeMount{
 						{
 							Name:      minioVolumeName,
 							MountPath: objectStoreDataDir,
 						},
 					},
+					LivenessProbe: &v1.Probe{
+						Handler: v1.Handler{
+							HTTPGet: &v1.HTTPGetAction{
+								Path:   "/minio/health/live",
+								Port:   intstr.FromInt(int(port)),
+								Scheme: probeScheme,
+							},
+						},
+						InitialDelaySeconds: int32(120),
+						PeriodSeconds:       int32(20),
+					},
+					ReadinessProbe: &v1.Probe{
+						Handler: v1.Handler{
+							HTTPGet: &v1.HTTPGetAction{
+								Path:   "/minio/health/ready",
+								Port:   intstr.FromInt(int(port)),
+								Scheme: probeScheme,
+							},
+						},
+						InitialDelaySeconds: int32(10),
+						PeriodSeconds:       int32(10),
+					},
 				},
 			},
 			Volumes: []v1.Volume{
@@ -161,9 +238,42 @@ func (c *MinioController) makeMinioPodSpec(name, namespace string, ctrName strin
 		},
 	}
 
+	if tlsSecret != nil {
+		// minio loads the certificate and the trusted CAs from the certs dir
+		items := []v1.KeyToPath{{Key: tlsCertKey, Path: "public.crt"}, {Key: tlsKeyKey, Path: "private.key"}}
+		if _, ok := tlsSecret.Data[tlsCAKey]; ok {
+			items = append(items, v1.KeyToPath{Key: tlsCAKey, Path: "CAs/ca.crt"})
+		}
+		podSpec.Spec.Volumes = append(podSpec.Spec.Volumes, v1.Volume{
+			Name: minioCertsVolumeName,
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{SecretName: tlsSecret.Name, Items: items},
+			},
+		})
+		podSpec.Spec.Containers[0].VolumeMounts = append(podSpec.Spec.Containers[0].VolumeMounts,
+			v1.VolumeMount{Name: minioCertsVolumeName, MountPath: minioCertsDir, ReadOnly: true})
+	}
+
 	return podSpec
 }
 
+// getTLSSecret returns the secret with the TLS certificate of the object store, or nil if TLS is not enabled
+func (c *MinioController) getTLSSecret(namespace string, spec miniov1alpha1.ObjectStoreSpec) (*v1.Secret, error) {
+	if spec.TLSSecret == "" {
+		return nil, nil
+	}
+	secret, err := c.context.Clientset.CoreV1().Secrets(namespace).Get(spec.TLSSecret, meta_v1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tls secret %s: %v", spec.TLSSecret, err)
+	}
+	for _, key := range []string{tlsCertKey, tlsKeyKey} {
+		if _, ok := secret.Data[key]; !ok {
+			return nil, fmt.Errorf("tls secret %s does not contain the %s key", spec.TLSSecret, key)
+		}
+	}
+	return secret, nil
+}
+
 func (c *MinioController) getAccessCredentials(secretName, namespace string) (string, string, error) {
 	coreV1Client := c.context.Clientset.CoreV1()
 	var getOpts meta_v1.GetOptions
@@ -191,9 +301,70 @@ func validateObjectStoreSpec(spec miniov1alpha1.ObjectStoreSpec) error {
 	return nil
 }
 
+// validateObjectStoreUpdate rejects the changes that minio cannot apply without losing data
+func validateObjectStoreUpdate(oldSpec, newSpec miniov1alpha1.ObjectStoreSpec) error {
+	// Servers cannot be removed from the erasure sets, and a new server pool must be a valid erasure set itself.
+	oldCount := oldSpec.Storage.NodeCount
+	newCount := newSpec.Storage.NodeCount
+	if newCount < oldCount {
+		return fmt.Errorf("Node count cannot be decreased from %d to %d.", oldCount, newCount)
+	}
+	if added := newCount - oldCount; added > 0 && (added < 4 || added%2 != 0) {
+		return fmt.Errorf("Node count must be increased by a server pool of more than 3 and an even number of nodes, not %d.", added)
+	}
+
+	// Volumes cannot be shrunk.
+	oldSize, err := resource.ParseQuantity(oldSpec.StorageSize)
+	if err != nil {
+		return fmt.Errorf("Invalid storage amount %s. %+v", oldSpec.StorageSize, err)
+	}
+	newSize, err := resource.ParseQuantity(newSpec.StorageSize)
+	if err != nil {
+		return fmt.Errorf("Invalid storage amount %s. %+v", newSpec.StorageSize, err)
+	}
+	if newSize.Cmp(oldSize) < 0 {
+		return fmt.Errorf("Storage amount cannot be decreased from %s to %s.", oldSpec.StorageSize, newSpec.StorageSize)
+	}
+
+	return nil
+}
+
+// getServerPools returns the number of servers in each server pool of the stateful set. The stateful sets created
+// before the pools were tracked have a single pool.
+func getServerPools(ss *v1beta2.StatefulSet) []int32 {
+	pools := []int32{}
+	for _, count := range strings.Split(ss.Annotations[serverPoolsAnnotation], ",") {
+		if n, err := strconv.Atoi(count); err == nil && n > 0 {
+			pools = append(pools, int32(n))
+		}
+	}
+	if len(pools) == 0 && ss.Spec.Replicas != nil {
+		pools = []int32{*ss.Spec.Replicas}
+	}
+	return pools
+}
+
+func serverPoolsValue(pools []int32) string {
+	counts := []string{}
+	for _, count := range pools {
+		counts = append(counts, strconv.Itoa(int(count)))
+	}
+	return strings.Join(counts, ",")
+}
+
 func (c *MinioController) makeMinioStatefulSet(name, namespace string, spec miniov1alpha1.ObjectStoreSpec, ownerRef meta_v1.OwnerReference) (*v1beta2.StatefulSet, error) {
 	appsClient := c.context.Clientset.AppsV1beta2()
 
+	ss, err := c.buildMinioStatefulSet(name, namespace, spec, []int32{int32(spec.Storage.NodeCount)})
+	if err != nil {
+		return nil, err
+	}
+	k8sutil.SetOwnerRef(c.context.Clientset, namespace, &ss.ObjectMeta, &ownerRef)
+
+	return appsClient.StatefulSets(namespace).Create(ss)
+}
+
+func (c *MinioController) buildMinioStatefulSet(name, namespace string, spec miniov1alpha1.ObjectStoreSpec, serverPools []int32) (*v1beta2.StatefulSet, error) {
 	accessKey, secretKey, err := c.getAccessCredentials(spec.Credentials.Name, spec.Credentials.Namespace)
 	if err != nil {
 		return nil, err
@@ -204,14 +375,20 @@ func (c *MinioController) makeMinioStatefulSet(name, namespace string, spec mini
 		"MINIO_SECRET_KEY": secretKey,
 	}
 
-	podSpec := c.makeMinioPodSpec(name, namespace, minioCtrName, c.rookImage, spec.Port, envVars, int32(spec.Storage.NodeCount))
+	tlsSecret, err := c.getTLSSecret(namespace, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	podSpec := c.makeMinioPodSpec(name, namespace, minioCtrName, c.rookImage, spec.Port, envVars, tlsSecret, serverPools)
 
 	nodeCount := int32(spec.Storage.NodeCount)
-	ss := v1beta2.StatefulSet{
+	ss := &v1beta2.StatefulSet{
 		ObjectMeta: meta_v1.ObjectMeta{
-			Name:      name,
-			Namespace: namespace,
-			Labels:    map[string]string{k8sutil.AppAttr: minioLabel},
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      map[string]string{k8sutil.AppAttr: minioLabel},
+			Annotations: map[string]string{serverPoolsAnnotation: serverPoolsValue(serverPools)},
 		},
 		Spec: v1beta2.StatefulSetSpec{
 			Replicas: &nodeCount,
@@ -219,6 +396,10 @@ func (c *MinioController) makeMinioStatefulSet(name, namespace string, spec mini
 				MatchLabels: map[string]string{k8sutil.AppAttr: minioLabel},
 			},
 			Template: podSpec,
+			// the servers are started together since a server is not ready until it reaches its peers
+			PodManagementPolicy: v1beta2.ParallelPodManagement,
+			// the operator restarts the servers together when their settings change, see restartMinioServers
+			UpdateStrategy: v1beta2.StatefulSetUpdateStrategy{Type: v1beta2.OnDeleteStatefulSetStrategyType},
 			VolumeClaimTemplates: []v1.PersistentVolumeClaim{
 				{
 					ObjectMeta: meta_v1.ObjectMeta{
@@ -239,9 +420,205 @@ func (c *MinioController) makeMinioStatefulSet(name, namespace string, spec mini
 			// TODO: liveness probe
 		},
 	}
-	k8sutil.SetOwnerRef(c.context.Clientset, namespace, &ss.ObjectMeta, &ownerRef)
 
-	return appsClient.StatefulSets(namespace).Create(&ss)
+	return ss, nil
+}
+
+// erasureSetSize returns the number of servers in the erasure sets of a server pool, which is the largest set size
+// accepted by minio that divides the pool evenly
+func erasureSetSize(poolSize int32) int32 {
+	for size := int32(maxErasureSetSize); size >= 4; size-- {
+		if poolSize%size == 0 {
+			return size
+		}
+	}
+	return poolSize
+}
+
+// maxUnavailableServers returns how many servers can be down while every erasure set keeps its write quorum of half
+// of its servers plus one
+func maxUnavailableServers(serverPools []int32) int32 {
+	maxUnavailable := int32(0)
+	for i, pool := range serverPools {
+		setSize := erasureSetSize(pool)
+		tolerated := setSize - (setSize/2 + 1)
+		if i == 0 || tolerated < maxUnavailable {
+			maxUnavailable = tolerated
+		}
+	}
+	if maxUnavailable < 1 {
+		maxUnavailable = 1
+	}
+	return maxUnavailable
+}
+
+func (c *MinioController) makeMinioPodDisruptionBudget(name, namespace string, serverPools []int32, ownerRefs []meta_v1.OwnerReference) error {
+	maxUnavailable := intstr.FromInt(int(maxUnavailableServers(serverPools)))
+	pdb := &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    map[string]string{k8sutil.AppAttr: minioLabel},
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			Selector: &meta_v1.LabelSelector{
+				MatchLabels: map[string]string{k8sutil.AppAttr: minioLabel},
+			},
+			MaxUnavailable: &maxUnavailable,
+		},
+	}
+	k8sutil.SetOwnerRefs(c.context.Clientset, namespace, &pdb.ObjectMeta, ownerRefs)
+
+	pdbClient := c.context.Clientset.PolicyV1beta1().PodDisruptionBudgets(namespace)
+	current, err := pdbClient.Get(name, meta_v1.GetOptions{})
+	if err == nil {
+		if reflect.DeepEqual(current.Spec.MaxUnavailable, pdb.Spec.MaxUnavailable) {
+			return nil
+		}
+		// the spec of a pod disruption budget cannot be updated
+		if err := pdbClient.Delete(name, &meta_v1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete pod disruption budget %s. %+v", name, err)
+		}
+	} else if !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to get pod disruption budget %s. %+v", name, err)
+	}
+
+	if _, err := pdbClient.Create(pdb); err != nil {
+		return fmt.Errorf("failed to create pod disruption budget %s. %+v", name, err)
+	}
+	logger.Infof("Pod disruption budget %s allows %s unavailable servers.", name, maxUnavailable.String())
+	return nil
+}
+
+// updateMinioStatefulSet updates the stateful set of the object store. New servers are added in a new server pool.
+// Minio requires all the servers to have the same list of servers, credentials, port and TLS setting, so when a server
+// pool is added or the settings of the servers change the existing servers are restarted together instead of being
+// rolled.
+func (c *MinioController) updateMinioStatefulSet(name, namespace string, spec miniov1alpha1.ObjectStoreSpec) error {
+	appsClient := c.context.Clientset.AppsV1beta2()
+	current, err := appsClient.StatefulSets(namespace).Get(name, meta_v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get minio stateful set %s. %+v", name, err)
+	}
+
+	pools := getServerPools(current)
+	var serverCount int32
+	for _, count := range pools {
+		serverCount += count
+	}
+	added := int32(spec.Storage.NodeCount) - serverCount
+	if added > 0 {
+		logger.Infof("Expanding Minio object store %s with a server pool of %d servers.", name, added)
+		pools = append(pools, added)
+	}
+
+	if err := c.makeMinioPodDisruptionBudget(name, namespace, pools, current.OwnerReferences); err != nil {
+		return err
+	}
+
+	ss, err := c.buildMinioStatefulSet(name, namespace, spec, pools)
+	if err != nil {
+		return err
+	}
+	ss.OwnerReferences = current.OwnerReferences
+
+	// The claim templates of a stateful set cannot be updated. The existing claims are expanded, and the stateful
+	// set is recreated without deleting its pods so that the claims of the new servers get the new size.
+	if !reflect.DeepEqual(current.Spec.VolumeClaimTemplates[0].Spec.Resources, ss.Spec.VolumeClaimTemplates[0].Spec.Resources) {
+		if err := c.expandClaims(name, namespace, *current.Spec.Replicas, spec.StorageSize); err != nil {
+			return err
+		}
+
+		logger.Infof("Recreating Minio stateful set %s with storage amount %s.", name, spec.StorageSize)
+		orphan := meta_v1.DeletePropagationOrphan
+		if err := appsClient.StatefulSets(namespace).Delete(name, &meta_v1.DeleteOptions{PropagationPolicy: &orphan}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete minio stateful set %s. %+v", name, err)
+		}
+		if _, err := appsClient.StatefulSets(namespace).Create(ss); err != nil {
+			return fmt.Errorf("failed to recreate minio stateful set %s. %+v", name, err)
+		}
+		if added > 0 || !reflect.DeepEqual(current.Spec.Template, ss.Spec.Template) {
+			return c.restartMinioServers(name, namespace, serverCount)
+		}
+		return nil
+	}
+
+	templateChanged := !reflect.DeepEqual(current.Spec.Template, ss.Spec.Template)
+	if reflect.DeepEqual(current.Spec.Replicas, ss.Spec.Replicas) &&
+		!templateChanged &&
+		reflect.DeepEqual(current.Spec.UpdateStrategy, ss.Spec.UpdateStrategy) &&
+		reflect.DeepEqual(current.Annotations, ss.Annotations) {
+		logger.Infof("Minio stateful set %s did not change.", name)
+		return nil
+	}
+	current.Annotations = ss.Annotations
+	current.Spec.Replicas = ss.Spec.Replicas
+	current.Spec.Template = ss.Spec.Template
+	current.Spec.UpdateStrategy = ss.Spec.UpdateStrategy
+	if _, err := appsClient.StatefulSets(namespace).Update(current); err != nil {
+		return fmt.Errorf("failed to update minio stateful set %s. %+v", name, err)
+	}
+	if added > 0 || templateChanged {
+		return c.restartMinioServers(name, namespace, serverCount)
+	}
+	return nil
+}
+
+// restartMinioServers deletes the pods of the existing servers so that the stateful set starts them together with the
+// new settings and the servers of a new pool. A rolling update would leave servers with different lists of servers,
+// credentials or schemes, which cannot reach each other.
+func (c *MinioController) restartMinioServers(name, namespace string, serverCount int32) error {
+	logger.Infof("Restarting the %d servers of Minio object store %s.", serverCount, name)
+	for i := int32(0); i < serverCount; i++ {
+		podName := fmt.Sprintf("%s-%d", name, i)
+		err := c.context.Clientset.CoreV1().Pods(namespace).Delete(podName, &meta_v1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to restart minio server %s. %+v", podName, err)
+		}
+	}
+	return nil
+}
+
+// expandClaims increases the size of the claims of the servers. The storage class of the claims must allow volume
+// expansion.
+func (c *MinioController) expandClaims(name, namespace string, serverCount int32, storageSize string) error {
+	size := resource.MustParse(storageSize)
+	for i := int32(0); i < serverCount; i++ {
+		claimName := fmt.Sprintf("%s-%s-%d", minioVolumeName, name, i)
+		claim, err := c.context.Clientset.CoreV1().PersistentVolumeClaims(namespace).Get(claimName, meta_v1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get claim %s. %+v", claimName, err)
+		}
+		if claim.Spec.Resources.Requests == nil {
+			claim.Spec.Resources.Requests = v1.ResourceList{}
+		}
+		claim.Spec.Resources.Requests[v1.ResourceStorage] = size
+		if _, err := c.context.Clientset.CoreV1().PersistentVolumeClaims(namespace).Update(claim); err != nil {
+			return fmt.Errorf("failed to expand claim %s to %s. %+v", claimName, storageSize, err)
+		}
+		logger.Infof("Expanded claim %s to %s.", claimName, storageSize)
+	}
+	return nil
+}
+
+func (c *MinioController) updateMinioService(name, namespace string, spec miniov1alpha1.ObjectStoreSpec) error {
+	svc, err := c.context.Clientset.CoreV1().Services(namespace).Get(name, meta_v1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get minio headless service %s. %+v", name, err)
+	}
+	ports := []v1.ServicePort{{Port: spec.Port}}
+	if reflect.DeepEqual(svc.Spec.Ports, ports) && svc.Spec.PublishNotReadyAddresses {
+		return nil
+	}
+	svc.Spec.Ports = ports
+	svc.Spec.PublishNotReadyAddresses = true
+	if _, err := c.context.Clientset.CoreV1().Services(namespace).Update(svc); err != nil {
+		return fmt.Errorf("failed to update minio headless service %s. %+v", name, err)
+	}
+	return nil
 }
 
 func (c *MinioController) onAdd(obj interface{}) {
@@ -258,6 +635,8 @@ func (c *MinioController) onAdd(obj interface{}) {
 	err := validateObjectStoreSpec(objectstore.Spec)
 	if err != nil {
 		logger.Errorf("failed to validate object store config")
+		metrics.ReconcileFailed(minioControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, objectstore, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "failed to validate object store config. %+v", err)
 		return
 	}
 
@@ -266,6 +645,8 @@ func (c *MinioController) onAdd(obj interface{}) {
 	_, err = c.makeMinioHeadlessService(objectstore.Name, objectstore.Namespace, objectstore.Spec, ownerRef)
 	if err != nil {
 		logger.Errorf("failed to create minio headless service: %v", err)
+		metrics.ReconcileFailed(minioControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, objectstore, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "failed to create minio headless service: %v", err)
 		return
 	}
 	logger.Infof("Finished creating Minio headless service %s in namespace %s.", objectstore.Name, objectstore.Namespace)
@@ -275,19 +656,103 @@ func (c *MinioController) onAdd(obj interface{}) {
 	_, err = c.makeMinioStatefulSet(objectstore.Name, objectstore.Namespace, objectstore.Spec, ownerRef)
 	if err != nil {
 		logger.Errorf("failed to create minio stateful set: %v", err)
+		metrics.ReconcileFailed(minioControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, objectstore, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "failed to create minio stateful set: %v", err)
 		return
 	}
 	logger.Infof("Finished creating Minio stateful set %s in namespace %s.", objectstore.Name, objectstore.Namespace)
+
+	// Create the pod disruption budget.
+	err = c.makeMinioPodDisruptionBudget(objectstore.Name, objectstore.Namespace, []int32{int32(objectstore.Spec.Storage.NodeCount)}, []meta_v1.OwnerReference{ownerRef})
+	if err != nil {
+		logger.Errorf("failed to create minio pod disruption budget: %v", err)
+		metrics.ReconcileFailed(minioControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, objectstore, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "failed to create minio pod disruption budget: %v", err)
+		return
+	}
+	k8sutil.RecordEvent(c.context.Recorder, objectstore, v1.EventTypeNormal, k8sutil.EventReasonCreated, "created minio object store %s", objectstore.Name)
 }
 
 func (c *MinioController) onUpdate(oldObj, newObj interface{}) {
 	oldStore := oldObj.(*miniov1alpha1.ObjectStore).DeepCopy()
 	newStore := newObj.(*miniov1alpha1.ObjectStore).DeepCopy()
 
-	_ = oldStore
-	_ = newStore
+	if reflect.DeepEqual(oldStore.Spec, newStore.Spec) {
+		logger.Debugf("Object store %s in namespace %s did not change.", newStore.Name, newStore.Namespace)
+		return
+	}
 
-	logger.Infof("Received update on object store %s in namespace %s. This is currently unsupported.", oldStore.Name, oldStore.Namespace)
+	// Validate object store config and the changes.
+	if err := validateObjectStoreSpec(newStore.Spec); err != nil {
+		logger.Errorf("failed to validate object store config: %v", err)
+		metrics.ReconcileFailed(minioControllerName, "update")
+		return
+	}
+	if err := validateObjectStoreUpdate(oldStore.Spec, newStore.Spec); err != nil {
+		logger.Errorf("failed to update object store %s: %v", newStore.Name, err)
+		metrics.ReconcileFailed(minioControllerName, "update")
+		return
+	}
+
+	logger.Infof("Updating Minio object store %s in namespace %s.", newStore.Name, newStore.Namespace)
+	if err := c.updateMinioService(newStore.Name, newStore.Namespace, newStore.Spec); err != nil {
+		logger.Errorf("failed to update minio headless service: %v", err)
+		metrics.ReconcileFailed(minioControllerName, "update")
+		return
+	}
+	if err := c.updateMinioStatefulSet(newStore.Name, newStore.Namespace, newStore.Spec); err != nil {
+		logger.Errorf("failed to update minio stateful set: %v", err)
+		metrics.ReconcileFailed(minioControllerName, "update")
+		return
+	}
+	logger.Infof("Finished updating Minio object store %s in namespace %s.", newStore.Name, newStore.Namespace)
+}
+
+func (c *MinioController) watchCredentials(namespace string, stopCh chan struct{}) {
+	source := &cache.ListWatch{
+		ListFunc: func(options meta_v1.ListOptions) (runtime.Object, error) {
+			return c.context.Clientset.CoreV1().Secrets(namespace).List(options)
+		},
+		WatchFunc: func(options meta_v1.ListOptions) (watch.Interface, error) {
+			return c.context.Clientset.CoreV1().Secrets(namespace).Watch(options)
+		},
+	}
+	_, controller := cache.NewInformer(source, &v1.Secret{}, 0, cache.ResourceEventHandlerFuncs{
+		UpdateFunc: c.onSecretUpdate,
+	})
+	go controller.Run(stopCh)
+}
+
+// onSecretUpdate restarts the servers of the object stores whose credentials changed. The object stores in all the
+// namespaces are checked since the credentials may be stored in another namespace than the object store.
+func (c *MinioController) onSecretUpdate(oldObj, newObj interface{}) {
+	oldSecret, ok := oldObj.(*v1.Secret)
+	if !ok {
+		return
+	}
+	newSecret, ok := newObj.(*v1.Secret)
+	if !ok {
+		return
+	}
+	if reflect.DeepEqual(oldSecret.Data, newSecret.Data) {
+		return
+	}
+
+	stores, err := c.context.RookClientset.MinioV1alpha1().ObjectStores(v1.NamespaceAll).List(meta_v1.ListOptions{})
+	if err != nil {
+		logger.Errorf("failed to list object stores using secret %s in namespace %s: %v", newSecret.Name, newSecret.Namespace, err)
+		return
+	}
+	for _, store := range stores.Items {
+		if store.Spec.Credentials.Name != newSecret.Name || store.Spec.Credentials.Namespace != newSecret.Namespace {
+			continue
+		}
+
+		logger.Infof("Credentials %s of object store %s changed, restarting the minio pods.", newSecret.Name, store.Name)
+		if err := c.updateMinioStatefulSet(store.Name, store.Namespace, store.Spec); err != nil {
+			logger.Errorf("failed to restart the minio pods of object store %s: %v", store.Name, err)
+		}
+	}
 }
 
 func (c *MinioController) onDelete(obj interface{}) {
@@ -16,14 +16,18 @@ limitations under the License.
 package minio
 
 import (
+	"fmt"
 	"testing"
 
 	miniov "github.com/rook/rook/pkg/apis/minio.rook.io/v1alpha1"
 	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
 	"github.com/rook/rook/pkg/clusterd"
 	testop "github.com/rook/rook/pkg/operator/test"
 	"github.com/stretchr/testify/assert"
+	"k8s.io/api/apps/v1beta2"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -74,12 +78,14 @@ func TestOnAdd(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, svc)
 	assert.Equal(t, magicPort, svc.Spec.Ports[0].Port)
+	assert.True(t, svc.Spec.PublishNotReadyAddresses)
 
 	// verify stateful set
 	ss, err := clientset.AppsV1beta2().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
 	assert.Nil(t, err)
 	assert.NotNil(t, ss)
 	assert.Equal(t, int32(6), *ss.Spec.Replicas)
+	assert.Equal(t, v1beta2.ParallelPodManagement, ss.Spec.PodManagementPolicy)
 	assert.Equal(t, 1, len(ss.Spec.VolumeClaimTemplates))
 	assert.Equal(t, 1, len(ss.Spec.Template.Spec.Containers))
 	container := ss.Spec.Template.Spec.Containers[0]
@@ -127,3 +133,240 @@ func TestSpecVerification(t *testing.T) {
 	err = validateObjectStoreSpec(objectstore.Spec)
 	assert.NotNil(t, err)
 }
+
+func TestOnUpdate(t *testing.T) {
+	namespace := "rook-minio-123"
+	objectstore := &miniov.ObjectStore{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      appName,
+			Namespace: namespace,
+		},
+		Spec: miniov.ObjectStoreSpec{
+			Storage: rookalpha.StorageScopeSpec{NodeCount: 4},
+			Port:    9000,
+			Credentials: v1.SecretReference{
+				Name:      "access-keys",
+				Namespace: namespace,
+			},
+			StorageSize: "10G",
+		},
+	}
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "access-keys", Namespace: namespace},
+		Data:       map[string][]byte{"username": []byte("user"), "password": []byte("pass")},
+	}
+
+	clientset := testop.New(3)
+	context := &clusterd.Context{Clientset: clientset, RookClientset: rookfake.NewSimpleClientset(objectstore)}
+	controller := NewMinioController(context, "rook/minio:mockTag")
+	_, err := clientset.CoreV1().Secrets(namespace).Create(secret)
+	assert.Nil(t, err)
+	controller.onAdd(objectstore)
+
+	ss, err := clientset.AppsV1beta2().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "4", ss.Annotations[serverPoolsAnnotation])
+	assert.Equal(t, []string{"server", "--address", ":9000",
+		"http://some_object_store-0.some_object_store.rook-minio-123/data",
+		"http://some_object_store-1.some_object_store.rook-minio-123/data",
+		"http://some_object_store-2.some_object_store.rook-minio-123/data",
+		"http://some_object_store-3.some_object_store.rook-minio-123/data"},
+		ss.Spec.Template.Spec.Containers[0].Args)
+
+	// adding nodes expands the object store with a new server pool and restarts the existing servers
+	for i := 0; i < 4; i++ {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%d", appName, i), Namespace: namespace}}
+		_, err = clientset.CoreV1().Pods(namespace).Create(pod)
+		assert.Nil(t, err)
+	}
+	updated := objectstore.DeepCopy()
+	updated.Spec.Storage.NodeCount = 8
+	updated.Spec.Port = 9001
+	controller.onUpdate(objectstore, updated)
+	ss, err = clientset.AppsV1beta2().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(8), *ss.Spec.Replicas)
+	assert.Equal(t, "4,4", ss.Annotations[serverPoolsAnnotation])
+	assert.Equal(t, []string{"server", "--address", ":9001",
+		"http://some_object_store-{0...3}.some_object_store.rook-minio-123/data",
+		"http://some_object_store-{4...7}.some_object_store.rook-minio-123/data"},
+		ss.Spec.Template.Spec.Containers[0].Args)
+	pods, err := clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(pods.Items))
+	svc, err := clientset.CoreV1().Services(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(9001), svc.Spec.Ports[0].Port)
+
+	// removing nodes or storage is rejected
+	objectstore = updated
+	updated = objectstore.DeepCopy()
+	updated.Spec.Storage.NodeCount = 6
+	controller.onUpdate(objectstore, updated)
+	updated.Spec.Storage.NodeCount = 8
+	updated.Spec.StorageSize = "5G"
+	controller.onUpdate(objectstore, updated)
+	ss, err = clientset.AppsV1beta2().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(8), *ss.Spec.Replicas)
+	size := ss.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests[v1.ResourceStorage]
+	assert.Equal(t, "10G", size.String())
+
+	// adding storage expands the claims and the claim template
+	claim := &v1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data-some_object_store-0", Namespace: namespace},
+		Spec: v1.PersistentVolumeClaimSpec{
+			Resources: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("10G")}},
+		},
+	}
+	_, err = clientset.CoreV1().PersistentVolumeClaims(namespace).Create(claim)
+	assert.Nil(t, err)
+	updated.Spec.StorageSize = "20G"
+	controller.onUpdate(objectstore, updated)
+	ss, err = clientset.AppsV1beta2().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	size = ss.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests[v1.ResourceStorage]
+	assert.Equal(t, "20G", size.String())
+	assert.Equal(t, "4,4", ss.Annotations[serverPoolsAnnotation])
+	claim, err = clientset.CoreV1().PersistentVolumeClaims(namespace).Get("data-some_object_store-0", metav1.GetOptions{})
+	assert.Nil(t, err)
+	size = claim.Spec.Resources.Requests[v1.ResourceStorage]
+	assert.Equal(t, "20G", size.String())
+
+	// rotating the credentials updates the pods and restarts all the servers together
+	for i := 0; i < 8; i++ {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%d", appName, i), Namespace: namespace}}
+		_, err = clientset.CoreV1().Pods(namespace).Create(pod)
+		assert.Nil(t, err)
+	}
+	objectstore = updated
+	_, err = context.RookClientset.MinioV1alpha1().ObjectStores(namespace).Update(objectstore)
+	assert.Nil(t, err)
+	rotated := secret.DeepCopy()
+	rotated.Data["password"] = []byte("newpass")
+	_, err = clientset.CoreV1().Secrets(namespace).Update(rotated)
+	assert.Nil(t, err)
+	controller.onSecretUpdate(secret, rotated)
+	ss, err = clientset.AppsV1beta2().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, ss.Spec.Template.Spec.Containers[0].Env, v1.EnvVar{Name: "MINIO_SECRET_KEY", Value: "newpass"})
+	assert.Equal(t, v1beta2.OnDeleteStatefulSetStrategyType, ss.Spec.UpdateStrategy.Type)
+	pods, err = clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(pods.Items))
+}
+
+func TestValidateObjectStoreUpdate(t *testing.T) {
+	oldSpec := miniov.ObjectStoreSpec{Storage: rookalpha.StorageScopeSpec{NodeCount: 4}, StorageSize: "10G"}
+	newSpec := oldSpec
+	assert.Nil(t, validateObjectStoreUpdate(oldSpec, newSpec))
+
+	// a new server pool must have at least four servers
+	newSpec.Storage.NodeCount = 6
+	assert.NotNil(t, validateObjectStoreUpdate(oldSpec, newSpec))
+	newSpec.Storage.NodeCount = 10
+	assert.Nil(t, validateObjectStoreUpdate(oldSpec, newSpec))
+	newSpec.Storage.NodeCount = 2
+	assert.NotNil(t, validateObjectStoreUpdate(oldSpec, newSpec))
+
+	newSpec.Storage.NodeCount = 4
+	newSpec.StorageSize = "1Ti"
+	assert.Nil(t, validateObjectStoreUpdate(oldSpec, newSpec))
+	newSpec.StorageSize = "1G"
+	assert.NotNil(t, validateObjectStoreUpdate(oldSpec, newSpec))
+	newSpec.StorageSize = "lots"
+	assert.NotNil(t, validateObjectStoreUpdate(oldSpec, newSpec))
+}
+
+func TestTLSAndDisruptionBudget(t *testing.T) {
+	namespace := "rook-minio-123"
+	objectstore := &miniov.ObjectStore{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      appName,
+			Namespace: namespace,
+		},
+		Spec: miniov.ObjectStoreSpec{
+			Storage:     rookalpha.StorageScopeSpec{NodeCount: 16},
+			Port:        9000,
+			Credentials: v1.SecretReference{Name: "access-keys", Namespace: namespace},
+			StorageSize: "10G",
+			TLSSecret:   "minio-tls",
+		},
+	}
+
+	clientset := testop.New(3)
+	context := &clusterd.Context{Clientset: clientset}
+	controller := NewMinioController(context, "rook/minio:mockTag")
+	_, err := clientset.CoreV1().Secrets(namespace).Create(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "access-keys", Namespace: namespace},
+		Data:       map[string][]byte{"username": []byte("user"), "password": []byte("pass")},
+	})
+	assert.Nil(t, err)
+
+	// the stateful set is not created without the tls secret
+	controller.onAdd(objectstore)
+	_, err = clientset.AppsV1beta2().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.NotNil(t, err)
+
+	_, err = clientset.CoreV1().Secrets(namespace).Create(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "minio-tls", Namespace: namespace},
+		Data:       map[string][]byte{"tls.crt": []byte("cert"), "tls.key": []byte("key"), "ca.crt": []byte("ca")},
+	})
+	assert.Nil(t, err)
+	clientset.CoreV1().Services(namespace).Delete(appName, &metav1.DeleteOptions{})
+	controller.onAdd(objectstore)
+	ss, err := clientset.AppsV1beta2().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+
+	// the servers are accessed with https and load the certificates from the secret
+	container := ss.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, []string{"server", "--address", ":9000", "--certs-dir", "/etc/minio/certs"}, container.Args[:5])
+	assert.Equal(t, "https://some_object_store-0.some_object_store.rook-minio-123/data", container.Args[5])
+	assert.Equal(t, v1.VolumeMount{Name: "certs", MountPath: "/etc/minio/certs", ReadOnly: true}, container.VolumeMounts[1])
+	volume := ss.Spec.Template.Spec.Volumes[1]
+	assert.Equal(t, "minio-tls", volume.Secret.SecretName)
+	assert.Equal(t, []v1.KeyToPath{{Key: "tls.crt", Path: "public.crt"}, {Key: "tls.key", Path: "private.key"}, {Key: "ca.crt", Path: "CAs/ca.crt"}}, volume.Secret.Items)
+
+	assert.Equal(t, "/minio/health/live", container.LivenessProbe.HTTPGet.Path)
+	assert.Equal(t, v1.URISchemeHTTPS, container.LivenessProbe.HTTPGet.Scheme)
+	assert.Equal(t, "/minio/health/ready", container.ReadinessProbe.HTTPGet.Path)
+	assert.Equal(t, 9000, container.ReadinessProbe.HTTPGet.Port.IntValue())
+
+	// a single erasure set of 16 servers keeps its write quorum with 7 servers down
+	pdb, err := clientset.PolicyV1beta1().PodDisruptionBudgets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 7, pdb.Spec.MaxUnavailable.IntValue())
+
+	// the budget is limited by the smallest erasure set when the object store is expanded
+	updated := objectstore.DeepCopy()
+	updated.Spec.Storage.NodeCount = 20
+	controller.onUpdate(objectstore, updated)
+	pdb, err = clientset.PolicyV1beta1().PodDisruptionBudgets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, pdb.Spec.MaxUnavailable.IntValue())
+
+	// disabling tls switches all the servers to http together
+	for i := 0; i < 20; i++ {
+		pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-%d", appName, i), Namespace: namespace}}
+		_, err = clientset.CoreV1().Pods(namespace).Create(pod)
+		assert.Nil(t, err)
+	}
+	objectstore = updated
+	updated = objectstore.DeepCopy()
+	updated.Spec.TLSSecret = ""
+	controller.onUpdate(objectstore, updated)
+	ss, err = clientset.AppsV1beta2().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "http://some_object_store-{0...15}.some_object_store.rook-minio-123/data", ss.Spec.Template.Spec.Containers[0].Args[3])
+	pods, err := clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(pods.Items))
+}
+
+func TestMaxUnavailableServers(t *testing.T) {
+	assert.Equal(t, int32(1), maxUnavailableServers([]int32{4}))
+	assert.Equal(t, int32(2), maxUnavailableServers([]int32{6}))
+	assert.Equal(t, int32(7), maxUnavailableServers([]int32{32}))
+	assert.Equal(t, int32(4), maxUnavailableServers([]int32{18}))
+	assert.Equal(t, int32(1), maxUnavailableServers([]int32{16, 4}))
+}
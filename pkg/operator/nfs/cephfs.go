@@ -0,0 +1,279 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfs
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	s "strings"
+
+	nfsv1alpha1 "github.com/rook/rook/pkg/apis/nfs.rook.io/v1alpha1"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	cephConfigSecretName = "rook-nfs-ceph-config"
+	cephConfigVolume     = "rook-nfs-ceph-config"
+	cephConfigPath       = "/etc/ceph"
+	cephConfigFile       = "ceph.conf"
+
+	cephKeyringTemplate = `[client.%s]
+	key = %s
+`
+	cephClientConfigTemplate = `[client.%s]
+	mon host = %s
+	keyring = %s
+	client mds namespace = %s
+`
+)
+
+// hasCephFSExports returns whether any export of the server shares a CephFS filesystem
+func hasCephFSExports(spec *nfsv1alpha1.NFSServerSpec) bool {
+	for _, export := range spec.Exports {
+		if export.CephFS != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// cephUserName returns the name of the ceph user with which ganesha accesses the filesystem of a CephFS export
+func cephUserName(namespace string, export nfsv1alpha1.ExportsSpec) string {
+	return fmt.Sprintf("nfs-ganesha.%s.%s", namespace, export.Name)
+}
+
+func cephFSPath(cephFS *nfsv1alpha1.CephFSExportSpec) string {
+	if cephFS.Path == "" {
+		return "/"
+	}
+	return path.Clean("/" + cephFS.Path)
+}
+
+// cephFSCaps returns the caps of the ceph user that restrict the access to the exported path of the filesystem
+func cephFSCaps(cephFS *nfsv1alpha1.CephFSExportSpec) []string {
+	return []string{
+		"mon", "allow r",
+		"mds", fmt.Sprintf("allow rw path=%s", cephFSPath(cephFS)),
+		"osd", fmt.Sprintf("allow rw tag cephfs data=%s", cephFS.Filesystem),
+	}
+}
+
+// createCephConfig creates the ceph users of the CephFS exports and stores their keyrings and the ceph config in a
+// secret that is mounted by the ganesha pods
+func (c *Controller) createCephConfig(nfsServer *nfsServer) error {
+	if !hasCephFSExports(&nfsServer.spec) {
+		return nil
+	}
+
+	clusters := map[string]*cephconfig.ClusterInfo{}
+	config := ""
+	data := map[string][]byte{}
+	for _, export := range nfsServer.spec.Exports {
+		if export.CephFS == nil {
+			continue
+		}
+
+		clusterNamespace := export.CephFS.ClusterNamespace
+		cluster, ok := clusters[clusterNamespace]
+		if !ok {
+			var err error
+			cluster, err = loadCephCluster(c.context, clusterNamespace)
+			if err != nil {
+				return err
+			}
+			clusters[clusterNamespace] = cluster
+		}
+
+		if _, err := c.context.RookClientset.CephV1beta1().Filesystems(clusterNamespace).Get(export.CephFS.Filesystem, metav1.GetOptions{}); err != nil {
+			return fmt.Errorf("failed to get filesystem %s of export %s. %+v", export.CephFS.Filesystem, export.Name, err)
+		}
+
+		username := cephUserName(nfsServer.namespace, export)
+		key, err := createCephUser(c.context, cluster.Name, username, cephFSCaps(export.CephFS))
+		if err != nil {
+			return err
+		}
+
+		keyringFile := username + ".keyring"
+		data[keyringFile] = []byte(fmt.Sprintf(cephKeyringTemplate, username, key))
+		config += fmt.Sprintf(cephClientConfigTemplate, username, monHosts(cluster), path.Join(cephConfigPath, keyringFile), export.CephFS.Filesystem)
+	}
+	data[cephConfigFile] = []byte(config)
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            cephConfigSecretName,
+			Namespace:       nfsServer.namespace,
+			OwnerReferences: []metav1.OwnerReference{nfsServer.ownerRef},
+			Labels:          createAppLabels(),
+		},
+		Data: data,
+	}
+	secrets := c.context.Clientset.CoreV1().Secrets(nfsServer.namespace)
+	if _, err := secrets.Create(secret); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create ceph config secret. %+v", err)
+		}
+		if _, err := secrets.Update(secret); err != nil {
+			return fmt.Errorf("failed to update ceph config secret. %+v", err)
+		}
+	}
+	logger.Infof("ceph config of the cephfs exports stored in secret %s", cephConfigSecretName)
+	return nil
+}
+
+// usesCephCluster returns whether any CephFS export of the server shares a filesystem of the ceph cluster
+func usesCephCluster(spec *nfsv1alpha1.NFSServerSpec, clusterNamespace string) bool {
+	for _, export := range spec.Exports {
+		if export.CephFS != nil && export.CephFS.ClusterNamespace == clusterNamespace {
+			return true
+		}
+	}
+	return false
+}
+
+// watchMonEndpoints refreshes the ceph config of the nfs servers when the mons of the ceph clusters of their CephFS
+// exports change, so that ganesha still finds the mons after they are failed over
+func (c *Controller) watchMonEndpoints(namespace string, stopCh chan struct{}) {
+	selector := fields.OneTermEqualSelector("metadata.name", mon.EndpointConfigMapName).String()
+	source := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			options.FieldSelector = selector
+			return c.context.Clientset.CoreV1().ConfigMaps(v1.NamespaceAll).List(options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = selector
+			return c.context.Clientset.CoreV1().ConfigMaps(v1.NamespaceAll).Watch(options)
+		},
+	}
+	_, controller := cache.NewInformer(source, &v1.ConfigMap{}, 0, cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			c.onMonEndpointsUpdate(namespace, oldObj, newObj)
+		},
+	})
+	go controller.Run(stopCh)
+}
+
+// onMonEndpointsUpdate rewrites the ceph config secret of the nfs servers that export a filesystem of the ceph cluster
+// whose mons changed
+func (c *Controller) onMonEndpointsUpdate(namespace string, oldObj, newObj interface{}) {
+	oldCM, ok := oldObj.(*v1.ConfigMap)
+	if !ok {
+		return
+	}
+	newCM, ok := newObj.(*v1.ConfigMap)
+	if !ok {
+		return
+	}
+	if oldCM.Data[mon.EndpointDataKey] == newCM.Data[mon.EndpointDataKey] {
+		return
+	}
+
+	servers, err := c.context.RookClientset.NfsV1alpha1().NFSServers(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		logger.Warningf("failed to list nfs servers to update their mons. %+v", err)
+		return
+	}
+	for i := range servers.Items {
+		server := &servers.Items[i]
+		if !usesCephCluster(&server.Spec, newCM.Namespace) {
+			continue
+		}
+		logger.Infof("mons of ceph cluster %s changed, updating the ceph config of nfs server %s", newCM.Namespace, server.Name)
+		if err := c.createCephConfig(newNfsServer(server, c.context)); err != nil {
+			logger.Errorf("failed to update the ceph config of nfs server %s. %+v", server.Name, err)
+		}
+	}
+}
+
+// deleteCephUsers deletes the ceph users of the CephFS exports that are not in the new spec
+func (c *Controller) deleteCephUsers(namespace string, oldSpec, newSpec *nfsv1alpha1.NFSServerSpec) {
+	current := map[string]bool{}
+	for _, export := range newSpec.Exports {
+		if export.CephFS != nil {
+			current[export.CephFS.ClusterNamespace+"/"+cephUserName(namespace, export)] = true
+		}
+	}
+
+	for _, export := range oldSpec.Exports {
+		if export.CephFS == nil {
+			continue
+		}
+		username := cephUserName(namespace, export)
+		if current[export.CephFS.ClusterNamespace+"/"+username] {
+			continue
+		}
+		cluster, err := loadCephCluster(c.context, export.CephFS.ClusterNamespace)
+		if err != nil {
+			logger.Warningf("failed to delete ceph user %s. %+v", username, err)
+			continue
+		}
+		if err := client.AuthDelete(c.context, cluster.Name, "client."+username); err != nil {
+			logger.Warningf("failed to delete ceph user %s. %+v", username, err)
+			continue
+		}
+		logger.Infof("deleted ceph user %s of removed export %s", username, export.Name)
+	}
+}
+
+// loadCephCluster loads the info of a rook ceph cluster and writes the admin config to run ceph commands against it
+func loadCephCluster(context *clusterd.Context, clusterNamespace string) (*cephconfig.ClusterInfo, error) {
+	cluster, _, _, err := mon.LoadClusterInfo(context, clusterNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ceph cluster info from namespace %s. %+v", clusterNamespace, err)
+	}
+	if err := cephconfig.GenerateAdminConnectionConfig(context, cluster); err != nil {
+		return nil, fmt.Errorf("failed to write admin config of ceph cluster in namespace %s. %+v", clusterNamespace, err)
+	}
+	return cluster, nil
+}
+
+// createCephUser creates the ceph user or updates its caps if it already exists, and returns its key
+func createCephUser(context *clusterd.Context, clusterName, username string, caps []string) (string, error) {
+	name := "client." + username
+	key, err := client.AuthGetOrCreateKey(context, clusterName, name, caps)
+	if err == nil {
+		return key, nil
+	}
+
+	// the user already exists with other caps if the path of the export changed
+	logger.Infof("updating caps of ceph user %s. %+v", username, err)
+	if err := client.AuthUpdateCaps(context, clusterName, name, caps); err != nil {
+		return "", err
+	}
+	return client.AuthGetKey(context, clusterName, name)
+}
+
+func monHosts(cluster *cephconfig.ClusterInfo) string {
+	hosts := []string{}
+	for _, monitor := range cluster.Monitors {
+		hosts = append(hosts, monitor.Endpoint)
+	}
+	sort.Strings(hosts)
+	return s.Join(hosts, ",")
+}
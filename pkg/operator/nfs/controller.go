@@ -19,7 +19,10 @@ package nfs
 
 import (
 	"fmt"
+	"path"
 	"reflect"
+	"regexp"
+	"strconv"
 	s "strings"
 
 	"github.com/coreos/pkg/capnslog"
@@ -27,8 +30,10 @@ import (
 	nfsv1alpha1 "github.com/rook/rook/pkg/apis/nfs.rook.io/v1alpha1"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/metrics"
 	"k8s.io/api/apps/v1beta1"
 	"k8s.io/api/core/v1"
+	rbacv1beta1 "k8s.io/api/rbac/v1beta1"
 	apiextensionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -45,10 +50,23 @@ const (
 	nfsConfigMapPath         = "/nfs-ganesha/config"
 	nfsPort                  = 2049
 	rpcPort                  = 111
+	mountdPort               = 20048
+	nlmPort                  = 32803
+	firstExportID            = 10
+	krb5KeytabVolume         = "nfs-krb5-keytab"
+	krb5KeytabPath           = "/etc/rook/nfs-krb5"
+	krb5KeytabKey            = "krb5.keytab"
+	krb5ConfVolume           = "nfs-krb5-config"
+	krb5ConfKey              = "krb5.conf"
+	serverServiceAccount     = "rook-nfs-server"
+	provisionerClusterRole   = "rook-nfs-provisioner"
+	nfsControllerName        = "nfs"
 )
 
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "nfs-operator")
 
+var exportIDRegex = regexp.MustCompile(`Export_Id = (\d+);\s*Path = [^;]+;\s*Pseudo = /([^;]+);`)
+
 // NFSResource represents the nfs export custom resource
 var NFSResource = opkit.CustomResource{
 	Name:    customResourceName,
@@ -82,27 +100,34 @@ func (c *Controller) StartWatch(namespace string, stopCh chan struct{}) error {
 	}
 
 	logger.Infof("start watching nfs server resources in namespace %s", namespace)
-	watcher := opkit.NewWatcher(NFSResource, namespace, resourceHandlerFuncs, c.context.RookClientset.NfsV1alpha1().RESTClient())
+	watcher := opkit.NewWatcher(NFSResource, namespace, metrics.InstrumentHandlers(nfsControllerName, resourceHandlerFuncs), c.context.RookClientset.NfsV1alpha1().RESTClient())
 	go watcher.Watch(&nfsv1alpha1.NFSServer{}, stopCh)
 
+	go c.refreshStatus(namespace, stopCh)
+
+	// update the mons of the cephfs exports when they are failed over
+	c.watchMonEndpoints(namespace, stopCh)
+
 	return nil
 }
 
 type nfsServer struct {
-	name      string
-	context   *clusterd.Context
-	namespace string
-	spec      nfsv1alpha1.NFSServerSpec
-	ownerRef  metav1.OwnerReference
+	name       string
+	serverName string
+	context    *clusterd.Context
+	namespace  string
+	spec       nfsv1alpha1.NFSServerSpec
+	ownerRef   metav1.OwnerReference
 }
 
 func newNfsServer(c *nfsv1alpha1.NFSServer, context *clusterd.Context) *nfsServer {
 	return &nfsServer{
-		name:      appName,
-		context:   context,
-		namespace: c.Namespace,
-		spec:      c.Spec,
-		ownerRef:  nfsOwnerRef(c.Namespace, string(c.UID)),
+		name:       appName,
+		serverName: c.Name,
+		context:    context,
+		namespace:  c.Namespace,
+		spec:       c.Spec,
+		ownerRef:   nfsOwnerRef(c.Namespace, string(c.UID)),
 	}
 }
 
@@ -117,41 +142,37 @@ func nfsOwnerRef(namespace, nfsServerID string) metav1.OwnerReference {
 	}
 }
 
-func getServerConfig(exports []nfsv1alpha1.ExportsSpec) map[string]map[string]string {
-	claimConfigOpt := make(map[string]map[string]string)
-	configOpt := make(map[string]string)
-
-	for _, export := range exports {
-		claimName := export.PersistentVolumeClaim.ClaimName
-		if claimName != "" {
-			configOpt["accessMode"] = export.Server.AccessMode
-			configOpt["squash"] = export.Server.Squash
-			claimConfigOpt[claimName] = configOpt
-		}
-	}
-
-	return claimConfigOpt
-}
-
 func createAppLabels() map[string]string {
 	return map[string]string{
 		k8sutil.AppAttr: appName,
 	}
 }
 
-func createServicePorts() []v1.ServicePort {
-	return []v1.ServicePort{
-		{
-			Name:       "nfs",
-			Port:       int32(nfsPort),
-			TargetPort: intstr.FromInt(int(nfsPort)),
-		},
-		{
-			Name:       "rpc",
-			Port:       int32(rpcPort),
-			TargetPort: intstr.FromInt(int(rpcPort)),
-		},
+func createServicePort(name string, port int, protocol v1.Protocol) v1.ServicePort {
+	return v1.ServicePort{
+		Name:       name,
+		Port:       int32(port),
+		TargetPort: intstr.FromInt(port),
+		Protocol:   protocol,
+	}
+}
+
+func createServicePorts(spec *nfsv1alpha1.NFSServerSpec) []v1.ServicePort {
+	ports := []v1.ServicePort{
+		createServicePort("nfs", nfsPort, v1.ProtocolTCP),
+		createServicePort("rpc", rpcPort, v1.ProtocolTCP),
+	}
+	if usesProtocol(spec, 3) {
+		ports = append(ports,
+			createServicePort("mountd", mountdPort, v1.ProtocolTCP),
+			createServicePort("nlm", nlmPort, v1.ProtocolTCP))
 	}
+	if usesTransport(spec, "UDP") {
+		for _, port := range ports {
+			ports = append(ports, createServicePort(port.Name+"-udp", port.TargetPort.IntValue(), v1.ProtocolUDP))
+		}
+	}
+	return ports
 }
 
 func (c *Controller) createNFSService(nfsServer *nfsServer) error {
@@ -166,7 +187,7 @@ func (c *Controller) createNFSService(nfsServer *nfsServer) error {
 		Spec: v1.ServiceSpec{
 			Selector: createAppLabels(),
 			Type:     v1.ServiceTypeClusterIP,
-			Ports:    createServicePorts(),
+			Ports:    createServicePorts(&nfsServer.spec),
 		},
 	}
 
@@ -182,54 +203,244 @@ func (c *Controller) createNFSService(nfsServer *nfsServer) error {
 	return nil
 }
 
-func createGaneshaExport(id int, path string, access string, squash string) string {
-	var accessType string
-	if access == "ReadWrite" {
-		accessType = "RW"
+// ganeshaAccessType returns the ganesha access type of an access mode. The access mode defaults to ReadWrite.
+func ganeshaAccessType(accessMode string) string {
+	switch accessMode {
+	case "ReadOnly":
+		return "RO"
+	case "none":
+		return "None"
+	default:
+		return "RW"
+	}
+}
+
+// ganeshaSquash returns the ganesha squash option. The squash defaults to none.
+func ganeshaSquash(squash string) string {
+	if squash == "" {
+		return "none"
+	}
+	return s.ToLower(squash)
+}
+
+// ganeshaExportPaths returns the path to export, the pseudo path in the NFSv4 namespace and the FSAL settings of an
+// export. The CephFS exports are accessed by ganesha with the ceph FSAL, the PVCs are mounted in the pod.
+func ganeshaExportPaths(namespace string, export nfsv1alpha1.ExportsSpec) (string, string, string) {
+	if export.CephFS != nil {
+		fsal := `Name = CEPH;
+		User_Id = "` + cephUserName(namespace, export) + `";`
+		return cephFSPath(export.CephFS), export.Name, fsal
+	}
+	claimName := export.PersistentVolumeClaim.ClaimName
+	return "/" + claimName, claimName, "Name = VFS;"
+}
+
+func provisionerRoleBindingName(namespace string) string {
+	return fmt.Sprintf("%s-%s", provisionerClusterRole, namespace)
+}
+
+// createProvisionerRBAC creates the service account of the nfs server pods and binds it to the cluster role of the
+// provisioner that runs in the pods
+func (c *Controller) createProvisionerRBAC(nfsServer *nfsServer) error {
+	serviceAccount := &v1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            serverServiceAccount,
+			Namespace:       nfsServer.namespace,
+			OwnerReferences: []metav1.OwnerReference{nfsServer.ownerRef},
+			Labels:          createAppLabels(),
+		},
+	}
+	if _, err := c.context.Clientset.CoreV1().ServiceAccounts(nfsServer.namespace).Create(serviceAccount); err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create service account %s. %+v", serverServiceAccount, err)
+	}
+
+	// the cluster role binding is not owned by the nfs server since it is not namespaced
+	binding := &rbacv1beta1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   provisionerRoleBindingName(nfsServer.namespace),
+			Labels: createAppLabels(),
+		},
+		RoleRef: rbacv1beta1.RoleRef{
+			APIGroup: "rbac.authorization.k8s.io",
+			Kind:     "ClusterRole",
+			Name:     provisionerClusterRole,
+		},
+		Subjects: []rbacv1beta1.Subject{
+			{
+				Kind:      "ServiceAccount",
+				Name:      serverServiceAccount,
+				Namespace: nfsServer.namespace,
+			},
+		},
+	}
+	if _, err := c.context.Clientset.RbacV1beta1().ClusterRoleBindings().Create(binding); err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create cluster role binding %s. %+v", binding.Name, err)
+	}
+	return nil
+}
+
+func createGaneshaExport(id int, namespace string, export nfsv1alpha1.ExportsSpec) string {
+	server := export.Server
+	exportPath, pseudo, fsal := ganeshaExportPaths(namespace, export)
+
+	protocols := []string{}
+	for _, protocol := range server.Protocols {
+		protocols = append(protocols, strconv.Itoa(protocol))
+	}
+	if len(protocols) == 0 {
+		protocols = []string{"4"}
+	}
+	transports := server.Transports
+	if len(transports) == 0 {
+		transports = []string{"TCP"}
+	}
+	securityFlavors := server.SecurityFlavors
+	if len(securityFlavors) == 0 {
+		securityFlavors = []string{"sys"}
+	}
+
+	// when the allowed clients are specified, the other clients do not have access to the export
+	accessType := ganeshaAccessType(server.AccessMode)
+	if len(server.AllowedClients) > 0 {
+		accessType = "None"
+	}
+
+	clients := ""
+	for _, allowed := range server.AllowedClients {
+		accessMode := allowed.AccessMode
+		if accessMode == "" {
+			accessMode = server.AccessMode
+		}
+		squash := allowed.Squash
+		if squash == "" {
+			squash = server.Squash
+		}
+		clients += `
+	CLIENT {
+		Clients = ` + s.Join(allowed.Clients, ", ") + `;
+		Access_Type = ` + ganeshaAccessType(accessMode) + `;
+		Squash = ` + ganeshaSquash(squash) + `;
+	}`
 	}
+
 	idStr := fmt.Sprintf("%v", id)
 	nfsGaneshaConfig := `
 EXPORT {
 	Export_Id = ` + idStr + `;
-	Path = /` + path + `;
-	Pseudo = /` + path + `;
-	Protocols = 4;
-	Transports = TCP;
-	Sectype = sys;
+	Path = ` + exportPath + `;
+	Pseudo = /` + pseudo + `;
+	Protocols = ` + s.Join(protocols, ", ") + `;
+	Transports = ` + s.Join(transports, ", ") + `;
+	Sectype = ` + s.Join(securityFlavors, ", ") + `;
 	Access_Type = ` + accessType + `;
-	Squash = ` + squash + `;
+	Squash = ` + ganeshaSquash(server.Squash) + `;
 	FSAL {
-		Name = VFS;
-	}
+		` + fsal + `
+	}` + clients + `
 }`
 
 	return nfsGaneshaConfig
 }
 
-func createGaneshaConfig(spec *nfsv1alpha1.NFSServerSpec) string {
-	serverConfig := getServerConfig(spec.Exports)
+// usesProtocol returns whether any export of the server uses the given NFS protocol version
+func usesProtocol(spec *nfsv1alpha1.NFSServerSpec, protocol int) bool {
+	for _, export := range spec.Exports {
+		protocols := export.Server.Protocols
+		if len(protocols) == 0 {
+			protocols = []int{4}
+		}
+		for _, p := range protocols {
+			if p == protocol {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// usesTransport returns whether any export of the server uses the given transport protocol
+func usesTransport(spec *nfsv1alpha1.NFSServerSpec, transport string) bool {
+	for _, export := range spec.Exports {
+		transports := export.Server.Transports
+		if len(transports) == 0 {
+			transports = []string{"TCP"}
+		}
+		for _, t := range transports {
+			if s.ToUpper(t) == transport {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getExportIDs returns the export ids by pseudo path of the exports in a ganesha config
+func getExportIDs(config string) map[string]int {
+	exportIDs := make(map[string]int)
+	for _, match := range exportIDRegex.FindAllStringSubmatch(config, -1) {
+		id, err := strconv.Atoi(match[1])
+		if err == nil {
+			exportIDs[match[2]] = id
+		}
+	}
+	return exportIDs
+}
+
+// createGaneshaConfig creates the ganesha config for the exports. The export ids of the existing exports are kept
+// so that the running ganesha server can reload only the exports that changed.
+func createGaneshaConfig(namespace string, spec *nfsv1alpha1.NFSServerSpec, exportIDs map[string]int) string {
+	usedIDs := make(map[int]bool)
+	for _, id := range exportIDs {
+		usedIDs[id] = true
+	}
+	nextID := firstExportID
 
 	exportsList := make([]string, 0)
-	id := 10
-	for claimName, claimConfig := range serverConfig {
-		exportsList = append(exportsList, createGaneshaExport(id, claimName, claimConfig["accessMode"], claimConfig["squash"]))
-		id++
+	for _, export := range spec.Exports {
+		if export.CephFS == nil && export.PersistentVolumeClaim.ClaimName == "" {
+			continue
+		}
+		_, pseudo, _ := ganeshaExportPaths(namespace, export)
+		id, ok := exportIDs[pseudo]
+		if !ok {
+			for usedIDs[nextID] {
+				nextID++
+			}
+			id = nextID
+			usedIDs[id] = true
+		}
+		exportsList = append(exportsList, createGaneshaExport(id, namespace, export))
 	}
 
 	// fsid_device parameter is important as in case of an overlayfs there is a chance that the fsid of the mounted share is same as that of the fsid of "/"
 	// so setting this to true uses device number as fsid
 	// related issue https://github.com/nfs-ganesha/nfs-ganesha/issues/140
-	exportsList = append(exportsList, `NFS_Core_Param
+	// the NFSv3 side protocols are bound to fixed ports so that they can be exposed by the service
+	coreParams := "\n\tfsid_device = true;"
+	if usesProtocol(spec, 3) {
+		coreParams += fmt.Sprintf("\n\tMNT_Port = %d;\n\tNLM_Port = %d;", mountdPort, nlmPort)
+	}
+	exportsList = append(exportsList, "NFS_Core_Param\n{"+coreParams+"\n}")
+
+	if spec.Kerberos.KeytabSecret != "" {
+		principalName := spec.Kerberos.PrincipalName
+		if principalName == "" {
+			principalName = "nfs"
+		}
+		exportsList = append(exportsList, `NFS_KRB5
 {
-	fsid_device = true;
+	PrincipalName = `+principalName+`;
+	KeytabPath = `+path.Join(krb5KeytabPath, krb5KeytabKey)+`;
+	Active_krb5 = true;
 }`)
+	}
 	nfsGaneshaConfig := s.Join(exportsList, "\n")
 
 	return nfsGaneshaConfig
 }
 
 func (c *Controller) createNFSConfigMap(nfsServer *nfsServer) error {
-	nfsGaneshaConfig := createGaneshaConfig(&nfsServer.spec)
+	nfsGaneshaConfig := createGaneshaConfig(nfsServer.namespace, &nfsServer.spec, nil)
 
 	configMap := &v1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -294,6 +505,36 @@ func createPVCSpecList(spec *nfsv1alpha1.NFSServerSpec) []v1.Volume {
 	}
 	pvcSpecList = append(pvcSpecList, configMapVol)
 
+	if hasCephFSExports(spec) {
+		pvcSpecList = append(pvcSpecList, v1.Volume{
+			Name: cephConfigVolume,
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{SecretName: cephConfigSecretName},
+			},
+		})
+	}
+	if spec.Kerberos.KeytabSecret != "" {
+		pvcSpecList = append(pvcSpecList, v1.Volume{
+			Name: krb5KeytabVolume,
+			VolumeSource: v1.VolumeSource{
+				Secret: &v1.SecretVolumeSource{
+					SecretName: spec.Kerberos.KeytabSecret,
+					Items:      []v1.KeyToPath{{Key: krb5KeytabKey, Path: krb5KeytabKey}},
+				},
+			},
+		})
+	}
+	if spec.Kerberos.ConfigMap != "" {
+		krb5ConfSrc := &v1.ConfigMapVolumeSource{
+			Items: []v1.KeyToPath{{Key: krb5ConfKey, Path: krb5ConfKey}},
+		}
+		krb5ConfSrc.Name = spec.Kerberos.ConfigMap
+		pvcSpecList = append(pvcSpecList, v1.Volume{
+			Name:         krb5ConfVolume,
+			VolumeSource: v1.VolumeSource{ConfigMap: krb5ConfSrc},
+		})
+	}
+
 	return pvcSpecList
 }
 
@@ -313,6 +554,28 @@ func createVolumeMountList(spec *nfsv1alpha1.NFSServerSpec) []v1.VolumeMount {
 	}
 	volumeMountList = append(volumeMountList, configMapVolMount)
 
+	if hasCephFSExports(spec) {
+		volumeMountList = append(volumeMountList, v1.VolumeMount{
+			Name:      cephConfigVolume,
+			MountPath: cephConfigPath,
+			ReadOnly:  true,
+		})
+	}
+	if spec.Kerberos.KeytabSecret != "" {
+		volumeMountList = append(volumeMountList, v1.VolumeMount{
+			Name:      krb5KeytabVolume,
+			MountPath: krb5KeytabPath,
+			ReadOnly:  true,
+		})
+	}
+	if spec.Kerberos.ConfigMap != "" {
+		volumeMountList = append(volumeMountList, v1.VolumeMount{
+			Name:      krb5ConfVolume,
+			MountPath: path.Join("/etc", krb5ConfKey),
+			SubPath:   krb5ConfKey,
+		})
+	}
+
 	return volumeMountList
 }
 
@@ -349,8 +612,17 @@ func (c *Controller) createNfsPodSpec(nfsServer *nfsServer) v1.PodTemplateSpec {
 						},
 					},
 				},
+				{
+					// the provisioner creates the directories of the volumes in the exports mounted in the pod
+					Name:         "provisioner",
+					Image:        c.containerImage,
+					Args:         []string{"nfs", "provisioner", "--nfs-server=" + nfsServer.serverName},
+					Env:          []v1.EnvVar{k8sutil.NamespaceEnvVar()},
+					VolumeMounts: createVolumeMountList(&nfsServer.spec),
+				},
 			},
-			Volumes: createPVCSpecList(&nfsServer.spec),
+			Volumes:            createPVCSpecList(&nfsServer.spec),
+			ServiceAccountName: serverServiceAccount,
 		},
 	}
 
@@ -376,6 +648,10 @@ func (c *Controller) createNfsStatefulSet(nfsServer *nfsServer, replicas int32)
 			},
 			Template:    nfsPodSpec,
 			ServiceName: nfsServer.name,
+			// the pods are restarted when the volumes of the exports change
+			UpdateStrategy: v1beta1.StatefulSetUpdateStrategy{
+				Type: v1beta1.RollingUpdateStatefulSetStrategyType,
+			},
 		},
 	}
 
@@ -383,7 +659,9 @@ func (c *Controller) createNfsStatefulSet(nfsServer *nfsServer, replicas int32)
 		if !errors.IsAlreadyExists(err) {
 			return err
 		}
+		// the stateful set of a server created by an earlier version of the operator may lack containers
 		logger.Infof("stateful set %s already exists in namespace %s", statefulSet.Name, statefulSet.Namespace)
+		return c.updateNfsStatefulSet(nfsServer, replicas)
 	} else {
 		logger.Infof("stateful set %s created in namespace %s", statefulSet.Name, statefulSet.Namespace)
 	}
@@ -398,29 +676,250 @@ func (c *Controller) onAdd(obj interface{}) {
 
 	logger.Infof("new NFS server %s added to namespace %s", nfsObj.Name, nfsServer.namespace)
 
+	if err := validateNFSServer(&nfsServer.spec); err != nil {
+		logger.Errorf("invalid NFS server %s. %+v", nfsObj.Name, err)
+		metrics.ReconcileFailed(nfsControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, nfsObj, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "invalid NFS server %s. %+v", nfsObj.Name, err)
+		c.updateStatus(nfsObj, err)
+		return
+	}
+
 	logger.Infof("creating nfs server service in namespace %s", nfsServer.namespace)
 	if err := c.createNFSService(nfsServer); err != nil {
 		logger.Errorf("Unable to create NFS service %+v", err)
+		metrics.ReconcileFailed(nfsControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, nfsObj, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "Unable to create NFS service %+v", err)
+	}
+
+	if err := c.createCephConfig(nfsServer); err != nil {
+		logger.Errorf("Unable to create the ceph config of the CephFS exports %+v", err)
+		metrics.ReconcileFailed(nfsControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, nfsObj, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "Unable to create the ceph config of the CephFS exports %+v", err)
 	}
 
 	logger.Infof("creating nfs server configuration in namespace %s", nfsServer.namespace)
 	if err := c.createNFSConfigMap(nfsServer); err != nil {
 		logger.Errorf("Unable to create NFS ConfigMap %+v", err)
+		metrics.ReconcileFailed(nfsControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, nfsObj, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "Unable to create NFS ConfigMap %+v", err)
+	}
+
+	if err := c.createProvisionerRBAC(nfsServer); err != nil {
+		logger.Errorf("Unable to create the service account of the NFS provisioner %+v", err)
+		metrics.ReconcileFailed(nfsControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, nfsObj, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "Unable to create the service account of the NFS provisioner %+v", err)
 	}
 
 	logger.Infof("creating nfs server stateful set in namespace %s", nfsServer.namespace)
 	if err := c.createNfsStatefulSet(nfsServer, int32(nfsServer.spec.Replicas)); err != nil {
 		logger.Errorf("Unable to create NFS stateful set %+v", err)
+		metrics.ReconcileFailed(nfsControllerName, "add")
+		k8sutil.RecordEvent(c.context.Recorder, nfsObj, v1.EventTypeWarning, k8sutil.EventReasonCreateFailed, "Unable to create NFS stateful set %+v", err)
 	}
+
+	c.updateStatus(nfsObj, nil)
 }
 
 func (c *Controller) onUpdate(oldObj, newObj interface{}) {
 	oldNfsServ := oldObj.(*nfsv1alpha1.NFSServer).DeepCopy()
+	newNfsServ := newObj.(*nfsv1alpha1.NFSServer).DeepCopy()
 
-	logger.Infof("Received update on NFS server %s in namespace %s. This is currently unsupported.", oldNfsServ.Name, oldNfsServ.Namespace)
+	// the status is written with a full update of the nfs server, so the updates that only change the status are ignored
+	if reflect.DeepEqual(oldNfsServ.Spec, newNfsServ.Spec) {
+		logger.Debugf("nfs server %s in namespace %s did not change", newNfsServ.Name, newNfsServ.Namespace)
+		return
+	}
+
+	nfsServer := newNfsServer(newNfsServ, c.context)
+	logger.Infof("updating nfs server %s in namespace %s", newNfsServ.Name, nfsServer.namespace)
+
+	if err := validateNFSServer(&nfsServer.spec); err != nil {
+		logger.Errorf("invalid NFS server %s. %+v", newNfsServ.Name, err)
+		metrics.ReconcileFailed(nfsControllerName, "update")
+		c.updateStatus(newNfsServ, err)
+		return
+	}
+
+	if err := c.updateNFSService(nfsServer); err != nil {
+		logger.Errorf("Unable to update NFS service %+v", err)
+		metrics.ReconcileFailed(nfsControllerName, "update")
+	}
+
+	if err := c.createCephConfig(nfsServer); err != nil {
+		logger.Errorf("Unable to update the ceph config of the CephFS exports %+v", err)
+		metrics.ReconcileFailed(nfsControllerName, "update")
+	}
+	c.deleteCephUsers(nfsServer.namespace, &oldNfsServ.Spec, &newNfsServ.Spec)
+
+	// the running ganesha servers reload the exports when the config changes
+	if err := c.updateNFSConfigMap(nfsServer); err != nil {
+		logger.Errorf("Unable to update NFS ConfigMap %+v", err)
+		metrics.ReconcileFailed(nfsControllerName, "update")
+	}
+
+	if err := c.updateNfsStatefulSet(nfsServer, int32(nfsServer.spec.Replicas)); err != nil {
+		logger.Errorf("Unable to update NFS stateful set %+v", err)
+		metrics.ReconcileFailed(nfsControllerName, "update")
+	}
+
+	c.updateStatus(newNfsServ, nil)
+}
+
+func (c *Controller) updateNFSService(nfsServer *nfsServer) error {
+	service, err := c.context.Clientset.CoreV1().Services(nfsServer.namespace).Get(nfsServer.name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	ports := createServicePorts(&nfsServer.spec)
+	if reflect.DeepEqual(service.Spec.Ports, ports) {
+		return nil
+	}
+	service.Spec.Ports = ports
+	if _, err := c.context.Clientset.CoreV1().Services(nfsServer.namespace).Update(service); err != nil {
+		return err
+	}
+	logger.Infof("nfs service %s updated in namespace %s", service.Name, service.Namespace)
+	return nil
+}
+
+func (c *Controller) updateNFSConfigMap(nfsServer *nfsServer) error {
+	configMap, err := c.context.Clientset.CoreV1().ConfigMaps(nfsServer.namespace).Get(nfsConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	currentConfig := configMap.Data[nfsConfigMapName]
+	nfsGaneshaConfig := createGaneshaConfig(nfsServer.namespace, &nfsServer.spec, getExportIDs(currentConfig))
+	if nfsGaneshaConfig == currentConfig {
+		logger.Infof("nfs server configuration in namespace %s did not change", nfsServer.namespace)
+		return nil
+	}
+
+	configMap.Data[nfsConfigMapName] = nfsGaneshaConfig
+	if _, err := c.context.Clientset.CoreV1().ConfigMaps(nfsServer.namespace).Update(configMap); err != nil {
+		return err
+	}
+	logger.Infof("nfs server configuration updated in namespace %s", nfsServer.namespace)
+	return nil
+}
+
+func (c *Controller) updateNfsStatefulSet(nfsServer *nfsServer, replicas int32) error {
+	appsClient := c.context.Clientset.AppsV1beta1()
+	statefulSet, err := appsClient.StatefulSets(nfsServer.namespace).Get(nfsServer.name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	if statefulSet.Spec.Replicas == nil || *statefulSet.Spec.Replicas != replicas {
+		logger.Infof("scaling stateful set %s to %d replicas", statefulSet.Name, replicas)
+		statefulSet.Spec.Replicas = &replicas
+		changed = true
+	}
+
+	// the volumes and containers can only be changed by restarting the pods, which is only done when they change
+	podSpec := c.createNfsPodSpec(nfsServer)
+	if !reflect.DeepEqual(getVolumeSources(statefulSet.Spec.Template.Spec.Volumes), getVolumeSources(podSpec.Spec.Volumes)) ||
+		!reflect.DeepEqual(getContainerNames(statefulSet.Spec.Template.Spec.Containers), getContainerNames(podSpec.Spec.Containers)) ||
+		statefulSet.Spec.Template.Spec.ServiceAccountName != podSpec.Spec.ServiceAccountName {
+		logger.Infof("updating the pods of stateful set %s", statefulSet.Name)
+		statefulSet.Spec.Template = podSpec
+		statefulSet.Spec.UpdateStrategy = v1beta1.StatefulSetUpdateStrategy{Type: v1beta1.RollingUpdateStatefulSetStrategyType}
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	if _, err := appsClient.StatefulSets(nfsServer.namespace).Update(statefulSet); err != nil {
+		return err
+	}
+	logger.Infof("stateful set %s updated in namespace %s", statefulSet.Name, statefulSet.Namespace)
+	return nil
+}
+
+func getClaimNames(volumes []v1.Volume) []string {
+	claimNames := make([]string, 0)
+	for _, volume := range volumes {
+		if volume.PersistentVolumeClaim != nil {
+			claimNames = append(claimNames, volume.PersistentVolumeClaim.ClaimName)
+		}
+	}
+	return claimNames
+}
+
+// getContainerNames returns the names of the containers of a pod
+func getContainerNames(containers []v1.Container) []string {
+	names := make([]string, 0)
+	for _, container := range containers {
+		names = append(names, container.Name)
+	}
+	return names
+}
+
+// getVolumeSources returns the names of the claims, secrets and config maps mounted by the volumes
+func getVolumeSources(volumes []v1.Volume) []string {
+	sources := make([]string, 0)
+	for _, volume := range volumes {
+		switch {
+		case volume.PersistentVolumeClaim != nil:
+			sources = append(sources, "claim/"+volume.PersistentVolumeClaim.ClaimName)
+		case volume.Secret != nil:
+			sources = append(sources, "secret/"+volume.Secret.SecretName)
+		case volume.ConfigMap != nil:
+			sources = append(sources, "configmap/"+volume.ConfigMap.Name)
+		}
+	}
+	return sources
 }
 
 func (c *Controller) onDelete(obj interface{}) {
-	cluster := obj.(*nfsv1alpha1.NFSServer).DeepCopy()
-	logger.Infof("cluster %s deleted from namespace %s", cluster.Name, cluster.Namespace)
+	nfsObj := obj.(*nfsv1alpha1.NFSServer).DeepCopy()
+	logger.Infof("nfs server %s deleted from namespace %s", nfsObj.Name, nfsObj.Namespace)
+
+	nfsServer := newNfsServer(nfsObj, c.context)
+	if err := c.deleteNFSServer(nfsServer); err != nil {
+		logger.Errorf("failed to clean up nfs server %s. %+v", nfsObj.Name, err)
+		metrics.ReconcileFailed(nfsControllerName, "delete")
+	}
+}
+
+// deleteNFSServer removes the resources of the nfs server and the ceph users of its CephFS exports
+func (c *Controller) deleteNFSServer(nfsServer *nfsServer) error {
+	var gracePeriod int64
+	propagation := metav1.DeletePropagationForeground
+	options := &metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod, PropagationPolicy: &propagation}
+	deleteActions := []struct {
+		resource string
+		delete   func() error
+	}{
+		{"stateful set", func() error {
+			return c.context.Clientset.AppsV1beta1().StatefulSets(nfsServer.namespace).Delete(nfsServer.name, options)
+		}},
+		{"service", func() error {
+			return c.context.Clientset.CoreV1().Services(nfsServer.namespace).Delete(nfsServer.name, options)
+		}},
+		{"config map", func() error {
+			return c.context.Clientset.CoreV1().ConfigMaps(nfsServer.namespace).Delete(nfsConfigMapName, options)
+		}},
+		{"ceph config secret", func() error {
+			return c.context.Clientset.CoreV1().Secrets(nfsServer.namespace).Delete(cephConfigSecretName, options)
+		}},
+		{"provisioner role binding", func() error {
+			return c.context.Clientset.RbacV1beta1().ClusterRoleBindings().Delete(provisionerRoleBindingName(nfsServer.namespace), options)
+		}},
+		{"service account", func() error {
+			return c.context.Clientset.CoreV1().ServiceAccounts(nfsServer.namespace).Delete(serverServiceAccount, options)
+		}},
+	}
+	for _, action := range deleteActions {
+		if err := action.delete(); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete %s. %+v", action.resource, err)
+		}
+		logger.Infof("deleted %s of nfs server %s", action.resource, nfsServer.serverName)
+	}
+
+	c.deleteCephUsers(nfsServer.namespace, &nfsServer.spec, &nfsv1alpha1.NFSServerSpec{})
+	return nil
 }
@@ -17,14 +17,21 @@ package nfs
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 
+	cephv1beta1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1beta1"
 	nfsv1alpha1 "github.com/rook/rook/pkg/apis/nfs.rook.io/v1alpha1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
 )
@@ -55,7 +62,7 @@ func TestOnAdd(t *testing.T) {
 
 	// initialize the controller and its dependencies
 	clientset := testop.New(3)
-	context := &clusterd.Context{Clientset: clientset}
+	context := &clusterd.Context{Clientset: clientset, RookClientset: rookfake.NewSimpleClientset(nfsserver)}
 	controller := NewController(context, "rook/nfs:mockTag")
 
 	// in a background thread, simulate the pods running (fake statefulsets don't automatically do that)
@@ -99,13 +106,433 @@ NFS_Core_Param
 	assert.Nil(t, err)
 	assert.NotNil(t, ss)
 	assert.Equal(t, int32(1), *ss.Spec.Replicas)
-	assert.Equal(t, 1, len(ss.Spec.Template.Spec.Containers))
+	assert.Equal(t, 2, len(ss.Spec.Template.Spec.Containers))
+	assert.Equal(t, serverServiceAccount, ss.Spec.Template.Spec.ServiceAccountName)
 
 	container := ss.Spec.Template.Spec.Containers[0]
 	assert.Equal(t, 2, len(container.VolumeMounts))
 
 	expectedVolumeMounts := []v1.VolumeMount{{Name: "test-claim", MountPath: "/test-claim"}, {Name: "nfs-ganesha-config", MountPath: "/nfs-ganesha/config"}}
 	assert.Equal(t, expectedVolumeMounts, container.VolumeMounts)
+
+	// the provisioner runs next to ganesha with the same exports mounted
+	provisioner := ss.Spec.Template.Spec.Containers[1]
+	assert.Equal(t, []string{"nfs", "provisioner", "--nfs-server=nfs-server-X"}, provisioner.Args)
+	assert.Equal(t, expectedVolumeMounts, provisioner.VolumeMounts)
+	_, err = clientset.CoreV1().ServiceAccounts(namespace).Get(serverServiceAccount, metav1.GetOptions{})
+	assert.Nil(t, err)
+	binding, err := clientset.RbacV1beta1().ClusterRoleBindings().Get("rook-nfs-provisioner-rook-nfs-test", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, provisionerClusterRole, binding.RoleRef.Name)
+	assert.Equal(t, namespace, binding.Subjects[0].Namespace)
+}
+
+func TestStatusAndDelete(t *testing.T) {
+	namespace := "rook-nfs-test"
+	nfsserver := &nfsv1alpha1.NFSServer{
+		ObjectMeta: metav1.ObjectMeta{Name: "nfs-server-X", Namespace: namespace},
+		Spec: nfsv1alpha1.NFSServerSpec{
+			Replicas: 1,
+			Exports: []nfsv1alpha1.ExportsSpec{
+				{
+					Name:                  "export-test",
+					Server:                nfsv1alpha1.ServerSpec{AccessMode: "ReadWrite", Squash: "none"},
+					PersistentVolumeClaim: v1.PersistentVolumeClaimVolumeSource{ClaimName: "test-claim"},
+				},
+			},
+		},
+	}
+
+	clientset := testop.New(3)
+	context := &clusterd.Context{Clientset: clientset, RookClientset: rookfake.NewSimpleClientset(nfsserver)}
+	controller := NewController(context, "rook/nfs:mockTag")
+	controller.onAdd(nfsserver)
+
+	// the export is not ready until the service has an ip and a ganesha server is ready
+	server, err := context.RookClientset.NfsV1alpha1().NFSServers(namespace).Get(nfsserver.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, nfsv1alpha1.NFSServerStateCreated, server.Status.State)
+	assert.Equal(t, "nfs.rook.io/rook-nfs-test-nfs-server-X", server.Status.Provisioner)
+	assert.Equal(t, []nfsv1alpha1.ExportStatus{{Name: "export-test", Path: "/test-claim"}}, server.Status.Exports)
+
+	service, _ := clientset.CoreV1().Services(namespace).Get(appName, metav1.GetOptions{})
+	service.Spec.ClusterIP = "10.0.0.12"
+	clientset.CoreV1().Services(namespace).Update(service)
+	ss, _ := clientset.AppsV1beta1().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	ss.Status.ReadyReplicas = 1
+	clientset.AppsV1beta1().StatefulSets(namespace).Update(ss)
+	controller.updateStatus(server, nil)
+	server, err = context.RookClientset.NfsV1alpha1().NFSServers(namespace).Get(nfsserver.Name, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []nfsv1alpha1.ExportStatus{{Name: "export-test", Path: "/test-claim", Endpoint: "10.0.0.12:/test-claim", Ready: true}}, server.Status.Exports)
+
+	// the resources of the server are removed when it is deleted
+	controller.onDelete(server)
+	_, err = clientset.AppsV1beta1().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	_, err = clientset.CoreV1().Services(namespace).Get(appName, metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	_, err = clientset.CoreV1().ConfigMaps(namespace).Get(nfsConfigMapName, metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	_, err = clientset.CoreV1().ServiceAccounts(namespace).Get(serverServiceAccount, metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	_, err = clientset.RbacV1beta1().ClusterRoleBindings().Get(provisionerRoleBindingName(namespace), metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestOnUpdate(t *testing.T) {
+	namespace := "rook-nfs-test"
+	export := func(claimName, squash string) nfsv1alpha1.ExportsSpec {
+		return nfsv1alpha1.ExportsSpec{
+			Name:                  "export-" + claimName,
+			Server:                nfsv1alpha1.ServerSpec{AccessMode: "ReadWrite", Squash: squash},
+			PersistentVolumeClaim: v1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+		}
+	}
+	oldServer := &nfsv1alpha1.NFSServer{
+		ObjectMeta: metav1.ObjectMeta{Name: "nfs-server-X", Namespace: namespace},
+		Spec: nfsv1alpha1.NFSServerSpec{
+			Replicas: 1,
+			Exports:  []nfsv1alpha1.ExportsSpec{export("claim-a", "none"), export("claim-b", "none")},
+		},
+	}
+
+	clientset := testop.New(3)
+	context := &clusterd.Context{Clientset: clientset, RookClientset: rookfake.NewSimpleClientset(oldServer)}
+	controller := NewController(context, "rook/nfs:mockTag")
+	controller.onAdd(oldServer)
+	configMap, err := clientset.CoreV1().ConfigMaps(namespace).Get(nfsConfigMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"claim-a": 10, "claim-b": 11}, getExportIDs(configMap.Data[nfsConfigMapName]))
+
+	// changing the options of an export only updates the config
+	newServer := oldServer.DeepCopy()
+	newServer.Spec.Exports[1].Server.Squash = "all"
+	controller.onUpdate(oldServer, newServer)
+	configMap, err = clientset.CoreV1().ConfigMaps(namespace).Get(nfsConfigMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, configMap.Data[nfsConfigMapName], "Squash = all;")
+	ss, err := clientset.AppsV1beta1().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"claim-a", "claim-b"}, getClaimNames(ss.Spec.Template.Spec.Volumes))
+
+	// removing and adding exports keeps the ids of the remaining exports and updates the volumes
+	oldServer = newServer
+	newServer = oldServer.DeepCopy()
+	newServer.Spec.Replicas = 2
+	newServer.Spec.Exports = []nfsv1alpha1.ExportsSpec{export("claim-c", "none"), export("claim-b", "all")}
+	controller.onUpdate(oldServer, newServer)
+	configMap, err = clientset.CoreV1().ConfigMaps(namespace).Get(nfsConfigMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int{"claim-b": 11, "claim-c": 12}, getExportIDs(configMap.Data[nfsConfigMapName]))
+
+	ss, err = clientset.AppsV1beta1().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), *ss.Spec.Replicas)
+	assert.Equal(t, []string{"claim-c", "claim-b"}, getClaimNames(ss.Spec.Template.Spec.Volumes))
+	expectedVolumeMounts := []v1.VolumeMount{
+		{Name: "claim-c", MountPath: "/claim-c"},
+		{Name: "claim-b", MountPath: "/claim-b"},
+		{Name: "nfs-ganesha-config", MountPath: "/nfs-ganesha/config"}}
+	assert.Equal(t, expectedVolumeMounts, ss.Spec.Template.Spec.Containers[0].VolumeMounts)
+
+	// an update of the status only does not change the resources of the server
+	oldServer = newServer
+	newServer = oldServer.DeepCopy()
+	newServer.Status.State = nfsv1alpha1.NFSServerStateCreated
+	clientset.ClearActions()
+	controller.onUpdate(oldServer, newServer)
+	assert.Equal(t, 0, len(clientset.Actions()))
+
+	// the provisioner is added to the stateful set of a server created by an earlier version
+	ss.Spec.Template.Spec.Containers = ss.Spec.Template.Spec.Containers[:1]
+	_, err = clientset.AppsV1beta1().StatefulSets(namespace).Update(ss)
+	assert.Nil(t, err)
+	controller.onAdd(newServer)
+	ss, err = clientset.AppsV1beta1().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"rook-nfs", "provisioner"}, getContainerNames(ss.Spec.Template.Spec.Containers))
+}
+
+func TestCreateGaneshaExport(t *testing.T) {
+	server := nfsv1alpha1.ServerSpec{
+		AccessMode: "ReadOnly",
+		Squash:     "root",
+		AllowedClients: []nfsv1alpha1.AllowedClientsSpec{
+			{Name: "group1", Clients: []string{"172.17.0.5"}},
+			{Name: "group2", Clients: []string{"172.17.0.0/16", "serverX"}, AccessMode: "ReadWrite", Squash: "none"},
+		},
+		Protocols:       []int{3, 4},
+		Transports:      []string{"TCP", "UDP"},
+		SecurityFlavors: []string{"krb5", "krb5p"},
+	}
+	expected := `
+EXPORT {
+	Export_Id = 10;
+	Path = /test-claim;
+	Pseudo = /test-claim;
+	Protocols = 3, 4;
+	Transports = TCP, UDP;
+	Sectype = krb5, krb5p;
+	Access_Type = None;
+	Squash = root;
+	FSAL {
+		Name = VFS;
+	}
+	CLIENT {
+		Clients = 172.17.0.5;
+		Access_Type = RO;
+		Squash = root;
+	}
+	CLIENT {
+		Clients = 172.17.0.0/16, serverX;
+		Access_Type = RW;
+		Squash = none;
+	}
+}`
+	export := nfsv1alpha1.ExportsSpec{
+		Name:                  "export-test",
+		Server:                server,
+		PersistentVolumeClaim: v1.PersistentVolumeClaimVolumeSource{ClaimName: "test-claim"},
+	}
+	assert.Equal(t, expected, createGaneshaExport(10, "rook-nfs-test", export))
+}
+
+func TestCephFSExport(t *testing.T) {
+	namespace := "rook-nfs-test"
+	clusterNamespace := "rook-ceph"
+	nfsserver := &nfsv1alpha1.NFSServer{
+		ObjectMeta: metav1.ObjectMeta{Name: "nfs-server-X", Namespace: namespace},
+		Spec: nfsv1alpha1.NFSServerSpec{
+			Replicas: 2,
+			Exports: []nfsv1alpha1.ExportsSpec{
+				{
+					Name:   "share",
+					Server: nfsv1alpha1.ServerSpec{AccessMode: "ReadWrite", Squash: "none"},
+					CephFS: &nfsv1alpha1.CephFSExportSpec{ClusterNamespace: clusterNamespace, Filesystem: "myfs", Path: "/volumes/share"},
+				},
+			},
+		},
+	}
+
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+	authCommands := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			authCommands = append(authCommands, strings.Join(args[:3], " "))
+			if args[1] == "get-or-create-key" {
+				return `{"key":"mykey"}`, nil
+			}
+			return "", nil
+		},
+	}
+	clientset := testop.New(1)
+	context := &clusterd.Context{
+		Clientset: clientset,
+		RookClientset: rookfake.NewSimpleClientset(&cephv1beta1.Filesystem{
+			ObjectMeta: metav1.ObjectMeta{Name: "myfs", Namespace: clusterNamespace},
+		}),
+		Executor:  executor,
+		ConfigDir: configDir,
+	}
+	clientset.CoreV1().Secrets(clusterNamespace).Create(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mon", Namespace: clusterNamespace},
+		Data:       map[string][]byte{"cluster-name": []byte(clusterNamespace), "admin-secret": []byte("adminkey")},
+	})
+	clientset.CoreV1().ConfigMaps(clusterNamespace).Create(&v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mon-endpoints", Namespace: clusterNamespace},
+		Data:       map[string]string{"data": "a=1.2.3.4:6790,b=1.2.3.5:6790"},
+	})
+	controller := NewController(context, "rook/nfs:mockTag")
+	controller.onAdd(nfsserver)
+
+	// a ceph user restricted to the exported path is created for ganesha
+	assert.Equal(t, []string{"auth get-or-create-key client.nfs-ganesha.rook-nfs-test.share"}, authCommands)
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(cephConfigSecretName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "[client.nfs-ganesha.rook-nfs-test.share]\n\tkey = mykey\n", string(secret.Data["nfs-ganesha.rook-nfs-test.share.keyring"]))
+	assert.Equal(t, `[client.nfs-ganesha.rook-nfs-test.share]
+	mon host = 1.2.3.4:6790,1.2.3.5:6790
+	keyring = /etc/ceph/nfs-ganesha.rook-nfs-test.share.keyring
+	client mds namespace = myfs
+`, string(secret.Data["ceph.conf"]))
+
+	// the export is served by the ceph FSAL
+	configMap, err := clientset.CoreV1().ConfigMaps(namespace).Get(nfsConfigMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	config := configMap.Data[nfsConfigMapName]
+	assert.Contains(t, config, `
+	Path = /volumes/share;
+	Pseudo = /share;`)
+	assert.Contains(t, config, `
+	FSAL {
+		Name = CEPH;
+		User_Id = "nfs-ganesha.rook-nfs-test.share";
+	}`)
+	assert.Equal(t, map[string]int{"share": 10}, getExportIDs(config))
+
+	// the ceph config is mounted instead of a claim
+	ss, err := clientset.AppsV1beta1().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	expectedVolumeMounts := []v1.VolumeMount{
+		{Name: "nfs-ganesha-config", MountPath: "/nfs-ganesha/config"},
+		{Name: "rook-nfs-ceph-config", MountPath: "/etc/ceph", ReadOnly: true}}
+	assert.Equal(t, expectedVolumeMounts, ss.Spec.Template.Spec.Containers[0].VolumeMounts)
+
+	// the user of a removed export is deleted
+	authCommands = []string{}
+	updated := nfsserver.DeepCopy()
+	updated.Spec.Exports[0].Name = "share2"
+	controller.onUpdate(nfsserver, updated)
+	assert.Equal(t, []string{
+		"auth get-or-create-key client.nfs-ganesha.rook-nfs-test.share2",
+		"auth del client.nfs-ganesha.rook-nfs-test.share"}, authCommands)
+
+	// the mons in the ceph config are updated when the mons of the cluster are failed over
+	_, err = context.RookClientset.NfsV1alpha1().NFSServers(namespace).Create(updated)
+	assert.Nil(t, err)
+	oldEndpoints, err := clientset.CoreV1().ConfigMaps(clusterNamespace).Get("rook-ceph-mon-endpoints", metav1.GetOptions{})
+	assert.Nil(t, err)
+	newEndpoints := oldEndpoints.DeepCopy()
+	newEndpoints.Data["data"] = "a=1.2.3.4:6790,c=1.2.3.6:6790"
+	_, err = clientset.CoreV1().ConfigMaps(clusterNamespace).Update(newEndpoints)
+	assert.Nil(t, err)
+	controller.onMonEndpointsUpdate(namespace, oldEndpoints, newEndpoints)
+	secret, err = clientset.CoreV1().Secrets(namespace).Get(cephConfigSecretName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Contains(t, string(secret.Data["ceph.conf"]), "mon host = 1.2.3.4:6790,1.2.3.6:6790\n")
+}
+
+func TestNFSv3AndKerberos(t *testing.T) {
+	namespace := "rook-nfs-test"
+	nfsserver := &nfsv1alpha1.NFSServer{
+		ObjectMeta: metav1.ObjectMeta{Name: "nfs-server-X", Namespace: namespace},
+		Spec: nfsv1alpha1.NFSServerSpec{
+			Replicas: 1,
+			Exports: []nfsv1alpha1.ExportsSpec{
+				{
+					Name:                  "export-test",
+					Server:                nfsv1alpha1.ServerSpec{Protocols: []int{3}, Transports: []string{"UDP"}, SecurityFlavors: []string{"krb5"}},
+					PersistentVolumeClaim: v1.PersistentVolumeClaimVolumeSource{ClaimName: "test-claim"},
+				},
+			},
+			Kerberos: nfsv1alpha1.KerberosSpec{KeytabSecret: "nfs-keytab", ConfigMap: "krb5-config"},
+		},
+	}
+
+	clientset := testop.New(3)
+	context := &clusterd.Context{Clientset: clientset, RookClientset: rookfake.NewSimpleClientset(nfsserver)}
+	controller := NewController(context, "rook/nfs:mockTag")
+	controller.onAdd(nfsserver)
+
+	// the side protocols of NFSv3 are bound to fixed ports that are exposed by the service
+	configMap, err := clientset.CoreV1().ConfigMaps(namespace).Get(nfsConfigMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	config := configMap.Data[nfsConfigMapName]
+	assert.Contains(t, config, "MNT_Port = 20048;")
+	assert.Contains(t, config, "NLM_Port = 32803;")
+	assert.Contains(t, config, `NFS_KRB5
+{
+	PrincipalName = nfs;
+	KeytabPath = /etc/rook/nfs-krb5/krb5.keytab;
+	Active_krb5 = true;
+}`)
+
+	service, err := clientset.CoreV1().Services(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	portNames := []string{}
+	for _, port := range service.Spec.Ports {
+		portNames = append(portNames, port.Name)
+	}
+	assert.Equal(t, []string{"nfs", "rpc", "mountd", "nlm", "nfs-udp", "rpc-udp", "mountd-udp", "nlm-udp"}, portNames)
+	assert.Equal(t, v1.ProtocolUDP, service.Spec.Ports[4].Protocol)
+
+	// the keytab and the kerberos config are mounted in the pods
+	ss, err := clientset.AppsV1beta1().StatefulSets(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	expectedVolumeMounts := []v1.VolumeMount{
+		{Name: "test-claim", MountPath: "/test-claim"},
+		{Name: "nfs-ganesha-config", MountPath: "/nfs-ganesha/config"},
+		{Name: "nfs-krb5-keytab", MountPath: "/etc/rook/nfs-krb5", ReadOnly: true},
+		{Name: "nfs-krb5-config", MountPath: "/etc/krb5.conf", SubPath: "krb5.conf"}}
+	assert.Equal(t, expectedVolumeMounts, ss.Spec.Template.Spec.Containers[0].VolumeMounts)
+	assert.Equal(t, "nfs-keytab", ss.Spec.Template.Spec.Volumes[2].Secret.SecretName)
+
+	// removing NFSv3 removes its ports from the service
+	updated := nfsserver.DeepCopy()
+	updated.Spec.Exports[0].Server.Protocols = []int{4}
+	updated.Spec.Exports[0].Server.Transports = nil
+	controller.onUpdate(nfsserver, updated)
+	service, err = clientset.CoreV1().Services(namespace).Get(appName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(service.Spec.Ports))
+}
+
+func TestValidateNFSServer(t *testing.T) {
+	spec := &nfsv1alpha1.NFSServerSpec{
+		Replicas: 1,
+		Exports: []nfsv1alpha1.ExportsSpec{
+			{
+				Name:                  "share1",
+				Server:                nfsv1alpha1.ServerSpec{AccessMode: "ReadWrite", Squash: "rootId"},
+				PersistentVolumeClaim: v1.PersistentVolumeClaimVolumeSource{ClaimName: "claim1"},
+			},
+		},
+	}
+	assert.Nil(t, validateNFSServer(spec))
+
+	// an invalid server is not created
+	invalid := &nfsv1alpha1.NFSServer{ObjectMeta: metav1.ObjectMeta{Name: "nfs", Namespace: "ns"}, Spec: *spec.DeepCopy()}
+	invalid.Spec.Exports[0].Server.AccessMode = "rw"
+	context := &clusterd.Context{Clientset: testop.New(1), RookClientset: rookfake.NewSimpleClientset(invalid)}
+	controller := NewController(context, "rook/nfs:mockTag")
+	controller.onAdd(invalid)
+	_, err := context.Clientset.AppsV1beta1().StatefulSets("ns").Get(appName, metav1.GetOptions{})
+	assert.NotNil(t, err)
+	server, err := context.RookClientset.NfsV1alpha1().NFSServers("ns").Get("nfs", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, nfsv1alpha1.NFSServerStateError, server.Status.State)
+	assert.NotEqual(t, "", server.Status.Message)
+
+	spec.Exports[0].Server.Squash = "some"
+	assert.NotNil(t, validateNFSServer(spec))
+	spec.Exports[0].Server.Squash = "all"
+
+	spec.Exports[0].Server.Protocols = []int{2}
+	assert.NotNil(t, validateNFSServer(spec))
+	spec.Exports[0].Server.Protocols = []int{3, 4}
+	spec.Exports[0].Server.Transports = []string{"SCTP"}
+	assert.NotNil(t, validateNFSServer(spec))
+	spec.Exports[0].Server.Transports = []string{"udp"}
+	assert.Nil(t, validateNFSServer(spec))
+
+	// kerberos flavors require a keytab
+	spec.Exports[0].Server.SecurityFlavors = []string{"krb5i"}
+	assert.NotNil(t, validateNFSServer(spec))
+	spec.Kerberos.KeytabSecret = "keytab"
+	assert.Nil(t, validateNFSServer(spec))
+
+	spec.Exports[0].Server.AllowedClients = []nfsv1alpha1.AllowedClientsSpec{{Name: "group1"}}
+	assert.NotNil(t, validateNFSServer(spec))
+	spec.Exports[0].Server.AllowedClients[0].Clients = []string{"all"}
+	assert.Nil(t, validateNFSServer(spec))
+
+	// the exports and their claims must be unique
+	spec.Exports = append(spec.Exports, spec.Exports[0])
+	assert.NotNil(t, validateNFSServer(spec))
+	spec.Exports[1].Name = "share2"
+	assert.NotNil(t, validateNFSServer(spec))
+	spec.Exports[1].PersistentVolumeClaim.ClaimName = "claim2"
+	assert.Nil(t, validateNFSServer(spec))
+
+	// an export shares either a claim or a cephfs filesystem
+	spec.Exports[1].CephFS = &nfsv1alpha1.CephFSExportSpec{ClusterNamespace: "rook-ceph", Filesystem: "myfs"}
+	assert.NotNil(t, validateNFSServer(spec))
+	spec.Exports[1].PersistentVolumeClaim.ClaimName = ""
+	assert.Nil(t, validateNFSServer(spec))
+	spec.Exports[1].CephFS.Filesystem = ""
+	assert.NotNil(t, validateNFSServer(spec))
 }
 
 func simulatePodsRunning(clientset *fake.Clientset, namespace string, podCount int) {
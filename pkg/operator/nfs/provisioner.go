@@ -0,0 +1,161 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	s "strings"
+
+	nfsv1alpha1 "github.com/rook/rook/pkg/apis/nfs.rook.io/v1alpha1"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/ceph/provisioner/controller"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	exportNameParameter = "exportName"
+
+	// the annotation of the volumes with the provisioner that created them
+	provisionedByAnnotation = "pv.kubernetes.io/provisioned-by"
+)
+
+// Provisioner provisions the volumes of the claims in subdirectories of the exports of an NFS server. It runs in the
+// NFS server pods where the claims of the exports are mounted.
+type Provisioner struct {
+	context    *clusterd.Context
+	namespace  string
+	serverName string
+
+	// the directory where the claims of the exports are mounted
+	rootDir string
+}
+
+// NewProvisioner creates a provisioner for the exports of an NFS server
+func NewProvisioner(context *clusterd.Context, namespace, serverName string) *Provisioner {
+	return &Provisioner{
+		context:    context,
+		namespace:  namespace,
+		serverName: serverName,
+		rootDir:    "/",
+	}
+}
+
+// ProvisionerName returns the name of the provisioner of an NFS server that is used in the storage classes
+func ProvisionerName(namespace, serverName string) string {
+	return fmt.Sprintf("%s/%s-%s", nfsv1alpha1.CustomResourceGroup, namespace, serverName)
+}
+
+// Provision creates a directory for the claim in the export and returns a PV that mounts it with NFS
+func (p *Provisioner) Provision(options controller.VolumeOptions) (*v1.PersistentVolume, error) {
+	if options.PVC.Spec.Selector != nil {
+		return nil, fmt.Errorf("claim Selector is not supported")
+	}
+
+	exportName := ""
+	for k, v := range options.Parameters {
+		if s.ToLower(k) != s.ToLower(exportNameParameter) {
+			return nil, fmt.Errorf("invalid option %q for the nfs provisioner", k)
+		}
+		exportName = v
+	}
+	if exportName == "" {
+		return nil, fmt.Errorf("StorageClass for provisioner %s must contain '%s' parameter", ProvisionerName(p.namespace, p.serverName), exportNameParameter)
+	}
+
+	server, err := p.context.RookClientset.NfsV1alpha1().NFSServers(p.namespace).Get(p.serverName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nfs server %s. %+v", p.serverName, err)
+	}
+	var export *nfsv1alpha1.ExportsSpec
+	for i := range server.Spec.Exports {
+		if server.Spec.Exports[i].Name == exportName {
+			export = &server.Spec.Exports[i]
+		}
+	}
+	if export == nil {
+		return nil, fmt.Errorf("export %s not found in nfs server %s", exportName, p.serverName)
+	}
+	if export.CephFS != nil {
+		return nil, fmt.Errorf("volumes cannot be provisioned from the cephfs export %s", exportName)
+	}
+
+	service, err := p.context.Clientset.CoreV1().Services(p.namespace).Get(appName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nfs service. %+v", err)
+	}
+
+	// the claim of the export is mounted and exported under its name
+	claimName := export.PersistentVolumeClaim.ClaimName
+	dirName := fmt.Sprintf("%s-%s-%s", options.PVC.Namespace, options.PVC.Name, options.PVName)
+	localPath := path.Join(p.rootDir, claimName, dirName)
+	if err := os.MkdirAll(localPath, 0777); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s. %+v", localPath, err)
+	}
+	// the permissions are set explicitly since they are restricted by the umask
+	if err := os.Chmod(localPath, 0777); err != nil {
+		return nil, fmt.Errorf("failed to set permissions of directory %s. %+v", localPath, err)
+	}
+
+	pv := &v1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: options.PVName,
+			Annotations: map[string]string{
+				provisionedByAnnotation: ProvisionerName(p.namespace, p.serverName),
+			},
+		},
+		Spec: v1.PersistentVolumeSpec{
+			PersistentVolumeReclaimPolicy: options.PersistentVolumeReclaimPolicy,
+			AccessModes:                   options.PVC.Spec.AccessModes,
+			Capacity: v1.ResourceList{
+				v1.ResourceStorage: options.PVC.Spec.Resources.Requests[v1.ResourceStorage],
+			},
+			PersistentVolumeSource: v1.PersistentVolumeSource{
+				NFS: &v1.NFSVolumeSource{
+					Server: service.Spec.ClusterIP,
+					Path:   path.Join("/", claimName, dirName),
+				},
+			},
+		},
+	}
+	logger.Infof("provisioned volume %s in directory %s of export %s", pv.Name, dirName, exportName)
+	return pv, nil
+}
+
+// Delete removes the directory of a volume from the export
+func (p *Provisioner) Delete(volume *v1.PersistentVolume) error {
+	if volume.Annotations[provisionedByAnnotation] != ProvisionerName(p.namespace, p.serverName) {
+		return &controller.IgnoredError{Reason: "the volume was not provisioned by this nfs server"}
+	}
+	if volume.Spec.NFS == nil {
+		return fmt.Errorf("volume %s is not an nfs volume", volume.Name)
+	}
+
+	// only the directories created in the exports can be removed, never an export itself
+	volumePath := path.Clean(volume.Spec.NFS.Path)
+	if s.Count(volumePath, "/") != 2 {
+		return fmt.Errorf("invalid path %s of volume %s", volume.Spec.NFS.Path, volume.Name)
+	}
+	localPath := path.Join(p.rootDir, volumePath)
+	if err := os.RemoveAll(localPath); err != nil {
+		return fmt.Errorf("failed to remove directory %s of volume %s. %+v", localPath, volume.Name, err)
+	}
+	logger.Infof("deleted volume %s in directory %s", volume.Name, volumePath)
+	return nil
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfs
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	nfsv1alpha1 "github.com/rook/rook/pkg/apis/nfs.rook.io/v1alpha1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/ceph/provisioner/controller"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestProvisioner(t *testing.T) {
+	namespace := "rook-nfs"
+	server := &nfsv1alpha1.NFSServer{
+		ObjectMeta: metav1.ObjectMeta{Name: "nfs1", Namespace: namespace},
+		Spec: nfsv1alpha1.NFSServerSpec{
+			Exports: []nfsv1alpha1.ExportsSpec{
+				{Name: "share1", PersistentVolumeClaim: v1.PersistentVolumeClaimVolumeSource{ClaimName: "claim1"}},
+				{Name: "cephfs", CephFS: &nfsv1alpha1.CephFSExportSpec{ClusterNamespace: "rook-ceph", Filesystem: "myfs"}},
+			},
+		},
+	}
+	clientset := testop.New(1)
+	clientset.CoreV1().Services(namespace).Create(&v1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: appName, Namespace: namespace},
+		Spec:       v1.ServiceSpec{ClusterIP: "10.0.0.12"},
+	})
+	context := &clusterd.Context{Clientset: clientset, RookClientset: rookfake.NewSimpleClientset(server)}
+
+	rootDir, err := ioutil.TempDir("", "nfs-provisioner")
+	assert.Nil(t, err)
+	defer os.RemoveAll(rootDir)
+	p := NewProvisioner(context, namespace, "nfs1")
+	p.rootDir = rootDir
+
+	options := controller.VolumeOptions{
+		PersistentVolumeReclaimPolicy: v1.PersistentVolumeReclaimDelete,
+		PVName:                        "pvc-1234",
+		Parameters:                    map[string]string{"exportName": "share1"},
+		PVC: &v1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "mypvc", Namespace: "default"},
+			Spec: v1.PersistentVolumeClaimSpec{
+				AccessModes: []v1.PersistentVolumeAccessMode{v1.ReadWriteMany},
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")},
+				},
+			},
+		},
+	}
+	pv, err := p.Provision(options)
+	assert.Nil(t, err)
+	assert.Equal(t, "pvc-1234", pv.Name)
+	assert.Equal(t, "nfs.rook.io/rook-nfs-nfs1", pv.Annotations[provisionedByAnnotation])
+	assert.Equal(t, "10.0.0.12", pv.Spec.NFS.Server)
+	assert.Equal(t, "/claim1/default-mypvc-pvc-1234", pv.Spec.NFS.Path)
+	assert.Equal(t, []v1.PersistentVolumeAccessMode{v1.ReadWriteMany}, pv.Spec.AccessModes)
+	_, err = os.Stat(path.Join(rootDir, "claim1", "default-mypvc-pvc-1234"))
+	assert.Nil(t, err)
+
+	// the export must exist and be backed by a claim
+	options.Parameters["exportName"] = "missing"
+	_, err = p.Provision(options)
+	assert.NotNil(t, err)
+	options.Parameters["exportName"] = "cephfs"
+	_, err = p.Provision(options)
+	assert.NotNil(t, err)
+	options.Parameters = map[string]string{}
+	_, err = p.Provision(options)
+	assert.NotNil(t, err)
+
+	// volumes of other provisioners are ignored
+	other := pv.DeepCopy()
+	other.Annotations[provisionedByAnnotation] = "nfs.rook.io/rook-nfs-nfs2"
+	err = p.Delete(other)
+	_, ignored := err.(*controller.IgnoredError)
+	assert.True(t, ignored)
+
+	// an export itself is never removed
+	export := pv.DeepCopy()
+	export.Spec.NFS.Path = "/claim1"
+	assert.NotNil(t, p.Delete(export))
+
+	assert.Nil(t, p.Delete(pv))
+	_, err = os.Stat(path.Join(rootDir, "claim1", "default-mypvc-pvc-1234"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(path.Join(rootDir, "claim1"))
+	assert.Nil(t, err)
+}
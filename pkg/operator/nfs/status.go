@@ -0,0 +1,121 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfs
+
+import (
+	"reflect"
+	"time"
+
+	nfsv1alpha1 "github.com/rook/rook/pkg/apis/nfs.rook.io/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the interval at which the status of the nfs servers is refreshed
+var statusInterval = 30 * time.Second
+
+// updateStatus sets the state of the nfs server and the endpoints and readiness of its exports in its status
+func (c *Controller) updateStatus(server *nfsv1alpha1.NFSServer, err error) {
+	status := nfsv1alpha1.NFSServerStatus{
+		State:       nfsv1alpha1.NFSServerStateCreated,
+		Provisioner: ProvisionerName(server.Namespace, server.Name),
+	}
+	if err != nil {
+		status.State = nfsv1alpha1.NFSServerStateError
+		status.Message = err.Error()
+	} else {
+		status.Exports = c.exportStatus(server)
+	}
+
+	if reflect.DeepEqual(server.Status, status) {
+		return
+	}
+
+	// the status is set on the latest version of the server so that a spec change made in the meantime is kept
+	servers := c.context.RookClientset.NfsV1alpha1().NFSServers(server.Namespace)
+	latest, err := servers.Get(server.Name, metav1.GetOptions{})
+	if err != nil {
+		logger.Warningf("failed to get nfs server %s to update its status. %+v", server.Name, err)
+		return
+	}
+	if !reflect.DeepEqual(latest.Spec, server.Spec) {
+		logger.Debugf("nfs server %s changed, its status is updated with the change", server.Name)
+		return
+	}
+	latest.Status = status
+	if _, err := servers.Update(latest); err != nil {
+		logger.Warningf("failed to update status of nfs server %s. %+v", server.Name, err)
+	}
+}
+
+// exportStatus returns the endpoints of the exports through the nfs service. The exports are ready when a ganesha
+// server is ready to serve them.
+func (c *Controller) exportStatus(server *nfsv1alpha1.NFSServer) []nfsv1alpha1.ExportStatus {
+	serviceIP := ""
+	service, err := c.context.Clientset.CoreV1().Services(server.Namespace).Get(appName, metav1.GetOptions{})
+	if err != nil {
+		logger.Warningf("failed to get nfs service. %+v", err)
+	} else {
+		serviceIP = service.Spec.ClusterIP
+	}
+
+	ready := false
+	statefulSet, err := c.context.Clientset.AppsV1beta1().StatefulSets(server.Namespace).Get(appName, metav1.GetOptions{})
+	if err != nil {
+		logger.Warningf("failed to get nfs stateful set. %+v", err)
+	} else {
+		ready = statefulSet.Status.ReadyReplicas > 0
+	}
+
+	exports := []nfsv1alpha1.ExportStatus{}
+	for _, export := range server.Spec.Exports {
+		_, pseudo, _ := ganeshaExportPaths(server.Namespace, export)
+		status := nfsv1alpha1.ExportStatus{
+			Name:  export.Name,
+			Path:  "/" + pseudo,
+			Ready: ready && serviceIP != "",
+		}
+		if serviceIP != "" {
+			status.Endpoint = serviceIP + ":" + status.Path
+		}
+		exports = append(exports, status)
+	}
+	return exports
+}
+
+// refreshStatus periodically updates the readiness of the exports of the nfs servers
+func (c *Controller) refreshStatus(namespace string, stopCh chan struct{}) {
+	for {
+		select {
+		case <-stopCh:
+			logger.Infof("stopping the nfs server status refresh")
+			return
+
+		case <-time.After(statusInterval):
+			servers, err := c.context.RookClientset.NfsV1alpha1().NFSServers(namespace).List(metav1.ListOptions{})
+			if err != nil {
+				logger.Warningf("failed to list nfs servers. %+v", err)
+				continue
+			}
+			for i := range servers.Items {
+				server := &servers.Items[i]
+				if server.Status.State == nfsv1alpha1.NFSServerStateCreated {
+					c.updateStatus(server, nil)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2018 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nfs
+
+import (
+	"fmt"
+	s "strings"
+
+	nfsv1alpha1 "github.com/rook/rook/pkg/apis/nfs.rook.io/v1alpha1"
+	"github.com/rook/rook/pkg/util"
+)
+
+var (
+	validAccessModes     = util.CreateSet([]string{"", "ReadOnly", "ReadWrite", "none"})
+	validSquashes        = util.CreateSet([]string{"", "none", "rootid", "root", "all"})
+	validTransports      = util.CreateSet([]string{"TCP", "UDP"})
+	validSecurityFlavors = util.CreateSet([]string{"sys", "krb5", "krb5i", "krb5p"})
+)
+
+// validateNFSServer validates the spec of an nfs server before its resources are created
+func validateNFSServer(spec *nfsv1alpha1.NFSServerSpec) error {
+	if spec.Replicas < 0 {
+		return fmt.Errorf("invalid replicas %d", spec.Replicas)
+	}
+
+	names := map[string]bool{}
+	pseudoPaths := map[string]bool{}
+	for _, export := range spec.Exports {
+		if export.Name == "" {
+			return fmt.Errorf("missing export name")
+		}
+		if names[export.Name] {
+			return fmt.Errorf("duplicate export %s", export.Name)
+		}
+		names[export.Name] = true
+
+		if err := validateExportSource(export); err != nil {
+			return fmt.Errorf("invalid export %s. %+v", export.Name, err)
+		}
+
+		// the exports are identified by their pseudo path in the ganesha config
+		_, pseudo, _ := ganeshaExportPaths("", export)
+		if pseudoPaths[pseudo] {
+			return fmt.Errorf("export %s has the same path /%s as another export", export.Name, pseudo)
+		}
+		pseudoPaths[pseudo] = true
+
+		if err := validateServer(spec, export.Server); err != nil {
+			return fmt.Errorf("invalid export %s. %+v", export.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateExportSource validates that the export shares either a claim or a CephFS filesystem
+func validateExportSource(export nfsv1alpha1.ExportsSpec) error {
+	claimName := export.PersistentVolumeClaim.ClaimName
+	if export.CephFS == nil {
+		if claimName == "" {
+			return fmt.Errorf("missing claim name or cephfs filesystem")
+		}
+		return nil
+	}
+	if claimName != "" {
+		return fmt.Errorf("both claim %s and a cephfs filesystem are specified", claimName)
+	}
+	if export.CephFS.ClusterNamespace == "" {
+		return fmt.Errorf("missing cephfs cluster namespace")
+	}
+	if export.CephFS.Filesystem == "" {
+		return fmt.Errorf("missing cephfs filesystem")
+	}
+	return nil
+}
+
+func validateServer(spec *nfsv1alpha1.NFSServerSpec, server nfsv1alpha1.ServerSpec) error {
+	if !validAccessModes.Contains(server.AccessMode) {
+		return fmt.Errorf("invalid access mode %s", server.AccessMode)
+	}
+	if !validSquashes.Contains(s.ToLower(server.Squash)) {
+		return fmt.Errorf("invalid squash %s", server.Squash)
+	}
+	for _, protocol := range server.Protocols {
+		if protocol != 3 && protocol != 4 {
+			return fmt.Errorf("invalid protocol %d", protocol)
+		}
+	}
+	for _, transport := range server.Transports {
+		if !validTransports.Contains(s.ToUpper(transport)) {
+			return fmt.Errorf("invalid transport %s", transport)
+		}
+	}
+	for _, flavor := range server.SecurityFlavors {
+		if !validSecurityFlavors.Contains(flavor) {
+			return fmt.Errorf("invalid security flavor %s", flavor)
+		}
+		if flavor != "sys" && spec.Kerberos.KeytabSecret == "" {
+			return fmt.Errorf("security flavor %s requires a kerberos keytab secret", flavor)
+		}
+	}
+	for _, allowed := range server.AllowedClients {
+		if len(allowed.Clients) == 0 {
+			return fmt.Errorf("missing clients of allowed clients %s", allowed.Name)
+		}
+		if !validAccessModes.Contains(allowed.AccessMode) {
+			return fmt.Errorf("invalid access mode %s of allowed clients %s", allowed.AccessMode, allowed.Name)
+		}
+		if !validSquashes.Contains(s.ToLower(allowed.Squash)) {
+			return fmt.Errorf("invalid squash %s of allowed clients %s", allowed.Squash, allowed.Name)
+		}
+	}
+	return nil
+}
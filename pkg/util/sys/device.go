@@ -33,6 +33,11 @@ const (
 	LVMType   = "lvm"
 	sgdisk    = "sgdisk"
 	mountCmd  = "mount"
+
+	// persistent links created by udev that do not change when the kernel names of devices change
+	diskByIDPath   = "/dev/disk/by-id/"
+	diskByPathPath = "/dev/disk/by-path/"
+	wwnLinkPrefix  = diskByIDPath + "wwn-"
 )
 
 type Partition struct {
@@ -80,6 +85,46 @@ type LocalDisk struct {
 	Empty bool `json:"empty"`
 }
 
+// GetDevLinks returns the persistent links of the device that udev created under /dev/disk
+func GetDevLinks(device *LocalDisk) []string {
+	return strings.Fields(device.DevLinks)
+}
+
+// GetPersistentDevicePath returns the most stable link to the device. The WWN link is preferred since it identifies the
+// disk wherever it is connected, followed by the other by-id links and finally the by-path link of the port the disk
+// is connected to. An empty string is returned if the device has no persistent link.
+func GetPersistentDevicePath(device *LocalDisk) string {
+	var byID, byPath string
+	for _, link := range GetDevLinks(device) {
+		switch {
+		case strings.HasPrefix(link, wwnLinkPrefix):
+			return link
+		case strings.HasPrefix(link, diskByIDPath) && byID == "":
+			byID = link
+		case strings.HasPrefix(link, diskByPathPath) && byPath == "":
+			byPath = link
+		}
+	}
+	if byID != "" {
+		return byID
+	}
+	return byPath
+}
+
+// MatchesDevice returns whether the name or path refers to the device. A device can be referred to by its kernel name
+// (sdb), its device path (/dev/sdb) or any of its persistent links such as /dev/disk/by-id/wwn-0x5000c500a0b1c2d3.
+func MatchesDevice(device *LocalDisk, nameOrPath string) bool {
+	if nameOrPath == device.Name || nameOrPath == "/dev/"+device.Name {
+		return true
+	}
+	for _, link := range GetDevLinks(device) {
+		if nameOrPath == link {
+			return true
+		}
+	}
+	return false
+}
+
 func ListDevices(executor exec.Executor) ([]string, error) {
 	cmd := "lsblk all"
 	devices, err := executor.ExecuteCommandWithOutput(false, cmd, "lsblk", "--all", "--noheadings", "--list", "--output", "KNAME")
@@ -201,3 +201,23 @@ func TestParseUdevInfo(t *testing.T) {
 	m := parseUdevInfo(udevOutput)
 	assert.Equal(t, m["ID_FS_TYPE"], "ext2")
 }
+
+func TestPersistentDevicePath(t *testing.T) {
+	device := &LocalDisk{Name: "sdk", DevLinks: parseUdevInfo(udevOutput)["DEVLINKS"]}
+	assert.Equal(t, "/dev/disk/by-id/wwn-0x6001405d27e5d898829468b90ce4ef8c", GetPersistentDevicePath(device))
+	assert.Equal(t, 4, len(GetDevLinks(device)))
+
+	device.DevLinks = "/dev/disk/by-path/pci-0000:00:1f.2-ata-1 /dev/disk/by-id/ata-ST1000DM003_Z1D5K6T2"
+	assert.Equal(t, "/dev/disk/by-id/ata-ST1000DM003_Z1D5K6T2", GetPersistentDevicePath(device))
+	device.DevLinks = "/dev/disk/by-path/pci-0000:00:1f.2-ata-1"
+	assert.Equal(t, "/dev/disk/by-path/pci-0000:00:1f.2-ata-1", GetPersistentDevicePath(device))
+	device.DevLinks = ""
+	assert.Equal(t, "", GetPersistentDevicePath(device))
+
+	device.DevLinks = parseUdevInfo(udevOutput)["DEVLINKS"]
+	assert.True(t, MatchesDevice(device, "sdk"))
+	assert.True(t, MatchesDevice(device, "/dev/sdk"))
+	assert.True(t, MatchesDevice(device, "/dev/disk/by-id/wwn-0x6001405d27e5d898829468b90ce4ef8c"))
+	assert.False(t, MatchesDevice(device, "sdb"))
+	assert.False(t, MatchesDevice(device, "/dev/disk/by-id/wwn-0x5000c500a0b1c2d3"))
+}
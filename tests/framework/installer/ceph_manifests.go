@@ -108,6 +108,20 @@ spec:
 ---
 apiVersion: apiextensions.k8s.io/v1beta1
 kind: CustomResourceDefinition
+metadata:
+  name: buckets.ceph.rook.io
+spec:
+  group: ceph.rook.io
+  names:
+    kind: Bucket
+    listKind: BucketList
+    plural: buckets
+    singular: bucket
+  scope: Namespaced
+  version: v1beta1
+---
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
 metadata:
   name: pools.ceph.rook.io
 spec:
@@ -132,6 +146,20 @@ spec:
     plural: volumes
     singular: volume
   scope: Namespaced
+  version: v1alpha2
+---
+apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: nodedevices.rook.io
+spec:
+  group: rook.io
+  names:
+    kind: NodeDevices
+    listKind: NodeDevicesList
+    plural: nodedevices
+    singular: nodedevices
+  scope: Cluster
   version: v1alpha2`
 }
 
@@ -60,10 +60,20 @@ rules:
   - configmaps
   - pods
   - services
+  - secrets
   verbs:
   - get
   - watch
   - create
+  - update
+  - delete
+- apiGroups:
+  - ""
+  resources:
+  - serviceaccounts
+  verbs:
+  - create
+  - delete
 - apiGroups:
   - apps
   resources:
@@ -71,6 +81,21 @@ rules:
   verbs:
   - get
   - create
+  - update
+  - delete
+- apiGroups:
+  - rbac.authorization.k8s.io
+  resources:
+  - clusterrolebindings
+  verbs:
+  - create
+  - delete
+- apiGroups:
+  - ceph.rook.io
+  resources:
+  - filesystems
+  verbs:
+  - get
 - apiGroups:
   - nfs.rook.io
   resources:
@@ -83,6 +108,114 @@ rules:
   - "*"
   verbs:
   - "*"
+# the operator binds the service account of the nfs servers to the provisioner role
+- apiGroups:
+  - ""
+  resources:
+  - persistentvolumes
+  verbs:
+  - get
+  - list
+  - watch
+  - create
+  - delete
+- apiGroups:
+  - ""
+  resources:
+  - persistentvolumeclaims
+  verbs:
+  - get
+  - list
+  - watch
+  - update
+- apiGroups:
+  - storage.k8s.io
+  resources:
+  - storageclasses
+  verbs:
+  - get
+  - list
+  - watch
+- apiGroups:
+  - ""
+  resources:
+  - events
+  verbs:
+  - get
+  - list
+  - watch
+  - create
+  - update
+  - patch
+- apiGroups:
+  - ""
+  resources:
+  - services
+  verbs:
+  - get
+- apiGroups:
+  - nfs.rook.io
+  resources:
+  - nfsservers
+  verbs:
+  - get
+---
+# the role of the provisioner that runs in the nfs server pods
+apiVersion: rbac.authorization.k8s.io/v1beta1
+kind: ClusterRole
+metadata:
+  name: rook-nfs-provisioner
+rules:
+- apiGroups:
+  - ""
+  resources:
+  - persistentvolumes
+  verbs:
+  - get
+  - list
+  - watch
+  - create
+  - delete
+- apiGroups:
+  - ""
+  resources:
+  - persistentvolumeclaims
+  verbs:
+  - get
+  - list
+  - watch
+  - update
+- apiGroups:
+  - storage.k8s.io
+  resources:
+  - storageclasses
+  verbs:
+  - get
+  - list
+  - watch
+- apiGroups:
+  - ""
+  resources:
+  - events
+  verbs:
+  - get
+  - list
+  - watch
+  - create
+  - update
+  - patch
+- apiGroups:
+  - ""
+  resources:
+  - services
+  verbs:
+  - get
+- apiGroups:
+  - nfs.rook.io
+  resources:
+  - nfsservers
+  verbs:
+  - get
 ---
 apiVersion: v1
 kind: ServiceAccount